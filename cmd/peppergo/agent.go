@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/agent"
+	"github.com/pimentel/peppergo/internal/capability"
+	"github.com/pimentel/peppergo/internal/memory"
+	"github.com/pimentel/peppergo/internal/tool"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// defaultMemoryPath is where agent run keeps session conversations
+const defaultMemoryPath = ".peppergo/memory.jsonl"
+
+// runAgent runs the agent subcommands
+func runAgent(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "run" {
+		return fmt.Errorf("usage: peppergo agent run [flags] <agent.yaml> <task>")
+	}
+	return runAgentRun(args[1:], stdin, stdout)
+}
+
+// runAgentRun executes an agent definition, such as those in assets/agents, on a task
+func runAgentRun(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs, common := newFlagSet("agent run", "<agent.yaml> <task|->")
+	provider := fs.String("provider", "", "provider the agent uses (defaults to the route for -model)")
+	model := fs.String("model", "", "model the agent uses (defaults to the provider default)")
+	temperature := fs.Float64("temperature", 0, "sampling temperature (overrides the agent settings)")
+	maxTokens := fs.Int("max-tokens", 0, "maximum tokens of the answer (overrides the agent settings)")
+	maxSteps := fs.Int("max-steps", 0, "maximum model turns when the agent calls tools (overrides the agent settings)")
+	session := fs.String("session", "", "continue the conversation stored under this session ID")
+	memoryPath := fs.String("memory", defaultMemoryPath, "log file that keeps -session conversations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("an agent file and a task are required")
+	}
+
+	// A task of "-" is read from stdin
+	task := strings.Join(fs.Args()[1:], " ")
+	if task == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read task: %w", err)
+		}
+		task = string(data)
+	}
+
+	cfg, logger, service, err := newClient(common)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	agentConfig, err := agent.LoadFromYAML(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	registry, err := newAgentRegistry(logger, agentConfig)
+	if err != nil {
+		return err
+	}
+	a, err := agent.FromYAML(fs.Arg(0), logger, registry)
+	if err != nil {
+		return err
+	}
+
+	if *session != "" {
+		store, err := memory.NewLogStore(logger, &memory.LogConfig{Path: *memoryPath})
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		a.UseMemory(store)
+	}
+
+	if *provider == "" {
+		*provider = cfg.ProviderFor(*model)
+	}
+	if _, err := service.GetProvider(*provider); err != nil {
+		return err
+	}
+	if err := a.UseProvider(service.Client(*provider)); err != nil {
+		return err
+	}
+
+	if err := a.Initialize(ctx); err != nil {
+		return fmt.Errorf("failed to initialize agent: %w", err)
+	}
+	defer a.Cleanup(context.Background())
+
+	// Flags override the agent's settings
+	var opts []types.ExecuteOption
+	if value, ok := floatSetting(agentConfig.Settings, "temperature"); ok {
+		opts = append(opts, types.WithTemperature(value))
+	}
+	if value, ok := agentConfig.Settings["max_tokens"].(int); ok {
+		opts = append(opts, types.WithMaxTokens(value))
+	}
+	if value, ok := agentConfig.Settings["max_steps"].(int); ok {
+		opts = append(opts, types.WithMaxSteps(value))
+	}
+	if *temperature > 0 {
+		opts = append(opts, types.WithTemperature(*temperature))
+	}
+	if *maxTokens > 0 {
+		opts = append(opts, types.WithMaxTokens(*maxTokens))
+	}
+	if *maxSteps > 0 {
+		opts = append(opts, types.WithMaxSteps(*maxSteps))
+	}
+	if *model != "" {
+		opts = append(opts, types.WithModel(*model))
+	}
+	if *session != "" {
+		opts = append(opts, types.WithSession(*session))
+	}
+
+	resp, err := a.Execute(ctx, task, opts...)
+	if err != nil {
+		return fmt.Errorf("agent %s failed: %w", a.Name(), err)
+	}
+	fmt.Fprintln(stdout, resp.Content)
+	return nil
+}
+
+// newAgentRegistry registers the built-in capabilities and tools the agent
+// uses, configured from the agent's metadata. Agent.Initialize initializes them.
+func newAgentRegistry(logger *zap.Logger, config *agent.Config) (*agent.Registry, error) {
+	chatConfig := &capability.Config{}
+	analysisConfig := &capability.CodeAnalysisConfig{}
+	readerConfig := &tool.Config{BasePath: "."}
+	for _, c := range []struct {
+		kind, name string
+		out        interface{}
+	}{
+		{"capabilities", "basic_chat", chatConfig},
+		{"capabilities", "code_analysis", analysisConfig},
+		{"tools", "file_reader", readerConfig},
+	} {
+		if err := config.ComponentConfig(c.kind, c.name, c.out); err != nil {
+			return nil, err
+		}
+	}
+
+	registry := agent.NewRegistry(logger)
+	for _, c := range []types.Capability{
+		capability.NewBasicChatCapability(logger, chatConfig),
+		capability.NewCodeAnalysisCapability(logger, analysisConfig),
+	} {
+		if !contains(config.Capabilities, c.Name()) {
+			continue
+		}
+		if err := registry.RegisterCapability(c); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range []types.Tool{
+		tool.NewFileReaderTool(logger, readerConfig),
+	} {
+		if !contains(config.Tools, t.Name()) {
+			continue
+		}
+		if err := registry.RegisterTool(t); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// floatSetting returns a numeric agent setting; YAML decodes whole numbers as ints
+func floatSetting(settings map[string]interface{}, key string) (float64, bool) {
+	switch value := settings[key].(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+const chatHelp = `Commands:
+  /provider [name]  show or switch the provider
+  /model [name]     show or switch the model (empty uses the provider default)
+  /system [prompt]  show or set the system prompt
+  /history          show the conversation
+  /estimate [text]  estimate the tokens and cost of sending text with the conversation
+  /reset            clear the conversation
+  /providers        list the configured providers
+  /help             show this help
+  /exit             leave the chat
+`
+
+// chatSession is an interactive conversation with a provider
+type chatSession struct {
+	service     *proxy.Service
+	provider    string
+	model       string
+	system      string
+	temperature float64
+	maxTokens   int
+	stream      bool
+	history     []types.Message
+	out         io.Writer
+}
+
+// runChat runs an interactive chat read from stdin
+func runChat(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs, common := newFlagSet("chat", "")
+	session := &chatSession{out: stdout}
+	fs.StringVar(&session.provider, "provider", "", "provider to chat with (defaults to the route for -model)")
+	fs.StringVar(&session.model, "model", "", "model to use (defaults to the provider default)")
+	fs.StringVar(&session.system, "system", "", "system prompt")
+	fs.Float64Var(&session.temperature, "temperature", 0, "sampling temperature (0 uses the provider default)")
+	fs.IntVar(&session.maxTokens, "max-tokens", 0, "maximum tokens per reply (0 uses the provider default)")
+	noStream := fs.Bool("no-stream", false, "wait for complete replies instead of streaming them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	session.stream = !*noStream
+
+	cfg, _, service, err := newClient(common)
+	if err != nil {
+		return err
+	}
+	session.service = service
+	if session.provider == "" {
+		session.provider = cfg.ProviderFor(session.model)
+	}
+	if _, err := service.GetProvider(session.provider); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Chatting with %s. Type /help for commands, /exit to leave.\n", session.describe())
+	return session.run(stdin)
+}
+
+// run reads messages and commands until /exit or the end of input
+func (s *chatSession) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(s.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "/"):
+			if done := s.command(line); done {
+				return nil
+			}
+		default:
+			if err := s.send(line); err != nil {
+				fmt.Fprintf(s.out, "error: %v\n", err)
+			}
+		}
+	}
+}
+
+// command runs a slash command, reporting whether the chat should end
+func (s *chatSession) command(line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "/exit", "/quit":
+		return true
+	case "/help":
+		fmt.Fprint(s.out, chatHelp)
+	case "/reset":
+		s.history = nil
+		fmt.Fprintln(s.out, "Conversation cleared.")
+	case "/history":
+		for _, msg := range s.history {
+			fmt.Fprintf(s.out, "%s: %s\n", msg.Role, msg.Text())
+		}
+	case "/providers":
+		for _, status := range s.service.ProviderStatuses() {
+			marker := " "
+			if status.Name == s.provider {
+				marker = "*"
+			}
+			fmt.Fprintf(s.out, "%s %s (%s)\n", marker, status.Name, strings.Join(status.Models, ", "))
+		}
+	case "/provider":
+		if arg != "" {
+			if _, err := s.service.GetProvider(arg); err != nil {
+				fmt.Fprintf(s.out, "error: %v\n", err)
+				return false
+			}
+			s.provider = arg
+		}
+		fmt.Fprintf(s.out, "Provider: %s\n", s.describe())
+	case "/model":
+		if arg != "" {
+			s.model = arg
+		}
+		fmt.Fprintf(s.out, "Provider: %s\n", s.describe())
+	case "/estimate":
+		s.estimate(arg)
+	case "/system":
+		if arg != "" {
+			s.system = arg
+		}
+		fmt.Fprintf(s.out, "System prompt: %s\n", s.system)
+	default:
+		fmt.Fprintf(s.out, "Unknown command %s. Type /help for commands.\n", name)
+	}
+	return false
+}
+
+// describe names the provider and model of the session
+func (s *chatSession) describe() string {
+	if s.model == "" {
+		return s.provider
+	}
+	return s.provider + " (" + s.model + ")"
+}
+
+// request returns the chat request sending content with the conversation so far
+func (s *chatSession) request(content string) *types.ChatRequest {
+	messages := make([]types.Message, 0, len(s.history)+2)
+	if s.system != "" {
+		messages = append(messages, types.Message{Role: "system", Content: s.system})
+	}
+	messages = append(messages, s.history...)
+	messages = append(messages, types.Message{Role: "user", Content: content})
+
+	return &types.ChatRequest{
+		Model:       s.model,
+		Messages:    messages,
+		MaxTokens:   s.maxTokens,
+		Temperature: float32(s.temperature),
+	}
+}
+
+// estimate prints the prompt tokens and cost of sending content
+func (s *chatSession) estimate(content string) {
+	estimate := s.service.EstimateChat(s.request(content))
+	counted := "estimated"
+	if estimate.Encoding != "" {
+		counted = estimate.Encoding
+	}
+	fmt.Fprintf(s.out, "Prompt: %d tokens (%s)\n", estimate.PromptTokens, counted)
+	if !estimate.Priced {
+		fmt.Fprintln(s.out, "Cost: unknown, no pricing configured for the model")
+		return
+	}
+	if estimate.MaxCompletionTokens > 0 {
+		fmt.Fprintf(s.out, "Cost: $%.6f, up to $%.6f with the longest reply\n", estimate.PromptCost, estimate.MaxCost)
+		return
+	}
+	fmt.Fprintf(s.out, "Cost: $%.6f before the reply\n", estimate.PromptCost)
+}
+
+// send sends a message with the conversation so far and prints the reply.
+// Interrupting a reply cancels it without leaving the chat. The exchange is
+// only added to the history once a complete, non-empty reply was received.
+func (s *chatSession) send(content string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	req := s.request(content)
+	var reply string
+	if s.stream {
+		stream, err := s.service.StreamChat(ctx, s.provider, req)
+		if err != nil {
+			return err
+		}
+		var b strings.Builder
+		var streamErr error
+		for resp := range stream {
+			if resp.Error != nil {
+				streamErr = resp.Error
+				continue
+			}
+			if len(resp.Choices) == 0 {
+				continue
+			}
+			delta := resp.Choices[0].Message.Content
+			b.WriteString(delta)
+			fmt.Fprint(s.out, delta)
+		}
+		fmt.Fprintln(s.out)
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("reply interrupted")
+		}
+		if streamErr != nil {
+			return streamErr
+		}
+		reply = b.String()
+	} else {
+		resp, err := s.service.Chat(ctx, s.provider, req)
+		if err != nil {
+			return err
+		}
+		if len(resp.Choices) > 0 {
+			reply = resp.Choices[0].Message.Text()
+		}
+		fmt.Fprintln(s.out, reply)
+	}
+	if reply == "" {
+		return fmt.Errorf("no reply received")
+	}
+
+	s.history = append(s.history,
+		types.Message{Role: "user", Content: content},
+		types.Message{Role: "assistant", Content: reply})
+	return nil
+}
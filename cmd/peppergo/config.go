@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pimentel/peppergo/internal/config"
+)
+
+// runConfig runs the config subcommands
+func runConfig(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return fmt.Errorf("usage: peppergo config validate [flags]")
+	}
+	return runConfigValidate(args[1:], stdout)
+}
+
+// runConfigValidate loads the configuration file and reports every problem in it
+func runConfigValidate(args []string, stdout io.Writer) error {
+	fs, common := newFlagSet("config validate", "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(common.config)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		names = append(names, p.ProviderName())
+	}
+	sort.Strings(names)
+	fmt.Fprintf(stdout, "%s is valid (providers: %s)\n", common.config, strings.Join(names, ", "))
+	return nil
+}
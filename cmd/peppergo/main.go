@@ -1,65 +1,115 @@
+// Command peppergo runs the peppergo proxy server and its command line tools
 package main
 
 import (
-	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"net/http"
+	"io"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
 
-	"github.com/pimentel/peppergo/internal/api"
-	"github.com/pimentel/peppergo/internal/provider"
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/config"
 	"github.com/pimentel/peppergo/internal/proxy"
 )
 
-func main() {
-	// Create proxy service
-	proxyService := proxy.NewService()
+const usage = `Usage: peppergo <command> [flags]
 
-	// Register providers
-	openRouterProvider := provider.NewOpenRouter()
-	if err := proxyService.RegisterProvider(openRouterProvider); err != nil {
-		log.Fatalf("Failed to register OpenRouter provider: %v", err)
-	}
+Commands:
+  serve                          run the proxy server (the default)
+  chat                           chat with a configured provider
+  models                         list the models of the configured providers
+  agent run <agent.yaml> <task>  run an agent on a task
+  config validate                check the configuration file
 
-	// Create API handler
-	handler := api.NewHandler(proxyService)
+Every command reads the configuration file given with -config, or else
+$PEPPERGO_CONFIG, or else peppergo.yaml. Run "peppergo <command> -h" for the
+flags of a command.
+`
 
-	// Create HTTP server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "peppergo: %v\n", err)
+		os.Exit(1)
 	}
+}
 
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%s", port),
-		Handler: handler.Router(),
+// run executes the command named by the first argument, defaulting to serve
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
 
-	// Start server in a goroutine
-	go func() {
-		log.Printf("Starting server on port %s", port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
+	switch command {
+	case "serve":
+		return runServe(args)
+	case "chat":
+		return runChat(args, stdin, stdout)
+	case "models":
+		return runModels(args, stdout)
+	case "agent":
+		return runAgent(args, stdin, stdout)
+	case "config":
+		return runConfig(args, stdout)
+	case "help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+}
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+// commandFlags holds the flags shared by all commands
+type commandFlags struct {
+	config  string
+	verbose bool
+}
 
-	// Graceful shutdown
-	log.Println("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// newFlagSet creates the flag set of a command with the shared flags registered
+func newFlagSet(name, args string) (*flag.FlagSet, *commandFlags) {
+	defaultConfig := os.Getenv("PEPPERGO_CONFIG")
+	if defaultConfig == "" {
+		defaultConfig = "peppergo.yaml"
+	}
+
+	common := &commandFlags{}
+	fs := flag.NewFlagSet("peppergo "+name, flag.ContinueOnError)
+	fs.StringVar(&common.config, "config", defaultConfig, "path to the server configuration file")
+	fs.BoolVar(&common.verbose, "v", false, "log at the configured level instead of warnings only")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s\n\nFlags:\n", strings.TrimSpace("peppergo "+name+" [flags] "+args))
+		fs.PrintDefaults()
+	}
+	return fs, common
+}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+// newClient loads the configuration and creates the logger and proxy service
+// of a command that sends requests itself instead of serving them
+func newClient(common *commandFlags) (*config.Config, *zap.Logger, *proxy.Service, error) {
+	cfg, err := config.Load(common.config)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	log.Println("Server exited properly")
-} 
\ No newline at end of file
+	// Keep informational logs out of the way of command output
+	if !common.verbose {
+		cfg.Logging.Level = "warn"
+		cfg.Logging.Format = "console"
+	}
+	logger, err := cfg.NewLogger()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	service, err := cfg.NewService(logger)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cfg, logger, service, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// fakeOpenAI is an OpenAI-compatible server that echoes the last message.
+// It streams nothing for "silence" and fails streams for "fail".
+type fakeOpenAI struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []types.ChatRequest
+}
+
+func newFakeOpenAI(t *testing.T) *fakeOpenAI {
+	t.Helper()
+	f := &fakeOpenAI{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+
+		reply := "echo: " + req.Messages[len(req.Messages)-1].Text()
+		if !req.Stream {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"model":   req.Model,
+				"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": reply}, "finish_reason": "stop"}},
+				"usage":   map[string]int{"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5},
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch req.Messages[len(req.Messages)-1].Text() {
+		case "silence":
+			reply = ""
+		case "fail":
+			fmt.Fprint(w, "data: {\"error\":{\"message\":\"overloaded\"}}\n\n")
+			return
+		}
+		for _, word := range strings.SplitAfter(reply, " ") {
+			data, _ := json.Marshal(map[string]interface{}{
+				"choices": []map[string]interface{}{{"delta": map[string]string{"content": word}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeOpenAI) lastRequest() types.ChatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[len(f.requests)-1]
+}
+
+// writeConfig writes a configuration using the fake provider
+func writeConfig(t *testing.T, baseURL string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "peppergo.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(fmt.Sprintf(`
+providers:
+  - type: openai
+    name: primary
+    api_key: sk-test
+    base_url: %s
+    models: [gpt-4o, gpt-4o-mini]
+  - type: ollama
+    disabled: true
+`, baseURL)), 0o600))
+	return filename
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	err := run([]string{"frobnicate"}, nil, &bytes.Buffer{})
+	assert.ErrorContains(t, err, `unknown command "frobnicate"`)
+}
+
+func TestConfigValidate(t *testing.T) {
+	filename := writeConfig(t, "http://localhost:1234/v1")
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"config", "validate", "-config", filename}, nil, &out))
+	assert.Equal(t, filename+" is valid (providers: ollama, primary)\n", out.String())
+
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("providers:\n  - type: anthropic\n"), 0o600))
+	err := run([]string{"config", "validate", "-config", invalid}, nil, &out)
+	assert.ErrorContains(t, err, "api_key is required")
+}
+
+func TestModels(t *testing.T) {
+	filename := writeConfig(t, "http://localhost:1234/v1")
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"models", "-config", filename}, nil, &out))
+	assert.Regexp(t, `primary\s+gpt-4o-mini\s+enabled`, out.String())
+	assert.Regexp(t, `ollama\s+llama3.1\s+disabled`, out.String())
+
+	out.Reset()
+	require.NoError(t, run([]string{"models", "-config", filename, "-provider", "primary", "-json"}, nil, &out))
+	var models []modelInfo
+	require.NoError(t, json.Unmarshal(out.Bytes(), &models))
+	assert.Equal(t, []modelInfo{
+		{Provider: "primary", Model: "gpt-4o", Enabled: true},
+		{Provider: "primary", Model: "gpt-4o-mini", Enabled: true},
+	}, models)
+}
+
+func TestChat(t *testing.T) {
+	server := newFakeOpenAI(t)
+	filename := writeConfig(t, server.URL)
+
+	input := strings.Join([]string{
+		"hello there",
+		"/model gpt-4o-mini",
+		"/system be brief",
+		"again",
+		"/history",
+		"/estimate one more",
+		"/provider missing",
+		"/reset",
+		"/exit",
+	}, "\n")
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"chat", "-config", filename}, strings.NewReader(input), &out))
+
+	output := out.String()
+	assert.Contains(t, output, "Chatting with primary.")
+	assert.Contains(t, output, "echo: hello there\n")
+	assert.Contains(t, output, "Provider: primary (gpt-4o-mini)")
+	assert.Contains(t, output, "user: again\nassistant: echo: again\n")
+	assert.Regexp(t, `Prompt: \d+ tokens \(estimated\)\nCost: unknown`, output)
+	assert.Contains(t, output, "error: provider not found: missing")
+	assert.Contains(t, output, "Conversation cleared.")
+
+	// The second message carries the system prompt and conversation
+	req := server.lastRequest()
+	assert.Equal(t, "gpt-4o-mini", req.Model)
+	require.Len(t, req.Messages, 4)
+	assert.Equal(t, "system", req.Messages[0].Role)
+	assert.Equal(t, "echo: hello there", req.Messages[2].Content)
+}
+
+func TestChatFailedReplies(t *testing.T) {
+	server := newFakeOpenAI(t)
+	filename := writeConfig(t, server.URL)
+
+	input := strings.Join([]string{"silence", "fail", "hello", "/history", "/exit"}, "\n")
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"chat", "-config", filename}, strings.NewReader(input), &out))
+
+	output := out.String()
+	assert.Contains(t, output, "error: no reply received")
+	assert.Contains(t, output, "error: provider primary stream chat failed: stream failed: overloaded")
+	assert.NotContains(t, output, "user: silence")
+	assert.NotContains(t, output, "user: fail")
+	assert.Contains(t, output, "user: hello\nassistant: echo: hello\n")
+
+	// Failed exchanges are not sent as conversation
+	require.Len(t, server.lastRequest().Messages, 1)
+}
+
+func TestAgentRun(t *testing.T) {
+	server := newFakeOpenAI(t)
+	filename := writeConfig(t, server.URL)
+
+	agentFile := filepath.Join(t.TempDir(), "agent.yaml")
+	require.NoError(t, os.WriteFile(agentFile, []byte(`
+name: helper
+version: "1.0.0"
+role:
+  name: Helper
+  instructions: You help.
+settings:
+  max_tokens: 256
+`), 0o600))
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"agent", "run", "-config", filename, "-temperature", "0.5", agentFile, "say", "hi"}, nil, &out))
+	assert.Equal(t, "echo: say hi\n", out.String())
+
+	req := server.lastRequest()
+	assert.Equal(t, []types.Message{
+		{Role: "system", Content: "You help."},
+		{Role: "user", Content: "say hi"},
+	}, req.Messages)
+	assert.Equal(t, 256, req.MaxTokens, "agent settings")
+	assert.Equal(t, float32(0.5), req.Temperature, "flags")
+
+	// A task of "-" is read from stdin
+	out.Reset()
+	require.NoError(t, run([]string{"agent", "run", "-config", filename, agentFile, "-"}, strings.NewReader("from stdin"), &out))
+	assert.Equal(t, "echo: from stdin\n", out.String())
+
+	err := run([]string{"agent", "run", "-config", filename, agentFile}, nil, &out)
+	assert.ErrorContains(t, err, "an agent file and a task are required")
+
+	// Whole numbers in settings are decoded as ints
+	require.NoError(t, os.WriteFile(agentFile, []byte("name: helper\nversion: \"1.0.0\"\nsettings:\n  temperature: 1\n"), 0o600))
+	require.NoError(t, run([]string{"agent", "run", "-config", filename, agentFile, "hi"}, nil, &out))
+	assert.Equal(t, float32(1), server.lastRequest().Temperature)
+}
+
+func TestAgentRunSession(t *testing.T) {
+	server := newFakeOpenAI(t)
+	filename := writeConfig(t, server.URL)
+	memoryFile := filepath.Join(t.TempDir(), "memory.jsonl")
+
+	agentFile := filepath.Join(t.TempDir(), "agent.yaml")
+	require.NoError(t, os.WriteFile(agentFile, []byte("name: helper\nversion: \"1.0.0\"\n"), 0o600))
+
+	// Each run is a new process, so the conversation comes from the memory file
+	args := []string{"agent", "run", "-config", filename, "-session", "s1", "-memory", memoryFile, agentFile}
+	var out bytes.Buffer
+	require.NoError(t, run(append(args, "first"), nil, &out))
+	require.NoError(t, run(append(args, "second"), nil, &out))
+
+	assert.Equal(t, []types.Message{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "echo: first"},
+		{Role: "user", Content: "second"},
+	}, server.lastRequest().Messages)
+	assert.FileExists(t, memoryFile)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+// modelInfo is a model in the catalog of a provider
+type modelInfo struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// runModels lists the model catalogs of the configured providers
+func runModels(args []string, stdout io.Writer) error {
+	fs, common := newFlagSet("models", "")
+	provider := fs.String("provider", "", "list the models of this provider only")
+	asJSON := fs.Bool("json", false, "print the models as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	_, _, service, err := newClient(common)
+	if err != nil {
+		return err
+	}
+
+	var statuses []proxy.ProviderStatus
+	if *provider != "" {
+		status, err := service.ProviderStatus(*provider)
+		if err != nil {
+			return err
+		}
+		statuses = []proxy.ProviderStatus{*status}
+	} else {
+		statuses = service.ProviderStatuses()
+	}
+
+	models := []modelInfo{}
+	for _, status := range statuses {
+		for _, model := range status.Models {
+			models = append(models, modelInfo{Provider: status.Name, Model: model, Enabled: status.Enabled})
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(models)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tMODEL\tSTATUS")
+	for _, m := range models {
+		status := "enabled"
+		if !m.Enabled {
+			status = "disabled"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", m.Provider, m.Model, status)
+	}
+	return w.Flush()
+}
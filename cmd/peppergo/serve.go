@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/api"
+	"github.com/pimentel/peppergo/internal/audit"
+	"github.com/pimentel/peppergo/internal/config"
+	"github.com/pimentel/peppergo/internal/server"
+	"github.com/pimentel/peppergo/internal/tracing"
+)
+
+// runServe runs the proxy server until it receives SIGINT or SIGTERM
+func runServe(args []string) error {
+	fs, common := newFlagSet("serve", "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := config.Load(common.config)
+	if err != nil {
+		return err
+	}
+
+	// Create logger
+	logger, err := cfg.NewLogger()
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer logger.Sync()
+
+	// Create proxy service with the configured providers
+	proxyService, err := cfg.NewService(logger)
+	if err != nil {
+		return fmt.Errorf("failed to create proxy service: %w", err)
+	}
+
+	// Background tasks stop on shutdown
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Probe provider health in the background
+	proxyService.StartHealthChecks(bgCtx, cfg.Server.HealthCheckInterval, cfg.Server.HealthCheckTimeout)
+
+	// Apply configuration changes without restarting
+	reloader := config.NewReloader(logger, common.config, cfg, proxyService)
+
+	opts := append([]api.Option{api.WithLogger(logger), api.WithReloader(reloader)}, cfg.HandlerOptions()...)
+
+	// Enable audit logging
+	if cfg.Logging.Audit != nil {
+		auditLog, err := audit.NewLogger(logger, cfg.Logging.Audit)
+		if err != nil {
+			return fmt.Errorf("failed to create audit log: %w", err)
+		}
+		defer auditLog.Close()
+		opts = append(opts, api.WithAuditLog(auditLog))
+	}
+
+	// Trace requests through the proxy to the providers
+	tracer, err := cfg.NewTracer(logger)
+	if err != nil {
+		return err
+	}
+	if tracer != nil {
+		tracing.SetTracer(tracer)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+			defer cancel()
+			if err := tracer.Shutdown(ctx); err != nil {
+				logger.Warn("Failed to export remaining spans", zap.Error(err))
+			}
+		}()
+		opts = append(opts, api.WithTracer(tracer))
+	}
+
+	// Record metrics, served with the API or on a listener of their own
+	registry := cfg.NewMetrics(proxyService)
+	opts = append(opts, cfg.MetricsOptions(registry)...)
+	var metricsSrv *http.Server
+	if registry != nil && cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", registry.Handler())
+		metricsSrv = &http.Server{Addr: cfg.Metrics.Addr, Handler: mux, ErrorLog: zap.NewStdLog(logger)}
+	}
+
+	// Process batch jobs; unfinished batches resume
+	batches, err := cfg.NewBatchManager(logger, proxyService)
+	if err != nil {
+		return err
+	}
+	if batches != nil {
+		defer batches.Close()
+		opts = append(opts, api.WithBatchManager(batches))
+	}
+
+	// Create API handler
+	handler := api.NewHandler(proxyService, opts...)
+	reloader.SetHandler(handler)
+
+	if cfg.Server.ReloadInterval > 0 {
+		go reloader.Watch(bgCtx, cfg.Server.ReloadInterval)
+	}
+
+	// Create HTTP server
+	srv := &http.Server{
+		Handler:      handler.Router(),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		ErrorLog:     zap.NewStdLog(logger),
+	}
+	if cfg.Server.TLS != nil {
+		srv.TLSConfig, err = server.NewTLSConfig(logger, cfg.Server.TLS)
+		if err != nil {
+			return fmt.Errorf("failed to configure TLS: %w", err)
+		}
+	}
+
+	// Listen on the unix domain socket or TCP address
+	listener, err := server.Listen(cfg.Server.Addr, cfg.Server.Socket, server.DefaultSocketMode)
+	if err != nil {
+		return err
+	}
+
+	// Start server in a goroutine
+	serveErr := make(chan error, 2)
+	if metricsSrv != nil {
+		go func() {
+			logger.Info("Serving metrics", zap.String("addr", metricsSrv.Addr))
+			if err := metricsSrv.ListenAndServe(); err != http.ErrServerClosed {
+				serveErr <- fmt.Errorf("metrics listener: %w", err)
+			}
+		}()
+	}
+	go func() {
+		logger.Info("Starting server",
+			zap.String("addr", listener.Addr().String()),
+			zap.Bool("tls", srv.TLSConfig != nil),
+			zap.String("config", common.config),
+			zap.Strings("providers", proxyService.ListProviders()))
+		if srv.TLSConfig != nil {
+			// Certificates are served by the TLS config
+			serveErr <- srv.ServeTLS(listener, "", "")
+		} else {
+			serveErr <- srv.Serve(listener)
+		}
+	}()
+
+	// Reload on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			logger.Info("Received SIGHUP, reloading configuration")
+			reloader.Reload()
+		}
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server failed: %w", err)
+	case <-quit:
+	}
+
+	// Graceful shutdown
+	logger.Info("Shutting down server")
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(ctx)
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+
+	logger.Info("Server exited properly")
+	return nil
+}
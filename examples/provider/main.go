@@ -56,7 +56,7 @@ func (p *ExampleProvider) Chat(ctx context.Context, req *types.ChatRequest) (*ty
 				Index: 0,
 				Message: types.Message{
 					Role:    "assistant",
-					Content: "This is an example response. Your message was: " + req.Messages[len(req.Messages)-1].Content,
+					Content: "This is an example response. Your message was: " + req.Messages[len(req.Messages)-1].Text(),
 				},
 				FinishReason: "stop",
 			},
@@ -3,11 +3,15 @@ package agent
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/internal/tracing"
 	"github.com/pimentel/peppergo/pkg/types"
 )
 
@@ -18,6 +22,9 @@ type BaseAgent struct {
 	version      string
 	description  string
 	provider     types.Provider
+	role         *RoleConfig
+	memory       types.Memory
+	window       *contextwindow.Manager
 	capabilities map[string]types.Capability
 	tools        map[string]types.Tool
 	logger       *zap.Logger
@@ -52,26 +59,318 @@ func (a *BaseAgent) Version() string {
 	return a.version
 }
 
-// Initialize sets up the agent
+// Description returns what the agent does
+func (a *BaseAgent) Description() string {
+	return a.description
+}
+
+// Initialize sets up the agent's capabilities and tools, checking that the
+// tools and capabilities each capability requires are present
 func (a *BaseAgent) Initialize(ctx context.Context) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for name, capability := range a.capabilities {
+		reqs := capability.Requirements()
+		if reqs == nil {
+			reqs = types.NewRequirements()
+		}
+		for _, tool := range reqs.Tools {
+			if _, ok := a.tools[tool]; !ok {
+				return fmt.Errorf("capability %s requires tool %s", name, tool)
+			}
+		}
+		for _, required := range reqs.Capabilities {
+			if _, ok := a.capabilities[required]; !ok {
+				return fmt.Errorf("capability %s requires capability %s", name, required)
+			}
+		}
+		if err := capability.Initialize(ctx); err != nil {
+			return fmt.Errorf("failed to initialize capability %s: %w", name, err)
+		}
+	}
+
+	for name, tool := range a.tools {
+		if err := tool.Initialize(ctx); err != nil {
+			return fmt.Errorf("failed to initialize tool %s: %w", name, err)
+		}
+	}
 	return nil
 }
 
-// Execute processes a task using the agent's capabilities
+// retryDelay is the wait before the first retry; later retries wait longer
+var retryDelay = time.Second
+
+// Execute processes a task with the agent's provider. When the agent has tools,
+// they are offered to the model, and the calls it makes are run and answered
+// until it gives a final answer or the step limit is reached; the turns are
+// recorded as []Step in the response metadata under "steps". The usage of
+// agents called as tools is included in the response's usage.
 func (a *BaseAgent) Execute(ctx context.Context, task string, opts ...types.ExecuteOption) (*types.Response, error) {
-	if a.provider == nil {
-		return nil, fmt.Errorf("no provider configured")
+	ctx, span := tracing.Start(ctx, "agent.execute")
+	defer span.End()
+	span.SetAttribute("agent", a.name)
+
+	a.mu.RLock()
+	provider := a.provider
+	role := a.role
+	memory := a.memory
+	a.mu.RUnlock()
+
+	if provider == nil {
+		err := fmt.Errorf("no provider configured")
+		span.RecordError(err)
+		return nil, err
+	}
+
+	options := &types.ExecuteOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	var history []types.Message
+	if options.Session != "" {
+		if memory == nil {
+			err := fmt.Errorf("no memory configured for session %s", options.Session)
+			span.RecordError(err)
+			return nil, err
+		}
+		var err error
+		if history, err = memory.Load(ctx, options.Session); err != nil {
+			err = fmt.Errorf("failed to load session %s: %w", options.Session, err)
+			span.RecordError(err)
+			return nil, err
+		}
+		span.SetAttribute("session", options.Session)
+	}
+	retries := 0
+	if r, ok := options.Metadata["retries"].(int); ok && r > 0 {
+		retries = r
+	}
+
+	req := &types.ChatRequest{
+		Model:            options.Model,
+		Messages:         buildMessages(role, history, task),
+		MaxTokens:        options.MaxTokens,
+		Temperature:      float32(options.Temperature),
+		TopP:             float32(options.TopP),
+		FrequencyPenalty: float32(options.FrequencyPenalty),
+		PresencePenalty:  float32(options.PresencePenalty),
+		Stop:             options.Stop,
+		Stream:           options.Stream,
+	}
+	span.SetAttribute("provider", provider.Name())
+	if req.Model != "" {
+		span.SetAttribute("model", req.Model)
+	}
+
+	tools := a.toolSnapshot()
+	if len(tools) == 0 {
+		resp, err := a.chat(ctx, provider, req, retries)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		span.SetAttribute("prompt_tokens", resp.Usage.PromptTokens)
+		span.SetAttribute("completion_tokens", resp.Usage.CompletionTokens)
+		response := newResponse(provider, resp, resp.Choices[0], resp.Usage)
+		a.remember(ctx, memory, options.Session, task, response)
+		return response, nil
+	}
+
+	// The model calls tools in turns, so the answer is not streamed
+	byName := make(map[string]types.Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Name()] = tool
+		req.Tools = append(req.Tools, types.ToolDefinitionFor(tool))
+	}
+	req.Stream = false
+	maxSteps := options.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	var steps []Step
+	var usage types.Usage
+	for {
+		resp, err := a.chat(ctx, provider, req, retries)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		addUsage(&usage, resp.Usage)
+		span.SetAttribute("prompt_tokens", usage.PromptTokens)
+		span.SetAttribute("completion_tokens", usage.CompletionTokens)
+
+		choice := resp.Choices[0]
+		step := Step{Content: choice.Message.Text(), Usage: resp.Usage}
+		calls := choice.Message.ToolCalls
+		if len(calls) == 0 || len(steps)+1 >= maxSteps {
+			if len(calls) > 0 {
+				a.logger.Warn("Task reached the step limit",
+					zap.String("agent", a.name),
+					zap.Int("max_steps", maxSteps))
+				choice.FinishReason = FinishReasonMaxSteps
+				for _, call := range calls {
+					step.ToolCalls = append(step.ToolCalls, ToolCallStep{ID: call.ID, Tool: call.Function.Name})
+				}
+			}
+			steps = append(steps, step)
+			span.SetAttribute("steps", len(steps))
+
+			response := newResponse(provider, resp, choice, usage)
+			response.Metadata["steps"] = steps
+			a.remember(ctx, memory, options.Session, task, response)
+			return response, nil
+		}
+
+		req.Messages = append(req.Messages, types.Message{
+			Role:      "assistant",
+			Content:   choice.Message.Text(),
+			ToolCalls: calls,
+		})
+		for _, call := range calls {
+			result := callTool(ctx, byName, call)
+			a.logger.Debug("Called tool",
+				zap.String("agent", a.name),
+				zap.String("tool", result.Tool),
+				zap.Duration("duration", result.Duration),
+				zap.String("error", result.Error))
+			step.ToolCalls = append(step.ToolCalls, result)
+			req.Messages = append(req.Messages, toolMessage(result))
+			addUsage(&usage, result.Usage)
+		}
+		steps = append(steps, step)
+
+		if err := ctx.Err(); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+}
+
+// newResponse builds the agent response for the final choice of a task
+func newResponse(provider types.Provider, resp *types.ChatResponse, choice types.Choice, usage types.Usage) *types.Response {
+	metadata := map[string]interface{}{
+		"provider": provider.Name(),
+	}
+	if resp.Model != "" {
+		metadata["model"] = resp.Model
+	}
+	if resp.ID != "" {
+		metadata["id"] = resp.ID
 	}
 
-	// TODO: Implement task execution logic
 	return &types.Response{
-		Content: "Task execution not implemented",
-	}, nil
+		Content:      choice.Message.Text(),
+		Metadata:     metadata,
+		Usage:        usage,
+		Timestamp:    time.Now().Unix(),
+		FinishReason: choice.FinishReason,
+	}
 }
 
-// Cleanup performs any necessary cleanup
+// remember adds a task and its answer to the session. The answer has already
+// been produced, so a failure to save it is logged rather than returned.
+func (a *BaseAgent) remember(ctx context.Context, memory types.Memory, session, task string, response *types.Response) {
+	if session == "" {
+		return
+	}
+	response.Metadata["session"] = session
+
+	err := memory.Append(ctx, session,
+		types.Message{Role: "user", Content: task},
+		types.Message{Role: "assistant", Content: response.Content})
+	if err != nil {
+		a.logger.Error("Failed to save session",
+			zap.String("agent", a.name),
+			zap.String("session", session),
+			zap.Error(err))
+	}
+}
+
+// buildMessages builds the chat messages for a task, instructing the model
+// with the agent's role and continuing the earlier turns of its session
+func buildMessages(role *RoleConfig, history []types.Message, task string) []types.Message {
+	messages := make([]types.Message, 0, len(history)+2)
+	if role != nil {
+		instructions := strings.TrimSpace(role.Instructions)
+		if instructions == "" {
+			instructions = strings.TrimSpace(role.Description)
+		}
+		if instructions != "" {
+			messages = append(messages, types.Message{Role: "system", Content: instructions})
+		}
+	}
+	messages = append(messages, history...)
+	return append(messages, types.Message{Role: "user", Content: task})
+}
+
+// complete sends a chat request, collecting a streamed reply into a single response
+func (a *BaseAgent) complete(ctx context.Context, provider types.Provider, req *types.ChatRequest) (*types.ChatResponse, error) {
+	if !req.Stream {
+		return provider.Chat(ctx, req)
+	}
+
+	stream, err := provider.StreamChat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.ChatResponse{}
+	var content strings.Builder
+	var finishReason string
+	for chunk := range stream {
+		if chunk.Error != nil {
+			err = chunk.Error
+			continue
+		}
+		if chunk.ID != "" {
+			resp.ID = chunk.ID
+		}
+		if chunk.Model != "" {
+			resp.Model = chunk.Model
+		}
+		if chunk.Usage.TotalTokens > 0 || chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0 {
+			resp.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) > 0 {
+			content.WriteString(chunk.Choices[0].Message.Content)
+			if chunk.Choices[0].FinishReason != "" {
+				finishReason = chunk.Choices[0].FinishReason
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resp.Choices = []types.Choice{{
+		Message:      types.Message{Role: "assistant", Content: content.String()},
+		FinishReason: finishReason,
+	}}
+	return resp, nil
+}
+
+// Cleanup cleans up the agent's capabilities and tools, returning the first error
 func (a *BaseAgent) Cleanup(ctx context.Context) error {
-	return nil
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var firstErr error
+	for name, capability := range a.capabilities {
+		if err := capability.Cleanup(ctx); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to clean up capability %s: %w", name, err)
+		}
+	}
+	for name, tool := range a.tools {
+		if err := tool.Cleanup(ctx); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to clean up tool %s: %w", name, err)
+		}
+	}
+	return firstErr
 }
 
 // AddCapability adds a new capability to the agent
@@ -110,6 +409,31 @@ func (a *BaseAgent) AddTool(tool types.Tool) error {
 	return nil
 }
 
+// SetRole sets the role whose instructions guide the agent's answers
+func (a *BaseAgent) SetRole(role *RoleConfig) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.role = role
+}
+
+// UseContextWindow keeps the messages of every provider call within the
+// context window of the model, as the manager's strategy decides
+func (a *BaseAgent) UseContextWindow(window *contextwindow.Manager) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.window = window
+}
+
+// UseMemory sets the memory that keeps the conversations of sessions
+func (a *BaseAgent) UseMemory(memory types.Memory) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.memory = memory
+}
+
 // UseProvider sets the provider for the agent
 func (a *BaseAgent) UseProvider(provider types.Provider) error {
 	if provider == nil {
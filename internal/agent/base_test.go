@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/internal/memory"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func newTestAgent(t *testing.T, provider types.Provider) *BaseAgent {
+	t.Helper()
+	agent := NewBaseAgent("test-agent", "1.0.0", "An agent under test", zaptest.NewLogger(t))
+	agent.SetRole(&RoleConfig{Name: "Tester", Instructions: "  You test things.\n"})
+	require.NoError(t, agent.UseProvider(provider))
+	return agent
+}
+
+func TestBaseAgentExecute(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no provider", func(t *testing.T) {
+		agent := NewBaseAgent("test-agent", "1.0.0", "", zaptest.NewLogger(t))
+		_, err := agent.Execute(ctx, "task")
+		assert.ErrorContains(t, err, "no provider configured")
+	})
+
+	t.Run("options", func(t *testing.T) {
+		provider := new(MockProvider)
+		provider.On("Name").Return("mock-provider")
+
+		var req *types.ChatRequest
+		resp := chatResponse("done")
+		resp.ID = "chatcmpl-1"
+		resp.Model = "gpt-4o-mini"
+		resp.Usage = types.Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}
+		provider.On("Chat", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			req = args.Get(1).(*types.ChatRequest)
+		}).Return(resp, nil)
+
+		agent := newTestAgent(t, provider)
+		before := time.Now().Unix()
+		response, err := agent.Execute(ctx, "Check the build",
+			types.WithModel("gpt-4o-mini"),
+			types.WithTemperature(0.25),
+			types.WithMaxTokens(512),
+			types.WithTopP(0.5),
+			types.WithFrequencyPenalty(0.75),
+			types.WithPresencePenalty(-0.5),
+			types.WithStop([]string{"END"}))
+		require.NoError(t, err)
+
+		assert.Equal(t, &types.ChatRequest{
+			Model: "gpt-4o-mini",
+			Messages: []types.Message{
+				{Role: "system", Content: "You test things."},
+				{Role: "user", Content: "Check the build"},
+			},
+			MaxTokens:        512,
+			Temperature:      0.25,
+			TopP:             0.5,
+			Stop:             []string{"END"},
+			FrequencyPenalty: 0.75,
+			PresencePenalty:  -0.5,
+		}, req)
+
+		assert.Equal(t, "done", response.Content)
+		assert.Equal(t, "stop", response.FinishReason)
+		assert.Equal(t, 15, response.Usage.TotalTokens)
+		assert.GreaterOrEqual(t, response.Timestamp, before)
+		assert.Equal(t, map[string]interface{}{
+			"provider": "mock-provider",
+			"model":    "gpt-4o-mini",
+			"id":       "chatcmpl-1",
+		}, response.Metadata)
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+		retryDelay = time.Millisecond
+
+		provider := new(MockProvider)
+		provider.On("Name").Return("mock-provider")
+		provider.On("Chat", mock.Anything, mock.Anything).Return(nil, errors.New("overloaded")).Twice()
+		provider.On("Chat", mock.Anything, mock.Anything).Return(chatResponse("finally"), nil).Once()
+
+		response, err := newTestAgent(t, provider).Execute(ctx, "task", types.WithRetries(2))
+		require.NoError(t, err)
+		assert.Equal(t, "finally", response.Content)
+		provider.AssertNumberOfCalls(t, "Chat", 3)
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+		retryDelay = time.Millisecond
+
+		provider := new(MockProvider)
+		provider.On("Name").Return("mock-provider")
+		provider.On("Chat", mock.Anything, mock.Anything).Return(nil, errors.New("overloaded"))
+
+		_, err := newTestAgent(t, provider).Execute(ctx, "task", types.WithRetries(1))
+		assert.ErrorContains(t, err, "provider mock-provider failed: overloaded")
+		provider.AssertNumberOfCalls(t, "Chat", 2)
+
+		// Without retries a failure is returned at once
+		provider.Calls = nil
+		_, err = newTestAgent(t, provider).Execute(ctx, "task")
+		assert.Error(t, err)
+		provider.AssertNumberOfCalls(t, "Chat", 1)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		stream := make(chan *types.ChatResponse, 3)
+		stream <- &types.ChatResponse{Model: "m", Choices: []types.Choice{{Message: types.Message{Content: "Hel"}}}}
+		stream <- &types.ChatResponse{Choices: []types.Choice{{Message: types.Message{Content: "lo"}, FinishReason: "length"}}}
+		stream <- &types.ChatResponse{Usage: types.Usage{PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6}}
+		close(stream)
+
+		provider := new(MockProvider)
+		provider.On("Name").Return("mock-provider")
+		provider.On("StreamChat", mock.Anything, mock.MatchedBy(func(req *types.ChatRequest) bool {
+			return req.Stream
+		})).Return((<-chan *types.ChatResponse)(stream), nil)
+
+		response, err := newTestAgent(t, provider).Execute(ctx, "task", types.WithStream(true))
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", response.Content)
+		assert.Equal(t, "length", response.FinishReason)
+		assert.Equal(t, 6, response.Usage.TotalTokens)
+		assert.Equal(t, "m", response.Metadata["model"])
+	})
+
+	t.Run("no choices", func(t *testing.T) {
+		provider := new(MockProvider)
+		provider.On("Name").Return("mock-provider")
+		provider.On("Chat", mock.Anything, mock.Anything).Return(&types.ChatResponse{}, nil)
+
+		_, err := newTestAgent(t, provider).Execute(ctx, "task")
+		assert.ErrorContains(t, err, "returned no choices")
+	})
+}
+
+func TestBaseAgentExecuteSession(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("continues the conversation", func(t *testing.T) {
+		provider := &scriptedProvider{responses: []*types.ChatResponse{
+			chatResponse("Nice to meet you, Ada"),
+			chatResponse("Your name is Ada"),
+		}}
+		agent := newTestAgent(t, provider)
+		store := memory.NewStore()
+		agent.UseMemory(store)
+
+		_, err := agent.Execute(ctx, "My name is Ada", types.WithSession("s1"))
+		require.NoError(t, err)
+		response, err := agent.Execute(ctx, "What is my name?", types.WithSession("s1"))
+		require.NoError(t, err)
+		assert.Equal(t, "s1", response.Metadata["session"])
+
+		assert.Equal(t, []types.Message{
+			{Role: "system", Content: "You test things."},
+			{Role: "user", Content: "My name is Ada"},
+			{Role: "assistant", Content: "Nice to meet you, Ada"},
+			{Role: "user", Content: "What is my name?"},
+		}, provider.requests[1].Messages)
+
+		messages, err := store.Load(ctx, "s1")
+		require.NoError(t, err)
+		assert.Len(t, messages, 4)
+		assert.Equal(t, "Your name is Ada", messages[3].Content)
+	})
+
+	t.Run("sessions are separate", func(t *testing.T) {
+		provider := &scriptedProvider{responses: []*types.ChatResponse{chatResponse("a"), chatResponse("b"), chatResponse("c")}}
+		agent := newTestAgent(t, provider)
+		agent.UseMemory(memory.NewStore())
+
+		_, err := agent.Execute(ctx, "first", types.WithSession("s1"))
+		require.NoError(t, err)
+		_, err = agent.Execute(ctx, "second", types.WithSession("s2"))
+		require.NoError(t, err)
+		_, err = agent.Execute(ctx, "stateless")
+		require.NoError(t, err)
+
+		assert.Len(t, provider.requests[1].Messages, 2)
+		assert.Len(t, provider.requests[2].Messages, 2)
+	})
+
+	t.Run("no memory", func(t *testing.T) {
+		agent := newTestAgent(t, &scriptedProvider{})
+		_, err := agent.Execute(ctx, "task", types.WithSession("s1"))
+		assert.ErrorContains(t, err, "no memory configured for session s1")
+	})
+
+	t.Run("invalid session", func(t *testing.T) {
+		agent := newTestAgent(t, &scriptedProvider{})
+		agent.UseMemory(memory.NewStore())
+		_, err := agent.Execute(ctx, "task", types.WithSession("../x"))
+		assert.ErrorContains(t, err, "failed to load session")
+	})
+}
+
+func TestBaseAgentExecuteContextWindow(t *testing.T) {
+	ctx := context.Background()
+	provider := &scriptedProvider{responses: []*types.ChatResponse{chatResponse("done")}}
+	agent := newTestAgent(t, provider)
+
+	store := memory.NewStore()
+	long := strings.Repeat("lorem ipsum ", 200)
+	require.NoError(t, store.Append(ctx, "s1",
+		types.Message{Role: "user", Content: long},
+		types.Message{Role: "assistant", Content: long},
+		types.Message{Role: "user", Content: "short"},
+		types.Message{Role: "assistant", Content: "reply"}))
+	agent.UseMemory(store)
+
+	window, err := contextwindow.NewManager(zaptest.NewLogger(t), &contextwindow.Config{ContextWindow: 300, Reserve: 100})
+	require.NoError(t, err)
+	agent.UseContextWindow(window)
+
+	_, err = agent.Execute(ctx, "next", types.WithSession("s1"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.Message{
+		{Role: "system", Content: "You test things."},
+		{Role: "user", Content: "short"},
+		{Role: "assistant", Content: "reply"},
+		{Role: "user", Content: "next"},
+	}, provider.requests[0].Messages)
+
+	// A task too large for the window fails before reaching the provider
+	_, err = agent.Execute(ctx, long)
+	assert.ErrorIs(t, err, contextwindow.ErrContextOverflow)
+	assert.Len(t, provider.requests, 1)
+}
+
+func TestBaseAgentInitialize(t *testing.T) {
+	ctx := context.Background()
+	agent := NewBaseAgent("test-agent", "1.0.0", "", zaptest.NewLogger(t))
+
+	capability := new(MockCapability)
+	capability.On("Name").Return("code_analysis")
+	capability.On("Requirements").Return(types.NewRequirements().AddTool("file_reader"))
+	capability.On("Initialize", ctx).Return(nil)
+	capability.On("Cleanup", ctx).Return(nil)
+	require.NoError(t, agent.AddCapability(capability))
+
+	assert.ErrorContains(t, agent.Initialize(ctx), "capability code_analysis requires tool file_reader")
+
+	tool := new(MockTool)
+	tool.On("Name").Return("file_reader")
+	tool.On("Initialize", ctx).Return(nil)
+	tool.On("Cleanup", ctx).Return(errors.New("busy"))
+	require.NoError(t, agent.AddTool(tool))
+
+	require.NoError(t, agent.Initialize(ctx))
+	assert.ErrorContains(t, agent.Cleanup(ctx), "failed to clean up tool file_reader: busy")
+
+	capability.AssertExpectations(t)
+	tool.AssertExpectations(t)
+}
@@ -8,6 +8,7 @@ import (
 	"gopkg.in/yaml.v3"
 	"go.uber.org/zap"
 
+	"github.com/pimentel/peppergo/internal/contextwindow"
 	"github.com/pimentel/peppergo/pkg/types"
 )
 
@@ -38,6 +39,40 @@ type Config struct {
 	Metadata map[string]interface{} `yaml:"metadata"`
 }
 
+// ContextWindowConfig returns the context window settings of the agent, or
+// nil if it sets no context_window. The context_strategy, context_keep_last
+// and context_reserve settings configure how messages are kept within it.
+func (c *Config) ContextWindowConfig() (*contextwindow.Config, error) {
+	if _, ok := c.Settings["context_window"]; !ok {
+		return nil, nil
+	}
+
+	var config contextwindow.Config
+	var ok bool
+	if config.ContextWindow, ok = c.Settings["context_window"].(int); !ok {
+		return nil, fmt.Errorf("settings.context_window must be a number of tokens")
+	}
+	if value, exists := c.Settings["context_strategy"]; exists {
+		if config.Strategy, ok = value.(string); !ok {
+			return nil, fmt.Errorf("settings.context_strategy must be a string")
+		}
+	}
+	if value, exists := c.Settings["context_keep_last"]; exists {
+		if config.KeepLast, ok = value.(int); !ok {
+			return nil, fmt.Errorf("settings.context_keep_last must be a number of messages")
+		}
+	}
+	if value, exists := c.Settings["context_reserve"]; exists {
+		if config.Reserve, ok = value.(int); !ok {
+			return nil, fmt.Errorf("settings.context_reserve must be a number of tokens")
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("settings: invalid context window: %w", err)
+	}
+	return &config, nil
+}
+
 // RoleConfig represents an agent's role configuration
 type RoleConfig struct {
 	// Name is the role's name
@@ -65,6 +100,34 @@ func LoadFromYAML(path string) (*Config, error) {
 	return &config, nil
 }
 
+// ComponentConfig decodes the configuration of a capability or tool from the
+// agent's metadata, found under metadata.<kind>.<name>.config where kind is
+// "capabilities" or "tools". out is left unchanged when there is none.
+func (c *Config) ComponentConfig(kind, name string, out interface{}) error {
+	components, ok := c.Metadata[kind].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	component, ok := components[name].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	config, ok := component["config"]
+	if !ok {
+		return nil
+	}
+
+	// Round-trip through YAML to decode into the component's config type
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s config: %w", name, err)
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode %s config: %w", name, err)
+	}
+	return nil
+}
+
 // FromYAML creates a new agent from a YAML configuration file
 func FromYAML(path string, logger *zap.Logger, registry *Registry) (*BaseAgent, error) {
 	config, err := LoadFromYAML(path)
@@ -73,6 +136,19 @@ func FromYAML(path string, logger *zap.Logger, registry *Registry) (*BaseAgent,
 	}
 
 	agent := NewBaseAgent(config.Name, config.Version, config.Description, logger)
+	agent.SetRole(config.Role)
+
+	windowConfig, err := config.ContextWindowConfig()
+	if err != nil {
+		return nil, err
+	}
+	if windowConfig != nil {
+		window, err := contextwindow.NewManager(logger, windowConfig)
+		if err != nil {
+			return nil, err
+		}
+		agent.UseContextWindow(window)
+	}
 
 	// Add capabilities
 	for _, name := range config.Capabilities {
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/internal/tool"
+)
+
+func TestComponentConfig(t *testing.T) {
+	config, err := LoadFromYAML(filepath.Join("..", "..", "assets", "agents", "code_reviewer.yaml"))
+	require.NoError(t, err)
+
+	var reader tool.Config
+	require.NoError(t, config.ComponentConfig("tools", "file_reader", &reader))
+	assert.Contains(t, reader.AllowedExtensions, ".go")
+	assert.Equal(t, int64(1048576), reader.MaxFileSize)
+
+	missing := tool.Config{BasePath: "unchanged"}
+	require.NoError(t, config.ComponentConfig("tools", "web_search", &missing))
+	assert.Equal(t, "unchanged", missing.BasePath)
+}
+
+func TestContextWindowConfig(t *testing.T) {
+	config, err := LoadFromYAML(filepath.Join("..", "..", "assets", "agents", "code_reviewer.yaml"))
+	require.NoError(t, err)
+
+	window, err := config.ContextWindowConfig()
+	require.NoError(t, err)
+	assert.Equal(t, &contextwindow.Config{ContextWindow: 8000, Strategy: contextwindow.StrategySummarize}, window)
+
+	window, err = (&Config{}).ContextWindowConfig()
+	require.NoError(t, err)
+	assert.Nil(t, window)
+
+	for name, settings := range map[string]map[string]interface{}{
+		"not a number":     {"context_window": "big"},
+		"unknown strategy": {"context_window": 8000, "context_strategy": "forget"},
+		"keep last":        {"context_window": 8000, "context_keep_last": "all"},
+		"reserve":          {"context_window": 8000, "context_reserve": 8000},
+	} {
+		_, err := (&Config{Settings: settings}).ContextWindowConfig()
+		assert.Error(t, err, name)
+	}
+}
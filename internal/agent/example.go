@@ -6,6 +6,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/pimentel/peppergo/internal/tracing"
 	"github.com/pimentel/peppergo/pkg/types"
 )
 
@@ -32,6 +33,10 @@ func NewExampleAgent(logger *zap.Logger) *ExampleAgent {
 
 // Execute overrides the base Execute method to add custom behavior
 func (a *ExampleAgent) Execute(ctx context.Context, task string, opts ...types.ExecuteOption) (*types.Response, error) {
+	ctx, span := tracing.Start(ctx, "agent.example.execute")
+	defer span.End()
+	span.SetAttribute("agent", a.Name())
+
 	// Log the incoming task
 	a.logger.Info("Executing task",
 		zap.String("task", task),
@@ -44,7 +49,7 @@ func (a *ExampleAgent) Execute(ctx context.Context, task string, opts ...types.E
 			zap.String("name", name),
 			zap.String("version", cap.Version()))
 
-		result, err := cap.Execute(ctx, task)
+		result, err := executeCapability(ctx, cap, task)
 		if err != nil {
 			return nil, fmt.Errorf("capability %s failed: %w", name, err)
 		}
@@ -55,26 +60,8 @@ func (a *ExampleAgent) Execute(ctx context.Context, task string, opts ...types.E
 			zap.Any("result", result))
 	}
 
-	// Use tools if needed
-	for name, tool := range a.tools {
-		a.logger.Debug("Using tool",
-			zap.String("name", name),
-			zap.String("version", tool.Version()))
-
-		result, err := tool.Execute(ctx, map[string]interface{}{
-			"task": task,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("tool %s failed: %w", name, err)
-		}
-
-		// Process tool result
-		a.logger.Debug("Tool result",
-			zap.String("name", name),
-			zap.Any("result", result))
-	}
-
-	// Call base implementation for provider interaction
+	// Call base implementation for provider interaction; the model decides
+	// which tools to call
 	return a.BaseAgent.Execute(ctx, task, opts...)
 }
 
@@ -6,7 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
 
 	"github.com/pimentel/peppergo/pkg/types"
 )
@@ -16,19 +16,16 @@ type MockProvider struct {
 	mock.Mock
 }
 
-func (m *MockProvider) Initialize(ctx context.Context) error {
-	args := m.Called(ctx)
-	return args.Error(0)
-}
-
-func (m *MockProvider) Generate(ctx context.Context, prompt string, opts ...types.GenerateOption) (*types.Response, error) {
-	args := m.Called(ctx, prompt, opts)
-	return args.Get(0).(*types.Response), args.Error(1)
+func (m *MockProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	args := m.Called(ctx, req)
+	resp, _ := args.Get(0).(*types.ChatResponse)
+	return resp, args.Error(1)
 }
 
-func (m *MockProvider) Stream(ctx context.Context, prompt string) (<-chan types.Response, error) {
-	args := m.Called(ctx, prompt)
-	return args.Get(0).(<-chan types.Response), args.Error(1)
+func (m *MockProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	args := m.Called(ctx, req)
+	stream, _ := args.Get(0).(<-chan *types.ChatResponse)
+	return stream, args.Error(1)
 }
 
 func (m *MockProvider) Name() string {
@@ -36,14 +33,19 @@ func (m *MockProvider) Name() string {
 	return args.String(0)
 }
 
-func (m *MockProvider) MaxTokens() int {
+func (m *MockProvider) AvailableModels() []string {
 	args := m.Called()
-	return args.Int(0)
+	return args.Get(0).([]string)
 }
 
-func (m *MockProvider) SupportsStreaming() bool {
-	args := m.Called()
-	return args.Bool(0)
+// chatResponse returns a provider response with the given content
+func chatResponse(content string) *types.ChatResponse {
+	return &types.ChatResponse{
+		Choices: []types.Choice{{
+			Message:      types.Message{Role: "assistant", Content: content},
+			FinishReason: "stop",
+		}},
+	}
 }
 
 // MockCapability is a mock implementation of types.Capability
@@ -140,15 +142,11 @@ func TestExampleAgent(t *testing.T) {
 	t.Run("execute with provider", func(t *testing.T) {
 		agent := NewExampleAgent(logger)
 		provider := new(MockProvider)
-		expectedResponse := &types.Response{
-			Content: "test response",
-		}
 
-		provider.On("Initialize", ctx).Return(nil)
-		provider.On("Generate", ctx, "test task", mock.Anything).Return(expectedResponse, nil)
+		provider.On("Chat", mock.Anything, mock.MatchedBy(func(req *types.ChatRequest) bool {
+			return req.Messages[len(req.Messages)-1].Content == "test task"
+		})).Return(chatResponse("test response"), nil)
 		provider.On("Name").Return("mock-provider")
-		provider.On("MaxTokens").Return(1000)
-		provider.On("SupportsStreaming").Return(true)
 
 		err := agent.UseProvider(provider)
 		assert.NoError(t, err)
@@ -158,7 +156,7 @@ func TestExampleAgent(t *testing.T) {
 
 		response, err := agent.Execute(ctx, "test task")
 		assert.NoError(t, err)
-		assert.Equal(t, expectedResponse, response)
+		assert.Equal(t, "test response", response.Content)
 
 		provider.AssertExpectations(t)
 	})
@@ -167,9 +165,6 @@ func TestExampleAgent(t *testing.T) {
 		agent := NewExampleAgent(logger)
 		capability := new(MockCapability)
 		provider := new(MockProvider)
-		expectedResponse := &types.Response{
-			Content: "test response",
-		}
 
 		capability.On("Name").Return("test-capability")
 		capability.On("Version").Return("1.0.0")
@@ -177,8 +172,8 @@ func TestExampleAgent(t *testing.T) {
 		capability.On("Execute", ctx, "test task").Return("capability result", nil)
 		capability.On("Requirements").Return(types.NewRequirements())
 
-		provider.On("Initialize", ctx).Return(nil)
-		provider.On("Generate", ctx, "test task", mock.Anything).Return(expectedResponse, nil)
+		provider.On("Chat", mock.Anything, mock.Anything).Return(chatResponse("test response"), nil)
+		provider.On("Name").Return("mock-provider")
 
 		err := agent.UseProvider(provider)
 		assert.NoError(t, err)
@@ -191,7 +186,7 @@ func TestExampleAgent(t *testing.T) {
 
 		response, err := agent.Execute(ctx, "test task")
 		assert.NoError(t, err)
-		assert.Equal(t, expectedResponse, response)
+		assert.Equal(t, "test response", response.Content)
 
 		capability.AssertExpectations(t)
 		provider.AssertExpectations(t)
@@ -201,17 +196,28 @@ func TestExampleAgent(t *testing.T) {
 		agent := NewExampleAgent(logger)
 		tool := new(MockTool)
 		provider := new(MockProvider)
-		expectedResponse := &types.Response{
-			Content: "test response",
-		}
 
 		tool.On("Name").Return("test-tool")
+		tool.On("Description").Return("A test tool")
+		tool.On("Schema").Return(types.NewToolSchema())
 		tool.On("Version").Return("1.0.0")
 		tool.On("Initialize", ctx).Return(nil)
-		tool.On("Execute", ctx, mock.Anything).Return("tool result", nil)
-
-		provider.On("Initialize", ctx).Return(nil)
-		provider.On("Generate", ctx, "test task", mock.Anything).Return(expectedResponse, nil)
+		tool.On("Execute", mock.Anything, map[string]interface{}{"input": "x"}).Return("tool result", nil)
+
+		call := chatResponse("")
+		call.Choices[0].Message.ToolCalls = []types.ToolCall{{
+			ID:       "call_1",
+			Type:     types.ToolTypeFunction,
+			Function: types.FunctionCall{Name: "test-tool", Arguments: `{"input":"x"}`},
+		}}
+		provider.On("Chat", mock.Anything, mock.MatchedBy(func(req *types.ChatRequest) bool {
+			return len(req.Messages) == 1
+		})).Return(call, nil).Once()
+		provider.On("Chat", mock.Anything, mock.MatchedBy(func(req *types.ChatRequest) bool {
+			last := req.Messages[len(req.Messages)-1]
+			return last.Role == "tool" && last.Content == "tool result"
+		})).Return(chatResponse("test response"), nil).Once()
+		provider.On("Name").Return("mock-provider")
 
 		err := agent.UseProvider(provider)
 		assert.NoError(t, err)
@@ -224,7 +230,7 @@ func TestExampleAgent(t *testing.T) {
 
 		response, err := agent.Execute(ctx, "test task")
 		assert.NoError(t, err)
-		assert.Equal(t, expectedResponse, response)
+		assert.Equal(t, "test response", response.Content)
 
 		tool.AssertExpectations(t)
 		provider.AssertExpectations(t)
@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// DefaultMaxSteps bounds the model turns of a task that calls tools
+const DefaultMaxSteps = 10
+
+// FinishReasonMaxSteps is the finish reason of a task stopped by the step limit
+const FinishReasonMaxSteps = "max_steps"
+
+// Step is a model turn of a task and the tool calls the model made in it
+type Step struct {
+	Content   string         `json:"content,omitempty"`
+	ToolCalls []ToolCallStep `json:"tool_calls,omitempty"`
+	Usage     types.Usage    `json:"usage"`
+}
+
+// ToolCallStep is a tool call requested by the model and its outcome
+type ToolCallStep struct {
+	ID        string                 `json:"id"`
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Output    string                 `json:"output,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Duration  time.Duration          `json:"duration"`
+
+	// Usage is the usage of an agent called as a tool
+	Usage types.Usage `json:"usage,omitempty"`
+}
+
+// toolSnapshot returns the agent's tools sorted by name
+func (a *BaseAgent) toolSnapshot() []types.Tool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	tools := make([]types.Tool, 0, len(a.tools))
+	for _, tool := range a.tools {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name() < tools[j].Name()
+	})
+	return tools
+}
+
+// chat sends a chat request, retrying failed attempts with a linear backoff.
+// The request's messages are first fitted into the context window, so later
+// turns of a task continue from the shortened conversation.
+func (a *BaseAgent) chat(ctx context.Context, provider types.Provider, req *types.ChatRequest, retries int) (*types.ChatResponse, error) {
+	a.mu.RLock()
+	window := a.window
+	a.mu.RUnlock()
+	if window != nil {
+		fitted, err := window.Fit(ctx, provider, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fit the context window: %w", err)
+		}
+		req.Messages = fitted.Messages
+	}
+
+	var resp *types.ChatResponse
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			a.logger.Warn("Retrying task",
+				zap.String("agent", a.name),
+				zap.Int("attempt", attempt+1),
+				zap.Int("max_attempts", retries+1),
+				zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * retryDelay):
+			}
+		}
+
+		resp, err = a.complete(ctx, provider, req)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("provider %s failed: %w", provider.Name(), err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("provider %s returned no choices", provider.Name())
+	}
+	return resp, nil
+}
+
+// callTool runs a tool call requested by the model. Unknown tools, invalid
+// arguments and tool failures are recorded as errors for the model to see
+// rather than failing the task.
+func callTool(ctx context.Context, tools map[string]types.Tool, call types.ToolCall) ToolCallStep {
+	step := ToolCallStep{ID: call.ID, Tool: call.Function.Name}
+	start := time.Now()
+	defer func() {
+		step.Duration = time.Since(start)
+	}()
+
+	tool, ok := tools[call.Function.Name]
+	if !ok {
+		step.Error = fmt.Sprintf("unknown tool %q", call.Function.Name)
+		return step
+	}
+	if call.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &step.Arguments); err != nil {
+			step.Error = fmt.Sprintf("invalid arguments: %v", err)
+			return step
+		}
+	}
+	if step.Arguments == nil {
+		step.Arguments = make(map[string]interface{})
+	}
+
+	result, err := executeTool(ctx, tool, step.Arguments)
+	if err != nil {
+		step.Error = err.Error()
+		return step
+	}
+	if resp, ok := result.(*types.Response); ok && resp != nil {
+		// An agent called as a tool answers with its response
+		step.Usage = resp.Usage
+		result = resp.Content
+	}
+	step.Output, err = toolOutput(result)
+	if err != nil {
+		step.Error = err.Error()
+	}
+	return step
+}
+
+// toolOutput formats a tool result for the model, encoding non-string results as JSON
+func toolOutput(result interface{}) (string, error) {
+	switch v := result.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tool result: %w", err)
+	}
+	return string(data), nil
+}
+
+// toolMessage is the message answering a tool call
+func toolMessage(call ToolCallStep) types.Message {
+	content := call.Output
+	if call.Error != "" {
+		content = "error: " + call.Error
+	}
+	return types.Message{Role: "tool", ToolCallID: call.ID, Name: call.Tool, Content: content}
+}
+
+// addUsage adds the token usage of a model turn to a total
+func addUsage(total *types.Usage, usage types.Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
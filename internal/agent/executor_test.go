@@ -0,0 +1,219 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/tool"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// scriptedProvider replies with its responses in order and records the requests it receives
+type scriptedProvider struct {
+	responses []*types.ChatResponse
+	requests  []types.ChatRequest
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	recorded := *req
+	recorded.Messages = append([]types.Message(nil), req.Messages...)
+	p.requests = append(p.requests, recorded)
+	if len(p.responses) == 0 {
+		return nil, errors.New("no more responses")
+	}
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+func (p *scriptedProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	return nil, errors.New("streaming not supported")
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) AvailableModels() []string { return nil }
+
+// funcTool is a tool backed by a function
+type funcTool struct {
+	name string
+	fn   func(args map[string]interface{}) (interface{}, error)
+}
+
+func (t *funcTool) Name() string                         { return t.name }
+func (t *funcTool) Description() string                  { return "The " + t.name + " tool" }
+func (t *funcTool) Version() string                      { return "1.0.0" }
+func (t *funcTool) Initialize(ctx context.Context) error { return nil }
+func (t *funcTool) Cleanup(ctx context.Context) error    { return nil }
+
+func (t *funcTool) Schema() *types.ToolSchema {
+	return types.NewToolSchema().AddProperty("a", types.NewPropertySchema("number"))
+}
+
+func (t *funcTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	return t.fn(args)
+}
+
+// toolCallResponse returns a provider response asking to call tools
+func toolCallResponse(content string, calls ...types.ToolCall) *types.ChatResponse {
+	resp := chatResponse(content)
+	resp.Choices[0].Message.ToolCalls = calls
+	resp.Choices[0].FinishReason = "tool_calls"
+	resp.Usage = types.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	return resp
+}
+
+func toolCall(id, name, arguments string) types.ToolCall {
+	return types.ToolCall{
+		ID:       id,
+		Type:     types.ToolTypeFunction,
+		Function: types.FunctionCall{Name: name, Arguments: arguments},
+	}
+}
+
+func TestBaseAgentExecuteTools(t *testing.T) {
+	ctx := context.Background()
+	double := &funcTool{name: "double", fn: func(args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"result": args["a"].(float64) * 2}, nil
+	}}
+	broken := &funcTool{name: "broken", fn: func(args map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("disk full")
+	}}
+
+	t.Run("calls tools until an answer", func(t *testing.T) {
+		final := chatResponse("The answer is 8")
+		final.Usage = types.Usage{PromptTokens: 20, CompletionTokens: 4, TotalTokens: 24}
+		provider := &scriptedProvider{responses: []*types.ChatResponse{
+			toolCallResponse("Doubling twice", toolCall("call_1", "double", `{"a":2}`)),
+			toolCallResponse("", toolCall("call_2", "double", `{"a":4}`)),
+			final,
+		}}
+		agent := newTestAgent(t, provider)
+		require.NoError(t, agent.AddTool(double))
+		require.NoError(t, agent.AddTool(broken))
+
+		response, err := agent.Execute(ctx, "Double 2 twice", types.WithStream(true))
+		require.NoError(t, err)
+		assert.Equal(t, "The answer is 8", response.Content)
+		assert.Equal(t, "stop", response.FinishReason)
+		assert.Equal(t, types.Usage{PromptTokens: 40, CompletionTokens: 14, TotalTokens: 54}, response.Usage)
+
+		require.Len(t, provider.requests, 3)
+		first := provider.requests[0]
+		assert.False(t, first.Stream, "tool turns are not streamed")
+		require.Len(t, first.Tools, 2)
+		assert.Equal(t, "broken", first.Tools[0].Function.Name)
+		assert.Equal(t, "double", first.Tools[1].Function.Name)
+		assert.Equal(t, "number", first.Tools[1].Function.Parameters.Properties["a"].Type)
+
+		second := provider.requests[1].Messages
+		require.Len(t, second, 4)
+		assert.Equal(t, "assistant", second[2].Role)
+		assert.Equal(t, "Doubling twice", second[2].Content)
+		assert.Equal(t, "call_1", second[2].ToolCalls[0].ID)
+		assert.Equal(t, types.Message{Role: "tool", ToolCallID: "call_1", Name: "double", Content: `{"result":4}`}, second[3])
+		assert.Len(t, provider.requests[2].Messages, 6)
+
+		steps, ok := response.Metadata["steps"].([]Step)
+		require.True(t, ok)
+		require.Len(t, steps, 3)
+		assert.Equal(t, "Doubling twice", steps[0].Content)
+		require.Len(t, steps[0].ToolCalls, 1)
+		assert.Equal(t, "double", steps[0].ToolCalls[0].Tool)
+		assert.Equal(t, map[string]interface{}{"a": 2.0}, steps[0].ToolCalls[0].Arguments)
+		assert.Equal(t, `{"result":8}`, steps[1].ToolCalls[0].Output)
+		assert.Equal(t, "The answer is 8", steps[2].Content)
+		assert.Empty(t, steps[2].ToolCalls)
+		assert.Equal(t, 24, steps[2].Usage.TotalTokens)
+	})
+
+	t.Run("tool errors are reported to the model", func(t *testing.T) {
+		provider := &scriptedProvider{responses: []*types.ChatResponse{
+			toolCallResponse("",
+				toolCall("call_1", "broken", `{}`),
+				toolCall("call_2", "missing", `{}`),
+				toolCall("call_3", "double", `not json`)),
+			chatResponse("Nothing worked"),
+		}}
+		agent := newTestAgent(t, provider)
+		require.NoError(t, agent.AddTool(double))
+		require.NoError(t, agent.AddTool(broken))
+
+		response, err := agent.Execute(ctx, "Try everything")
+		require.NoError(t, err)
+		assert.Equal(t, "Nothing worked", response.Content)
+
+		messages := provider.requests[1].Messages
+		require.Len(t, messages, 6)
+		assert.Equal(t, "error: disk full", messages[3].Content)
+		assert.Equal(t, `error: unknown tool "missing"`, messages[4].Content)
+		assert.Contains(t, messages[5].Content, "error: invalid arguments")
+
+		steps := response.Metadata["steps"].([]Step)
+		assert.Equal(t, "disk full", steps[0].ToolCalls[0].Error)
+	})
+
+	t.Run("step limit", func(t *testing.T) {
+		provider := &scriptedProvider{responses: []*types.ChatResponse{
+			toolCallResponse("", toolCall("call_1", "double", `{"a":1}`)),
+			toolCallResponse("Still going", toolCall("call_2", "double", `{"a":2}`)),
+		}}
+		agent := newTestAgent(t, provider)
+		require.NoError(t, agent.AddTool(double))
+
+		response, err := agent.Execute(ctx, "Keep doubling", types.WithMaxSteps(2))
+		require.NoError(t, err)
+		assert.Equal(t, FinishReasonMaxSteps, response.FinishReason)
+		assert.Equal(t, "Still going", response.Content)
+		assert.Len(t, provider.requests, 2)
+
+		steps := response.Metadata["steps"].([]Step)
+		require.Len(t, steps, 2)
+		require.Len(t, steps[1].ToolCalls, 1)
+		assert.Empty(t, steps[1].ToolCalls[0].Output, "calls past the limit are not run")
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		provider := &scriptedProvider{responses: []*types.ChatResponse{
+			toolCallResponse("", toolCall("call_1", "double", `{"a":1}`)),
+		}}
+		agent := newTestAgent(t, provider)
+		require.NoError(t, agent.AddTool(double))
+
+		_, err := agent.Execute(ctx, "Double 1")
+		assert.ErrorContains(t, err, "provider scripted failed: no more responses")
+	})
+}
+
+func TestBaseAgentExecuteAgentTool(t *testing.T) {
+	ctx := context.Background()
+
+	answer := chatResponse("No issues in main.go")
+	answer.Usage = types.Usage{PromptTokens: 6, CompletionTokens: 4, TotalTokens: 10}
+	specialist := newTestAgent(t, &scriptedProvider{responses: []*types.ChatResponse{answer}})
+
+	final := chatResponse("The review found no issues")
+	final.Usage = types.Usage{PromptTokens: 20, CompletionTokens: 4, TotalTokens: 24}
+	provider := &scriptedProvider{responses: []*types.ChatResponse{
+		toolCallResponse("", toolCall("call_1", "test-agent", `{"task":"Review main.go"}`)),
+		final,
+	}}
+	coordinator := newTestAgent(t, provider)
+	require.NoError(t, coordinator.AddTool(tool.NewAgentTool(zaptest.NewLogger(t), &tool.AgentToolConfig{Agent: specialist})))
+
+	response, err := coordinator.Execute(ctx, "Review main.go with the specialist")
+	require.NoError(t, err)
+	assert.Equal(t, "The review found no issues", response.Content)
+	assert.Equal(t, types.Usage{PromptTokens: 36, CompletionTokens: 13, TotalTokens: 49}, response.Usage, "usage of the specialist is rolled up")
+
+	assert.Equal(t, "An agent under test", provider.requests[0].Tools[0].Function.Description)
+	assert.Equal(t, "No issues in main.go", provider.requests[1].Messages[3].Content)
+
+	steps := response.Metadata["steps"].([]Step)
+	assert.Equal(t, 10, steps[0].ToolCalls[0].Usage.TotalTokens)
+}
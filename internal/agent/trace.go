@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/pimentel/peppergo/internal/tracing"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// executeCapability runs a capability inside a trace span
+func executeCapability(ctx context.Context, capability types.Capability, input interface{}) (interface{}, error) {
+	ctx, span := tracing.Start(ctx, "capability.execute")
+	defer span.End()
+	span.SetAttribute("capability", capability.Name())
+	span.SetAttribute("capability.version", capability.Version())
+
+	result, err := capability.Execute(ctx, input)
+	span.RecordError(err)
+	return result, err
+}
+
+// executeTool runs a tool inside a trace span
+func executeTool(ctx context.Context, tool types.Tool, args map[string]interface{}) (interface{}, error) {
+	ctx, span := tracing.Start(ctx, "tool.execute")
+	defer span.End()
+	span.SetAttribute("tool", tool.Name())
+	span.SetAttribute("tool.version", tool.Version())
+
+	result, err := tool.Execute(ctx, args)
+	span.RecordError(err)
+	return result, err
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/provider"
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+// WithAdminKeys enables the /admin endpoints for requests authenticated with one of the given API keys
+func WithAdminKeys(keys []string) Option {
+	return func(h *Handler) {
+		h.settings.adminKeys = keys
+	}
+}
+
+// adminAuth rejects requests that do not carry an admin API key.
+// Without admin keys the admin API does not exist.
+func (h *Handler) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		adminKeys := h.current().adminKeys
+		if len(adminKeys) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		key := apiKeyFromRequest(r)
+		for _, adminKey := range adminKeys {
+			if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// adminRoutes mounts the provider and configuration administration endpoints
+func (h *Handler) adminRoutes(r chi.Router) {
+	r.Use(h.adminAuth)
+
+	r.Get("/providers", h.handleListProviders)
+	r.Post("/providers", h.handleAddProvider)
+	r.Get("/providers/{name}", h.handleProviderStatus)
+	r.Put("/providers/{name}", h.handleUpdateProvider)
+	r.Delete("/providers/{name}", h.handleRemoveProvider)
+	r.Post("/providers/{name}/enable", h.handleEnableProvider)
+	r.Post("/providers/{name}/disable", h.handleDisableProvider)
+
+	r.Get("/reload", h.handleReloadStatus)
+	r.Post("/reload", h.handleReload)
+}
+
+func (h *Handler) handleAddProvider(w http.ResponseWriter, r *http.Request) {
+	var config provider.Config
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	p, err := provider.New(h.logger, &config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.service.RegisterProvider(p); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	h.logger.Info("Provider added",
+		zap.String("provider", p.Name()),
+		zap.String("type", config.Type))
+	h.writeProviderStatus(w, p.Name(), http.StatusCreated)
+}
+
+func (h *Handler) handleUpdateProvider(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var config provider.Config
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if config.Name == "" {
+		config.Name = name
+	}
+	if config.Name != name {
+		http.Error(w, "Provider name does not match the URL", http.StatusBadRequest)
+		return
+	}
+
+	p, err := provider.New(h.logger, &config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.service.ReplaceProvider(p); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	h.logger.Info("Provider updated",
+		zap.String("provider", name),
+		zap.String("type", config.Type))
+	h.writeProviderStatus(w, name, http.StatusOK)
+}
+
+func (h *Handler) handleRemoveProvider(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := h.service.RemoveProvider(name); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	h.logger.Info("Provider removed", zap.String("provider", name))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleEnableProvider(w http.ResponseWriter, r *http.Request) {
+	h.setProviderEnabled(w, chi.URLParam(r, "name"), true)
+}
+
+func (h *Handler) handleDisableProvider(w http.ResponseWriter, r *http.Request) {
+	h.setProviderEnabled(w, chi.URLParam(r, "name"), false)
+}
+
+func (h *Handler) setProviderEnabled(w http.ResponseWriter, name string, enabled bool) {
+	if err := h.service.SetProviderEnabled(name, enabled); err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	h.logger.Info("Provider state changed",
+		zap.String("provider", name),
+		zap.Bool("enabled", enabled))
+	h.writeProviderStatus(w, name, http.StatusOK)
+}
+
+func (h *Handler) writeProviderStatus(w http.ResponseWriter, name string, code int) {
+	status, err := h.service.ProviderStatus(name)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
+
+// writeProviderError maps provider registry errors to HTTP status codes
+func writeProviderError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, proxy.ErrProviderNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, proxy.ErrProviderExists):
+		code = http.StatusConflict
+	}
+	http.Error(w, err.Error(), code)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+func TestAdminProviders(t *testing.T) {
+	service := proxy.NewService()
+	h := NewHandler(service, WithLogger(zaptest.NewLogger(t)), WithAdminKeys([]string{"admin-key"}))
+	server := httptest.NewServer(h.Router())
+	defer server.Close()
+
+	do := func(method, path, body, key string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("requires admin key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/admin/providers", "", "").StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/admin/providers", "", "wrong").StatusCode)
+	})
+
+	t.Run("add", func(t *testing.T) {
+		resp := do(http.MethodPost, "/admin/providers",
+			`{"type":"openai","name":"local","base_url":"http://localhost:9999/v1","models":["m1"]}`, "admin-key")
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var status proxy.ProviderStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		assert.Equal(t, "local", status.Name)
+		assert.True(t, status.Enabled)
+		assert.Equal(t, []string{"m1"}, status.Models)
+
+		resp = do(http.MethodPost, "/admin/providers", `{"type":"openai","name":"local"}`, "admin-key")
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp = do(http.MethodPost, "/admin/providers", `{"type":"unknown"}`, "admin-key")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		old, err := service.GetProvider("local")
+		require.NoError(t, err)
+
+		resp := do(http.MethodPut, "/admin/providers/local", `{"type":"openai","models":["m2"]}`, "admin-key")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		current, err := service.GetProvider("local")
+		require.NoError(t, err)
+		assert.NotSame(t, old, current)
+		assert.Equal(t, []string{"m2"}, current.AvailableModels())
+
+		resp = do(http.MethodPut, "/admin/providers/local", `{"type":"openai","name":"other"}`, "admin-key")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = do(http.MethodPut, "/admin/providers/missing", `{"type":"openai"}`, "admin-key")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		resp := do(http.MethodPost, "/admin/providers/local/disable", "", "admin-key")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions",
+			strings.NewReader(`{"model":"m2","messages":[{"role":"user","content":"hi"}]}`))
+		require.NoError(t, err)
+		req.Header.Set("X-Provider", "local")
+		chat, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		chat.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, chat.StatusCode)
+
+		resp = do(http.MethodPost, "/admin/providers/local/enable", "", "admin-key")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var status proxy.ProviderStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		assert.True(t, status.Enabled)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/providers/local", "", "admin-key").StatusCode)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/providers/local", "", "admin-key").StatusCode)
+		assert.Empty(t, service.ListProviders())
+	})
+}
+
+func TestAdminDisabledWithoutKeys(t *testing.T) {
+	h := NewHandler(proxy.NewService())
+	req := httptest.NewRequest(http.MethodGet, "/admin/providers", nil)
+	rec := httptest.NewRecorder()
+	h.Router().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,571 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// messagesRequest is an Anthropic Messages API request
+type messagesRequest struct {
+	Model         string            `json:"model"`
+	System        messagesSystem    `json:"system,omitempty"`
+	Messages      []messagesMessage `json:"messages"`
+	MaxTokens     int               `json:"max_tokens"`
+	Temperature   float32           `json:"temperature,omitempty"`
+	TopP          float32           `json:"top_p,omitempty"`
+	StopSequences []string          `json:"stop_sequences,omitempty"`
+	Stream        bool              `json:"stream,omitempty"`
+
+	Tools      []messagesTool      `json:"tools,omitempty"`
+	ToolChoice *messagesToolChoice `json:"tool_choice,omitempty"`
+}
+
+type messagesTool struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	InputSchema *types.ToolSchema `json:"input_schema"`
+}
+
+type messagesToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// messagesSystem accepts the system prompt as a string or an array of text blocks
+type messagesSystem string
+
+func (s *messagesSystem) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*s = messagesSystem(text)
+		return nil
+	}
+
+	var blocks []messagesBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return fmt.Errorf("system must be a string or an array of text blocks: %w", err)
+	}
+	texts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		texts = append(texts, block.Text)
+	}
+	*s = messagesSystem(strings.Join(texts, "\n\n"))
+	return nil
+}
+
+// messagesMessage is a message whose content is a string or an array of blocks
+type messagesMessage struct {
+	Role    string
+	Content []messagesBlock
+}
+
+func (m *messagesMessage) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	m.Role = aux.Role
+
+	var text string
+	if err := json.Unmarshal(aux.Content, &text); err == nil {
+		m.Content = []messagesBlock{{Type: "text", Text: text}}
+		return nil
+	}
+	return json.Unmarshal(aux.Content, &m.Content)
+}
+
+type messagesBlock struct {
+	Type   string          `json:"type"`
+	Text   string          `json:"text,omitempty"`
+	Source *messagesSource `json:"source,omitempty"`
+
+	// Tool use and tool result blocks; a tool result's content is a string
+	// or an array of text blocks
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+}
+
+type messagesSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+}
+
+type messagesUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// messagesResponse is an Anthropic Messages API response
+type messagesResponse struct {
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	Role         string          `json:"role"`
+	Model        string          `json:"model"`
+	Content      []messagesBlock `json:"content"`
+	StopReason   *string         `json:"stop_reason"`
+	StopSequence *string         `json:"stop_sequence"`
+	Usage        messagesUsage   `json:"usage"`
+}
+
+// toChatRequest translates an Anthropic Messages request into a standardized chat request
+func (m *messagesRequest) toChatRequest() (*types.ChatRequest, error) {
+	req := &types.ChatRequest{
+		Model:       m.Model,
+		MaxTokens:   m.MaxTokens,
+		Temperature: m.Temperature,
+		TopP:        m.TopP,
+		Stop:        m.StopSequences,
+		Stream:      m.Stream,
+	}
+
+	for _, tool := range m.Tools {
+		schema := tool.InputSchema
+		if schema == nil {
+			schema = types.NewToolSchema()
+		}
+		req.Tools = append(req.Tools, types.ToolDefinition{
+			Type: types.ToolTypeFunction,
+			Function: types.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  schema,
+			},
+		})
+	}
+	if m.ToolChoice != nil {
+		choice, err := toolChoiceFromMessages(*m.ToolChoice)
+		if err != nil {
+			return nil, err
+		}
+		req.ToolChoice = choice
+	}
+
+	if m.System != "" {
+		req.Messages = append(req.Messages, types.Message{Role: "system", Content: string(m.System)})
+	}
+
+	for _, msg := range m.Messages {
+		converted, err := messagesFromBlocks(msg)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, converted...)
+	}
+
+	return req, nil
+}
+
+// toolChoiceFromMessages converts an Anthropic tool choice into a standardized one
+func toolChoiceFromMessages(choice messagesToolChoice) (*types.ToolChoice, error) {
+	switch choice.Type {
+	case "auto":
+		return &types.ToolChoice{Mode: types.ToolChoiceAuto}, nil
+	case "any":
+		return &types.ToolChoice{Mode: types.ToolChoiceRequired}, nil
+	case "none":
+		return &types.ToolChoice{Mode: types.ToolChoiceNone}, nil
+	case "tool":
+		if choice.Name == "" {
+			return nil, fmt.Errorf("tool_choice of type tool requires a name")
+		}
+		return &types.ToolChoice{Function: choice.Name}, nil
+	default:
+		return nil, fmt.Errorf("unsupported tool_choice type: %s", choice.Type)
+	}
+}
+
+// messagesFromBlocks converts an Anthropic message into standardized messages.
+// Tool results become tool messages, which precede the rest of the message,
+// and tool use blocks become the tool calls of the message.
+func messagesFromBlocks(msg messagesMessage) ([]types.Message, error) {
+	var messages []types.Message
+	var content []messagesBlock
+	var calls []types.ToolCall
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "tool_result":
+			text, err := toolResultText(block.Content)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, types.Message{Role: "tool", ToolCallID: block.ToolUseID, Content: text})
+		case "tool_use":
+			arguments := string(block.Input)
+			if arguments == "" {
+				arguments = "{}"
+			}
+			calls = append(calls, types.ToolCall{
+				ID:       block.ID,
+				Type:     types.ToolTypeFunction,
+				Function: types.FunctionCall{Name: block.Name, Arguments: arguments},
+			})
+		default:
+			content = append(content, block)
+		}
+	}
+	if len(content) == 0 && len(calls) == 0 && len(messages) > 0 {
+		return messages, nil
+	}
+
+	converted, err := messageFromBlocks(msg.Role, content)
+	if err != nil {
+		return nil, err
+	}
+	converted.ToolCalls = calls
+	return append(messages, converted), nil
+}
+
+// messageFromBlocks converts Anthropic content blocks into a standardized message
+func messageFromBlocks(role string, blocks []messagesBlock) (types.Message, error) {
+	if len(blocks) == 0 {
+		return types.Message{Role: role}, nil
+	}
+	if len(blocks) == 1 && blocks[0].Type == "text" {
+		return types.Message{Role: role, Content: blocks[0].Text}, nil
+	}
+
+	parts := make([]types.ContentPart, 0, len(blocks))
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			parts = append(parts, types.TextPart(block.Text))
+		case "image", "document":
+			part, err := partFromSource(block)
+			if err != nil {
+				return types.Message{}, err
+			}
+			parts = append(parts, part)
+		default:
+			return types.Message{}, fmt.Errorf("unsupported content block type: %s", block.Type)
+		}
+	}
+
+	return types.Message{Role: role, Parts: parts}, nil
+}
+
+// toolResultText returns the text of a tool result's content
+func toolResultText(content json.RawMessage) (string, error) {
+	if len(content) == 0 {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text, nil
+	}
+
+	var blocks []messagesBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return "", fmt.Errorf("tool_result content must be a string or an array of text blocks: %w", err)
+	}
+	texts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type != "text" {
+			return "", fmt.Errorf("unsupported tool_result content block type: %s", block.Type)
+		}
+		texts = append(texts, block.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// toolUseBlocks converts the tool calls of a response into tool_use blocks
+func toolUseBlocks(calls []types.ToolCall) ([]messagesBlock, error) {
+	blocks := make([]messagesBlock, 0, len(calls))
+	for _, call := range calls {
+		input := json.RawMessage(call.Function.Arguments)
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		if !json.Valid(input) {
+			return nil, fmt.Errorf("invalid arguments for tool call %s: not JSON", call.ID)
+		}
+		blocks = append(blocks, messagesBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+	}
+	return blocks, nil
+}
+
+// partFromSource converts an image or document block source into a content part
+func partFromSource(block messagesBlock) (types.ContentPart, error) {
+	src := block.Source
+	if src == nil {
+		return types.ContentPart{}, fmt.Errorf("%s block is missing source", block.Type)
+	}
+
+	var uri string
+	switch src.Type {
+	case "base64":
+		uri = fmt.Sprintf("data:%s;base64,%s", src.MediaType, src.Data)
+	case "text":
+		uri = "data:text/plain," + url.PathEscape(src.Data)
+	case "url":
+		uri = src.URL
+	case "file":
+		return types.ContentPart{Type: types.ContentTypeFile, File: &types.FileRef{FileID: src.FileID}}, nil
+	default:
+		return types.ContentPart{}, fmt.Errorf("unsupported %s source type: %s", block.Type, src.Type)
+	}
+
+	if block.Type == "image" {
+		return types.ImagePart(uri), nil
+	}
+	return types.FilePart("", uri), nil
+}
+
+// anthropicStopReason maps OpenAI-style finish reasons onto Anthropic stop reasons
+func anthropicStopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls":
+		return "tool_use"
+	case "", "stop":
+		return "end_turn"
+	default:
+		return finishReason
+	}
+}
+
+func (h *Handler) handleMessages(w http.ResponseWriter, r *http.Request) {
+	var msgReq messagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&msgReq); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "Invalid request body")
+		return
+	}
+	if msgReq.MaxTokens < 1 {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "max_tokens is required")
+		return
+	}
+
+	req, err := msgReq.toChatRequest()
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	provider := h.providerFor(r, req.Model)
+	if provider == "" {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid_request_error", "Provider not specified")
+		return
+	}
+
+	if req.Stream {
+		h.handleStreamMessages(w, r, provider, req)
+		return
+	}
+
+	resp, err := h.service.Chat(r.Context(), provider, req)
+	if err != nil {
+		writeAnthropicServiceError(w, err)
+		return
+	}
+
+	var content []messagesBlock
+	finishReason := ""
+	if len(resp.Choices) > 0 {
+		msg := resp.Choices[0].Message
+		finishReason = resp.Choices[0].FinishReason
+		if text := msg.Text(); text != "" || len(msg.ToolCalls) == 0 {
+			content = append(content, messagesBlock{Type: "text", Text: text})
+		}
+		toolUse, err := toolUseBlocks(msg.ToolCalls)
+		if err != nil {
+			writeAnthropicError(w, http.StatusBadGateway, "api_error", err.Error())
+			return
+		}
+		content = append(content, toolUse...)
+	}
+	if content == nil {
+		content = []messagesBlock{{Type: "text"}}
+	}
+	stopReason := anthropicStopReason(finishReason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messagesResponse{
+		ID:         messageID(resp.ID),
+		Type:       "message",
+		Role:       "assistant",
+		Model:      resp.Model,
+		Content:    content,
+		StopReason: &stopReason,
+		Usage: messagesUsage{
+			InputTokens:  resp.Usage.PromptTokens,
+			OutputTokens: resp.Usage.CompletionTokens,
+		},
+	})
+}
+
+func (h *Handler) handleStreamMessages(w http.ResponseWriter, r *http.Request, provider string, req *types.ChatRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAnthropicError(w, http.StatusInternalServerError, "api_error", "Streaming not supported")
+		return
+	}
+
+	respChan, err := h.service.StreamChat(r.Context(), provider, req)
+	if err != nil {
+		writeAnthropicServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	writeEvent := func(event string, data interface{}) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		flusher.Flush()
+	}
+
+	// Text and tool calls are streamed as consecutive content blocks; a
+	// tool call with an ID starts a new tool_use block
+	started := false
+	index, open := 0, ""
+	startBlock := func(block messagesBlock) {
+		if open != "" {
+			writeEvent("content_block_stop", map[string]interface{}{
+				"type":  "content_block_stop",
+				"index": index,
+			})
+			index++
+		}
+		writeEvent("content_block_start", map[string]interface{}{
+			"type":          "content_block_start",
+			"index":         index,
+			"content_block": block,
+		})
+		open = block.Type
+	}
+
+	finishReason := ""
+	var usage types.Usage
+	for resp := range respChan {
+		if resp.Error != nil {
+			writeEvent("error", map[string]interface{}{
+				"type":  "error",
+				"error": map[string]string{"type": "api_error", "message": resp.Error.Error()},
+			})
+			return
+		}
+		if !started {
+			writeEvent("message_start", map[string]interface{}{
+				"type": "message_start",
+				"message": messagesResponse{
+					ID:      messageID(resp.ID),
+					Type:    "message",
+					Role:    "assistant",
+					Model:   resp.Model,
+					Content: []messagesBlock{},
+					Usage:   messagesUsage{InputTokens: resp.Usage.PromptTokens},
+				},
+			})
+			started = true
+		}
+
+		if resp.Usage.TotalTokens > 0 {
+			usage = resp.Usage
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+		if text := resp.Choices[0].Message.Text(); text != "" {
+			if open != "text" {
+				startBlock(messagesBlock{Type: "text"})
+			}
+			writeEvent("content_block_delta", map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": index,
+				"delta": map[string]string{"type": "text_delta", "text": text},
+			})
+		}
+		for _, call := range resp.Choices[0].Message.ToolCalls {
+			if call.ID != "" || open != "tool_use" {
+				startBlock(messagesBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: json.RawMessage("{}")})
+			}
+			if call.Function.Arguments != "" {
+				writeEvent("content_block_delta", map[string]interface{}{
+					"type":  "content_block_delta",
+					"index": index,
+					"delta": map[string]string{"type": "input_json_delta", "partial_json": call.Function.Arguments},
+				})
+			}
+		}
+		if resp.Choices[0].FinishReason != "" {
+			finishReason = resp.Choices[0].FinishReason
+		}
+	}
+
+	if !started {
+		writeEvent("error", map[string]interface{}{
+			"type":  "error",
+			"error": map[string]string{"type": "api_error", "message": "provider returned no response"},
+		})
+		return
+	}
+
+	if open == "" {
+		startBlock(messagesBlock{Type: "text"})
+	}
+	writeEvent("content_block_stop", map[string]interface{}{
+		"type":  "content_block_stop",
+		"index": index,
+	})
+	writeEvent("message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": anthropicStopReason(finishReason), "stop_sequence": nil},
+		"usage": map[string]int{"output_tokens": usage.CompletionTokens},
+	})
+	writeEvent("message_stop", map[string]string{"type": "message_stop"})
+}
+
+// messageID returns an Anthropic-style message ID, generating one when the provider gave none
+func messageID(id string) string {
+	if id == "" {
+		return "msg_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	}
+	return id
+}
+
+// writeAnthropicError writes an error in the Anthropic API error format
+func writeAnthropicError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    errType,
+			"message": message,
+		},
+	})
+}
+
+// writeAnthropicServiceError writes a service error using the Anthropic error types
+func writeAnthropicServiceError(w http.ResponseWriter, err error) {
+	status := statusForError(err)
+	switch status {
+	case http.StatusServiceUnavailable:
+		writeAnthropicError(w, status, "overloaded_error", err.Error())
+	case http.StatusBadRequest:
+		writeAnthropicError(w, status, "invalid_request_error", err.Error())
+	default:
+		writeAnthropicError(w, status, "api_error", err.Error())
+	}
+}
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func TestMessagesRequestToChatRequest(t *testing.T) {
+	input := `{
+		"model": "claude-test",
+		"max_tokens": 256,
+		"system": "You are terse.",
+		"stop_sequences": ["END"],
+		"messages": [
+			{"role": "user", "content": "Hi"},
+			{"role": "assistant", "content": [{"type": "text", "text": "Hello"}]},
+			{"role": "user", "content": [
+				{"type": "text", "text": "Summarize"},
+				{"type": "document", "source": {"type": "base64", "media_type": "application/pdf", "data": "JVBERi0="}},
+				{"type": "image", "source": {"type": "url", "url": "https://example.com/a.png"}}
+			]}
+		]
+	}`
+
+	var msgReq messagesRequest
+	require.NoError(t, json.Unmarshal([]byte(input), &msgReq))
+
+	req, err := msgReq.toChatRequest()
+	require.NoError(t, err)
+
+	assert.Equal(t, "claude-test", req.Model)
+	assert.Equal(t, 256, req.MaxTokens)
+	assert.Equal(t, []string{"END"}, req.Stop)
+	require.Len(t, req.Messages, 4)
+	assert.Equal(t, types.Message{Role: "system", Content: "You are terse."}, req.Messages[0])
+	assert.Equal(t, "Hi", req.Messages[1].Content)
+	assert.Equal(t, "Hello", req.Messages[2].Content)
+
+	parts := req.Messages[3].Parts
+	require.Len(t, parts, 3)
+	assert.Equal(t, types.ContentTypeFile, parts[1].Type)
+	assert.Equal(t, "data:application/pdf;base64,JVBERi0=", parts[1].File.FileData)
+	assert.Equal(t, types.ContentTypeImageURL, parts[2].Type)
+	assert.Equal(t, "https://example.com/a.png", parts[2].ImageURL.URL)
+}
+
+func TestMessagesRequestUnsupportedBlock(t *testing.T) {
+	var msgReq messagesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"messages":[{"role":"user","content":[{"type":"audio"}]}]}`), &msgReq))
+
+	_, err := msgReq.toChatRequest()
+	assert.Error(t, err)
+}
+
+func TestMessagesRequestTools(t *testing.T) {
+	input := `{
+		"model": "claude-test",
+		"max_tokens": 256,
+		"tools": [{"name": "lookup", "description": "Looks things up", "input_schema": {"type": "object", "properties": {"query": {"type": "string"}}}}],
+		"tool_choice": {"type": "tool", "name": "lookup"},
+		"messages": [
+			{"role": "user", "content": "Look up go"},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "Checking."},
+				{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"query": "go"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "a language"}]},
+				{"type": "text", "text": "Thanks"}
+			]}
+		]
+	}`
+
+	var msgReq messagesRequest
+	require.NoError(t, json.Unmarshal([]byte(input), &msgReq))
+
+	req, err := msgReq.toChatRequest()
+	require.NoError(t, err)
+
+	require.Len(t, req.Tools, 1)
+	assert.Equal(t, "lookup", req.Tools[0].Function.Name)
+	assert.Equal(t, "string", req.Tools[0].Function.Parameters.Properties["query"].Type)
+	assert.Equal(t, &types.ToolChoice{Function: "lookup"}, req.ToolChoice)
+
+	require.Len(t, req.Messages, 4)
+	assert.Equal(t, "Checking.", req.Messages[1].Content)
+	require.Len(t, req.Messages[1].ToolCalls, 1)
+	assert.Equal(t, "toolu_1", req.Messages[1].ToolCalls[0].ID)
+	assert.JSONEq(t, `{"query":"go"}`, req.Messages[1].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, types.Message{Role: "tool", ToolCallID: "toolu_1", Content: "a language"}, req.Messages[2])
+	assert.Equal(t, types.Message{Role: "user", Content: "Thanks"}, req.Messages[3])
+
+	msgReq.ToolChoice = &messagesToolChoice{Type: "sometimes"}
+	_, err = msgReq.toChatRequest()
+	assert.EqualError(t, err, "unsupported tool_choice type: sometimes")
+}
+
+// toolCallProvider answers every request with a call to the lookup tool
+type toolCallProvider struct {
+	echoProvider
+}
+
+func (p *toolCallProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	return &types.ChatResponse{
+		ID: "msg_1",
+		Choices: []types.Choice{{
+			Message: types.Message{Role: "assistant", ToolCalls: []types.ToolCall{
+				{ID: "toolu_1", Type: types.ToolTypeFunction, Function: types.FunctionCall{Name: "lookup", Arguments: `{"query":"go"}`}},
+			}},
+			FinishReason: "tool_calls",
+		}},
+	}, nil
+}
+
+func (p *toolCallProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	ch := make(chan *types.ChatResponse, 4)
+	ch <- &types.ChatResponse{ID: "msg_1", Choices: []types.Choice{{Message: types.Message{Content: "Checking."}}}}
+	ch <- &types.ChatResponse{Choices: []types.Choice{{Message: types.Message{ToolCalls: []types.ToolCall{
+		{ID: "toolu_1", Type: types.ToolTypeFunction, Function: types.FunctionCall{Name: "lookup", Arguments: `{"query":`}},
+	}}}}}
+	ch <- &types.ChatResponse{Choices: []types.Choice{{Message: types.Message{ToolCalls: []types.ToolCall{
+		{Function: types.FunctionCall{Arguments: `"go"}`}},
+	}}}}}
+	ch <- &types.ChatResponse{Choices: []types.Choice{{FinishReason: "tool_calls"}}}
+	close(ch)
+	return ch, nil
+}
+
+func TestMessagesToolUseResponse(t *testing.T) {
+	service := proxy.NewService()
+	require.NoError(t, service.RegisterProvider(&toolCallProvider{}))
+	server := httptest.NewServer(NewHandler(service).Router())
+	defer server.Close()
+
+	post := func(body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/messages", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Provider", "echo")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := post(`{"model":"echo-model","max_tokens":100,"messages":[{"role":"user","content":"Look up go"}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var msgResp messagesResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&msgResp))
+	assert.Equal(t, "tool_use", *msgResp.StopReason)
+	require.Len(t, msgResp.Content, 1)
+	assert.Equal(t, "tool_use", msgResp.Content[0].Type)
+	assert.Equal(t, "toolu_1", msgResp.Content[0].ID)
+	assert.Equal(t, "lookup", msgResp.Content[0].Name)
+	assert.JSONEq(t, `{"query":"go"}`, string(msgResp.Content[0].Input))
+
+	resp = post(`{"model":"echo-model","max_tokens":100,"stream":true,"messages":[{"role":"user","content":"Look up go"}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var events []string
+	var partialJSON strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Type         string        `json:"type"`
+			Index        int           `json:"index"`
+			ContentBlock messagesBlock `json:"content_block"`
+			Delta        struct {
+				Type        string `json:"type"`
+				PartialJSON string `json:"partial_json"`
+			} `json:"delta"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		switch event.Type {
+		case "content_block_start":
+			events = append(events, fmt.Sprintf("start %d %s", event.Index, event.ContentBlock.Type))
+		case "content_block_stop":
+			events = append(events, fmt.Sprintf("stop %d", event.Index))
+		case "content_block_delta":
+			if event.Delta.Type == "input_json_delta" {
+				partialJSON.WriteString(event.Delta.PartialJSON)
+			}
+		}
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"start 0 text", "stop 0", "start 1 tool_use", "stop 1"}, events)
+	assert.JSONEq(t, `{"query":"go"}`, partialJSON.String())
+}
+
+// failingStreamProvider streams a few words and then fails
+type failingStreamProvider struct {
+	echoProvider
+}
+
+func (p *failingStreamProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	ch := make(chan *types.ChatResponse, 2)
+	ch <- &types.ChatResponse{ID: "msg_1", Choices: []types.Choice{{Message: types.Message{Content: "Hel"}}}}
+	ch <- &types.ChatResponse{ID: "msg_1", Error: fmt.Errorf("overloaded")}
+	close(ch)
+	return ch, nil
+}
+
+func TestStreamErrorEvents(t *testing.T) {
+	service := proxy.NewService()
+	require.NoError(t, service.RegisterProvider(&failingStreamProvider{}))
+	server := httptest.NewServer(NewHandler(service).Router())
+	defer server.Close()
+
+	stream := func(path, body string) []string {
+		req, err := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Provider", "echo")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var lines []string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		require.NoError(t, scanner.Err())
+		return lines
+	}
+
+	lines := stream("/v1/messages", `{"model":"echo-model","max_tokens":100,"stream":true,"messages":[{"role":"user","content":"Hi"}]}`)
+	require.GreaterOrEqual(t, len(lines), 2)
+	assert.Equal(t, "event: error", lines[len(lines)-2])
+	assert.JSONEq(t, `{"type":"error","error":{"type":"api_error","message":"provider echo stream chat failed: overloaded"}}`,
+		strings.TrimPrefix(lines[len(lines)-1], "data: "))
+
+	lines = stream("/v1/chat/completions", `{"model":"echo-model","stream":true,"messages":[{"role":"user","content":"Hi"}]}`)
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"content":"Hel"`)
+	assert.JSONEq(t, `{"error":{"type":"api_error","message":"provider echo stream chat failed: overloaded"}}`,
+		strings.TrimPrefix(lines[1], "data: "))
+	assert.Equal(t, "data: [DONE]", lines[2])
+
+	lines = stream("/v1/completions", `{"model":"echo-model","stream":true,"prompt":"Hi"}`)
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"text":"Hel"`)
+	assert.JSONEq(t, `{"error":{"type":"api_error","message":"provider echo stream chat failed: overloaded"}}`,
+		strings.TrimPrefix(lines[1], "data: "))
+	assert.Equal(t, "data: [DONE]", lines[2])
+}
+
+func TestChatCompletionsStreamDone(t *testing.T) {
+	service := proxy.NewService()
+	require.NoError(t, service.RegisterProvider(&toolCallProvider{}))
+	server := httptest.NewServer(NewHandler(service).Router())
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions",
+		strings.NewReader(`{"model":"echo-model","stream":true,"messages":[{"role":"user","content":"Hi"}]}`))
+	require.NoError(t, err)
+	req.Header.Set("X-Provider", "echo")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, lines, 5)
+	assert.Contains(t, lines[3], `"finish_reason":"tool_calls"`)
+	assert.Equal(t, "data: [DONE]", lines[4])
+}
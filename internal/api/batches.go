@@ -0,0 +1,111 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+func (h *Handler) handleCreateBatch(w http.ResponseWriter, r *http.Request) {
+	provider := h.providerFor(r, "")
+	if provider == "" {
+		http.Error(w, "Provider not specified", http.StatusBadRequest)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, h.batches.MaxInputBytes())
+	batch, err := h.batches.CreateBatch(r.Context(), batchOwner(r), provider, body)
+	if err != nil {
+		writeBatchError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(batch)
+}
+
+func (h *Handler) handleListBatches(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object": "list",
+		"data":   h.batches.ListBatches(batchOwner(r)),
+	})
+}
+
+func (h *Handler) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := h.batches.GetBatch(batchOwner(r), chi.URLParam(r, "id"))
+	if err != nil {
+		writeBatchError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+func (h *Handler) handleCancelBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := h.batches.CancelBatch(batchOwner(r), chi.URLParam(r, "id"))
+	if err != nil {
+		writeBatchError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+func (h *Handler) handleBatchOutput(w http.ResponseWriter, r *http.Request) {
+	h.streamBatchResults(w, r, h.batches.OpenOutput)
+}
+
+func (h *Handler) handleBatchErrors(w http.ResponseWriter, r *http.Request) {
+	h.streamBatchResults(w, r, h.batches.OpenErrors)
+}
+
+func (h *Handler) streamBatchResults(w http.ResponseWriter, r *http.Request, open func(owner, id string) (io.ReadCloser, error)) {
+	results, err := open(batchOwner(r), chi.URLParam(r, "id"))
+	if err != nil {
+		writeBatchError(w, err)
+		return
+	}
+	defer results.Close()
+
+	w.Header().Set("Content-Type", "application/jsonl")
+	_, _ = io.Copy(w, results)
+}
+
+// batchOwner identifies the caller a batch belongs to: its tenant or, for
+// callers without one, a fingerprint of its API key. Other callers cannot see the batch.
+func batchOwner(r *http.Request) string {
+	if tenant := proxy.TenantFromContext(r.Context()); tenant != "" {
+		return "tenant:" + tenant
+	}
+	if key := apiKeyFromRequest(r); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	return ""
+}
+
+// writeBatchError maps batch manager errors onto HTTP status codes
+func writeBatchError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, proxy.ErrBatchNotFound):
+		status = http.StatusNotFound
+	case errors.As(err, &tooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, proxy.ErrInvalidBatchInput), errors.Is(err, proxy.ErrProviderNotFound):
+		status = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+func TestBatchesAreScopedToTheirOwner(t *testing.T) {
+	service := proxy.NewService()
+	require.NoError(t, service.RegisterProvider(&echoProvider{}))
+	batches, err := proxy.NewBatchManager(service, proxy.BatchConfig{Dir: t.TempDir()}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer batches.Close()
+
+	h := NewHandler(service,
+		WithBatchManager(batches),
+		WithAPIKeys([]string{"acme-key", "globex-key", "other-key"}),
+		WithTenantKeys(map[string]string{"acme-key": "acme", "globex-key": "globex"}))
+	server := httptest.NewServer(h.Router())
+	defer server.Close()
+
+	do := func(method, path, key, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("X-Provider", "echo")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/v1/batches", "acme-key",
+		`{"custom_id":"a","body":{"model":"echo-model","messages":[{"role":"user","content":"hi"}]}}`)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var batch proxy.Batch
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&batch))
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/v1/batches/"+batch.ID, "acme-key", "").StatusCode)
+	for _, key := range []string{"globex-key", "other-key"} {
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/v1/batches/"+batch.ID, key, "").StatusCode, key)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/v1/batches/"+batch.ID+"/output", key, "").StatusCode, key)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/v1/batches/"+batch.ID+"/cancel", key, "").StatusCode, key)
+
+		body, err := io.ReadAll(do(http.MethodGet, "/v1/batches", key, "").Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"object":"list","data":[]}`, string(body), key)
+	}
+}
+
+func TestCreateBatchErrors(t *testing.T) {
+	service := proxy.NewService()
+	require.NoError(t, service.RegisterProvider(&echoProvider{}))
+	dir := t.TempDir()
+	batches, err := proxy.NewBatchManager(service, proxy.BatchConfig{Dir: dir, MaxInputBytes: 256}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer batches.Close()
+
+	server := httptest.NewServer(NewHandler(service, WithBatchManager(batches)).Router())
+	defer server.Close()
+
+	create := func(body string) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/batches", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Provider", "echo")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	line := `{"custom_id":"a","body":{"model":"echo-model","messages":[{"role":"user","content":"hi"}]}}`
+
+	assert.Equal(t, http.StatusBadRequest, create("not json"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, create(strings.Repeat(line+"\n", 10)))
+
+	// Batches can no longer be stored
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, os.WriteFile(dir, nil, 0o644))
+	assert.Equal(t, http.StatusInternalServerError, create(line))
+}
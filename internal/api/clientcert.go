@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+)
+
+// WithClientTenants attributes requests authenticated with a verified TLS client
+// certificate to a tenant. Keys are matched against the certificate's common
+// name, DNS names, URIs (such as SPIFFE IDs) and email addresses. Callers with a
+// mapped certificate do not need an API key.
+func WithClientTenants(identities map[string]string) Option {
+	return func(h *Handler) {
+		h.settings.clientTenants = identities
+	}
+}
+
+// clientIdentities returns the identities of the verified client certificate of a request
+func clientIdentities(r *http.Request) []string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+
+	var identities []string
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	identities = append(identities, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return append(identities, cert.EmailAddresses...)
+}
+
+// clientTenant returns the tenant mapped to the verified client certificate of a request
+func (h *Handler) clientTenant(r *http.Request) (string, bool) {
+	tenants := h.current().clientTenants
+	if len(tenants) == 0 {
+		return "", false
+	}
+	for _, identity := range clientIdentities(r) {
+		if tenant, ok := tenants[identity]; ok {
+			return tenant, true
+		}
+	}
+	return "", false
+}
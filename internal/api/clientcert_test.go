@@ -0,0 +1,79 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+// withClientCert marks req as made with a verified client certificate
+func withClientCert(req *http.Request, cert *x509.Certificate) *http.Request {
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return req
+}
+
+func TestClientTenants(t *testing.T) {
+	h := NewHandler(proxy.NewService(),
+		WithAPIKeys([]string{"acme-key"}),
+		WithTenantKeys(map[string]string{"acme-key": "acme"}),
+		WithClientTenants(map[string]string{
+			"billing-service":                       "billing",
+			"spiffe://example.org/ns/search/sa/api": "search",
+		}))
+
+	var got string
+	next := h.authenticate(h.tenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = proxy.TenantFromContext(r.Context())
+	})))
+
+	spiffe, _ := url.Parse("spiffe://example.org/ns/search/sa/api")
+	tests := []struct {
+		name    string
+		cert    *x509.Certificate
+		headers map[string]string
+		status  int
+		tenant  string
+	}{
+		{name: "common name", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "billing-service"}}, status: http.StatusOK, tenant: "billing"},
+		{name: "uri", cert: &x509.Certificate{URIs: []*url.URL{spiffe}}, status: http.StatusOK, tenant: "search"},
+		{name: "overrides header", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "billing-service"}}, headers: map[string]string{"X-Tenant": "globex"}, status: http.StatusOK, tenant: "billing"},
+		{name: "api key overrides certificate", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "billing-service"}}, headers: map[string]string{"Authorization": "Bearer acme-key"}, status: http.StatusOK, tenant: "acme"},
+		{name: "unmapped certificate", cert: &x509.Certificate{Subject: pkix.Name{CommonName: "unknown"}}, status: http.StatusUnauthorized},
+		{name: "no certificate", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = "unset"
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tt.cert != nil {
+				req = withClientCert(req, tt.cert)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.tenant, got)
+			}
+		})
+	}
+
+	// Unverified certificates are ignored
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "billing-service"}}}}
+	rec := httptest.NewRecorder()
+	next.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// stringOrArray accepts either a single string or an array of strings in JSON
+type stringOrArray []string
+
+func (s *stringOrArray) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = stringOrArray{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("must be a string or an array of strings: %w", err)
+	}
+	*s = many
+	return nil
+}
+
+// completionRequest is a legacy prompt-style completions request
+type completionRequest struct {
+	Model       string        `json:"model"`
+	Prompt      stringOrArray `json:"prompt"`
+	Suffix      string        `json:"suffix,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Temperature float32       `json:"temperature,omitempty"`
+	TopP        float32       `json:"top_p,omitempty"`
+	Stop        stringOrArray `json:"stop,omitempty"`
+	Echo        bool          `json:"echo,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+
+	FrequencyPenalty float32 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  float32 `json:"presence_penalty,omitempty"`
+}
+
+// completionResponse is a legacy text completion response or stream chunk
+type completionResponse struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []completionChoice `json:"choices"`
+	Usage   *types.Usage       `json:"usage,omitempty"`
+}
+
+type completionChoice struct {
+	Text         string      `json:"text"`
+	Index        int         `json:"index"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// toChatRequest maps a single prompt onto a standardized chat request
+func (c *completionRequest) toChatRequest(prompt string) *types.ChatRequest {
+	return &types.ChatRequest{
+		Model:       c.Model,
+		Messages:    types.PromptMessages(prompt, c.Suffix),
+		MaxTokens:   c.MaxTokens,
+		Temperature: c.Temperature,
+		TopP:        c.TopP,
+		Stop:        c.Stop,
+		Stream:      c.Stream,
+
+		FrequencyPenalty: c.FrequencyPenalty,
+		PresencePenalty:  c.PresencePenalty,
+	}
+}
+
+func (h *Handler) handleCompletions(w http.ResponseWriter, r *http.Request) {
+	var req completionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Prompt) == 0 {
+		http.Error(w, "Prompt must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	provider := h.providerFor(r, req.Model)
+	if provider == "" {
+		http.Error(w, "Provider not specified", http.StatusBadRequest)
+		return
+	}
+
+	if req.Stream {
+		h.handleStreamCompletions(w, r, provider, &req)
+		return
+	}
+
+	resp := completionResponse{
+		ID:      "cmpl-" + uuid.New().String(),
+		Object:  "text_completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: make([]completionChoice, 0, len(req.Prompt)),
+		Usage:   &types.Usage{},
+	}
+
+	for i, prompt := range req.Prompt {
+		chatResp, err := h.service.Chat(r.Context(), provider, req.toChatRequest(prompt))
+		if err != nil {
+			http.Error(w, err.Error(), statusForError(err))
+			return
+		}
+
+		choice := completionChoice{Index: i}
+		if len(chatResp.Choices) > 0 {
+			choice.Text = chatResp.Choices[0].Message.Text()
+			choice.FinishReason = chatResp.Choices[0].FinishReason
+		}
+		if req.Echo {
+			choice.Text = prompt + choice.Text
+		}
+		if chatResp.Model != "" {
+			resp.Model = chatResp.Model
+		}
+
+		resp.Choices = append(resp.Choices, choice)
+		resp.Usage.PromptTokens += chatResp.Usage.PromptTokens
+		resp.Usage.CompletionTokens += chatResp.Usage.CompletionTokens
+		resp.Usage.TotalTokens += chatResp.Usage.TotalTokens
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) handleStreamCompletions(w http.ResponseWriter, r *http.Request, provider string, req *completionRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	id := "cmpl-" + uuid.New().String()
+	created := time.Now().Unix()
+	headerSent := false
+
+	writeChunk := func(model string, choice completionChoice) {
+		data, err := json.Marshal(completionResponse{
+			ID:      id,
+			Object:  "text_completion",
+			Created: created,
+			Model:   model,
+			Choices: []completionChoice{choice},
+		})
+		if err != nil {
+			return
+		}
+		_, _ = w.Write([]byte("data: "))
+		_, _ = w.Write(data)
+		_, _ = w.Write([]byte("\n\n"))
+		flusher.Flush()
+	}
+
+	// Once the stream has started, failures are reported as an error chunk
+	// and the stream still ends with [DONE]
+	defer func() {
+		if headerSent {
+			_, _ = w.Write([]byte("data: [DONE]\n\n"))
+			flusher.Flush()
+		}
+	}()
+
+	for i, prompt := range req.Prompt {
+		respChan, err := h.service.StreamChat(r.Context(), provider, req.toChatRequest(prompt))
+		if err != nil {
+			if !headerSent {
+				http.Error(w, err.Error(), statusForError(err))
+				return
+			}
+			writeStreamError(w, flusher, err)
+			return
+		}
+
+		if !headerSent {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			headerSent = true
+		}
+
+		if req.Echo {
+			writeChunk(req.Model, completionChoice{Index: i, Text: prompt})
+		}
+
+		var streamErr error
+		for resp := range respChan {
+			if resp.Error != nil {
+				streamErr = resp.Error
+				continue
+			}
+			if len(resp.Choices) == 0 {
+				continue
+			}
+			text := resp.Choices[0].Message.Text()
+			finishReason := resp.Choices[0].FinishReason
+			if text == "" && finishReason == "" {
+				continue
+			}
+
+			model := resp.Model
+			if model == "" {
+				model = req.Model
+			}
+			writeChunk(model, completionChoice{
+				Index:        i,
+				Text:         text,
+				FinishReason: finishReason,
+			})
+		}
+		if streamErr != nil {
+			writeStreamError(w, flusher, streamErr)
+			return
+		}
+	}
+}
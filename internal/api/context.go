@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+// priority attaches the request's queue priority to its context.
+// A priority bound to the caller's API key takes precedence over the X-Priority header.
+func (h *Handler) priority(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := apiKeyFromRequest(r); key != "" {
+			if priority, ok := h.current().priorityKeys[key]; ok {
+				next.ServeHTTP(w, r.WithContext(proxy.WithPriority(r.Context(), priority)))
+				return
+			}
+		}
+
+		header := r.Header.Get("X-Priority")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		priority, err := proxy.ParsePriority(header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(proxy.WithPriority(r.Context(), priority)))
+	})
+}
+
+// tenant attaches the tenant a request is made for to its context.
+// A tenant bound to the caller's API key takes precedence over one mapped to its
+// client certificate. The X-Tenant header is only honoured when it was enabled
+// with WithTenantHeader, since callers could otherwise pick another tenant's
+// policy and limits.
+func (h *Handler) tenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tenant string
+		if h.current().tenantHeader {
+			tenant = r.Header.Get("X-Tenant")
+		}
+		if certTenant, ok := h.clientTenant(r); ok {
+			tenant = certTenant
+		}
+		if key := apiKeyFromRequest(r); key != "" {
+			if keyTenant, ok := h.current().tenantKeys[key]; ok {
+				tenant = keyTenant
+			}
+		}
+		if tenant == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(proxy.WithTenant(r.Context(), tenant)))
+	})
+}
+
+// apiKeyFromRequest returns the bearer token or x-api-key header of a request
+func apiKeyFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return r.Header.Get("X-Api-Key")
+}
+
+// cacheControl lets clients bypass the response cache with Cache-Control: no-cache or no-store
+func cacheControl(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := strings.ToLower(r.Header.Get("Cache-Control"))
+		if strings.Contains(header, "no-cache") || strings.Contains(header, "no-store") {
+			r = r.WithContext(proxy.WithoutCache(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+func TestPriorityMiddleware(t *testing.T) {
+	h := NewHandler(proxy.NewService(), WithPriorityKeys(map[string]proxy.Priority{
+		"batch-key": proxy.PriorityBatch,
+	}))
+
+	var got proxy.Priority
+	next := h.priority(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = proxy.PriorityFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		status   int
+		priority proxy.Priority
+	}{
+		{name: "default", status: http.StatusOK, priority: proxy.PriorityDefault},
+		{name: "header", headers: map[string]string{"X-Priority": "interactive"}, status: http.StatusOK, priority: proxy.PriorityInteractive},
+		{name: "api key overrides header", headers: map[string]string{"Authorization": "Bearer batch-key", "X-Priority": "high"}, status: http.StatusOK, priority: proxy.PriorityBatch},
+		{name: "x-api-key", headers: map[string]string{"X-Api-Key": "batch-key"}, status: http.StatusOK, priority: proxy.PriorityBatch},
+		{name: "invalid header", headers: map[string]string{"X-Priority": "urgent"}, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = -1
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.priority, got)
+			}
+		})
+	}
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	h := NewHandler(proxy.NewService(), WithTenantKeys(map[string]string{
+		"acme-key": "acme",
+	}))
+
+	var got string
+	next := h.tenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = proxy.TenantFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		tenant  string
+	}{
+		{name: "none"},
+		{name: "header is ignored", headers: map[string]string{"X-Tenant": "globex"}},
+		{name: "api key", headers: map[string]string{"Authorization": "Bearer acme-key", "X-Tenant": "globex"}, tenant: "acme"},
+		{name: "unknown key", headers: map[string]string{"Authorization": "Bearer other", "X-Tenant": "globex"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = "unset"
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			next.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.tenant, got)
+		})
+	}
+}
+
+func TestTenantMiddlewareHeader(t *testing.T) {
+	h := NewHandler(proxy.NewService(), WithTenantHeader(), WithTenantKeys(map[string]string{
+		"acme-key": "acme",
+	}))
+
+	var got string
+	next := h.tenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = proxy.TenantFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("X-Tenant", "globex")
+	next.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "globex", got)
+
+	req.Header.Set("Authorization", "Bearer acme-key")
+	next.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "acme", got, "the key's tenant overrides the header")
+
+	h.Reconfigure(WithTenantKeys(map[string]string{"acme-key": "acme"}))
+	req.Header.Del("Authorization")
+	got = "unset"
+	next.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Empty(t, got, "reconfiguring without the option disables the header")
+}
@@ -1,25 +1,97 @@
 package api
 
 import (
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/audit"
+	"github.com/pimentel/peppergo/internal/metrics"
+	"github.com/pimentel/peppergo/internal/policy"
 	"github.com/pimentel/peppergo/internal/proxy"
+	"github.com/pimentel/peppergo/internal/redact"
+	"github.com/pimentel/peppergo/internal/tracing"
 	"github.com/pimentel/peppergo/pkg/types"
 )
 
 // Handler represents the HTTP API handler
 type Handler struct {
-	service *proxy.Service
+	service  *proxy.Service
+	batches  *proxy.BatchManager
+	settings *settings
+	live     atomic.Pointer[settings]
+	reloader Reloader
+	registry *metrics.Registry
+	metrics  *httpMetrics
+	tracer   *tracing.Tracer
+	logger   *zap.Logger
+	audit    *audit.Logger
+
+	// serveMetrics serves registry on /metrics
+	serveMetrics bool
+}
+
+// Option configures optional Handler features
+type Option func(*Handler)
+
+// WithBatchManager enables the /v1/batches endpoints
+func WithBatchManager(batches *proxy.BatchManager) Option {
+	return func(h *Handler) {
+		h.batches = batches
+	}
+}
+
+// WithPriorityKeys assigns a queue priority to requests authenticated with the given API keys.
+// Requests with other keys may still choose a priority with the X-Priority header.
+func WithPriorityKeys(keys map[string]proxy.Priority) Option {
+	return func(h *Handler) {
+		h.settings.priorityKeys = keys
+	}
+}
+
+// WithTenantKeys attributes requests authenticated with the given API keys to a tenant
+func WithTenantKeys(keys map[string]string) Option {
+	return func(h *Handler) {
+		h.settings.tenantKeys = keys
+	}
+}
+
+// WithTenantHeader lets requests without a tenant bound to their API key or
+// client certificate name their tenant with the X-Tenant header. Enable it only
+// when every caller is trusted, for example behind a gateway that sets the header.
+func WithTenantHeader() Option {
+	return func(h *Handler) {
+		h.settings.tenantHeader = true
+	}
+}
+
+// WithTracer records a server span per request, continuing incoming W3C traceparent headers
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(h *Handler) {
+		h.tracer = tracer
+	}
 }
 
 // NewHandler creates a new API handler
-func NewHandler(service *proxy.Service) *Handler {
-	return &Handler{
-		service: service,
+func NewHandler(service *proxy.Service, opts ...Option) *Handler {
+	h := &Handler{
+		service:  service,
+		settings: &settings{},
+		logger:   zap.NewNop(),
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.live.Store(h.settings)
+	return h
 }
 
 // Router returns the HTTP router for the API
@@ -27,17 +99,68 @@ func (h *Handler) Router() http.Handler {
 	r := chi.NewRouter()
 
 	// Middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
+	r.Use(h.tenant)
+	if h.tracer != nil {
+		r.Use(tracing.Middleware(h.tracer, routePattern))
+	}
+	if h.metrics != nil {
+		r.Use(h.metrics.instrument)
+	}
+	r.Use(h.accessLog)
+	if h.audit != nil {
+		r.Use(h.auditLog)
+	}
+	r.Use(middleware.Recoverer)
+	r.Use(h.priority)
+	r.Use(cacheControl)
+
+	// Liveness and readiness probes
+	r.Get("/healthz", h.handleHealthz)
+	r.Get("/readyz", h.handleReadyz)
+
+	// Metrics endpoint, which needs an API key like /v1
+	if h.serveMetrics {
+		r.With(h.authenticate).Method(http.MethodGet, "/metrics", h.registry.Handler())
+	}
+
+	// Provider administration
+	r.Route("/admin", h.adminRoutes)
 
 	// Routes
 	r.Route("/v1", func(r chi.Router) {
+		r.Use(h.authenticate)
+
 		// Chat completion endpoint
 		r.Post("/chat/completions", h.handleChat)
-		
+
+		// Legacy text completion endpoint
+		r.Post("/completions", h.handleCompletions)
+
+		// Anthropic Messages API endpoint
+		r.Post("/messages", h.handleMessages)
+
+		// Embeddings endpoint
+		r.Post("/embeddings", h.handleEmbeddings)
+
+		// Batch jobs
+		if h.batches != nil {
+			r.Route("/batches", func(r chi.Router) {
+				r.Post("/", h.handleCreateBatch)
+				r.Get("/", h.handleListBatches)
+				r.Get("/{id}", h.handleGetBatch)
+				r.Post("/{id}/cancel", h.handleCancelBatch)
+				r.Get("/{id}/output", h.handleBatchOutput)
+				r.Get("/{id}/errors", h.handleBatchErrors)
+			})
+		}
+
 		// Provider management
 		r.Get("/providers", h.handleListProviders)
+		r.Get("/providers/{name}/status", h.handleProviderStatus)
+
+		// Provider queue statistics
+		r.Get("/queues", h.handleListQueues)
 	})
 
 	return r
@@ -50,11 +173,7 @@ func (h *Handler) handleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get provider from header or query param
-	provider := r.Header.Get("X-Provider")
-	if provider == "" {
-		provider = r.URL.Query().Get("provider")
-	}
+	provider := h.providerFor(r, req.Model)
 	if provider == "" {
 		http.Error(w, "Provider not specified", http.StatusBadRequest)
 		return
@@ -67,7 +186,7 @@ func (h *Handler) handleChat(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := h.service.Chat(r.Context(), provider, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), statusForError(err))
 		return
 	}
 
@@ -89,11 +208,21 @@ func (h *Handler) handleStreamChat(w http.ResponseWriter, r *http.Request, provi
 
 	respChan, err := h.service.StreamChat(r.Context(), provider, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), statusForError(err))
 		return
 	}
 
+	// The stream ends with [DONE], also after an error chunk
+	defer func() {
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+		flusher.Flush()
+	}()
+
 	for resp := range respChan {
+		if resp.Error != nil {
+			writeStreamError(w, flusher, resp.Error)
+			return
+		}
 		data, err := json.Marshal(resp)
 		if err != nil {
 			continue
@@ -107,11 +236,131 @@ func (h *Handler) handleStreamChat(w http.ResponseWriter, r *http.Request, provi
 	}
 }
 
+// writeStreamError ends an OpenAI-style event stream with an error chunk
+func writeStreamError(w http.ResponseWriter, flusher http.Flusher, err error) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]string{"type": "api_error", "message": err.Error()},
+	})
+	_, _ = w.Write([]byte("data: "))
+	_, _ = w.Write(data)
+	_, _ = w.Write([]byte("\n\n"))
+	flusher.Flush()
+}
+
+func (h *Handler) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req types.EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Input) == 0 {
+		http.Error(w, "Input must not be empty", http.StatusBadRequest)
+		return
+	}
+	switch req.EncodingFormat {
+	case "", types.EncodingFormatFloat, types.EncodingFormatBase64:
+	default:
+		http.Error(w, "encoding_format must be float or base64", http.StatusBadRequest)
+		return
+	}
+
+	provider := h.providerFor(r, req.Model)
+	if provider == "" {
+		http.Error(w, "Provider not specified", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.Embed(r.Context(), provider, &req)
+	if err != nil {
+		http.Error(w, err.Error(), statusForError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if req.EncodingFormat == types.EncodingFormatBase64 {
+		json.NewEncoder(w).Encode(base64Embeddings(resp))
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// base64Embedding is an embedding encoded as base64 little-endian float32s
+type base64Embedding struct {
+	Object    string `json:"object"`
+	Index     int    `json:"index"`
+	Embedding string `json:"embedding"`
+}
+
+// base64Embeddings encodes the vectors of an embeddings response as base64
+func base64Embeddings(resp *types.EmbeddingResponse) interface{} {
+	data := make([]base64Embedding, len(resp.Data))
+	for i, embedding := range resp.Data {
+		buf := make([]byte, 4*len(embedding.Embedding))
+		for j, v := range embedding.Embedding {
+			binary.LittleEndian.PutUint32(buf[4*j:], math.Float32bits(v))
+		}
+		data[i] = base64Embedding{
+			Object:    embedding.Object,
+			Index:     embedding.Index,
+			Embedding: base64.StdEncoding.EncodeToString(buf),
+		}
+	}
+	return struct {
+		Object string            `json:"object"`
+		Data   []base64Embedding `json:"data"`
+		Model  string            `json:"model"`
+		Usage  types.Usage       `json:"usage"`
+	}{resp.Object, data, resp.Model, resp.Usage}
+}
+
+// routePattern returns the matched chi route pattern of a served request
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// statusForError maps a service error onto an HTTP status code
+func statusForError(err error) int {
+	var violation *policy.Violation
+	switch {
+	case errors.As(err, &violation):
+		if violation.Stage == policy.StageOutput {
+			return http.StatusBadGateway
+		}
+		return http.StatusBadRequest
+	case errors.Is(err, proxy.ErrQueueTimeout), errors.Is(err, proxy.ErrQueueFull), errors.Is(err, proxy.ErrCircuitOpen),
+		errors.Is(err, proxy.ErrProviderDisabled):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, proxy.ErrEmbeddingsNotSupported), errors.Is(err, redact.ErrSensitiveData):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// providerFromRequest returns the target provider from the X-Provider header or provider query param
+func providerFromRequest(r *http.Request) string {
+	provider := r.Header.Get("X-Provider")
+	if provider == "" {
+		provider = r.URL.Query().Get("provider")
+	}
+	return provider
+}
+
 func (h *Handler) handleListProviders(w http.ResponseWriter, r *http.Request) {
-	providers := h.service.ListProviders()
+	providers := h.service.ProviderStatuses()
 	
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string][]string{
+	json.NewEncoder(w).Encode(map[string][]proxy.ProviderStatus{
 		"providers": providers,
 	})
-} 
\ No newline at end of file
+}
+
+func (h *Handler) handleListQueues(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]proxy.QueueStats{
+		"queues": h.service.QueueStats(),
+	})
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// handleHealthz reports that the process is up
+func (h *Handler) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports whether at least one provider can serve requests
+func (h *Handler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	status := "ready"
+	code := http.StatusOK
+	if !h.service.Ready() {
+		status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    status,
+		"providers": h.service.ProviderStatuses(),
+	})
+}
+
+func (h *Handler) handleProviderStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.service.ProviderStatus(chi.URLParam(r, "name"))
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
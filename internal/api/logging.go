@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/audit"
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+// WithLogger writes a structured access log entry for every request
+func WithLogger(logger *zap.Logger) Option {
+	return func(h *Handler) {
+		h.logger = logger
+	}
+}
+
+// WithAuditLog records redacted request and response bodies to the audit log
+func WithAuditLog(auditLog *audit.Logger) Option {
+	return func(h *Handler) {
+		h.audit = auditLog
+	}
+}
+
+// accessLog logs each request with the provider calls made to serve it
+func (h *Handler) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ctx, record := proxy.WithRequestRecord(r.Context())
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		fields := []zap.Field{
+			zap.String("request_id", middleware.GetReqID(r.Context())),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", status),
+			zap.Int("bytes", ww.BytesWritten()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("remote_addr", r.RemoteAddr),
+		}
+		if tenant := proxy.TenantFromContext(r.Context()); tenant != "" {
+			fields = append(fields, zap.String("tenant", tenant))
+		}
+		if calls := record.Calls(); len(calls) > 0 {
+			provider, model, usage := record.Summary()
+			fields = append(fields,
+				zap.String("provider", provider),
+				zap.String("model", model),
+				zap.Int("provider_calls", len(calls)),
+				zap.Int("prompt_tokens", usage.PromptTokens),
+				zap.Int("completion_tokens", usage.CompletionTokens),
+				zap.Int("total_tokens", usage.TotalTokens))
+		}
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			h.logger.Error("Request failed", fields...)
+		case status >= http.StatusBadRequest:
+			h.logger.Warn("Request rejected", fields...)
+		default:
+			h.logger.Info("Request served", fields...)
+		}
+	})
+}
+
+// auditLog records the request and response bodies of each request
+func (h *Handler) auditLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		limit := h.audit.MaxBodyBytes()
+
+		var reqBody []byte
+		truncated := false
+		if r.Body != nil {
+			var err error
+			reqBody, err = io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			if len(reqBody) > limit {
+				truncated = true
+			}
+			// Replay what was read, followed by the unread remainder
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+			if truncated {
+				reqBody = reqBody[:limit]
+			}
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		respBody := &limitedBuffer{limit: limit}
+		ww.Tee(respBody)
+		ctx, record := proxy.WithRequestRecord(r.Context())
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		provider, model, _ := record.Summary()
+
+		h.audit.Record(&audit.Entry{
+			Time:      start.UTC(),
+			RequestID: middleware.GetReqID(r.Context()),
+			Tenant:    proxy.TenantFromContext(r.Context()),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Status:    status,
+			Provider:  provider,
+			Model:     model,
+			LatencyMS: time.Since(start).Milliseconds(),
+			Truncated: truncated || respBody.truncated,
+		}, reqBody, respBody.Bytes())
+	})
+}
+
+// limitedBuffer keeps the first limit bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.Buffer.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
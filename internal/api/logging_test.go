@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/pimentel/peppergo/internal/audit"
+	"github.com/pimentel/peppergo/internal/proxy"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// echoProvider replies with the last message
+type echoProvider struct{}
+
+func (p *echoProvider) Name() string { return "echo" }
+
+func (p *echoProvider) AvailableModels() []string { return []string{"echo-model"} }
+
+func (p *echoProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	return &types.ChatResponse{
+		ID:      "echo-1",
+		Model:   req.Model,
+		Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: req.Messages[len(req.Messages)-1].Content}}},
+		Usage:   types.Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7},
+	}, nil
+}
+
+func (p *echoProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func TestAccessAndAuditLog(t *testing.T) {
+	service := proxy.NewService()
+	require.NoError(t, service.RegisterProvider(&echoProvider{}))
+
+	core, logs := observer.New(zap.InfoLevel)
+	dir := t.TempDir()
+	auditLog, err := audit.NewLogger(zaptest.NewLogger(t), &audit.Config{Dir: dir, RedactFields: []string{"content"}})
+	require.NoError(t, err)
+
+	h := NewHandler(service, WithLogger(zap.New(core)), WithAuditLog(auditLog), WithTenantHeader())
+	server := httptest.NewServer(h.Router())
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions",
+		strings.NewReader(`{"model":"echo-model","messages":[{"role":"user","content":"secret prompt"}]}`))
+	require.NoError(t, err)
+	req.Header.Set("X-Provider", "echo")
+	req.Header.Set("X-Tenant", "acme")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/v1/chat/completions")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Access log
+	entries := logs.FilterMessage("Request served").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.NotEmpty(t, fields["request_id"])
+	assert.Equal(t, "echo", fields["provider"])
+	assert.Equal(t, "echo-model", fields["model"])
+	assert.Equal(t, "acme", fields["tenant"])
+	assert.Equal(t, int64(7), fields["total_tokens"])
+	assert.Equal(t, int64(http.StatusOK), fields["status"])
+	assert.Len(t, logs.FilterMessage("Request rejected").All(), 1)
+
+	// Audit log
+	require.NoError(t, auditLog.Close())
+	file, err := os.Open(filepath.Join(dir, "audit.jsonl"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	require.True(t, scanner.Scan())
+	var entry audit.Entry
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+	assert.Equal(t, "acme", entry.Tenant)
+	assert.Equal(t, "echo", entry.Provider)
+	assert.Equal(t, fields["request_id"], entry.RequestID)
+	assert.NotContains(t, string(entry.Request), "secret prompt")
+	assert.NotContains(t, string(entry.Response), "secret prompt")
+	assert.Contains(t, string(entry.Response), `"id":"echo-1"`)
+}
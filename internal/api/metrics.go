@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/pimentel/peppergo/internal/metrics"
+)
+
+// httpMetrics records HTTP-level request metrics
+type httpMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.GaugeVec
+}
+
+// WithMetrics records HTTP metrics in the registry and serves it on /metrics
+// to callers with an API key. Provider-level metrics are recorded by
+// proxy.Service; see proxy.NewMetrics.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(h *Handler) {
+		WithHTTPMetrics(registry)(h)
+		h.serveMetrics = true
+	}
+}
+
+// WithHTTPMetrics records HTTP metrics in the registry without serving it,
+// for registries exposed on a separate listener
+func WithHTTPMetrics(registry *metrics.Registry) Option {
+	return func(h *Handler) {
+		h.registry = registry
+		h.metrics = &httpMetrics{
+			requests: registry.NewCounter("peppergo_http_requests_total",
+				"HTTP requests by route and status code.", "method", "route", "code"),
+			duration: registry.NewHistogram("peppergo_http_request_duration_seconds",
+				"HTTP request latency.", nil, "method", "route"),
+			inFlight: registry.NewGauge("peppergo_http_requests_in_flight",
+				"HTTP requests currently being served."),
+		}
+	}
+}
+
+// instrument records metrics for every request served by the router
+func (m *httpMetrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		m.inFlight.With().Inc()
+		defer m.inFlight.With().Dec()
+
+		next.ServeHTTP(ww, r)
+
+		route := routePattern(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.requests.With(r.Method, route, strconv.Itoa(status)).Inc()
+		m.duration.With(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+// settings holds the handler configuration that can be replaced at runtime
+type settings struct {
+	priorityKeys  map[string]proxy.Priority
+	tenantKeys    map[string]string
+	tenantHeader  bool
+	clientTenants map[string]string
+	apiKeys       map[string]bool
+	adminKeys     []string
+	routes        []Route
+	defaultRoute  string
+}
+
+// current returns the settings in effect
+func (h *Handler) current() *settings {
+	return h.live.Load()
+}
+
+// Reconfigure atomically replaces the routes and API keys of a running handler.
+// Options are applied from scratch, so settings not given are cleared; options
+// other than WithRoutes, WithAPIKeys, WithAdminKeys, WithTenantKeys,
+// WithTenantHeader, WithClientTenants and WithPriorityKeys are ignored.
+func (h *Handler) Reconfigure(opts ...Option) {
+	next := &Handler{settings: &settings{}}
+	for _, opt := range opts {
+		opt(next)
+	}
+	h.live.Store(next.settings)
+}
+
+// ReloadStatus reports the outcome of configuration reloads
+type ReloadStatus struct {
+	Success     bool       `json:"success"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	Changes     []string   `json:"changes,omitempty"`
+	Reloads     int        `json:"reloads"`
+	Failures    int        `json:"failures"`
+}
+
+// Reloader reloads the server configuration
+type Reloader interface {
+	// Reload applies the current configuration file
+	Reload() error
+
+	// Status returns the outcome of the latest reloads
+	Status() ReloadStatus
+}
+
+// WithReloader exposes configuration reloads on the admin API
+func WithReloader(reloader Reloader) Option {
+	return func(h *Handler) {
+		h.reloader = reloader
+	}
+}
+
+func (h *Handler) handleReloadStatus(w http.ResponseWriter, r *http.Request) {
+	if h.reloader == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.reloader.Status())
+}
+
+func (h *Handler) handleReload(w http.ResponseWriter, r *http.Request) {
+	if h.reloader == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	code := http.StatusOK
+	if err := h.reloader.Reload(); err != nil {
+		code = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(h.reloader.Status())
+}
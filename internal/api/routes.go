@@ -0,0 +1,73 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"path"
+)
+
+// Route sends requests for models matching Model to Provider.
+// Model may be an exact name or a path.Match pattern such as "claude-*".
+type Route struct {
+	Model    string
+	Provider string
+}
+
+// WithRoutes picks the provider for requests that do not name one with the
+// X-Provider header or provider query param. The first route matching the
+// requested model wins; defaultProvider is used when none match.
+func WithRoutes(defaultProvider string, routes []Route) Option {
+	return func(h *Handler) {
+		h.settings.defaultRoute = defaultProvider
+		h.settings.routes = routes
+	}
+}
+
+// WithAPIKeys requires requests to the /v1 endpoints to carry one of the given API keys
+func WithAPIKeys(keys []string) Option {
+	return func(h *Handler) {
+		h.settings.apiKeys = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			h.settings.apiKeys[key] = true
+		}
+	}
+}
+
+// providerFor returns the provider a request for model should be sent to
+func (h *Handler) providerFor(r *http.Request, model string) string {
+	if provider := providerFromRequest(r); provider != "" {
+		return provider
+	}
+	settings := h.current()
+	for _, route := range settings.routes {
+		if matched, _ := path.Match(route.Model, model); matched {
+			return route.Provider
+		}
+	}
+	return settings.defaultRoute
+}
+
+// authenticate rejects requests without a known API key or mapped client
+// certificate, if API keys are configured
+func (h *Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeys := h.current().apiKeys
+		if len(apiKeys) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, ok := h.clientTenant(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := apiKeyFromRequest(r)
+		for apiKey := range apiKeys {
+			if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
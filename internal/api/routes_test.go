@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pimentel/peppergo/internal/proxy"
+)
+
+func TestProviderFor(t *testing.T) {
+	h := NewHandler(proxy.NewService(), WithRoutes("default", []Route{
+		{Model: "claude-*", Provider: "anthropic"},
+		{Model: "gpt-4o", Provider: "openai"},
+	}))
+
+	tests := []struct {
+		model  string
+		header string
+		want   string
+	}{
+		{model: "claude-3-5-sonnet-latest", want: "anthropic"},
+		{model: "gpt-4o", want: "openai"},
+		{model: "gpt-4o-mini", want: "default"},
+		{model: "claude-2", header: "openrouter", want: "openrouter"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		if tt.header != "" {
+			req.Header.Set("X-Provider", tt.header)
+		}
+		assert.Equal(t, tt.want, h.providerFor(req, tt.model), tt.model)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	assert.Empty(t, NewHandler(proxy.NewService()).providerFor(req, "gpt-4o"))
+}
+
+func TestAuthenticate(t *testing.T) {
+	h := NewHandler(proxy.NewService(), WithAPIKeys([]string{"key"}))
+	router := h.Router()
+
+	for key, want := range map[string]int{
+		"":      http.StatusUnauthorized,
+		"wrong": http.StatusUnauthorized,
+		"key":   http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/providers", nil)
+		if key != "" {
+			req.Header.Set("X-Api-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code, key)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "probes are not authenticated")
+}
@@ -0,0 +1,127 @@
+// Package audit persists request and response bodies to rotating JSONL
+// files for compliance review.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Config represents the configuration for the audit Logger
+type Config struct {
+	// Dir is the directory audit files are written to
+	Dir string `yaml:"dir"`
+
+	// MaxFileSize is the size in bytes at which the audit file is rotated
+	MaxFileSize int64 `yaml:"max_file_size"`
+
+	// MaxFiles is the number of rotated files kept (0 keeps all)
+	MaxFiles int `yaml:"max_files"`
+
+	// MaxBodyBytes truncates recorded bodies
+	MaxBodyBytes int `yaml:"max_body_bytes"`
+
+	// RedactFields are JSON fields whose values are never recorded
+	RedactFields []string `yaml:"redact_fields"`
+
+	// RedactPatterns are regular expressions removed from recorded bodies
+	RedactPatterns []string `yaml:"redact_patterns"`
+}
+
+// DefaultRedactFields are always redacted
+var DefaultRedactFields = []string{"api_key", "apikey", "authorization", "password", "secret", "token"}
+
+// Entry is a single audit record
+type Entry struct {
+	Time      time.Time       `json:"time"`
+	RequestID string          `json:"request_id,omitempty"`
+	Tenant    string          `json:"tenant,omitempty"`
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	Status    int             `json:"status"`
+	Provider  string          `json:"provider,omitempty"`
+	Model     string          `json:"model,omitempty"`
+	LatencyMS int64           `json:"latency_ms"`
+	Request   json.RawMessage `json:"request,omitempty"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// Logger writes audit entries to rotating JSONL files
+type Logger struct {
+	logger   *zap.Logger
+	config   *Config
+	redactor *Redactor
+	file     *rotatingFile
+	mu       sync.Mutex
+}
+
+// NewLogger creates a new audit logger
+func NewLogger(logger *zap.Logger, config *Config) (*Logger, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("audit directory is required")
+	}
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = 100 << 20
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 1 << 20
+	}
+
+	redactor, err := NewRedactor(append(append([]string{}, DefaultRedactFields...), config.RedactFields...), config.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := openRotatingFile(config.Dir, "audit", config.MaxFileSize, config.MaxFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Audit logging enabled",
+		zap.String("dir", config.Dir),
+		zap.Int64("max_file_size", config.MaxFileSize),
+		zap.Int("max_files", config.MaxFiles))
+
+	return &Logger{
+		logger:   logger,
+		config:   config,
+		redactor: redactor,
+		file:     file,
+	}, nil
+}
+
+// MaxBodyBytes returns the maximum number of body bytes recorded
+func (l *Logger) MaxBodyBytes() int {
+	return l.config.MaxBodyBytes
+}
+
+// Record redacts the raw bodies into the entry and writes it
+func (l *Logger) Record(entry *Entry, request, response []byte) {
+	entry.Request = l.redactor.Body(request)
+	entry.Response = l.redactor.Body(response)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		l.logger.Error("Failed to encode audit entry", zap.Error(err))
+		return
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(data); err != nil {
+		l.logger.Error("Failed to write audit entry", zap.Error(err))
+	}
+}
+
+// Close closes the audit file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
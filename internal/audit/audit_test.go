@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestRedactor(t *testing.T) {
+	r, err := NewRedactor([]string{"API_KEY", "content"}, []string{`\b\d{3}-\d{2}-\d{4}\b`})
+	require.NoError(t, err)
+
+	body := r.Body([]byte(`{"api_key":"sk-123","model":"m","messages":[{"role":"user","content":"hi"}],"note":"ssn 123-45-6789"}`))
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, Redacted, got["api_key"])
+	assert.Equal(t, "m", got["model"])
+	assert.Equal(t, Redacted, got["messages"].([]interface{})[0].(map[string]interface{})["content"])
+	assert.Equal(t, "ssn "+Redacted, got["note"])
+
+	// Non-JSON bodies such as event streams are recorded as strings
+	assert.JSONEq(t, `"data: ssn `+Redacted+`\n\n"`, string(r.Body([]byte("data: ssn 123-45-6789\n\n"))))
+	assert.Nil(t, r.Body(nil))
+
+	_, err = NewRedactor(nil, []string{"("})
+	assert.Error(t, err)
+}
+
+func TestLoggerRotation(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLogger(zaptest.NewLogger(t), &Config{Dir: dir, MaxFileSize: 300, MaxFiles: 2})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		l.Record(&Entry{Method: "POST", Path: "/v1/chat/completions", Status: 200}, []byte(`{"model":"m","password":"hunter2"}`), []byte(`{"id":"x"}`))
+	}
+	require.NoError(t, l.Close())
+
+	rotated, err := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	require.NoError(t, err)
+	assert.Len(t, rotated, 2)
+
+	file, err := os.Open(filepath.Join(dir, "audit.jsonl"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	require.True(t, scanner.Scan())
+	var entry struct {
+		Path    string `json:"path"`
+		Request struct {
+			Model    string `json:"model"`
+			Password string `json:"password"`
+		} `json:"request"`
+	}
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+	assert.Equal(t, "/v1/chat/completions", entry.Path)
+	assert.Equal(t, "m", entry.Request.Model)
+	assert.Equal(t, Redacted, entry.Request.Password)
+}
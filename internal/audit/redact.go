@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces removed values in audit records
+const Redacted = "[REDACTED]"
+
+// Redactor removes sensitive values from request and response bodies
+type Redactor struct {
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor creates a redactor removing the values of the given JSON
+// fields (matched case-insensitively at any depth) and any text matching
+// the given regular expressions
+func NewRedactor(fields, patterns []string) (*Redactor, error) {
+	r := &Redactor{fields: make(map[string]bool, len(fields))}
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = true
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Body returns a redacted body, as JSON if it parses and as a string otherwise
+func (r *Redactor) Body(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err == nil {
+		if redacted, err := json.Marshal(r.value(value)); err == nil {
+			return redacted
+		}
+	}
+
+	text, _ := json.Marshal(r.text(string(body)))
+	return text
+}
+
+func (r *Redactor) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if r.fields[strings.ToLower(key)] {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = r.value(field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.value(item)
+		}
+		return v
+	case string:
+		return r.text(v)
+	default:
+		return v
+	}
+}
+
+func (r *Redactor) text(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rotatingFile is an append-only file that rolls over once it reaches a
+// maximum size, keeping a bounded number of rotated files
+type rotatingFile struct {
+	dir      string
+	name     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func openRotatingFile(dir, name string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	f := &rotatingFile{dir: dir, name: name, maxSize: maxSize, maxFiles: maxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) path() string {
+	return filepath.Join(f.dir, f.name+".jsonl")
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if it would exceed the size limit
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file with a timestamp suffix and starts a new one
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit file: %w", err)
+	}
+
+	rotated := filepath.Join(f.dir, fmt.Sprintf("%s-%s.jsonl", f.name, time.Now().UTC().Format("20060102T150405.000000000")))
+	if err := os.Rename(f.path(), rotated); err != nil {
+		return fmt.Errorf("failed to rotate audit file: %w", err)
+	}
+	if err := f.prune(); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// prune removes the oldest rotated files beyond the retention limit
+func (f *rotatingFile) prune() error {
+	if f.maxFiles <= 0 {
+		return nil
+	}
+
+	rotated, err := filepath.Glob(filepath.Join(f.dir, f.name+"-*.jsonl"))
+	if err != nil {
+		return fmt.Errorf("failed to list audit files: %w", err)
+	}
+	if len(rotated) <= f.maxFiles {
+		return nil
+	}
+
+	// Timestamp suffixes sort chronologically
+	sort.Strings(rotated)
+	for _, path := range rotated[:len(rotated)-f.maxFiles] {
+		if !strings.HasPrefix(filepath.Base(path), f.name+"-") {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove audit file: %w", err)
+		}
+	}
+	return nil
+}
+
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
@@ -6,7 +6,6 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -29,7 +28,7 @@ type CodeAnalysisConfig struct {
 	// ExcludePatterns defines patterns to exclude from analysis
 	ExcludePatterns []string `yaml:"exclude_patterns"`
 
-	// MaxComplexity is the maximum allowed cyclomatic complexity
+	// MaxComplexity is the maximum allowed nesting-weighted complexity
 	MaxComplexity int `yaml:"max_complexity"`
 
 	// MinTestCoverage is the minimum required test coverage percentage
@@ -99,9 +98,6 @@ func (c *CodeAnalysisCapability) Execute(ctx context.Context, input interface{})
 
 	result := &AnalysisResult{
 		Issues: make([]Issue, 0),
-		Stats: Statistics{
-			FilesAnalyzed: len(files),
-		},
 	}
 
 	fset := token.NewFileSet()
@@ -121,6 +117,7 @@ func (c *CodeAnalysisCapability) Execute(ctx context.Context, input interface{})
 		issues := c.analyzeFile(fset, node)
 		result.Issues = append(result.Issues, issues...)
 		result.Stats.IssuesFound += len(issues)
+		result.Stats.FilesAnalyzed++
 	}
 
 	return result, nil
@@ -143,12 +140,18 @@ func (c *CodeAnalysisCapability) Version() string {
 	return "1.0.0"
 }
 
-// shouldExclude checks if a file should be excluded from analysis
+// shouldExclude checks if a file should be excluded from analysis. Patterns
+// match the path as given or, when they have no separator, the file name
 func (c *CodeAnalysisCapability) shouldExclude(file string) bool {
 	for _, pattern := range c.config.ExcludePatterns {
 		if matched, _ := filepath.Match(pattern, file); matched {
 			return true
 		}
+		if !strings.Contains(pattern, "/") {
+			if matched, _ := filepath.Match(pattern, filepath.Base(file)); matched {
+				return true
+			}
+		}
 	}
 	return false
 }
@@ -222,7 +225,7 @@ func (c *CodeAnalysisCapability) checkComplexity(fset *token.FileSet, node *ast.
 					Line:     fset.Position(fn.Pos()).Line,
 					Rule:     "high-complexity",
 					Severity: "warning",
-					Message:  fmt.Sprintf("function %s has cyclomatic complexity of %d (max %d)", fn.Name.Name, complexity, c.config.MaxComplexity),
+					Message:  fmt.Sprintf("function %s has complexity of %d (max %d)", fn.Name.Name, complexity, c.config.MaxComplexity),
 					Suggestion: "Consider breaking down the function into smaller functions",
 				})
 			}
@@ -260,22 +263,31 @@ func (c *CodeAnalysisCapability) checkNaming(fset *token.FileSet, node *ast.File
 // checkErrorHandling checks error handling patterns
 func (c *CodeAnalysisCapability) checkErrorHandling(fset *token.FileSet, node *ast.File) []Issue {
 	var issues []Issue
+	local := localErrorFuncs(node)
+
+	unchecked := func(pos token.Pos, message string) {
+		issues = append(issues, Issue{
+			File:     fset.Position(pos).Filename,
+			Line:     fset.Position(pos).Line,
+			Rule:     "unchecked-error",
+			Severity: "error",
+			Message:  message,
+			Suggestion: "Add error handling code",
+		})
+	}
 
 	ast.Inspect(node, func(n ast.Node) bool {
-		if assign, ok := n.(*ast.AssignStmt); ok {
-			for _, expr := range assign.Rhs {
-				if call, ok := expr.(*ast.CallExpr); ok {
-					if isErrorReturningFunc(call) {
-						if !hasErrorCheck(assign) {
-							issues = append(issues, Issue{
-								File:     fset.Position(assign.Pos()).Filename,
-								Line:     fset.Position(assign.Pos()).Line,
-								Rule:     "unchecked-error",
-								Severity: "error",
-								Message:  "error is not checked",
-								Suggestion: "Add error handling code",
-							})
-						}
+		switch stmt := n.(type) {
+		case *ast.ExprStmt:
+			// A bare call drops every result, including the error
+			if call, ok := stmt.X.(*ast.CallExpr); ok && isErrorReturningFunc(call, local) {
+				unchecked(stmt.Pos(), fmt.Sprintf("error returned by %s is not checked", callName(call)))
+			}
+		case *ast.AssignStmt:
+			for _, expr := range stmt.Rhs {
+				if call, ok := expr.(*ast.CallExpr); ok && isErrorReturningFunc(call, local) {
+					if !hasErrorCheck(stmt) {
+						unchecked(stmt.Pos(), "error is not checked")
 					}
 				}
 			}
@@ -288,37 +300,143 @@ func (c *CodeAnalysisCapability) checkErrorHandling(fset *token.FileSet, node *a
 
 // Helper functions
 
+// calculateComplexity scores a function the way cognitive complexity does:
+// every branch or loop costs one plus its nesting depth, so deeply nested
+// code scores higher than the same number of flat branches
 func calculateComplexity(fn *ast.FuncDecl) int {
-	complexity := 1
-	ast.Inspect(fn, func(n ast.Node) bool {
-		switch n.(type) {
-		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.CaseClause, *ast.CommClause:
-			complexity++
+	if fn.Body == nil {
+		return 1
+	}
+	return 1 + nestedComplexity(fn.Body, 0)
+}
+
+func nestedComplexity(node ast.Node, nesting int) int {
+	if node == nil {
+		return 0
+	}
+	complexity := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.IfStmt:
+			complexity += 1 + nesting + nestedComplexity(s.Init, nesting) + ifComplexity(s, nesting)
+			return false
+		case *ast.ForStmt:
+			complexity += 1 + nesting + nestedComplexity(s.Cond, nesting) + nestedComplexity(s.Body, nesting+1)
+			return false
+		case *ast.RangeStmt:
+			complexity += 1 + nesting + nestedComplexity(s.Body, nesting+1)
+			return false
+		case *ast.SwitchStmt:
+			complexity += 1 + nesting + nestedComplexity(s.Body, nesting+1)
+			return false
+		case *ast.TypeSwitchStmt:
+			complexity += 1 + nesting + nestedComplexity(s.Body, nesting+1)
+			return false
+		case *ast.SelectStmt:
+			complexity += 1 + nesting + nestedComplexity(s.Body, nesting+1)
+			return false
+		case *ast.FuncLit:
+			complexity += nestedComplexity(s.Body, nesting+1)
+			return false
+		case *ast.BinaryExpr:
+			if s.Op == token.LAND || s.Op == token.LOR {
+				complexity++
+			}
 		}
 		return true
 	})
 	return complexity
 }
 
+// ifComplexity scores the condition and branches of an if statement; else
+// and else-if branches cost one each without a nesting increment
+func ifComplexity(s *ast.IfStmt, nesting int) int {
+	complexity := nestedComplexity(s.Cond, nesting) + nestedComplexity(s.Body, nesting+1)
+	switch e := s.Else.(type) {
+	case *ast.IfStmt:
+		complexity += 1 + nestedComplexity(e.Init, nesting) + ifComplexity(e, nesting)
+	case *ast.BlockStmt:
+		complexity += 1 + nestedComplexity(e, nesting+1)
+	}
+	return complexity
+}
+
 func isValidFuncName(name string) bool {
 	return !strings.Contains(name, "_") && name != strings.ToLower(name)
 }
 
-func isErrorReturningFunc(call *ast.CallExpr) bool {
-	if ident, ok := call.Fun.(*ast.Ident); ok {
-		return strings.HasSuffix(ident.Name, "Error") || strings.HasPrefix(ident.Name, "Error")
+// errorFuncs lists common standard library calls that return an error.
+// Without type information these are the calls the rule can recognise
+// besides functions declared in the analysed file
+var errorFuncs = map[string]bool{
+	"os.Open":        true,
+	"os.OpenFile":    true,
+	"os.Create":      true,
+	"os.ReadFile":    true,
+	"os.WriteFile":   true,
+	"os.Remove":      true,
+	"os.RemoveAll":   true,
+	"os.Rename":      true,
+	"os.Mkdir":       true,
+	"os.MkdirAll":    true,
+	"os.Chdir":       true,
+	"os.Setenv":      true,
+	"io.Copy":        true,
+	"io.ReadAll":     true,
+	"io.WriteString": true,
+	"json.Marshal":   true,
+	"json.Unmarshal": true,
+	"strconv.Atoi":   true,
+}
+
+// localErrorFuncs collects the functions declared in the file whose last
+// result is an error
+func localErrorFuncs(node *ast.File) map[string]bool {
+	funcs := make(map[string]bool)
+	for _, decl := range node.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Type.Results == nil {
+			continue
+		}
+		results := fn.Type.Results.List
+		if ident, ok := results[len(results)-1].Type.(*ast.Ident); ok && ident.Name == "error" {
+			funcs[fn.Name.Name] = true
+		}
 	}
-	return false
+	return funcs
 }
 
-func hasErrorCheck(assign *ast.AssignStmt) bool {
-	if len(assign.Lhs) != 2 {
-		return false
+func callName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		if pkg, ok := fun.X.(*ast.Ident); ok {
+			return pkg.Name + "." + fun.Sel.Name
+		}
+		return fun.Sel.Name
 	}
-	if ident, ok := assign.Lhs[1].(*ast.Ident); ok {
-		return ident.Name == "err"
+	return ""
+}
+
+func isErrorReturningFunc(call *ast.CallExpr, local map[string]bool) bool {
+	name := callName(call)
+	if _, ok := call.Fun.(*ast.Ident); ok {
+		if local[name] {
+			return true
+		}
+		return strings.HasSuffix(name, "Error") || strings.HasPrefix(name, "Error")
 	}
-	return false
+	return errorFuncs[name]
+}
+
+// hasErrorCheck reports whether the error result of an assignment is kept;
+// assigning it to the blank identifier discards it
+func hasErrorCheck(assign *ast.AssignStmt) bool {
+	if ident, ok := assign.Lhs[len(assign.Lhs)-1].(*ast.Ident); ok {
+		return ident.Name != "_"
+	}
+	return true
 }
 
 // Example YAML configuration:
@@ -2,6 +2,7 @@ package capability
 
 import (
 	"context"
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"os"
@@ -268,6 +269,56 @@ func SimpleFunction() {
 		assert.Equal(t, 2, complexity) // Base complexity (1) + if statement (1)
 	})
 
+	t.Run("calculateComplexity weights nesting", func(t *testing.T) {
+		code := `
+package test
+
+func NestedFunction(x int) {
+	if x > 0 {
+		for i := 0; i < x; i++ {
+			if i > 1 && i < 5 {
+				x++
+			} else {
+				x--
+			}
+		}
+	}
+}
+`
+		fset := token.NewFileSet()
+		node, err := parser.ParseFile(fset, "", code, parser.ParseComments)
+		assert.NoError(t, err)
+
+		fn := node.Decls[0].(*ast.FuncDecl)
+		// Base (1) + if (1) + for at depth 1 (2) + if at depth 2 (3) + && (1) + else (1)
+		assert.Equal(t, 9, calculateComplexity(fn))
+	})
+
+	t.Run("checkErrorHandling", func(t *testing.T) {
+		code := `
+package test
+
+func load() error { return nil }
+
+func Run() {
+	load()
+	f, _ := os.Open("a")
+	data, err := os.ReadFile("b")
+	_, _, _ = f, data, err
+}
+`
+		fset := token.NewFileSet()
+		node, err := parser.ParseFile(fset, "", code, parser.ParseComments)
+		assert.NoError(t, err)
+
+		cap := NewCodeAnalysisCapability(zaptest.NewLogger(t), &CodeAnalysisConfig{})
+		issues := cap.checkErrorHandling(fset, node)
+		if assert.Len(t, issues, 2) {
+			assert.Equal(t, 7, issues[0].Line)
+			assert.Equal(t, 8, issues[1].Line)
+		}
+	})
+
 	t.Run("isValidFuncName", func(t *testing.T) {
 		tests := []struct {
 			name     string
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"fmt"
+	"path"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/pimentel/peppergo/internal/api"
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/internal/metrics"
+	"github.com/pimentel/peppergo/internal/policy"
+	"github.com/pimentel/peppergo/internal/provider"
+	"github.com/pimentel/peppergo/internal/proxy"
+	"github.com/pimentel/peppergo/internal/redact"
+	"github.com/pimentel/peppergo/internal/tokenizer"
+	"github.com/pimentel/peppergo/internal/tracing"
+)
+
+// NewLogger creates the server logger at the configured level and format
+func (c *Config) NewLogger() (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(c.Logging.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+
+	zc := zap.NewProductionConfig()
+	zc.Level = zap.NewAtomicLevelAt(level)
+	zc.Encoding = c.Logging.Format
+	if c.Logging.Format == "console" {
+		zc.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	return zc.Build()
+}
+
+// NewService creates a proxy service with the configured providers, queues and response cache
+func (c *Config) NewService(logger *zap.Logger) (*proxy.Service, error) {
+	service := proxy.NewService()
+	service.SetCircuitConfig(proxy.CircuitConfig{
+		FailureThreshold: c.Server.CircuitFailureThreshold,
+		Cooldown:         c.Server.CircuitCooldown,
+	})
+	if err := RegisterProviders(logger, service, c.Providers); err != nil {
+		return nil, err
+	}
+	engine, err := newPolicyEngine(logger, c.policyConfig())
+	if err != nil {
+		return nil, err
+	}
+	if engine != nil {
+		service.SetPolicy(engine)
+	}
+	if c.Cache.Enabled {
+		service.SetCache(c.Cache.proxyConfig())
+	}
+	redactor, err := newRedactor(logger, c.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	if redactor != nil {
+		service.SetRedactor(redactor)
+	}
+	registry, err := newTokenizer(logger, c.Tokenizer)
+	if err != nil {
+		return nil, err
+	}
+	service.SetTokenizer(registry)
+	manager, err := newContextWindow(logger, c.ContextWindow, registry)
+	if err != nil {
+		return nil, err
+	}
+	service.SetContextWindow(manager)
+	return service, nil
+}
+
+// NewMetrics creates the metrics registry and records the service's request
+// metrics into it, or returns nil if metrics are not configured
+func (c *Config) NewMetrics(service *proxy.Service) *metrics.Registry {
+	if c.Metrics == nil {
+		return nil
+	}
+	registry := metrics.NewRegistry()
+	m := proxy.NewMetrics(registry)
+	if c.Tokenizer != nil {
+		for model, pricing := range c.Tokenizer.Pricing {
+			m.SetPricing(model, pricing)
+		}
+	}
+	service.SetMetrics(m)
+	return registry
+}
+
+// MetricsOptions returns the API options recording HTTP metrics into registry,
+// serving it with the API unless it has a listener of its own
+func (c *Config) MetricsOptions(registry *metrics.Registry) []api.Option {
+	if registry == nil {
+		return nil
+	}
+	if c.Metrics.Addr != "" {
+		return []api.Option{api.WithHTTPMetrics(registry)}
+	}
+	return []api.Option{api.WithMetrics(registry)}
+}
+
+// NewTracer creates the tracer exporting request spans, or returns nil if
+// tracing is not configured
+func (c *Config) NewTracer(logger *zap.Logger) (*tracing.Tracer, error) {
+	t := c.Tracing
+	if t == nil {
+		return nil, nil
+	}
+
+	var exporter tracing.Exporter
+	switch t.Exporter {
+	case TracingExporterOTLP:
+		exporter = tracing.NewOTLPExporter(&tracing.OTLPConfig{
+			Endpoint:    t.Endpoint,
+			ServiceName: t.ServiceName,
+			Headers:     t.Headers,
+		})
+	case TracingExporterFile:
+		file, err := tracing.NewFileExporter(t.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create span exporter: %w", err)
+		}
+		exporter = file
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", t.Exporter)
+	}
+
+	sampleRate := 1.0
+	if t.SampleRate != nil {
+		sampleRate = *t.SampleRate
+	}
+	return tracing.NewTracer(logger, &tracing.TracerConfig{
+		SampleRate:    sampleRate,
+		Exporter:      exporter,
+		FlushInterval: t.FlushInterval,
+	}), nil
+}
+
+// NewBatchManager creates the batch manager serving /v1/batches, resuming
+// unfinished batches, or returns nil if batches are not configured
+func (c *Config) NewBatchManager(logger *zap.Logger, service *proxy.Service) (*proxy.BatchManager, error) {
+	if c.Batches == nil {
+		return nil, nil
+	}
+	manager, err := proxy.NewBatchManager(service, proxy.BatchConfig{
+		Dir:           c.Batches.Dir,
+		Concurrency:   c.Batches.Concurrency,
+		MaxInputBytes: c.Batches.MaxInputBytes,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch manager: %w", err)
+	}
+	return manager, nil
+}
+
+// newTokenizer creates the tokenizer registry of the service, or nil if none is configured
+func newTokenizer(logger *zap.Logger, config *tokenizer.Config) (*tokenizer.Registry, error) {
+	if config == nil {
+		return nil, nil
+	}
+	return tokenizer.NewRegistry(logger, config)
+}
+
+// newRedactor creates the redactor of the service, or nil if redaction is not configured
+func newRedactor(logger *zap.Logger, config *redact.Config) (*redact.Redactor, error) {
+	if config == nil {
+		return nil, nil
+	}
+	// The redactor fills in the default mode, which must not leak into the loaded configuration
+	copied := *config
+	return redact.NewRedactor(logger, &copied)
+}
+
+// newContextWindow creates the context window manager of the service, or nil
+// if none is configured. Tokens are counted with registry when it is set.
+func newContextWindow(logger *zap.Logger, config *contextwindow.Config, registry *tokenizer.Registry) (*contextwindow.Manager, error) {
+	if config == nil {
+		return nil, nil
+	}
+	var opts []contextwindow.Option
+	if registry != nil {
+		opts = append(opts, contextwindow.WithCounter(registry))
+	}
+	// The manager fills in defaults, which must not leak into the loaded configuration
+	copied := *config
+	return contextwindow.NewManager(logger, &copied, opts...)
+}
+
+// proxyConfig returns the proxy cache configuration; a disabled cache has no TTL
+func (c CacheConfig) proxyConfig() proxy.CacheConfig {
+	if !c.Enabled {
+		return proxy.CacheConfig{}
+	}
+	return proxy.CacheConfig{TTL: c.TTL, MaxEntries: c.MaxEntries}
+}
+
+// RegisterProviders creates the configured providers and registers them with
+// their queue limits into the service
+func RegisterProviders(logger *zap.Logger, service *proxy.Service, providers []ProviderConfig) error {
+	for _, p := range providers {
+		if err := registerProvider(logger, service, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// policyConfig returns the default, provider and tenant policies, or nil if there are none
+func (c *Config) policyConfig() *policy.Config {
+	config := &policy.Config{Tenants: c.Policy.Tenants}
+	for _, p := range c.Providers {
+		if p.Policy != nil {
+			if config.Providers == nil {
+				config.Providers = make(map[string]policy.Policy)
+			}
+			config.Providers[p.ProviderName()] = *p.Policy
+		}
+	}
+	if c.Policy.Default != nil {
+		config.Default = *c.Policy.Default
+	}
+	if c.Policy.Default == nil && len(config.Providers) == 0 && len(config.Tenants) == 0 {
+		return nil
+	}
+	return config
+}
+
+// newPolicyEngine compiles the policies, returning nil if there are none
+func newPolicyEngine(logger *zap.Logger, config *policy.Config) (*policy.Engine, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	engine, err := policy.NewEngine(logger, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	return engine, nil
+}
+
+func registerProvider(logger *zap.Logger, service *proxy.Service, p ProviderConfig) error {
+	config := p.Config
+	instance, err := provider.New(logger, &config)
+	if err != nil {
+		return err
+	}
+	if err := service.RegisterProvider(instance); err != nil {
+		return err
+	}
+
+	name := instance.Name()
+	if p.MaxConcurrent > 0 {
+		if err := service.SetQueueConfig(name, p.queueConfig()); err != nil {
+			return err
+		}
+	}
+	if p.Disabled {
+		return service.SetProviderEnabled(name, false)
+	}
+	return nil
+}
+
+// HandlerOptions returns the API options for the configured routes, API keys and client certificates
+func (c *Config) HandlerOptions() []api.Option {
+	routes := make([]api.Route, 0, len(c.Routes.Models))
+	for _, route := range c.Routes.Models {
+		routes = append(routes, api.Route{Model: route.Model, Provider: route.Provider})
+	}
+	opts := []api.Option{api.WithRoutes(c.Routes.Default, routes)}
+
+	if len(c.Auth.Keys) > 0 {
+		keys := make([]string, 0, len(c.Auth.Keys))
+		tenants := make(map[string]string)
+		priorities := make(map[string]proxy.Priority)
+		for _, key := range c.Auth.Keys {
+			keys = append(keys, key.Key)
+			if key.Tenant != "" {
+				tenants[key.Key] = key.Tenant
+			}
+			if key.Priority != "" {
+				// Priorities were checked by Validate
+				priorities[key.Key], _ = proxy.ParsePriority(key.Priority)
+			}
+		}
+		opts = append(opts,
+			api.WithAPIKeys(keys),
+			api.WithTenantKeys(tenants),
+			api.WithPriorityKeys(priorities))
+	}
+	if len(c.Auth.AdminKeys) > 0 {
+		opts = append(opts, api.WithAdminKeys(c.Auth.AdminKeys))
+	}
+	if len(c.Auth.ClientTenants) > 0 {
+		opts = append(opts, api.WithClientTenants(c.Auth.ClientTenants))
+	}
+	if c.Auth.TrustTenantHeader {
+		opts = append(opts, api.WithTenantHeader())
+	}
+	return opts
+}
+
+// ProviderFor returns the provider a request for model is routed to: the first
+// matching model route, the default route, or else the first enabled provider
+func (c *Config) ProviderFor(model string) string {
+	for _, route := range c.Routes.Models {
+		if matched, _ := path.Match(route.Model, model); matched {
+			return route.Provider
+		}
+	}
+	if c.Routes.Default != "" {
+		return c.Routes.Default
+	}
+	for _, p := range c.Providers {
+		if !p.Disabled {
+			return p.ProviderName()
+		}
+	}
+	return ""
+}
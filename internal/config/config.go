@@ -0,0 +1,540 @@
+// Package config loads and validates the peppergo server configuration file
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pimentel/peppergo/internal/audit"
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/internal/policy"
+	"github.com/pimentel/peppergo/internal/provider"
+	"github.com/pimentel/peppergo/internal/proxy"
+	"github.com/pimentel/peppergo/internal/redact"
+	"github.com/pimentel/peppergo/internal/server"
+	"github.com/pimentel/peppergo/internal/tokenizer"
+)
+
+// Config is the server configuration
+type Config struct {
+	Server    ServerConfig     `yaml:"server"`
+	Providers []ProviderConfig `yaml:"providers"`
+
+	// ProviderDir is a directory of provider definitions, as in assets/providers,
+	// to register in addition to Providers. Relative paths are resolved against
+	// the directory of the configuration file.
+	ProviderDir string `yaml:"provider_dir"`
+
+	Routes  RoutesConfig  `yaml:"routes"`
+	Auth    AuthConfig    `yaml:"auth"`
+	Logging LoggingConfig `yaml:"logging"`
+	Cache   CacheConfig   `yaml:"cache"`
+
+	// Policy limits requests of all callers and of tenants, in addition
+	// to the policies of providers
+	Policy PolicyConfig `yaml:"policy"`
+
+	// Batches enables the /v1/batches endpoints when set
+	Batches *BatchesConfig `yaml:"batches"`
+
+	// Metrics records Prometheus metrics when set
+	Metrics *MetricsConfig `yaml:"metrics"`
+
+	// Tracing records and exports request spans when set
+	Tracing *TracingConfig `yaml:"tracing"`
+
+	// Redaction masks, blocks or audits sensitive values in chat and
+	// embeddings requests before they are sent when set
+	Redaction *redact.Config `yaml:"redaction"`
+
+	// ContextWindow fits chat requests into the context window of their
+	// model before they are sent when set
+	ContextWindow *contextwindow.Config `yaml:"context_window"`
+
+	// Tokenizer counts tokens with local vocabularies and prices models when
+	// set; requests that cannot fit their model's window are then rejected
+	// before they are sent. A relative vocab_dir is resolved against the
+	// directory of the configuration file.
+	Tokenizer *tokenizer.Config `yaml:"tokenizer"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	// Addr is the TCP address to listen on (defaults to ":8080")
+	Addr string `yaml:"addr"`
+
+	// Socket is a unix domain socket path to listen on instead of Addr
+	Socket string `yaml:"socket"`
+
+	// TLS serves HTTPS when set. Relative paths are resolved against the
+	// directory of the configuration file.
+	TLS *server.TLSConfig `yaml:"tls"`
+
+	// ReadTimeout bounds reading a request, including its body
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+
+	// WriteTimeout bounds writing a response; leave unset to allow long streams
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+
+	// ShutdownTimeout is how long in-flight requests may run on shutdown (defaults to 30s)
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// HealthCheckInterval is the time between provider health probes (defaults to 30s)
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"`
+
+	// HealthCheckTimeout bounds a single provider health probe (defaults to 5s)
+	HealthCheckTimeout time.Duration `yaml:"health_check_timeout"`
+
+	// ReloadInterval is how often the configuration files are checked for
+	// changes (defaults to 5s; negative disables watching)
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+
+	// CircuitFailureThreshold is the number of consecutive provider failures
+	// that opens a provider's circuit breaker (defaults to 5)
+	CircuitFailureThreshold int `yaml:"circuit_failure_threshold"`
+
+	// CircuitCooldown is how long an open circuit rejects requests before
+	// letting a trial request through (defaults to 30s)
+	CircuitCooldown time.Duration `yaml:"circuit_cooldown"`
+}
+
+// ProviderConfig configures a provider and its request queue
+type ProviderConfig struct {
+	provider.Config `yaml:",inline"`
+
+	// MaxConcurrent bounds concurrent requests to the provider (0 means unlimited)
+	MaxConcurrent int `yaml:"max_concurrent"`
+
+	// MaxQueueTime bounds how long a request waits for a concurrency slot
+	MaxQueueTime time.Duration `yaml:"max_queue_time"`
+
+	// MaxQueueDepth bounds the number of waiting requests
+	MaxQueueDepth int `yaml:"max_queue_depth"`
+
+	// Disabled registers the provider without sending it requests
+	Disabled bool `yaml:"disabled"`
+
+	// Policy limits requests sent to the provider
+	Policy *policy.Policy `yaml:"policy"`
+}
+
+func (p ProviderConfig) queueConfig() proxy.QueueConfig {
+	return proxy.QueueConfig{
+		MaxConcurrent: p.MaxConcurrent,
+		MaxQueueTime:  p.MaxQueueTime,
+		MaxQueueDepth: p.MaxQueueDepth,
+	}
+}
+
+// PolicyConfig configures the policies that are not bound to a provider.
+// Every policy that applies to a request is enforced, so the strictest limit wins.
+type PolicyConfig struct {
+	// Default applies to all requests
+	Default *policy.Policy `yaml:"default"`
+
+	// Tenants apply to requests made for the named tenant
+	Tenants map[string]policy.Policy `yaml:"tenants"`
+}
+
+// RoutesConfig selects providers for requests that do not name one
+type RoutesConfig struct {
+	// Default is the provider used when no model route matches
+	Default string `yaml:"default"`
+
+	// Models routes models, by exact name or pattern, to providers
+	Models []RouteConfig `yaml:"models"`
+}
+
+// RouteConfig routes requests for matching models to a provider
+type RouteConfig struct {
+	Model    string `yaml:"model"`
+	Provider string `yaml:"provider"`
+}
+
+// AuthConfig configures API key authentication
+type AuthConfig struct {
+	// Keys are the API keys accepted on /v1 endpoints. When empty, requests are not authenticated.
+	Keys []KeyConfig `yaml:"keys"`
+
+	// AdminKeys are the API keys accepted on /admin endpoints. When empty, the admin API is disabled.
+	AdminKeys []string `yaml:"admin_keys"`
+
+	// ClientTenants maps TLS client certificate identities (common name, DNS
+	// name, URI or email address) to tenants. Callers with a mapped certificate
+	// are accepted on /v1 endpoints without an API key.
+	ClientTenants map[string]string `yaml:"client_tenants"`
+
+	// TrustTenantHeader lets callers without a tenant bound to their key or
+	// certificate name their tenant with the X-Tenant header. Only enable it
+	// when every caller is trusted, such as behind a gateway setting the header.
+	TrustTenantHeader bool `yaml:"trust_tenant_header"`
+}
+
+// KeyConfig is an API key and the tenant and queue priority of its requests
+type KeyConfig struct {
+	Key      string `yaml:"key"`
+	Tenant   string `yaml:"tenant"`
+	Priority string `yaml:"priority"`
+}
+
+// LoggingConfig configures the server logs
+type LoggingConfig struct {
+	// Level is one of debug, info, warn or error (defaults to info)
+	Level string `yaml:"level"`
+
+	// Format is json or console (defaults to json)
+	Format string `yaml:"format"`
+
+	// Audit enables the audit log when set
+	Audit *audit.Config `yaml:"audit"`
+}
+
+// CacheConfig configures the chat response cache
+type CacheConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"max_entries"`
+}
+
+// BatchesConfig configures batch job processing
+type BatchesConfig struct {
+	// Dir is where batch inputs, results and progress are kept. Relative
+	// paths are resolved against the directory of the configuration file.
+	Dir string `yaml:"dir"`
+
+	// Concurrency bounds the batch requests in flight (defaults to 4)
+	Concurrency int `yaml:"concurrency"`
+
+	// MaxInputBytes bounds the size of an uploaded batch input file
+	// (defaults to 100 MiB)
+	MaxInputBytes int64 `yaml:"max_input_bytes"`
+}
+
+// MetricsConfig configures the Prometheus metrics endpoint. Spend is
+// estimated with the pricing of the tokenizer section.
+type MetricsConfig struct {
+	// Addr serves /metrics without authentication on a separate listener,
+	// which must only be reachable by the scraper. When empty, /metrics is
+	// served with the API and needs an API key.
+	Addr string `yaml:"addr"`
+}
+
+// Tracing exporters
+const (
+	TracingExporterOTLP = "otlp"
+	TracingExporterFile = "file"
+)
+
+// TracingConfig configures distributed tracing
+type TracingConfig struct {
+	// Exporter is otlp, which posts spans to an OpenTelemetry collector, or
+	// file, which appends them as JSON lines to File
+	Exporter string `yaml:"exporter"`
+
+	// Endpoint is the collector base URL of the otlp exporter, e.g. http://localhost:4318
+	Endpoint string `yaml:"endpoint"`
+
+	// Headers are added to otlp export requests, e.g. for authentication
+	Headers map[string]string `yaml:"headers"`
+
+	// File is the span file of the file exporter. Relative paths are
+	// resolved against the directory of the configuration file.
+	File string `yaml:"file"`
+
+	// ServiceName is reported as the service.name resource attribute (defaults to peppergo)
+	ServiceName string `yaml:"service_name"`
+
+	// SampleRate is the fraction of new traces that are recorded (defaults to 1);
+	// traces continued from a sampled traceparent are always recorded
+	SampleRate *float64 `yaml:"sample_rate"`
+
+	// FlushInterval is the maximum time a finished span waits before export (defaults to 5s)
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// Load reads, interpolates and validates a configuration file
+func Load(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	config, err := parse(data, filepath.Dir(filename))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return config, nil
+}
+
+// Parse interpolates environment variables into a configuration, decodes
+// it, loads provider definitions, applies defaults and validates the result
+func Parse(data []byte) (*Config, error) {
+	return parse(data, "")
+}
+
+// parse resolves a relative provider directory, TLS files, span file, batch
+// directory and vocabulary directory against baseDir
+func parse(data []byte, baseDir string) (*Config, error) {
+	data, err := Interpolate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if config.ProviderDir != "" {
+		if !filepath.IsAbs(config.ProviderDir) {
+			config.ProviderDir = filepath.Join(baseDir, config.ProviderDir)
+		}
+		definitions, err := LoadProviderDefinitions(config.ProviderDir)
+		if err != nil {
+			return nil, err
+		}
+		config.Providers = append(config.Providers, definitions...)
+	}
+	if tls := config.Server.TLS; tls != nil {
+		for _, file := range []*string{&tls.CertFile, &tls.KeyFile, &tls.ClientCAFile} {
+			if *file != "" && !filepath.IsAbs(*file) {
+				*file = filepath.Join(baseDir, *file)
+			}
+		}
+	}
+
+	if config.Tracing != nil && config.Tracing.File != "" && !filepath.IsAbs(config.Tracing.File) {
+		config.Tracing.File = filepath.Join(baseDir, config.Tracing.File)
+	}
+	if config.Batches != nil && config.Batches.Dir != "" && !filepath.IsAbs(config.Batches.Dir) {
+		config.Batches.Dir = filepath.Join(baseDir, config.Batches.Dir)
+	}
+	if config.Tokenizer != nil && config.Tokenizer.VocabDir != "" && !filepath.IsAbs(config.Tokenizer.VocabDir) {
+		config.Tokenizer.VocabDir = filepath.Join(baseDir, config.Tokenizer.VocabDir)
+	}
+
+	config.setDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Config) setDefaults() {
+	if c.Server.Addr == "" {
+		c.Server.Addr = ":8080"
+	}
+	if c.Server.ShutdownTimeout == 0 {
+		c.Server.ShutdownTimeout = 30 * time.Second
+	}
+	if c.Server.HealthCheckInterval == 0 {
+		c.Server.HealthCheckInterval = 30 * time.Second
+	}
+	if c.Server.HealthCheckTimeout == 0 {
+		c.Server.HealthCheckTimeout = 5 * time.Second
+	}
+	if c.Server.ReloadInterval == 0 {
+		c.Server.ReloadInterval = 5 * time.Second
+	}
+	if c.Logging.Level == "" {
+		c.Logging.Level = "info"
+	}
+	if c.Logging.Format == "" {
+		c.Logging.Format = "json"
+	}
+}
+
+// Validate checks the configuration and reports every problem found
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.ShutdownTimeout < 0 {
+		fail("server: timeouts must not be negative")
+	}
+	if c.Server.HealthCheckInterval <= 0 || c.Server.HealthCheckTimeout <= 0 {
+		fail("server: health check interval and timeout must be positive")
+	}
+	if c.Server.CircuitFailureThreshold < 0 || c.Server.CircuitCooldown < 0 {
+		fail("server: circuit failure threshold and cooldown must not be negative")
+	}
+	if c.Server.TLS != nil {
+		if err := c.Server.TLS.Validate(); err != nil {
+			fail("server.tls: %w", err)
+		}
+	}
+
+	if len(c.Providers) == 0 {
+		fail("providers: at least one provider is required")
+	}
+	names := make(map[string]bool, len(c.Providers))
+	for i, p := range c.Providers {
+		// Constructing a provider does no I/O and catches type-specific errors
+		config := p.Config
+		if _, err := provider.New(zap.NewNop(), &config); err != nil {
+			fail("providers[%d]: %w", i, err)
+			continue
+		}
+		name := p.ProviderName()
+		if names[name] {
+			fail("providers[%d]: duplicate provider name %q", i, name)
+		}
+		names[name] = true
+		if p.MaxConcurrent < 0 || p.MaxQueueDepth < 0 || p.MaxQueueTime < 0 {
+			fail("providers[%d] (%s): queue limits must not be negative", i, name)
+		}
+		if p.Policy != nil {
+			if _, err := policy.NewEngine(zap.NewNop(), &policy.Config{Default: *p.Policy}); err != nil {
+				fail("providers[%d] (%s): invalid policy: %w", i, name, err)
+			}
+		}
+	}
+
+	if c.Policy.Default != nil {
+		if _, err := policy.NewEngine(zap.NewNop(), &policy.Config{Default: *c.Policy.Default}); err != nil {
+			fail("policy.default: %w", err)
+		}
+	}
+	for tenant, p := range c.Policy.Tenants {
+		if tenant == "" {
+			fail("policy.tenants: tenant name is required")
+			continue
+		}
+		if _, err := policy.NewEngine(zap.NewNop(), &policy.Config{Default: p}); err != nil {
+			fail("policy.tenants[%s]: %w", tenant, err)
+		}
+	}
+
+	if c.Routes.Default != "" && !names[c.Routes.Default] {
+		fail("routes.default: unknown provider %q", c.Routes.Default)
+	}
+	for i, route := range c.Routes.Models {
+		if route.Model == "" {
+			fail("routes.models[%d]: model is required", i)
+		} else if _, err := path.Match(route.Model, ""); err != nil {
+			fail("routes.models[%d]: invalid model pattern %q", i, route.Model)
+		}
+		if !names[route.Provider] {
+			fail("routes.models[%d]: unknown provider %q", i, route.Provider)
+		}
+	}
+
+	keys := make(map[string]bool, len(c.Auth.Keys))
+	for i, key := range c.Auth.Keys {
+		if key.Key == "" {
+			fail("auth.keys[%d]: key is required", i)
+			continue
+		}
+		if keys[key.Key] {
+			fail("auth.keys[%d]: duplicate key", i)
+		}
+		keys[key.Key] = true
+		if key.Priority != "" {
+			if _, err := proxy.ParsePriority(key.Priority); err != nil {
+				fail("auth.keys[%d]: %w", i, err)
+			}
+		}
+	}
+	for i, key := range c.Auth.AdminKeys {
+		if key == "" {
+			fail("auth.admin_keys[%d]: key is required", i)
+		}
+	}
+	if len(c.Auth.ClientTenants) > 0 && (c.Server.TLS == nil || c.Server.TLS.ClientCAFile == "") {
+		fail("auth.client_tenants: server.tls.client_ca_file is required to verify client certificates")
+	}
+	for identity, tenant := range c.Auth.ClientTenants {
+		if tenant == "" {
+			fail("auth.client_tenants[%s]: tenant is required", identity)
+		}
+	}
+
+	if _, err := zapcore.ParseLevel(c.Logging.Level); err != nil {
+		fail("logging.level: unknown level %q", c.Logging.Level)
+	}
+	if c.Logging.Format != "json" && c.Logging.Format != "console" {
+		fail("logging.format: must be json or console, got %q", c.Logging.Format)
+	}
+	if c.Logging.Audit != nil && c.Logging.Audit.Dir == "" {
+		fail("logging.audit.dir: directory is required")
+	}
+
+	if c.Cache.Enabled && c.Cache.TTL <= 0 {
+		fail("cache.ttl: must be positive when the cache is enabled")
+	}
+	if c.Cache.MaxEntries < 0 {
+		fail("cache.max_entries: must not be negative")
+	}
+
+	if c.Batches != nil {
+		if c.Batches.Dir == "" {
+			fail("batches.dir: directory is required")
+		}
+		if c.Batches.Concurrency < 0 {
+			fail("batches.concurrency: must not be negative")
+		}
+		if c.Batches.MaxInputBytes < 0 {
+			fail("batches.max_input_bytes: must not be negative")
+		}
+	}
+
+	if c.Metrics != nil && c.Metrics.Addr != "" && c.Metrics.Addr == c.Server.Addr && c.Server.Socket == "" {
+		fail("metrics.addr: must differ from server.addr")
+	}
+
+	if t := c.Tracing; t != nil {
+		switch t.Exporter {
+		case TracingExporterOTLP:
+			if t.Endpoint == "" {
+				fail("tracing.endpoint: endpoint is required by the otlp exporter")
+			}
+		case TracingExporterFile:
+			if t.File == "" {
+				fail("tracing.file: file is required by the file exporter")
+			}
+		default:
+			fail("tracing.exporter: must be otlp or file, got %q", t.Exporter)
+		}
+		if t.SampleRate != nil && (*t.SampleRate < 0 || *t.SampleRate > 1) {
+			fail("tracing.sample_rate: must be between 0 and 1")
+		}
+		if t.FlushInterval < 0 {
+			fail("tracing.flush_interval: must not be negative")
+		}
+	}
+
+	if c.Redaction != nil {
+		if _, err := newRedactor(zap.NewNop(), c.Redaction); err != nil {
+			fail("redaction: %w", err)
+		}
+	}
+
+	if c.ContextWindow != nil {
+		if err := c.ContextWindow.Validate(); err != nil {
+			fail("context_window: %w", err)
+		}
+	}
+	if c.Tokenizer != nil {
+		if err := c.Tokenizer.Validate(); err != nil {
+			fail("tokenizer: %w", err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
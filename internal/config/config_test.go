@@ -0,0 +1,362 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/api"
+	"github.com/pimentel/peppergo/internal/metrics"
+	"github.com/pimentel/peppergo/internal/proxy"
+	"github.com/pimentel/peppergo/internal/tracing"
+)
+
+const testConfig = `
+server:
+  addr: ":9090"
+  shutdown_timeout: "5s"
+
+providers:
+  - type: openai
+    name: primary
+    api_key: "${TEST_OPENAI_KEY}"
+    base_url: "${TEST_OPENAI_URL:-http://localhost:1234/v1}"
+    models: [gpt-4o, text-embedding-3-small]
+    rate_limit: 2
+    max_concurrent: 4
+    max_queue_time: "2s"
+  - type: ollama
+    disabled: true
+
+routes:
+  default: primary
+  models:
+    - model: "llama*"
+      provider: ollama
+
+auth:
+  keys:
+    - key: team-key
+      tenant: team
+      priority: batch
+  admin_keys: [admin-key]
+
+logging:
+  level: debug
+  format: console
+
+cache:
+  enabled: true
+  ttl: "1m"
+`
+
+func TestParse(t *testing.T) {
+	t.Setenv("TEST_OPENAI_KEY", "sk-test")
+
+	cfg, err := Parse([]byte(testConfig))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9090", cfg.Server.Addr)
+	assert.Equal(t, 5*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, 30*time.Second, cfg.Server.HealthCheckInterval, "default")
+
+	require.Len(t, cfg.Providers, 2)
+	assert.Equal(t, "sk-test", cfg.Providers[0].APIKey)
+	assert.Equal(t, "http://localhost:1234/v1", cfg.Providers[0].BaseURL)
+	assert.Equal(t, 2.0, cfg.Providers[0].RateLimit)
+	assert.Equal(t, 4, cfg.Providers[0].MaxConcurrent)
+	assert.Equal(t, 2*time.Second, cfg.Providers[0].MaxQueueTime)
+	assert.True(t, cfg.Providers[1].Disabled)
+	assert.Equal(t, time.Minute, cfg.Cache.TTL)
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Run("unset variable", func(t *testing.T) {
+		_, err := Parse([]byte("providers:\n  - type: openai\n    api_key: ${TEST_UNSET_KEY}\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "line 3: environment variable TEST_UNSET_KEY is not set")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := Parse([]byte("providers:\n  - type: openai\n    apikey: x\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "apikey")
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		_, err := Parse([]byte(`
+server:
+  circuit_cooldown: -1s
+providers:
+  - type: anthropic
+  - type: openai
+  - type: openai
+routes:
+  default: missing
+auth:
+  keys:
+    - key: k
+      priority: urgent
+logging:
+  level: loud
+cache:
+  enabled: true
+context_window:
+  strategy: forget
+tokenizer:
+  encodings:
+    my-model: p50k_base
+batches:
+  concurrency: -1
+  max_input_bytes: -1
+tracing:
+  exporter: zipkin
+  sample_rate: 2
+redaction:
+  mode: scrub
+policy:
+  default:
+    input:
+      deny_patterns: ["("]
+  tenants:
+    trial:
+      output:
+        deny_patterns: ["["]
+`))
+		require.Error(t, err)
+		for _, want := range []string{
+			"server: circuit failure threshold and cooldown must not be negative",
+			"providers[0]: provider anthropic: api_key is required",
+			`providers[2]: duplicate provider name "openai"`,
+			`routes.default: unknown provider "missing"`,
+			"auth.keys[0]",
+			`logging.level: unknown level "loud"`,
+			"cache.ttl",
+			`context_window: unknown strategy "forget"`,
+			`tokenizer: encodings: unknown encoding "p50k_base" for my-model`,
+			"policy.default: default input rules: invalid deny pattern",
+			"batches.dir: directory is required",
+			"batches.concurrency: must not be negative",
+			"batches.max_input_bytes: must not be negative",
+			`tracing.exporter: must be otlp or file, got "zipkin"`,
+			"tracing.sample_rate: must be between 0 and 1",
+			"redaction: unknown redaction mode: scrub",
+			"policy.tenants[trial]: default output rules: invalid deny pattern",
+		} {
+			assert.Contains(t, err.Error(), want)
+		}
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		_, err := Parse([]byte(`
+server:
+  tls:
+    cert_file: server.crt
+    client_auth: require
+providers:
+  - type: ollama
+auth:
+  client_tenants:
+    billing-service: billing
+`))
+		require.Error(t, err)
+		for _, want := range []string{
+			"server.tls: cert_file and key_file are required",
+			"auth.client_tenants: server.tls.client_ca_file is required",
+		} {
+			assert.Contains(t, err.Error(), want)
+		}
+	})
+
+	t.Run("no providers", func(t *testing.T) {
+		_, err := Parse([]byte("server:\n  addr: \":8080\"\n"))
+		assert.ErrorContains(t, err, "at least one provider is required")
+	})
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("TEST_VALUE", "value")
+
+	out, err := Interpolate([]byte("a: ${TEST_VALUE}\n# b: ${TEST_UNSET}\nc: ${TEST_UNSET:-fallback}\nd: $TEST_VALUE\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "a: value\n# b: ${TEST_UNSET}\nc: fallback\nd: $TEST_VALUE\n", string(out))
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("TEST_OPENAI_KEY", "sk-test")
+	filename := filepath.Join(t.TempDir(), "peppergo.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(testConfig), 0o600))
+
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+	assert.Len(t, cfg.Providers, 2)
+
+	// TLS files are relative to the configuration file
+	dir := t.TempDir()
+	filename = filepath.Join(dir, "tls.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(`
+server:
+  socket: /run/peppergo.sock
+  tls:
+    cert_file: certs/server.crt
+    key_file: /etc/peppergo/server.key
+    client_ca_file: certs/ca.crt
+providers:
+  - type: ollama
+auth:
+  client_tenants:
+    billing-service: billing
+tokenizer:
+  vocab_dir: vocab
+batches:
+  dir: batches
+`), 0o600))
+	cfg, err = Load(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "/run/peppergo.sock", cfg.Server.Socket)
+	assert.Equal(t, filepath.Join(dir, "certs/server.crt"), cfg.Server.TLS.CertFile)
+	assert.Equal(t, "/etc/peppergo/server.key", cfg.Server.TLS.KeyFile)
+	assert.Equal(t, filepath.Join(dir, "certs/ca.crt"), cfg.Server.TLS.ClientCAFile)
+	assert.Equal(t, map[string]string{"billing-service": "billing"}, cfg.Auth.ClientTenants)
+	assert.Equal(t, filepath.Join(dir, "vocab"), cfg.Tokenizer.VocabDir, "vocabularies are relative to the configuration file")
+	assert.Equal(t, filepath.Join(dir, "batches"), cfg.Batches.Dir)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestNewTracer(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "peppergo.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(`
+providers:
+  - type: ollama
+tracing:
+  exporter: file
+  file: spans.jsonl
+`), 0o600))
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "spans.jsonl"), cfg.Tracing.File)
+
+	tracer, err := cfg.NewTracer(zaptest.NewLogger(t))
+	require.NoError(t, err)
+	_, span := tracer.Start(context.Background(), "test", tracing.SpanKindServer)
+	span.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(cfg.Tracing.File)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"test"`, "spans are sampled by default")
+
+	cfg.Tracing = nil
+	tracer, err = cfg.NewTracer(zaptest.NewLogger(t))
+	require.NoError(t, err)
+	assert.Nil(t, tracer)
+}
+
+func TestProviderFor(t *testing.T) {
+	t.Setenv("TEST_OPENAI_KEY", "sk-test")
+	cfg, err := Parse([]byte(testConfig))
+	require.NoError(t, err)
+
+	assert.Equal(t, "ollama", cfg.ProviderFor("llama3.1"))
+	assert.Equal(t, "primary", cfg.ProviderFor("gpt-4o"))
+	assert.Equal(t, "primary", cfg.ProviderFor(""))
+
+	cfg.Routes = RoutesConfig{}
+	cfg.Providers[0].Disabled = true
+	cfg.Providers[1].Disabled = false
+	assert.Equal(t, "ollama", cfg.ProviderFor("gpt-4o"), "first enabled provider")
+}
+
+func TestBuild(t *testing.T) {
+	t.Setenv("TEST_OPENAI_KEY", "sk-test")
+	cfg, err := Parse([]byte(testConfig))
+	require.NoError(t, err)
+
+	logger, err := cfg.NewLogger()
+	require.NoError(t, err)
+	assert.NotNil(t, logger)
+
+	service, err := cfg.NewService(zaptest.NewLogger(t))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"primary", "ollama"}, service.ListProviders())
+
+	status, err := service.ProviderStatus("ollama")
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	stats := service.QueueStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, "primary", stats[0].Provider)
+	assert.Equal(t, 4, stats[0].MaxConcurrent)
+
+	batches, err := cfg.NewBatchManager(zaptest.NewLogger(t), service)
+	require.NoError(t, err)
+	assert.Nil(t, batches, "batches are not configured")
+	cfg.Batches = &BatchesConfig{Dir: t.TempDir()}
+	batches, err = cfg.NewBatchManager(zaptest.NewLogger(t), service)
+	require.NoError(t, err)
+	defer batches.Close()
+
+	assert.Nil(t, cfg.NewMetrics(service), "metrics are not configured")
+	cfg.Metrics = &MetricsConfig{}
+	registry := cfg.NewMetrics(service)
+	require.NotNil(t, registry)
+
+	opts := append(cfg.HandlerOptions(), api.WithBatchManager(batches))
+	router := api.NewHandler(service, append(opts, cfg.MetricsOptions(registry)...)...).Router()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "api keys are required")
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/providers", nil)
+	req.Header.Set("Authorization", "Bearer admin-key")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/batches", nil)
+	req.Header.Set("Authorization", "Bearer team-key")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "batches are served")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "metrics need an API key")
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer team-key")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "peppergo_http_requests_total")
+
+	// Metrics with a listener of their own are not served with the API
+	cfg.Metrics.Addr = "127.0.0.1:9090"
+	separate := api.NewHandler(service, append(cfg.HandlerOptions(), cfg.MetricsOptions(metrics.NewRegistry())...)...).Router()
+	rec = httptest.NewRecorder()
+	separate.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Requests for routed models reach the disabled provider
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"llama3.1","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Authorization", "Bearer team-key")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), proxy.ErrProviderDisabled.Error())
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// envPattern matches ${NAME} and ${NAME:-default}
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Interpolate replaces ${NAME} references with the value of the environment
+// variable NAME, or the default given as ${NAME:-default} when it is unset or
+// empty. Comment lines are left untouched. Referencing an unset variable
+// without a default is an error.
+func Interpolate(data []byte) ([]byte, error) {
+	var errs []error
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			continue
+		}
+		lines[i] = envPattern.ReplaceAllFunc(line, func(match []byte) []byte {
+			groups := envPattern.FindSubmatch(match)
+			name := string(groups[1])
+			if value := os.Getenv(name); value != "" {
+				return []byte(value)
+			}
+			if groups[2] != nil {
+				return groups[3]
+			}
+			errs = append(errs, fmt.Errorf("line %d: environment variable %s is not set", i+1, name))
+			return nil
+		})
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return bytes.Join(lines, []byte("\n")), nil
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pimentel/peppergo/internal/policy"
+	"github.com/pimentel/peppergo/internal/provider"
+)
+
+// ProviderDefinition is a provider described in its own YAML file, as in assets/providers.
+// Settings without a counterpart in the proxy, such as retries and response formats, are ignored.
+type ProviderDefinition struct {
+	// Name is the name the provider is registered under
+	Name string `yaml:"name"`
+
+	// Type is the provider type (defaults to Name)
+	Type string `yaml:"type"`
+
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+
+	Config struct {
+		API struct {
+			Key     string        `yaml:"key"`
+			BaseURL string        `yaml:"base_url"`
+			Timeout time.Duration `yaml:"timeout"`
+		} `yaml:"api"`
+
+		Model struct {
+			Name           string   `yaml:"name"`
+			Models         []string `yaml:"models"`
+			EmbeddingModel string   `yaml:"embedding_model"`
+			MaxTokens      int      `yaml:"max_tokens"`
+		} `yaml:"model"`
+
+		Request struct {
+			MaxConcurrent int           `yaml:"max_concurrent"`
+			MaxQueueTime  time.Duration `yaml:"max_queue_time"`
+			MaxQueueDepth int           `yaml:"max_queue_depth"`
+			RateLimit     struct {
+				RequestsPerMinute float64 `yaml:"requests_per_minute"`
+				Burst             int     `yaml:"burst"`
+			} `yaml:"rate_limit"`
+		} `yaml:"request"`
+
+		Security struct {
+			RequestValidation *policy.Policy `yaml:"request_validation"`
+		} `yaml:"security"`
+	} `yaml:"config"`
+}
+
+// ProviderConfig converts the definition to a provider configuration
+func (d *ProviderDefinition) ProviderConfig() ProviderConfig {
+	providerType := d.Type
+	if providerType == "" {
+		providerType = d.Name
+	}
+
+	c := d.Config
+	return ProviderConfig{
+		Config: provider.Config{
+			Type:           providerType,
+			Name:           d.Name,
+			APIKey:         c.API.Key,
+			BaseURL:        c.API.BaseURL,
+			Model:          c.Model.Name,
+			EmbeddingModel: c.Model.EmbeddingModel,
+			Models:         c.Model.Models,
+			MaxTokens:      c.Model.MaxTokens,
+			RateLimit:      c.Request.RateLimit.RequestsPerMinute / 60,
+			Burst:          c.Request.RateLimit.Burst,
+			Timeout:        c.API.Timeout,
+		},
+		MaxConcurrent: c.Request.MaxConcurrent,
+		MaxQueueTime:  c.Request.MaxQueueTime,
+		MaxQueueDepth: c.Request.MaxQueueDepth,
+		Policy:        c.Security.RequestValidation,
+	}
+}
+
+// LoadProviderDefinitions reads every *.yaml and *.yml provider definition in
+// dir, in file name order, interpolating environment variables
+func LoadProviderDefinitions(dir string) ([]ProviderConfig, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list provider definitions: %w", err)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	configs := make([]ProviderConfig, 0, len(files))
+	for _, filename := range files {
+		definition, err := loadProviderDefinition(filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		configs = append(configs, definition.ProviderConfig())
+	}
+	return configs, nil
+}
+
+func loadProviderDefinition(filename string) (*ProviderDefinition, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider definition: %w", err)
+	}
+	data, err = Interpolate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var definition ProviderDefinition
+	if err := yaml.Unmarshal(data, &definition); err != nil {
+		return nil, fmt.Errorf("failed to parse provider definition: %w", err)
+	}
+	if definition.Name == "" {
+		return nil, fmt.Errorf("provider definition has no name")
+	}
+	return &definition, nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/policy"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func TestLoadProviderDefinitions(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-test")
+
+	configs, err := LoadProviderDefinitions("../../assets/providers")
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+
+	c := configs[0]
+	assert.Equal(t, "anthropic", c.Type)
+	assert.Equal(t, "anthropic", c.Name)
+	assert.Equal(t, "sk-ant-test", c.APIKey)
+	assert.Equal(t, "https://api.anthropic.com/v1", c.BaseURL)
+	assert.Equal(t, 30*time.Second, c.Timeout)
+	assert.Equal(t, "claude-2", c.Model)
+	assert.Equal(t, 1.0, c.RateLimit)
+	assert.Equal(t, 10, c.Burst)
+	assert.Equal(t, 10, c.MaxConcurrent)
+	require.NotNil(t, c.Policy)
+	assert.Equal(t, 4096, c.Policy.MaxTokens)
+	assert.Equal(t, []string{"claude-2", "claude-instant-1"}, c.Policy.AllowedModels)
+}
+
+func TestLoadProviderDefinitionsErrors(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "broken.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte("name: broken\nconfig:\n  api:\n    timeout: soon\n"), 0o600))
+
+	_, err := LoadProviderDefinitions(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), filename)
+
+	require.NoError(t, os.WriteFile(filename, []byte("name: broken\nconfig:\n  api:\n    key: ${TEST_UNSET_KEY}\n"), 0o600))
+	_, err = LoadProviderDefinitions(dir)
+	assert.ErrorContains(t, err, "TEST_UNSET_KEY")
+}
+
+func TestProviderDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "providers"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "providers", "local.yml"), []byte(`
+name: local
+type: ollama
+config:
+  model:
+    models: [llama3.1]
+  request:
+    max_concurrent: 2
+  security:
+    request_validation:
+      allowed_models: [llama3.1]
+`), 0o600))
+	filename := filepath.Join(dir, "peppergo.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte("provider_dir: providers\n"), 0o600))
+
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+	require.Len(t, cfg.Providers, 1)
+
+	service, err := cfg.NewService(zaptest.NewLogger(t))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"local"}, service.ListProviders())
+	require.Len(t, service.QueueStats(), 1)
+
+	_, err = service.Chat(context.Background(), "local", &types.ChatRequest{
+		Model:    "mistral",
+		Messages: []types.Message{{Role: "user", Content: "hi"}},
+	})
+	assert.ErrorIs(t, err, policy.ErrPolicyViolation)
+}
@@ -0,0 +1,376 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/api"
+	"github.com/pimentel/peppergo/internal/provider"
+	"github.com/pimentel/peppergo/internal/proxy"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// Reloader applies changes to the configuration file to a running server.
+// Providers, queue limits, policies, the response cache, routes and API keys
+// are swapped in place; server and logging settings require a restart.
+//
+// Providers can also be changed through the admin API. Changes made there
+// last until the provider's entry in the file changes: the file wins for the
+// entries it changes, re-adding, replacing or removing the live provider,
+// and leaves every other provider as the admin API left it.
+type Reloader struct {
+	logger  *zap.Logger
+	path    string
+	service *proxy.Service
+	handler *api.Handler
+	current *Config
+	loaded  map[string]types.Provider // provider instances installed from the file
+	digest  string
+	status  api.ReloadStatus
+	mu      sync.Mutex
+}
+
+// NewReloader creates a reloader for a service created from the configuration at path
+func NewReloader(logger *zap.Logger, path string, current *Config, service *proxy.Service) *Reloader {
+	r := &Reloader{
+		logger:  logger,
+		path:    path,
+		service: service,
+		current: current,
+		loaded:  make(map[string]types.Provider),
+	}
+	for _, p := range current.Providers {
+		if instance, err := service.GetProvider(p.Config.Name); err == nil {
+			r.loaded[p.Config.Name] = instance
+		}
+	}
+	r.digest, _ = r.computeDigest()
+	return r
+}
+
+// SetHandler makes reloads reconfigure the routes and API keys of the handler
+func (r *Reloader) SetHandler(handler *api.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handler = handler
+}
+
+// Watch reloads the configuration whenever the configuration file or a
+// provider definition changes, checking at each interval until ctx is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		digest, err := r.computeDigest()
+		if err != nil {
+			// The file may be in the middle of being replaced
+			continue
+		}
+		r.mu.Lock()
+		changed := digest != r.digest
+		r.mu.Unlock()
+		if changed {
+			r.Reload()
+		}
+	}
+}
+
+// Reload loads, validates and applies the configuration file. An invalid
+// configuration leaves the running one untouched.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.status.LastAttempt = &now
+	if digest, err := r.computeDigest(); err == nil {
+		r.digest = digest
+	}
+
+	changes, err := r.reload()
+	if err != nil {
+		r.status.Success = false
+		r.status.Error = err.Error()
+		r.status.Changes = nil
+		r.status.Failures++
+		r.logger.Error("Configuration reload failed",
+			zap.String("config", r.path),
+			zap.Error(err))
+		return err
+	}
+
+	r.status.Success = true
+	r.status.LastSuccess = &now
+	r.status.Error = ""
+	r.status.Changes = changes
+	r.status.Reloads++
+	r.logger.Info("Configuration reloaded",
+		zap.String("config", r.path),
+		zap.Strings("changes", changes))
+	return nil
+}
+
+// Status returns the outcome of the latest reloads
+func (r *Reloader) Status() api.ReloadStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.status
+	status.Changes = append([]string(nil), r.status.Changes...)
+	return status
+}
+
+// Current returns the configuration in effect
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+func (r *Reloader) reload() ([]string, error) {
+	next, err := Load(r.path)
+	if err != nil {
+		return nil, err
+	}
+	prev := r.current
+
+	// Build everything that can fail before changing anything
+	providerChanges, changes, err := r.diffProviders(prev, next)
+	if err != nil {
+		return nil, err
+	}
+	policyChanged := !reflect.DeepEqual(prev.policyConfig(), next.policyConfig())
+	engine, err := newPolicyEngine(r.logger, next.policyConfig())
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := newRedactor(r.logger, next.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := newTokenizer(r.logger, next.Tokenizer)
+	if err != nil {
+		return nil, err
+	}
+	window, err := newContextWindow(r.logger, next.ContextWindow, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.service.UpdateProviders(providerChanges); err != nil {
+		return nil, fmt.Errorf("failed to update providers: %w", err)
+	}
+	for _, instance := range append(providerChanges.Add, providerChanges.Replace...) {
+		r.loaded[instance.Name()] = instance
+	}
+	for _, name := range providerChanges.Remove {
+		delete(r.loaded, name)
+	}
+	changes = append(changes, r.updateQueues(prev, next)...)
+	if policyChanged {
+		r.service.SetPolicy(engine)
+		changes = append(changes, "policies updated")
+	}
+	if !reflect.DeepEqual(prev.Cache, next.Cache) {
+		r.service.SetCache(next.Cache.proxyConfig())
+		changes = append(changes, "cache updated")
+	}
+	if !reflect.DeepEqual(prev.Redaction, next.Redaction) {
+		r.service.SetRedactor(redactor)
+		changes = append(changes, "redaction updated")
+	}
+	tokenizerChanged := !reflect.DeepEqual(prev.Tokenizer, next.Tokenizer)
+	if tokenizerChanged {
+		r.service.SetTokenizer(registry)
+		changes = append(changes, "tokenizer updated")
+	}
+	// The context window manager counts with the tokenizer, so it is replaced with it
+	if tokenizerChanged && next.ContextWindow != nil || !reflect.DeepEqual(prev.ContextWindow, next.ContextWindow) {
+		r.service.SetContextWindow(window)
+		changes = append(changes, "context window updated")
+	}
+	if !reflect.DeepEqual(prev.Routes, next.Routes) {
+		changes = append(changes, "routes updated")
+	}
+	if !reflect.DeepEqual(prev.Auth, next.Auth) {
+		changes = append(changes, "auth updated")
+	}
+	if r.handler != nil {
+		r.handler.Reconfigure(next.HandlerOptions()...)
+	}
+
+	if !reflect.DeepEqual(prev.Server, next.Server) || !reflect.DeepEqual(prev.Logging, next.Logging) ||
+		!reflect.DeepEqual(prev.Batches, next.Batches) || !reflect.DeepEqual(prev.Metrics, next.Metrics) ||
+		!reflect.DeepEqual(prev.Tracing, next.Tracing) {
+		r.logger.Warn("Server, logging, batch, metrics and tracing settings changed; restart to apply them",
+			zap.String("config", r.path))
+	}
+
+	r.current = next
+	return changes, nil
+}
+
+// diffProviders creates the provider instances needed to apply the provider
+// entries that changed between prev and next to the live providers of the service
+func (r *Reloader) diffProviders(prev, next *Config) (proxy.ProviderChanges, []string, error) {
+	var changes []string
+	result := proxy.ProviderChanges{Enabled: make(map[string]bool)}
+	before := providersByName(prev.Providers)
+	after := providersByName(next.Providers)
+
+	for _, name := range sortedNames(after) {
+		p := after[name]
+		old, existed := before[name]
+		if existed && reflect.DeepEqual(old, p) {
+			continue
+		}
+
+		live, err := r.service.GetProvider(name)
+		registered := err == nil
+		if existed && registered && reflect.DeepEqual(old.Config, p.Config) {
+			result.Enabled[name] = !p.Disabled
+			changes = append(changes, fmt.Sprintf("provider %s %s", name, enabledWord(!p.Disabled)))
+			continue
+		}
+
+		instance, err := r.newProvider(p)
+		if err != nil {
+			return result, nil, err
+		}
+		r.warnOverride(name, live, existed)
+		result.Enabled[name] = !p.Disabled
+		if registered {
+			result.Replace = append(result.Replace, instance)
+			changes = append(changes, fmt.Sprintf("provider %s updated", name))
+		} else {
+			result.Add = append(result.Add, instance)
+			changes = append(changes, fmt.Sprintf("provider %s added", name))
+		}
+	}
+	for _, name := range sortedNames(before) {
+		if _, exists := after[name]; exists {
+			continue
+		}
+		live, err := r.service.GetProvider(name)
+		if err != nil {
+			// Already removed through the admin API
+			continue
+		}
+		r.warnOverride(name, live, true)
+		result.Remove = append(result.Remove, name)
+		changes = append(changes, fmt.Sprintf("provider %s removed", name))
+	}
+	return result, changes, nil
+}
+
+// warnOverride logs when a reload overwrites a provider changed through the
+// admin API, i.e. one whose live instance was not installed from the file
+func (r *Reloader) warnOverride(name string, live types.Provider, inFile bool) {
+	if live == nil && !inFile || live != nil && live == r.loaded[name] {
+		return
+	}
+	r.logger.Warn("Configuration file overrides provider changed through the admin API",
+		zap.String("provider", name),
+		zap.String("config", r.path))
+}
+
+func (r *Reloader) newProvider(p ProviderConfig) (types.Provider, error) {
+	config := p.Config
+	return provider.New(r.logger, &config)
+}
+
+// updateQueues applies changed provider queue limits
+func (r *Reloader) updateQueues(prev, next *Config) []string {
+	var changes []string
+	before := providersByName(prev.Providers)
+	after := providersByName(next.Providers)
+
+	for _, name := range sortedNames(after) {
+		p := after[name]
+		old, exists := before[name]
+		if exists && old.queueConfig() == p.queueConfig() {
+			continue
+		}
+		if p.MaxConcurrent > 0 {
+			// Limits were checked by Validate
+			r.service.SetQueueConfig(name, p.queueConfig())
+		} else if exists {
+			r.service.RemoveQueueConfig(name)
+		} else {
+			continue
+		}
+		if exists {
+			changes = append(changes, fmt.Sprintf("queue limits of %s updated", name))
+		}
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			r.service.RemoveQueueConfig(name)
+		}
+	}
+	return changes
+}
+
+// computeDigest hashes the configuration file and the provider definitions it loads
+func (r *Reloader) computeDigest() (string, error) {
+	files := []string{r.path}
+	if r.current != nil && r.current.ProviderDir != "" {
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, _ := filepath.Glob(filepath.Join(r.current.ProviderDir, pattern))
+			files = append(files, matches...)
+		}
+		sort.Strings(files[1:])
+	}
+
+	h := sha256.New()
+	for _, filename := range files {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filename, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func providersByName(providers []ProviderConfig) map[string]ProviderConfig {
+	byName := make(map[string]ProviderConfig, len(providers))
+	for _, p := range providers {
+		byName[p.ProviderName()] = p
+	}
+	return byName
+}
+
+func sortedNames(providers map[string]ProviderConfig) []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func enabledWord(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/api"
+	"github.com/pimentel/peppergo/internal/policy"
+	"github.com/pimentel/peppergo/internal/provider"
+	"github.com/pimentel/peppergo/internal/proxy"
+	"github.com/pimentel/peppergo/internal/redact"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+const reloadConfig = `
+providers:
+  - type: openai
+    name: primary
+    api_key: key-1
+    models: [gpt-4o]
+    max_concurrent: 2
+  - type: ollama
+    name: local
+routes:
+  default: primary
+auth:
+  admin_keys: [admin]
+`
+
+func newTestReloader(t *testing.T, content string) (*Reloader, *proxy.Service, http.Handler, string) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "peppergo.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o600))
+
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+	logger := zaptest.NewLogger(t)
+	service, err := cfg.NewService(logger)
+	require.NoError(t, err)
+
+	reloader := NewReloader(logger, filename, cfg, service)
+	handler := api.NewHandler(service, append(cfg.HandlerOptions(), api.WithReloader(reloader))...)
+	reloader.SetHandler(handler)
+	return reloader, service, handler.Router(), filename
+}
+
+func TestReload(t *testing.T) {
+	reloader, service, router, filename := newTestReloader(t, reloadConfig)
+	local, err := service.GetProvider("local")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filename, []byte(`
+providers:
+  - type: openai
+    name: primary
+    api_key: key-2
+    models: [gpt-4o, gpt-4o-mini]
+  - type: ollama
+    name: local
+    disabled: true
+  - type: openai
+    name: secondary
+routes:
+  default: secondary
+auth:
+  keys:
+    - key: client
+  admin_keys: [admin]
+context_window:
+  strategy: summarize
+tokenizer:
+  pricing:
+    gpt-4o: {prompt_per_million: 2.5, completion_per_million: 10}
+`), 0o600))
+	require.NoError(t, reloader.Reload())
+
+	assert.ElementsMatch(t, []string{"primary", "local", "secondary"}, service.ListProviders())
+	primary, err := service.GetProvider("primary")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, primary.AvailableModels())
+
+	current, err := service.GetProvider("local")
+	require.NoError(t, err)
+	assert.Same(t, local, current, "unchanged providers are kept")
+	status, err := service.ProviderStatus("local")
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	assert.Empty(t, service.QueueStats(), "queue limit removed")
+
+	reload := reloader.Status()
+	assert.True(t, reload.Success)
+	assert.Equal(t, 1, reload.Reloads)
+	assert.ElementsMatch(t, []string{
+		"provider primary updated",
+		"provider local disabled",
+		"provider secondary added",
+		"queue limits of primary updated",
+		"routes updated",
+		"auth updated",
+		"context window updated",
+		"tokenizer updated",
+	}, reload.Changes)
+	assert.Zero(t, reloader.Current().ContextWindow.Reserve, "defaults are not filled into the configuration")
+	assert.True(t, service.EstimateChat(&types.ChatRequest{Model: "gpt-4o"}).Priced, "the tokenizer is installed")
+
+	// Auth and routes are reconfigured on the handler
+	req := httptest.NewRequest(http.MethodGet, "/v1/providers", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	t.Run("invalid configuration is rejected", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filename, []byte("providers:\n  - type: bedrock\n"), 0o600))
+		err := reloader.Reload()
+		require.Error(t, err)
+
+		status := reloader.Status()
+		assert.False(t, status.Success)
+		assert.Equal(t, 1, status.Failures)
+		assert.Contains(t, status.Error, `unknown type "bedrock"`)
+		assert.ElementsMatch(t, []string{"primary", "local", "secondary"}, service.ListProviders())
+	})
+
+	t.Run("admin endpoint", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "bedrock")
+
+		require.NoError(t, os.WriteFile(filename, []byte(reloadConfig), 0o600))
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "provider secondary removed")
+
+		req = httptest.NewRequest(http.MethodGet, "/admin/reload", nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"reloads":2`)
+	})
+}
+
+func TestReloadAfterAdminChanges(t *testing.T) {
+	reloader, service, _, filename := newTestReloader(t, reloadConfig)
+	logger := zaptest.NewLogger(t)
+
+	// Remove local and rotate the key of primary through the admin API
+	require.NoError(t, service.RemoveProvider("local"))
+	rotated, err := provider.New(logger, &provider.Config{Type: "openai", Name: "primary", APIKey: "key-rotated", Models: []string{"gpt-4o"}})
+	require.NoError(t, err)
+	require.NoError(t, service.ReplaceProvider(rotated))
+
+	// Entries that did not change in the file keep the admin changes
+	withKeys := reloadConfig + "  keys:\n    - key: client\n"
+	require.NoError(t, os.WriteFile(filename, []byte(withKeys), 0o600))
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, []string{"auth updated"}, reloader.Status().Changes)
+	assert.ElementsMatch(t, []string{"primary"}, service.ListProviders())
+	current, err := service.GetProvider("primary")
+	require.NoError(t, err)
+	assert.Same(t, rotated, current)
+
+	// Changed entries win over the admin changes
+	updated := strings.Replace(withKeys, "models: [gpt-4o]", "models: [gpt-4o, o1]", 1)
+	updated = strings.Replace(updated, "name: local", "name: local\n    disabled: true", 1)
+	require.NoError(t, os.WriteFile(filename, []byte(updated), 0o600))
+	require.NoError(t, reloader.Reload())
+	assert.ElementsMatch(t, []string{"provider primary updated", "provider local added"}, reloader.Status().Changes)
+	current, err = service.GetProvider("primary")
+	require.NoError(t, err)
+	assert.NotSame(t, rotated, current)
+	assert.Equal(t, []string{"gpt-4o", "o1"}, current.AvailableModels())
+	status, err := service.ProviderStatus("local")
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	// Removing an entry whose provider is already gone is not an error
+	require.NoError(t, service.RemoveProvider("local"))
+	removed := strings.Replace(updated, "  - type: ollama\n    name: local\n    disabled: true\n", "", 1)
+	require.NoError(t, os.WriteFile(filename, []byte(removed), 0o600))
+	require.NoError(t, reloader.Reload())
+	assert.Empty(t, reloader.Status().Changes)
+	assert.ElementsMatch(t, []string{"primary"}, service.ListProviders())
+}
+
+func TestReloadPolicy(t *testing.T) {
+	reloader, service, _, filename := newTestReloader(t, reloadConfig)
+	trial := proxy.WithTenant(context.Background(), "trial")
+	req := &types.ChatRequest{Model: "gpt-4o", MaxTokens: 100, Messages: []types.Message{{Role: "user", Content: "hi"}}}
+
+	require.NoError(t, os.WriteFile(filename, []byte(reloadConfig+`
+policy:
+  default:
+    max_tokens: 4096
+  tenants:
+    trial:
+      max_tokens: 50
+`), 0o600))
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, []string{"policies updated"}, reloader.Status().Changes)
+
+	_, err := service.Chat(trial, "primary", req)
+	assert.ErrorIs(t, err, policy.ErrPolicyViolation)
+	assert.ErrorContains(t, err, "tenant:trial")
+
+	require.NoError(t, os.WriteFile(filename, []byte(reloadConfig), 0o600))
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, []string{"policies updated"}, reloader.Status().Changes)
+}
+
+func TestReloadRedaction(t *testing.T) {
+	reloader, service, _, filename := newTestReloader(t, reloadConfig)
+	req := &types.ChatRequest{Model: "gpt-4o", Messages: []types.Message{{Role: "user", Content: "mail alice@example.com"}}}
+
+	require.NoError(t, os.WriteFile(filename, []byte(reloadConfig+`
+redaction:
+  mode: block
+  detectors: [email]
+`), 0o600))
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, []string{"redaction updated"}, reloader.Status().Changes)
+
+	_, err := service.Chat(context.Background(), "primary", req)
+	assert.ErrorIs(t, err, redact.ErrSensitiveData)
+
+	require.NoError(t, os.WriteFile(filename, []byte(reloadConfig), 0o600))
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, []string{"redaction updated"}, reloader.Status().Changes)
+}
+
+func TestReloaderWatch(t *testing.T) {
+	reloader, service, _, filename := newTestReloader(t, reloadConfig)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 10*time.Millisecond)
+
+	updated := strings.Replace(reloadConfig, "models: [gpt-4o]", "models: [gpt-4o, o1]", 1)
+	require.NoError(t, os.WriteFile(filename, []byte(updated), 0o600))
+
+	require.Eventually(t, func() bool {
+		p, err := service.GetProvider("primary")
+		return err == nil && len(p.AvailableModels()) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, reloader.Status().Reloads)
+}
@@ -0,0 +1,175 @@
+package contextwindow
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/tokenizer"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// Strategy names accepted in Config
+const (
+	StrategyDropOldest = "drop_oldest"
+	StrategyKeepLast   = "keep_last"
+	StrategySummarize  = "summarize"
+	StrategyMapReduce  = "map_reduce"
+)
+
+// Defaults applied to an unset Config
+const (
+	DefaultReserve  = 1024
+	DefaultKeepLast = 6
+)
+
+// Config configures how requests are kept within their context window
+type Config struct {
+	// Strategy is drop_oldest, keep_last, summarize or map_reduce (defaults to drop_oldest)
+	Strategy string `yaml:"strategy"`
+
+	// ContextWindow is the context window in tokens; when unset, the window
+	// of known models is used and requests for other models are sent as is
+	ContextWindow int `yaml:"context_window"`
+
+	// Reserve is the number of tokens kept free for the answer of requests
+	// that do not set max_tokens (defaults to 1024)
+	Reserve int `yaml:"reserve"`
+
+	// KeepLast is the number of recent messages kept by keep_last and kept
+	// verbatim by summarize (defaults to 6)
+	KeepLast int `yaml:"keep_last"`
+
+	// ChunkTokens bounds the text summarize and map_reduce send in one model
+	// call (defaults to half the window)
+	ChunkTokens int `yaml:"chunk_tokens"`
+
+	// SummaryTokens bounds the length of model-written summaries (defaults to 1024)
+	SummaryTokens int `yaml:"summary_tokens"`
+}
+
+// Validate checks the configuration
+func (c *Config) Validate() error {
+	switch c.Strategy {
+	case "", StrategyDropOldest, StrategyKeepLast, StrategySummarize, StrategyMapReduce:
+	default:
+		return fmt.Errorf("unknown strategy %q", c.Strategy)
+	}
+	if c.ContextWindow < 0 || c.Reserve < 0 || c.KeepLast < 0 || c.ChunkTokens < 0 || c.SummaryTokens < 0 {
+		return fmt.Errorf("token limits must not be negative")
+	}
+	if c.ContextWindow > 0 && c.Reserve >= c.ContextWindow {
+		return fmt.Errorf("reserve must be smaller than the context window")
+	}
+	return nil
+}
+
+// Manager fits chat requests into the context window of their model
+type Manager struct {
+	logger   *zap.Logger
+	config   *Config
+	counter  tokenizer.Counter
+	strategy Strategy
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithCounter counts tokens with counter instead of estimating them
+func WithCounter(counter tokenizer.Counter) Option {
+	return func(m *Manager) {
+		m.counter = counter
+	}
+}
+
+// WithStrategy uses strategy instead of the configured one
+func WithStrategy(strategy Strategy) Option {
+	return func(m *Manager) {
+		m.strategy = strategy
+	}
+}
+
+// NewManager creates a manager for the configured strategy
+func NewManager(logger *zap.Logger, config *Config, opts ...Option) (*Manager, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid context window config: %w", err)
+	}
+	if config.Reserve == 0 {
+		config.Reserve = DefaultReserve
+	}
+	if config.KeepLast == 0 {
+		config.KeepLast = DefaultKeepLast
+	}
+
+	m := &Manager{
+		logger:  logger,
+		config:  config,
+		counter: tokenizer.Heuristic{},
+	}
+	switch config.Strategy {
+	case StrategyKeepLast:
+		m.strategy = KeepLast{N: config.KeepLast}
+	case StrategySummarize:
+		m.strategy = Summarize{KeepLast: config.KeepLast, MaxTokens: config.SummaryTokens, ChunkTokens: config.ChunkTokens}
+	case StrategyMapReduce:
+		m.strategy = MapReduce{ChunkTokens: config.ChunkTokens, MaxTokens: config.SummaryTokens}
+	default:
+		m.strategy = DropOldest{}
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// Window returns the token budget of a request's messages: the context
+// window of its model less the tokens reserved for the answer and taken by
+// tool definitions. The limit is 0 when the model's window is unknown.
+func (m *Manager) Window(provider types.Provider, req *types.ChatRequest) Window {
+	window := Window{Model: req.Model, Counter: m.counter, Provider: provider}
+
+	size := m.config.ContextWindow
+	if size == 0 {
+		size = ModelContextWindow(req.Model)
+	}
+	if size == 0 {
+		return window
+	}
+
+	reserve := m.config.Reserve
+	if req.MaxTokens > 0 {
+		reserve = req.MaxTokens
+	}
+	window.Limit = size - reserve - tokenizer.CountTools(m.counter, req.Model, req.Tools)
+	if window.Limit < 1 {
+		window.Limit = 1
+	}
+	return window
+}
+
+// Fit returns req, or a copy of it whose messages fit the context window of
+// its model. Requests for models with an unknown window are returned as is.
+func (m *Manager) Fit(ctx context.Context, provider types.Provider, req *types.ChatRequest) (*types.ChatRequest, error) {
+	window := m.Window(provider, req)
+	if window.Limit == 0 || window.Fits(req.Messages) {
+		return req, nil
+	}
+
+	before := window.Count(req.Messages)
+	messages, err := m.strategy.Fit(ctx, window, req.Messages)
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Debug("Fitted messages into the context window",
+		zap.String("model", req.Model),
+		zap.Int("limit", window.Limit),
+		zap.Int("tokens_before", before),
+		zap.Int("tokens_after", window.Count(messages)),
+		zap.Int("messages_before", len(req.Messages)),
+		zap.Int("messages_after", len(messages)))
+
+	fitted := *req
+	fitted.Messages = messages
+	return &fitted, nil
+}
@@ -0,0 +1,106 @@
+package contextwindow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config Config
+		err    string
+	}{
+		"empty":           {config: Config{}},
+		"summarize":       {config: Config{Strategy: StrategySummarize, ContextWindow: 8000, Reserve: 1000}},
+		"unknown":         {config: Config{Strategy: "forget"}, err: `unknown strategy "forget"`},
+		"negative":        {config: Config{KeepLast: -1}, err: "must not be negative"},
+		"reserve too big": {config: Config{ContextWindow: 100, Reserve: 100}, err: "reserve must be smaller"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	t.Run("strategies", func(t *testing.T) {
+		for name, want := range map[string]Strategy{
+			"":                 DropOldest{},
+			StrategyKeepLast:   KeepLast{N: DefaultKeepLast},
+			StrategySummarize:  Summarize{KeepLast: DefaultKeepLast, ChunkTokens: 10},
+			StrategyMapReduce:  MapReduce{ChunkTokens: 10},
+			StrategyDropOldest: DropOldest{},
+		} {
+			m, err := NewManager(logger, &Config{Strategy: name, ChunkTokens: 10})
+			require.NoError(t, err)
+			assert.Equal(t, want, m.strategy, name)
+		}
+
+		_, err := NewManager(logger, &Config{Strategy: "forget"})
+		assert.ErrorContains(t, err, "invalid context window config")
+	})
+
+	t.Run("window", func(t *testing.T) {
+		m, err := NewManager(logger, &Config{}, WithCounter(wordCounter{}))
+		require.NoError(t, err)
+
+		assert.Equal(t, 8192-DefaultReserve, m.Window(nil, &types.ChatRequest{Model: "gpt-4"}).Limit)
+		assert.Equal(t, 8192-500, m.Window(nil, &types.ChatRequest{Model: "gpt-4", MaxTokens: 500}).Limit)
+		assert.Equal(t, 0, m.Window(nil, &types.ChatRequest{Model: "unknown"}).Limit)
+
+		// Tool definitions take part of the window
+		tools := []types.ToolDefinition{{Type: types.ToolTypeFunction, Function: types.FunctionDefinition{Name: "search", Description: "Searches the web"}}}
+		assert.Less(t, m.Window(nil, &types.ChatRequest{Model: "gpt-4", Tools: tools}).Limit, 8192-DefaultReserve)
+	})
+
+	t.Run("fit", func(t *testing.T) {
+		m, err := NewManager(logger, &Config{ContextWindow: 3 + 6*4 + 10, Reserve: 10}, WithCounter(wordCounter{}))
+		require.NoError(t, err)
+
+		req := &types.ChatRequest{Model: "m", Messages: conversation(), Temperature: 0.5}
+		fitted, err := m.Fit(ctx, nil, req)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sys", "u2", "a2", "u3"}, contents(fitted.Messages))
+		assert.Equal(t, float32(0.5), fitted.Temperature)
+		assert.Len(t, req.Messages, 6, "the request is not modified")
+
+		small := &types.ChatRequest{Model: "m", Messages: conversation()[:2]}
+		same, err := m.Fit(ctx, nil, small)
+		require.NoError(t, err)
+		assert.Same(t, small, same)
+	})
+
+	t.Run("summarize with provider", func(t *testing.T) {
+		provider := &fakeProvider{}
+		m, err := NewManager(logger, &Config{Strategy: StrategySummarize, ContextWindow: 3 + 6*5 + 10, Reserve: 10, KeepLast: 2}, WithCounter(wordCounter{}))
+		require.NoError(t, err)
+
+		fitted, err := m.Fit(ctx, provider, &types.ChatRequest{Model: "m", Messages: conversation()})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sys", summaryPrefix + "summary1", "a2", "u3"}, contents(fitted.Messages))
+	})
+
+	t.Run("unknown model", func(t *testing.T) {
+		m, err := NewManager(logger, &Config{})
+		require.NoError(t, err)
+		req := &types.ChatRequest{Model: "unknown", Messages: []types.Message{{Role: "user", Content: words(100000)}}}
+		fitted, err := m.Fit(ctx, nil, req)
+		require.NoError(t, err)
+		assert.Same(t, req, fitted)
+	})
+}
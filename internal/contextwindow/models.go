@@ -0,0 +1,48 @@
+// Package contextwindow keeps chat requests within the context window of
+// their model, dropping, summarizing or condensing messages that do not fit
+package contextwindow
+
+import (
+	"strings"
+
+	"github.com/pimentel/peppergo/internal/tokenizer"
+)
+
+// contextWindows are the context windows of known models by name prefix;
+// the longest matching prefix wins
+var contextWindows = map[string]int{
+	"gpt-3.5-turbo":  16385,
+	"gpt-4":          8192,
+	"gpt-4-32k":      32768,
+	"gpt-4-turbo":    128000,
+	"gpt-4o":         128000,
+	"gpt-4.1":        1047576,
+	"o1":             200000,
+	"o3":             200000,
+	"o4-mini":        200000,
+	"claude":         200000,
+	"claude-2":       100000,
+	"gemini-1.5":     1048576,
+	"gemini-2":       1048576,
+	"llama2":         4096,
+	"llama3":         8192,
+	"llama3.1":       131072,
+	"llama3.2":       131072,
+	"mistral":        32768,
+	"mixtral":        32768,
+	"codellama":      16384,
+	"qwen2.5":        32768,
+	"deepseek-coder": 16384,
+}
+
+// ModelContextWindow returns the context window of a known model, or 0 if the model is unknown
+func ModelContextWindow(model string) int {
+	name := tokenizer.ModelName(model)
+	best, window := "", 0
+	for prefix, tokens := range contextWindows {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+			best, window = prefix, tokens
+		}
+	}
+	return window
+}
@@ -0,0 +1,24 @@
+package contextwindow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelContextWindow(t *testing.T) {
+	for model, window := range map[string]int{
+		"gpt-4":                      8192,
+		"gpt-4-0613":                 8192,
+		"gpt-4o-mini":                128000,
+		"gpt-4-turbo-preview":        128000,
+		"openai/gpt-4o":              128000,
+		"claude-3-5-sonnet-20241022": 200000,
+		"llama3.1:8b":                131072,
+		"llama3:8b":                  8192,
+		"unknown-model":              0,
+		"":                           0,
+	} {
+		assert.Equal(t, window, ModelContextWindow(model), model)
+	}
+}
@@ -0,0 +1,450 @@
+package contextwindow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pimentel/peppergo/internal/tokenizer"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// ErrContextOverflow is returned when messages cannot be made to fit the context window
+var ErrContextOverflow = errors.New("messages exceed the context window")
+
+// Window is the token budget of a request's messages
+type Window struct {
+	// Model is the model the messages are sent to
+	Model string
+
+	// Limit is the number of prompt tokens the messages may use
+	Limit int
+
+	// Counter counts the tokens of the messages
+	Counter tokenizer.Counter
+
+	// Provider serves the model calls of strategies that summarize
+	Provider types.Provider
+}
+
+// Count counts the prompt tokens of messages
+func (w Window) Count(messages []types.Message) int {
+	return tokenizer.CountMessages(w.Counter, w.Model, messages)
+}
+
+// Fits reports whether messages fit within the window
+func (w Window) Fits(messages []types.Message) bool {
+	return w.Count(messages) <= w.Limit
+}
+
+// overflow returns an ErrContextOverflow for messages
+func (w Window) overflow(messages []types.Message) error {
+	return fmt.Errorf("%w: %d tokens, limit %d", ErrContextOverflow, w.Count(messages), w.Limit)
+}
+
+// Strategy shortens messages that do not fit a window
+type Strategy interface {
+	// Fit returns messages that fit the window, or ErrContextOverflow
+	Fit(ctx context.Context, window Window, messages []types.Message) ([]types.Message, error)
+}
+
+// split separates the leading system messages from the turns that follow.
+// An assistant message that calls tools forms a single turn with the tool
+// messages answering it, so they are never separated.
+func split(messages []types.Message) (head []types.Message, turns [][]types.Message) {
+	i := 0
+	for i < len(messages) && messages[i].Role == "system" {
+		i++
+	}
+	head = messages[:i]
+
+	for _, message := range messages[i:] {
+		if message.Role == "tool" && len(turns) > 0 {
+			turns[len(turns)-1] = append(turns[len(turns)-1], message)
+			continue
+		}
+		turns = append(turns, []types.Message{message})
+	}
+	return head, turns
+}
+
+// join concatenates the head and turns into a new slice
+func join(head []types.Message, turns [][]types.Message) []types.Message {
+	messages := append([]types.Message(nil), head...)
+	for _, turn := range turns {
+		messages = append(messages, turn...)
+	}
+	return messages
+}
+
+// lastUserTurn returns the index of the last turn starting with a user message, or -1
+func lastUserTurn(turns [][]types.Message) int {
+	for i := len(turns) - 1; i >= 0; i-- {
+		if turns[i][0].Role == "user" {
+			return i
+		}
+	}
+	return -1
+}
+
+// DropOldest drops the oldest turns until the messages fit. System messages
+// at the start, the latest user message and the final turn are kept.
+type DropOldest struct{}
+
+// Fit drops turns before the latest user message, then the tool turns after it
+func (DropOldest) Fit(ctx context.Context, window Window, messages []types.Message) ([]types.Message, error) {
+	if window.Fits(messages) {
+		return messages, nil
+	}
+
+	head, turns := split(messages)
+	// Dropping turns[i] means keeping the others, so drop them in order of age
+	// while never dropping the latest user turn or the final turn
+	protected := lastUserTurn(turns)
+	dropped := make([]bool, len(turns))
+	order := make([]int, 0, len(turns))
+	for i := range turns {
+		if i != protected && i != len(turns)-1 {
+			order = append(order, i)
+		}
+	}
+	// Turns before the latest user message go first
+	for pass := 0; pass < 2; pass++ {
+		for _, i := range order {
+			if (pass == 0) != (i < protected) {
+				continue
+			}
+			dropped[i] = true
+			candidate := keep(head, turns, dropped)
+			if window.Fits(candidate) {
+				return candidate, nil
+			}
+		}
+	}
+	return nil, window.overflow(keep(head, turns, dropped))
+}
+
+// keep joins the head and the turns not dropped
+func keep(head []types.Message, turns [][]types.Message, dropped []bool) []types.Message {
+	kept := make([][]types.Message, 0, len(turns))
+	for i, turn := range turns {
+		if !dropped[i] {
+			kept = append(kept, turn)
+		}
+	}
+	return join(head, kept)
+}
+
+// KeepLast keeps the leading system messages and the last N messages,
+// dropping older turns even when they would fit, and then drops further
+// turns as DropOldest does if the messages still do not fit
+type KeepLast struct {
+	N int
+}
+
+// Fit keeps the last N messages, extended to the start of the turn they begin in
+func (s KeepLast) Fit(ctx context.Context, window Window, messages []types.Message) ([]types.Message, error) {
+	head, turns := split(messages)
+	start, count := len(turns), 0
+	for start > 0 && count < s.N {
+		start--
+		count += len(turns[start])
+	}
+	return DropOldest{}.Fit(ctx, window, join(head, turns[start:]))
+}
+
+// summaryPrefix marks the system message holding the summary of earlier turns
+const summaryPrefix = "Summary of the earlier conversation:\n"
+
+const summarizePrompt = "Summarize the conversation below for the assistant taking part in it. " +
+	"Keep every fact, decision, open question and instruction that later turns may depend on, " +
+	"and leave out pleasantries. Answer with the summary only."
+
+// Summarize replaces the turns before the last KeepLast messages with a
+// summary written by the model. A summary from an earlier call is folded
+// into the next one, so the summary rolls forward as the conversation grows.
+// If the messages still do not fit, turns are dropped as DropOldest does.
+type Summarize struct {
+	// KeepLast is the number of recent messages kept verbatim
+	KeepLast int
+
+	// MaxTokens bounds the length of the summary
+	MaxTokens int
+
+	// ChunkTokens bounds the text summarized in one model call
+	ChunkTokens int
+}
+
+// Fit summarizes older turns when the messages do not fit
+func (s Summarize) Fit(ctx context.Context, window Window, messages []types.Message) ([]types.Message, error) {
+	if window.Fits(messages) {
+		return messages, nil
+	}
+
+	head, turns := split(messages)
+	start, count := len(turns), 0
+	for start > 0 && count < s.KeepLast {
+		start--
+		count += len(turns[start])
+	}
+	// The latest user message is the task at hand, so it is never summarized
+	if last := lastUserTurn(turns); last >= 0 && last < start {
+		start = last
+	}
+
+	var system []types.Message
+	var previous string
+	for _, message := range head {
+		if strings.HasPrefix(message.Content, summaryPrefix) {
+			previous = strings.TrimPrefix(message.Content, summaryPrefix)
+			continue
+		}
+		system = append(system, message)
+	}
+	if start == 0 {
+		return DropOldest{}.Fit(ctx, window, messages)
+	}
+
+	var transcript strings.Builder
+	if previous != "" {
+		transcript.WriteString("Summary of the conversation so far:\n" + previous + "\n\n")
+	}
+	for _, turn := range turns[:start] {
+		for _, message := range turn {
+			writeTranscript(&transcript, message)
+		}
+	}
+
+	m := mapReduce{window: window, maxTokens: s.MaxTokens, chunkTokens: s.ChunkTokens}
+	summary, err := m.condense(ctx, summarizePrompt, transcript.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize the conversation: %w", err)
+	}
+
+	fitted := append(system, types.Message{Role: "system", Content: summaryPrefix + summary})
+	fitted = join(fitted, turns[start:])
+	return DropOldest{}.Fit(ctx, window, fitted)
+}
+
+// writeTranscript writes a message as a line of a conversation transcript
+func writeTranscript(b *strings.Builder, message types.Message) {
+	switch {
+	case message.Role == "tool":
+		fmt.Fprintf(b, "tool %s returned: %s\n", message.Name, message.Text())
+	case len(message.ToolCalls) > 0:
+		if text := message.Text(); text != "" {
+			fmt.Fprintf(b, "%s: %s\n", message.Role, text)
+		}
+		for _, call := range message.ToolCalls {
+			fmt.Fprintf(b, "%s called tool %s with %s\n", message.Role, call.Function.Name, call.Function.Arguments)
+		}
+	default:
+		fmt.Fprintf(b, "%s: %s\n", message.Role, message.Text())
+	}
+}
+
+const condensePrompt = "The text below is too long to process at once. " +
+	"Rewrite it as concisely as possible while keeping every detail needed " +
+	"to act on it: facts, names, numbers, code, requirements and questions. " +
+	"Answer with the rewritten text only."
+
+// MapReduce condenses messages too large for the window, such as long
+// documents or files, by splitting their text into chunks, condensing each
+// chunk with the model and joining the results, repeating until the text
+// fits. If the messages still do not fit, turns are dropped as DropOldest does.
+type MapReduce struct {
+	// ChunkTokens bounds the text condensed in one model call
+	ChunkTokens int
+
+	// MaxTokens bounds the length of each condensed chunk
+	MaxTokens int
+}
+
+// Fit condenses the largest messages until the messages fit
+func (s MapReduce) Fit(ctx context.Context, window Window, messages []types.Message) ([]types.Message, error) {
+	if window.Fits(messages) {
+		return messages, nil
+	}
+
+	fitted := append([]types.Message(nil), messages...)
+	condensed := make([]bool, len(fitted))
+	m := mapReduce{window: window, maxTokens: s.MaxTokens, chunkTokens: s.ChunkTokens}
+	for !window.Fits(fitted) {
+		largest, size := -1, 0
+		for i, message := range fitted {
+			tokens := tokenizer.CountMessage(window.Counter, window.Model, message)
+			if !condensed[i] && textOnly(message) && tokens > size {
+				largest, size = i, tokens
+			}
+		}
+		// Messages smaller than a chunk cannot be condensed usefully
+		if largest < 0 || size <= m.chunkSize() {
+			break
+		}
+
+		text, err := m.condense(ctx, condensePrompt, fitted[largest].Text())
+		if err != nil {
+			return nil, fmt.Errorf("failed to condense message: %w", err)
+		}
+		fitted[largest].Content = text
+		fitted[largest].Parts = nil
+		condensed[largest] = true
+	}
+	return DropOldest{}.Fit(ctx, window, fitted)
+}
+
+// textOnly reports whether a message holds nothing but text, so it can be rewritten
+func textOnly(message types.Message) bool {
+	if len(message.ToolCalls) > 0 || message.Role == "tool" {
+		return false
+	}
+	for _, part := range message.Parts {
+		if part.Type != types.ContentTypeText {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultSummaryTokens bounds a model-written summary when no limit is configured
+const defaultSummaryTokens = 1024
+
+// maxReduceRounds bounds how many times condensed text is condensed again
+const maxReduceRounds = 4
+
+// mapReduce condenses text with a model, chunk by chunk
+type mapReduce struct {
+	window      Window
+	maxTokens   int
+	chunkTokens int
+}
+
+// chunkSize is the number of tokens condensed in one model call; it
+// defaults to half the window, leaving room for the prompt and answer
+func (m mapReduce) chunkSize() int {
+	if m.chunkTokens > 0 && m.chunkTokens < m.window.Limit {
+		return m.chunkTokens
+	}
+	return m.window.Limit / 2
+}
+
+func (m mapReduce) summaryTokens() int {
+	if m.maxTokens > 0 {
+		return m.maxTokens
+	}
+	return defaultSummaryTokens
+}
+
+// condense rewrites text following instruction, condensing chunks separately
+// and then their joined results until a single call covers the text
+func (m mapReduce) condense(ctx context.Context, instruction, text string) (string, error) {
+	if m.window.Provider == nil {
+		return "", fmt.Errorf("no provider to condense messages with")
+	}
+
+	for round := 0; round < maxReduceRounds; round++ {
+		chunks := chunkText(m.window.Counter, m.window.Model, text, m.chunkSize())
+		if len(chunks) == 1 {
+			return m.complete(ctx, instruction, chunks[0])
+		}
+
+		parts := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			prompt := fmt.Sprintf("%s\n\nThis is part %d of %d of the text.", instruction, i+1, len(chunks))
+			part, err := m.complete(ctx, prompt, chunk)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		text = strings.Join(parts, "\n\n")
+	}
+	return "", fmt.Errorf("text still spans several chunks after %d rounds", maxReduceRounds)
+}
+
+// complete asks the model to follow instruction on text
+func (m mapReduce) complete(ctx context.Context, instruction, text string) (string, error) {
+	resp, err := m.window.Provider.Chat(ctx, &types.ChatRequest{
+		Model: m.window.Model,
+		Messages: []types.Message{
+			{Role: "system", Content: instruction},
+			{Role: "user", Content: text},
+		},
+		MaxTokens: m.summaryTokens(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("model returned no choices")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Text()), nil
+}
+
+// chunkText splits text into chunks of at most size tokens, at line breaks
+// or spaces where possible
+func chunkText(counter tokenizer.Counter, model, text string, size int) []string {
+	if size < 1 {
+		size = 1
+	}
+
+	var chunks []string
+	var chunk strings.Builder
+	tokens := 0
+	flush := func() {
+		if chunk.Len() > 0 {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+			tokens = 0
+		}
+	}
+
+	for _, word := range splitWords(text) {
+		n := counter.Count(model, word)
+		if n > size {
+			// A single word longer than a chunk is split by characters
+			flush()
+			runes := []rune(word)
+			step := len(runes) * size / n
+			if step < 1 {
+				step = 1
+			}
+			for len(runes) > 0 {
+				end := step
+				if end > len(runes) {
+					end = len(runes)
+				}
+				chunks = append(chunks, string(runes[:end]))
+				runes = runes[end:]
+			}
+			continue
+		}
+		if tokens+n > size {
+			flush()
+		}
+		chunk.WriteString(word)
+		tokens += n
+	}
+	flush()
+	if len(chunks) == 0 {
+		chunks = []string{""}
+	}
+	return chunks
+}
+
+// splitWords splits text after each space or line break, keeping the separators
+func splitWords(text string) []string {
+	var words []string
+	start := 0
+	for i, r := range text {
+		if r == ' ' || r == '\n' {
+			words = append(words, text[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
@@ -0,0 +1,213 @@
+package contextwindow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pimentel/peppergo/internal/tokenizer"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// wordCounter counts a token per word, making budgets easy to follow
+type wordCounter struct{}
+
+func (wordCounter) Count(model, text string) int {
+	return len(strings.Fields(text))
+}
+
+// fakeProvider answers every request with a numbered summary and records the requests
+type fakeProvider struct {
+	requests []*types.ChatRequest
+	err      error
+}
+
+func (p *fakeProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	p.requests = append(p.requests, req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &types.ChatResponse{Choices: []types.Choice{{
+		Message: types.Message{Role: "assistant", Content: fmt.Sprintf("summary%d", len(p.requests))},
+	}}}, nil
+}
+
+func (p *fakeProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) AvailableModels() []string { return nil }
+
+func msg(role, content string) types.Message {
+	return types.Message{Role: role, Content: content}
+}
+
+// contents returns the content of each message
+func contents(messages []types.Message) []string {
+	out := make([]string, len(messages))
+	for i, message := range messages {
+		out[i] = message.Content
+	}
+	return out
+}
+
+// words returns a text of n words
+func words(n int) string {
+	return strings.TrimSpace(strings.Repeat("word ", n))
+}
+
+// conversation is a system prompt and six turns; each message counts 6
+// tokens (5 of overhead and role, plus a word) and the reply 3
+func conversation() []types.Message {
+	return []types.Message{
+		msg("system", "sys"),
+		msg("user", "u1"),
+		msg("assistant", "a1"),
+		msg("user", "u2"),
+		msg("assistant", "a2"),
+		msg("user", "u3"),
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	ctx := context.Background()
+	window := Window{Counter: wordCounter{}, Limit: 3 + 6*4}
+
+	fitted, err := DropOldest{}.Fit(ctx, window, conversation())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sys", "u2", "a2", "u3"}, contents(fitted))
+
+	t.Run("fits", func(t *testing.T) {
+		messages := conversation()
+		fitted, err := DropOldest{}.Fit(ctx, Window{Counter: wordCounter{}, Limit: 1000}, messages)
+		require.NoError(t, err)
+		assert.Equal(t, messages, fitted)
+	})
+
+	t.Run("keeps tool results with their call", func(t *testing.T) {
+		messages := []types.Message{
+			msg("system", "sys"),
+			msg("user", "task"),
+			{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "1", Function: types.FunctionCall{Name: "t"}}}},
+			{Role: "tool", ToolCallID: "1", Content: "r1"},
+			{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "2", Function: types.FunctionCall{Name: "t"}}}},
+			{Role: "tool", ToolCallID: "2", Content: "r2"},
+		}
+		// The task and the latest call are kept; the first call goes with its result
+		limit := window.Count(messages) - 1
+		fitted, err := DropOldest{}.Fit(ctx, Window{Counter: wordCounter{}, Limit: limit}, messages)
+		require.NoError(t, err)
+		require.Len(t, fitted, 4)
+		assert.Equal(t, "task", fitted[1].Content)
+		assert.Equal(t, "2", fitted[2].ToolCalls[0].ID)
+		assert.Equal(t, "r2", fitted[3].Content)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		_, err := DropOldest{}.Fit(ctx, Window{Counter: wordCounter{}, Limit: 10}, conversation())
+		assert.ErrorIs(t, err, ErrContextOverflow)
+	})
+}
+
+func TestKeepLast(t *testing.T) {
+	ctx := context.Background()
+	fitted, err := KeepLast{N: 3}.Fit(ctx, Window{Counter: wordCounter{}, Limit: 1000}, conversation())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sys", "u2", "a2", "u3"}, contents(fitted))
+
+	// Drops further turns when the last N do not fit
+	fitted, err = KeepLast{N: 3}.Fit(ctx, Window{Counter: wordCounter{}, Limit: 3 + 6*2}, conversation())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sys", "u3"}, contents(fitted))
+}
+
+func TestSummarize(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeProvider{}
+	// The summary message counts 11 tokens
+	window := Window{Model: "m", Counter: wordCounter{}, Limit: 3 + 6*5, Provider: provider}
+	strategy := Summarize{KeepLast: 2, MaxTokens: 50}
+
+	fitted, err := strategy.Fit(ctx, window, conversation())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sys", summaryPrefix + "summary1", "a2", "u3"}, contents(fitted))
+
+	require.Len(t, provider.requests, 1)
+	req := provider.requests[0]
+	assert.Equal(t, "m", req.Model)
+	assert.Equal(t, 50, req.MaxTokens)
+	assert.Equal(t, "user: u1\nassistant: a1\nuser: u2\n", req.Messages[1].Content)
+
+	t.Run("rolls the summary forward", func(t *testing.T) {
+		next := append(fitted, msg("assistant", "a3"), msg("user", "u4"))
+		fitted, err := strategy.Fit(ctx, window, next)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sys", summaryPrefix + "summary2", "a3", "u4"}, contents(fitted))
+		assert.Equal(t, "Summary of the conversation so far:\nsummary1\n\nassistant: a2\nuser: u3\n",
+			provider.requests[1].Messages[1].Content)
+	})
+
+	t.Run("never summarizes the latest user message", func(t *testing.T) {
+		provider := &fakeProvider{}
+		window := window
+		window.Provider = provider
+		window.Limit = 40
+		messages := append(conversation(),
+			types.Message{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "1", Function: types.FunctionCall{Name: "t"}}}},
+			types.Message{Role: "tool", ToolCallID: "1", Name: "t", Content: "r"})
+		fitted, err := strategy.Fit(ctx, window, messages)
+		require.NoError(t, err)
+		assert.Equal(t, "u3", fitted[2].Content)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		window := window
+		window.Provider = &fakeProvider{err: errors.New("down")}
+		_, err := strategy.Fit(ctx, window, conversation())
+		assert.ErrorContains(t, err, "failed to summarize the conversation: down")
+	})
+}
+
+func TestMapReduce(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeProvider{}
+	window := Window{Model: "m", Counter: wordCounter{}, Limit: 200, Provider: provider}
+
+	messages := []types.Message{msg("system", "sys"), msg("user", words(250))}
+	fitted, err := MapReduce{ChunkTokens: 100}.Fit(ctx, window, messages)
+	require.NoError(t, err)
+
+	// Three chunks are condensed, then their joined results in a final call
+	require.Len(t, provider.requests, 4)
+	assert.Contains(t, provider.requests[0].Messages[0].Content, "This is part 1 of 3 of the text.")
+	assert.Equal(t, "summary1\n\nsummary2\n\nsummary3", provider.requests[3].Messages[1].Content)
+	assert.Equal(t, []string{"sys", "summary4"}, contents(fitted))
+
+	t.Run("keeps images", func(t *testing.T) {
+		messages := []types.Message{{Role: "user", Parts: []types.ContentPart{
+			types.TextPart(words(250)), types.ImagePart("https://example.com/a.png"),
+		}}}
+		_, err := MapReduce{ChunkTokens: 100}.Fit(ctx, window, messages)
+		assert.ErrorIs(t, err, ErrContextOverflow)
+	})
+}
+
+func TestChunkText(t *testing.T) {
+	chunks := chunkText(wordCounter{}, "", "a b c d e", 2)
+	assert.Equal(t, []string{"a b ", "c d ", "e"}, chunks)
+	assert.Equal(t, []string{""}, chunkText(wordCounter{}, "", "", 2))
+
+	// A word longer than a chunk is split by characters
+	chunks = chunkText(tokenizer.Heuristic{}, "gpt-4o", strings.Repeat("x", 40), 5)
+	assert.Equal(t, strings.Repeat("x", 40), strings.Join(chunks, ""))
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, tokenizer.Heuristic{}.Count("gpt-4o", chunk), 5)
+	}
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// FileConfig configures a FileStore
+type FileConfig struct {
+	// Dir is the directory holding a <session>.json file per session
+	Dir string `yaml:"dir"`
+}
+
+// FileStore keeps each session in a JSON file, rewriting it on every change
+type FileStore struct {
+	logger *zap.Logger
+	config *FileConfig
+	mu     sync.Mutex
+}
+
+// NewFileStore creates a store in the configured directory, creating it if needed
+func NewFileStore(logger *zap.Logger, config *FileConfig) (*FileStore, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("memory directory is required")
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	return &FileStore{logger: logger, config: config}, nil
+}
+
+func (s *FileStore) path(session string) string {
+	return filepath.Join(s.config.Dir, session+".json")
+}
+
+// Load returns the messages of a session, oldest first
+func (s *FileStore) Load(ctx context.Context, session string) ([]types.Message, error) {
+	if err := ValidateSession(session); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read(session)
+}
+
+func (s *FileStore) read(session string) ([]types.Message, error) {
+	data, err := os.ReadFile(s.path(session))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", session, err)
+	}
+
+	var messages []types.Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode session %s: %w", session, err)
+	}
+	return messages, nil
+}
+
+// Append adds messages to the end of a session
+func (s *FileStore) Append(ctx context.Context, session string, messages ...types.Message) error {
+	if err := ValidateSession(session); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.read(session)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(append(existing, messages...), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session %s: %w", session, err)
+	}
+	return writeFile(s.path(session), data)
+}
+
+// writeFile replaces a file through a temporary file, so a crash never leaves it half written
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// Clear removes a session and its messages
+func (s *FileStore) Clear(ctx context.Context, session string) error {
+	if err := ValidateSession(session); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(session)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove session %s: %w", session, err)
+	}
+	return nil
+}
+
+// Sessions returns the IDs of the stored sessions in order
+func (s *FileStore) Sessions(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || ValidateSession(id) != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Close does nothing; files are closed after every change
+func (s *FileStore) Close() error {
+	return nil
+}
@@ -0,0 +1,266 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// LogConfig configures a LogStore
+type LogConfig struct {
+	// Path is the log file, created if it does not exist
+	Path string `yaml:"path"`
+}
+
+// logRecord is a change written to the log file
+type logRecord struct {
+	Op       string          `json:"op"`
+	Session  string          `json:"session"`
+	Messages []types.Message `json:"messages,omitempty"`
+}
+
+const (
+	opAppend = "append"
+	opClear  = "clear"
+)
+
+// compactSlack is how many records beyond two per session are kept before compacting
+const compactSlack = 100
+
+// LogStore keeps every session in a single append-only log file of JSON
+// lines, one record per change. It is not a database: there are no indexes or
+// transactions, and all sessions are held in memory. Changes are appended to
+// the file and synced before they are acknowledged; the file is replayed into
+// memory when opened, and compacted to a record per session when it holds
+// many more records than sessions. A record left incomplete by a crash is
+// discarded on open.
+type LogStore struct {
+	logger   *zap.Logger
+	config   *LogConfig
+	file     *os.File
+	sessions map[string][]types.Message
+	records  int
+	closed   bool
+	mu       sync.RWMutex
+}
+
+// NewLogStore opens the configured log file, creating it if needed
+func NewLogStore(logger *zap.Logger, config *LogConfig) (*LogStore, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("memory log path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create memory log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(config.Path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory log: %w", err)
+	}
+
+	s := &LogStore{
+		logger:   logger,
+		config:   config,
+		file:     file,
+		sessions: make(map[string][]types.Message),
+	}
+	if err := s.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if s.records > 2*len(s.sessions)+compactSlack {
+		if err := s.compact(); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// replay loads the records of the log file, truncating an incomplete last record
+func (s *LogStore) replay() error {
+	reader := bufio.NewReader(s.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				return s.truncate(offset, "incomplete record")
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read memory log: %w", err)
+		}
+
+		var record logRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			if _, peekErr := reader.Peek(1); errors.Is(peekErr, io.EOF) {
+				return s.truncate(offset, "corrupt last record")
+			}
+			return fmt.Errorf("memory log %s is corrupt at offset %d: %w", s.config.Path, offset, err)
+		}
+		s.apply(record)
+		s.records++
+		offset += int64(len(line))
+	}
+
+	if _, err := s.file.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("failed to seek memory log: %w", err)
+	}
+	return nil
+}
+
+// truncate drops the tail of the log file from offset
+func (s *LogStore) truncate(offset int64, reason string) error {
+	s.logger.Warn("Discarding the tail of the memory log",
+		zap.String("path", s.config.Path),
+		zap.String("reason", reason),
+		zap.Int64("offset", offset))
+
+	if err := s.file.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate memory log: %w", err)
+	}
+	if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek memory log: %w", err)
+	}
+	return nil
+}
+
+func (s *LogStore) apply(record logRecord) {
+	switch record.Op {
+	case opAppend:
+		s.sessions[record.Session] = append(s.sessions[record.Session], record.Messages...)
+	case opClear:
+		delete(s.sessions, record.Session)
+	}
+}
+
+// write appends a record to the log file and applies it
+func (s *LogStore) write(record logRecord) error {
+	if s.closed {
+		return fmt.Errorf("memory log is closed")
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode session %s: %w", record.Session, err)
+	}
+	offset, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to seek memory log: %w", err)
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		// Drop a partial record so later records stay readable
+		s.truncate(offset, "failed write")
+		return fmt.Errorf("failed to write memory log: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync memory log: %w", err)
+	}
+	s.apply(record)
+	s.records++
+	return nil
+}
+
+// compact rewrites the log file with a single record per session
+func (s *LogStore) compact() error {
+	var buf bytes.Buffer
+	for _, id := range sessionIDs(s.sessions) {
+		data, err := json.Marshal(logRecord{Op: opAppend, Session: id, Messages: s.sessions[id]})
+		if err != nil {
+			return fmt.Errorf("failed to encode session %s: %w", id, err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if err := writeFile(s.config.Path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to compact memory log: %w", err)
+	}
+
+	file, err := os.OpenFile(s.config.Path, os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to reopen memory log: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek memory log: %w", err)
+	}
+	s.file.Close()
+	s.file = file
+	s.records = len(s.sessions)
+	return nil
+}
+
+// Load returns the messages of a session, oldest first
+func (s *LogStore) Load(ctx context.Context, session string) ([]types.Message, error) {
+	if err := ValidateSession(session); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]types.Message(nil), s.sessions[session]...), nil
+}
+
+// Append adds messages to the end of a session
+func (s *LogStore) Append(ctx context.Context, session string, messages ...types.Message) error {
+	if err := ValidateSession(session); err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(logRecord{Op: opAppend, Session: session, Messages: messages})
+}
+
+// Clear removes a session and its messages
+func (s *LogStore) Clear(ctx context.Context, session string) error {
+	if err := ValidateSession(session); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[session]; !ok {
+		return nil
+	}
+	return s.write(logRecord{Op: opClear, Session: session})
+}
+
+// Sessions returns the IDs of the stored sessions in order
+func (s *LogStore) Sessions(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sessionIDs(s.sessions), nil
+}
+
+// Close closes the log file
+func (s *LogStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.file.Close()
+}
@@ -0,0 +1,109 @@
+// Package memory stores the conversation turns of agent sessions in memory,
+// in JSON files or in an append-only log file
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// maxSessionLength bounds the length of a session ID
+const maxSessionLength = 128
+
+// ValidateSession checks that a session ID is 1 to 128 letters, digits, dots,
+// dashes or underscores, so it can safely name a file
+func ValidateSession(session string) error {
+	if session == "" {
+		return fmt.Errorf("session ID is required")
+	}
+	if len(session) > maxSessionLength {
+		return fmt.Errorf("session ID is longer than %d characters", maxSessionLength)
+	}
+	if session == "." || session == ".." {
+		return fmt.Errorf("invalid session ID %q", session)
+	}
+	for _, r := range session {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return fmt.Errorf("invalid session ID %q: only letters, digits, '.', '-' and '_' are allowed", session)
+		}
+	}
+	return nil
+}
+
+// Store keeps sessions in memory for the life of the process
+type Store struct {
+	sessions map[string][]types.Message
+	mu       sync.RWMutex
+}
+
+// NewStore creates an empty in-memory store
+func NewStore() *Store {
+	return &Store{sessions: make(map[string][]types.Message)}
+}
+
+// Load returns the messages of a session, oldest first
+func (s *Store) Load(ctx context.Context, session string) ([]types.Message, error) {
+	if err := ValidateSession(session); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]types.Message(nil), s.sessions[session]...), nil
+}
+
+// Append adds messages to the end of a session
+func (s *Store) Append(ctx context.Context, session string, messages ...types.Message) error {
+	if err := ValidateSession(session); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session] = append(s.sessions[session], messages...)
+	return nil
+}
+
+// Clear removes a session and its messages
+func (s *Store) Clear(ctx context.Context, session string) error {
+	if err := ValidateSession(session); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, session)
+	return nil
+}
+
+// Sessions returns the IDs of the stored sessions in order
+func (s *Store) Sessions(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sessionIDs(s.sessions), nil
+}
+
+// Close does nothing; an in-memory store holds no resources
+func (s *Store) Close() error {
+	return nil
+}
+
+// sessionIDs returns the sorted keys of a session map
+func sessionIDs(sessions map[string][]types.Message) []string {
+	ids := make([]string, 0, len(sessions))
+	for id := range sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// testStore checks the behavior every memory implementation shares
+func testStore(t *testing.T, store types.Memory) {
+	ctx := context.Background()
+
+	messages, err := store.Load(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	require.NoError(t, store.Append(ctx, "s1", types.Message{Role: "user", Content: "Hi"}))
+	require.NoError(t, store.Append(ctx, "s1",
+		types.Message{Role: "assistant", Content: "Hello"},
+		types.Message{Role: "user", Parts: []types.ContentPart{types.TextPart("Look")}}))
+	require.NoError(t, store.Append(ctx, "s2", types.Message{Role: "user", Content: "Other"}))
+
+	messages, err = store.Load(ctx, "s1")
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "Hi", messages[0].Content)
+	assert.Equal(t, "Hello", messages[1].Content)
+	assert.Equal(t, "Look", messages[2].Text())
+
+	messages[0].Content = "changed"
+	again, err := store.Load(ctx, "s1")
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", again[0].Content, "loaded messages are copies")
+
+	sessions, err := store.Sessions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s1", "s2"}, sessions)
+
+	require.NoError(t, store.Clear(ctx, "s1"))
+	require.NoError(t, store.Clear(ctx, "s1"))
+	messages, err = store.Load(ctx, "s1")
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+	sessions, err = store.Sessions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s2"}, sessions)
+
+	assert.Error(t, store.Append(ctx, "../escape", types.Message{Role: "user"}))
+	_, err = store.Load(ctx, "")
+	assert.Error(t, err)
+}
+
+func TestStore(t *testing.T) {
+	testStore(t, NewStore())
+}
+
+func TestFileStore(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	t.Run("contract", func(t *testing.T) {
+		store, err := NewFileStore(logger, &FileConfig{Dir: t.TempDir()})
+		require.NoError(t, err)
+		testStore(t, store)
+	})
+
+	t.Run("persists across instances", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "sessions")
+		store, err := NewFileStore(logger, &FileConfig{Dir: dir})
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, "chat-1", types.Message{Role: "user", Content: "Remember me"}))
+
+		reopened, err := NewFileStore(logger, &FileConfig{Dir: dir})
+		require.NoError(t, err)
+		messages, err := reopened.Load(ctx, "chat-1")
+		require.NoError(t, err)
+		assert.Equal(t, []types.Message{{Role: "user", Content: "Remember me"}}, messages)
+		assert.FileExists(t, filepath.Join(dir, "chat-1.json"))
+	})
+
+	t.Run("no directory", func(t *testing.T) {
+		_, err := NewFileStore(logger, &FileConfig{})
+		assert.Error(t, err)
+	})
+}
+
+func TestLogStore(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	t.Run("contract", func(t *testing.T) {
+		store, err := NewLogStore(logger, &LogConfig{Path: filepath.Join(t.TempDir(), "memory.jsonl")})
+		require.NoError(t, err)
+		defer store.Close()
+		testStore(t, store)
+	})
+
+	t.Run("persists across instances", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "memory.jsonl")
+		store, err := NewLogStore(logger, &LogConfig{Path: path})
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, "a", types.Message{Role: "user", Content: "one"}))
+		require.NoError(t, store.Append(ctx, "b", types.Message{Role: "user", Content: "two"}))
+		require.NoError(t, store.Clear(ctx, "b"))
+		require.NoError(t, store.Close())
+		assert.Error(t, store.Append(ctx, "a", types.Message{Role: "user"}), "closed")
+
+		reopened, err := NewLogStore(logger, &LogConfig{Path: path})
+		require.NoError(t, err)
+		defer reopened.Close()
+		messages, err := reopened.Load(ctx, "a")
+		require.NoError(t, err)
+		assert.Equal(t, []types.Message{{Role: "user", Content: "one"}}, messages)
+		sessions, err := reopened.Sessions(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, sessions)
+	})
+
+	t.Run("discards an incomplete last record", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "memory.jsonl")
+		store, err := NewLogStore(logger, &LogConfig{Path: path})
+		require.NoError(t, err)
+		require.NoError(t, store.Append(ctx, "a", types.Message{Role: "user", Content: "kept"}))
+		require.NoError(t, store.Close())
+
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"op":"append","session":"a","messages":[{"role":"us`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		reopened, err := NewLogStore(logger, &LogConfig{Path: path})
+		require.NoError(t, err)
+		require.NoError(t, reopened.Append(ctx, "a", types.Message{Role: "assistant", Content: "next"}))
+		require.NoError(t, reopened.Close())
+
+		reopened, err = NewLogStore(logger, &LogConfig{Path: path})
+		require.NoError(t, err)
+		defer reopened.Close()
+		messages, err := reopened.Load(ctx, "a")
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		assert.Equal(t, "kept", messages[0].Content)
+		assert.Equal(t, "next", messages[1].Content)
+	})
+
+	t.Run("rejects corruption before the end", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "memory.jsonl")
+		data := "not json\n" + `{"op":"append","session":"a","messages":[{"role":"user","content":"x"}]}` + "\n"
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+		_, err := NewLogStore(logger, &LogConfig{Path: path})
+		assert.ErrorContains(t, err, "corrupt at offset 0")
+	})
+
+	t.Run("compacts on open", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "memory.jsonl")
+		store, err := NewLogStore(logger, &LogConfig{Path: path})
+		require.NoError(t, err)
+		for i := 0; i <= compactSlack; i++ {
+			require.NoError(t, store.Append(ctx, "a", types.Message{Role: "user", Content: "x"}))
+		}
+		require.NoError(t, store.Append(ctx, "gone", types.Message{Role: "user"}))
+		require.NoError(t, store.Clear(ctx, "gone"))
+		require.NoError(t, store.Close())
+
+		reopened, err := NewLogStore(logger, &LogConfig{Path: path})
+		require.NoError(t, err)
+		require.NoError(t, reopened.Append(ctx, "b", types.Message{Role: "user", Content: "y"}))
+		require.NoError(t, reopened.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+		reopened, err = NewLogStore(logger, &LogConfig{Path: path})
+		require.NoError(t, err)
+		defer reopened.Close()
+		messages, err := reopened.Load(ctx, "a")
+		require.NoError(t, err)
+		assert.Len(t, messages, compactSlack+1)
+	})
+}
+
+func TestValidateSession(t *testing.T) {
+	for _, session := range []string{"a", "chat-1", "user_2.session", strings.Repeat("x", maxSessionLength)} {
+		assert.NoError(t, ValidateSession(session), session)
+	}
+	for _, session := range []string{"", ".", "..", "a/b", "a b", "ü", strings.Repeat("x", maxSessionLength+1)} {
+		assert.Error(t, ValidateSession(session), session)
+	}
+}
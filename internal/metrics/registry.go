@@ -0,0 +1,374 @@
+// Package metrics implements a small metrics registry with a Prometheus
+// text exposition writer, so metrics can be scraped without external services.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are latency histogram buckets in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Registry holds metric families and writes them in text exposition format
+type Registry struct {
+	families  []family
+	names     map[string]bool
+	onCollect []func()
+	mu        sync.Mutex
+}
+
+// family is a named group of series of a single metric type
+type family interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// OnCollect registers a function run before every write, used to refresh
+// gauges that mirror state owned elsewhere
+func (r *Registry) OnCollect(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onCollect = append(r.onCollect, fn)
+}
+
+// NewCounter registers a counter family
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// NewGauge registers a gauge family
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(name, help, "gauge", labels)}
+	r.register(g)
+	return g
+}
+
+// NewHistogram registers a histogram family; nil buckets use DefaultBuckets
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{vec: newVec(name, help, "histogram", labels), buckets: buckets}
+	r.register(h)
+	return h
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[f.name()] {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", f.name()))
+	}
+	r.names[f.name()] = true
+	r.families = append(r.families, f)
+}
+
+// WriteText writes all metric families in text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	onCollect := append([]func(){}, r.onCollect...)
+	families := append([]family{}, r.families...)
+	r.mu.Unlock()
+
+	for _, fn := range onCollect {
+		fn()
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns an HTTP handler serving the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.WriteText(w)
+	})
+}
+
+// vec holds the series of a family keyed by label values
+type vec struct {
+	metricName string
+	help       string
+	metricType string
+	labels     []string
+	series     map[string]*series
+	mu         sync.Mutex
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func newVec(name, help, metricType string, labels []string) vec {
+	return vec{
+		metricName: name,
+		help:       help,
+		metricType: metricType,
+		labels:     labels,
+		series:     make(map[string]*series),
+	}
+}
+
+func (v *vec) name() string {
+	return v.metricName
+}
+
+// getLocked returns the series for the label values, creating it if needed
+func (v *vec) getLocked(values []string, buckets int) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), values...)}
+		if buckets > 0 {
+			s.counts = make([]uint64, buckets)
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sortedLocked returns the series ordered by label values
+func (v *vec) sortedLocked() []*series {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]*series, len(keys))
+	for i, key := range keys {
+		sorted[i] = v.series[key]
+	}
+	return sorted
+}
+
+func (v *vec) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.metricName, escapeHelp(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.metricName, v.metricType)
+}
+
+func (v *vec) writeValues(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.series) == 0 {
+		return
+	}
+	v.writeHeader(w)
+	for _, s := range v.sortedLocked() {
+		writeSample(w, v.metricName, v.labels, s.labelValues, "", "", s.value)
+	}
+}
+
+// CounterVec is a family of monotonically increasing values
+type CounterVec struct {
+	vec
+}
+
+// Counter is a single counter series
+type Counter struct {
+	vec    *vec
+	values []string
+}
+
+// With returns the counter series for the label values
+func (c *CounterVec) With(values ...string) Counter {
+	return Counter{vec: &c.vec, values: values}
+}
+
+// Inc increments the counter by one
+func (c Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increases the counter; negative values are ignored
+func (c Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.vec.mu.Lock()
+	defer c.vec.mu.Unlock()
+	c.vec.getLocked(c.values, 0).value += delta
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeValues(w)
+}
+
+// GaugeVec is a family of values that can go up and down
+type GaugeVec struct {
+	vec
+}
+
+// Gauge is a single gauge series
+type Gauge struct {
+	vec    *vec
+	values []string
+}
+
+// With returns the gauge series for the label values
+func (g *GaugeVec) With(values ...string) Gauge {
+	return Gauge{vec: &g.vec, values: values}
+}
+
+// Set sets the gauge value
+func (g Gauge) Set(value float64) {
+	g.vec.mu.Lock()
+	defer g.vec.mu.Unlock()
+	g.vec.getLocked(g.values, 0).value = value
+}
+
+// Add adds delta to the gauge value
+func (g Gauge) Add(delta float64) {
+	g.vec.mu.Lock()
+	defer g.vec.mu.Unlock()
+	g.vec.getLocked(g.values, 0).value += delta
+}
+
+// Inc increments the gauge by one
+func (g Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec decrements the gauge by one
+func (g Gauge) Dec() {
+	g.Add(-1)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.writeValues(w)
+}
+
+// HistogramVec is a family of bucketed observations
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+// Histogram is a single histogram series
+type Histogram struct {
+	vec    *HistogramVec
+	values []string
+}
+
+// With returns the histogram series for the label values
+func (h *HistogramVec) With(values ...string) Histogram {
+	return Histogram{vec: h, values: values}
+}
+
+// Observe records a single observation
+func (h Histogram) Observe(value float64) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+
+	s := h.vec.getLocked(h.values, len(h.vec.buckets))
+	for i, bound := range h.vec.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.series) == 0 {
+		return
+	}
+	h.writeHeader(w)
+	for _, s := range h.sortedLocked() {
+		for i, bound := range h.buckets {
+			writeSample(w, h.metricName+"_bucket", h.labels, s.labelValues, "le", formatFloat(bound), float64(s.counts[i]))
+		}
+		writeSample(w, h.metricName+"_bucket", h.labels, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, h.metricName+"_sum", h.labels, s.labelValues, "", "", s.sum)
+		writeSample(w, h.metricName+"_count", h.labels, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// writeSample writes one sample line with an optional extra label
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabelValue(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
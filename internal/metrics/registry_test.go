@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	registry := NewRegistry()
+
+	requests := registry.NewCounter("test_requests_total", "Requests served.", "provider", "status")
+	requests.With("openai", "success").Inc()
+	requests.With("openai", "success").Add(2)
+	requests.With("anthropic", "error").Inc()
+	requests.With("anthropic", "error").Add(-5)
+
+	inFlight := registry.NewGauge("test_in_flight", "In-flight requests.")
+	inFlight.With().Inc()
+	inFlight.With().Inc()
+	inFlight.With().Dec()
+
+	latency := registry.NewHistogram("test_latency_seconds", "Latency.", []float64{1, 0.1}, "provider")
+	latency.With("openai").Observe(0.05)
+	latency.With("openai").Observe(0.5)
+	latency.With("openai").Observe(5)
+
+	registry.NewCounter("test_unused_total", "Never observed.")
+
+	collected := registry.NewGauge("test_collected", "Refreshed on collect.", "name")
+	registry.OnCollect(func() {
+		collected.With(`quote"back\slash`).Set(7)
+	})
+
+	var b strings.Builder
+	require.NoError(t, registry.WriteText(&b))
+
+	expected := `# HELP test_requests_total Requests served.
+# TYPE test_requests_total counter
+test_requests_total{provider="anthropic",status="error"} 1
+test_requests_total{provider="openai",status="success"} 3
+# HELP test_in_flight In-flight requests.
+# TYPE test_in_flight gauge
+test_in_flight 1
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{provider="openai",le="0.1"} 1
+test_latency_seconds_bucket{provider="openai",le="1"} 2
+test_latency_seconds_bucket{provider="openai",le="+Inf"} 3
+test_latency_seconds_sum{provider="openai"} 5.55
+test_latency_seconds_count{provider="openai"} 3
+# HELP test_collected Refreshed on collect.
+# TYPE test_collected gauge
+test_collected{name="quote\"back\\slash"} 7
+`
+	assert.Equal(t, expected, b.String())
+}
+
+func TestRegistryHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("test_total", "Test.").With().Inc()
+
+	rec := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "test_total 1\n")
+}
+
+func TestRegistryPanics(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("test_total", "Test.", "a")
+
+	assert.Panics(t, func() { registry.NewGauge("test_total", "Duplicate.") })
+	assert.Panics(t, func() { counter.With("x", "y").Inc() })
+}
@@ -0,0 +1,205 @@
+// Package orchestration composes agents into teams: sequential pipelines,
+// parallel fan-out with an optional aggregator, and supervisors and routers
+// that dispatch tasks to other members
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/tracing"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// Member is an agent or a team taking part in a team. Every types.Agent is a
+// Member, and so is every team, so teams can be nested.
+type Member interface {
+	Name() string
+	Execute(ctx context.Context, task string, opts ...types.ExecuteOption) (*types.Response, error)
+}
+
+// Delegate is a member a supervisor or router may hand tasks to
+type Delegate struct {
+	Member Member
+
+	// Description tells the supervisor or router what the member is good at
+	Description string
+}
+
+// Step is a task run by a member of a team
+type Step struct {
+	Member   string        `json:"member"`
+	Task     string        `json:"task"`
+	Content  string        `json:"content,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Usage    types.Usage   `json:"usage"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Shared is the context shared by the members of a team and of the teams
+// nested in it: values set by members or their tools, and every step run
+type Shared struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+	steps  []Step
+}
+
+// NewShared creates an empty shared context
+func NewShared() *Shared {
+	return &Shared{values: make(map[string]interface{})}
+}
+
+// Set sets a shared value
+func (s *Shared) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Get returns a shared value
+func (s *Shared) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Steps returns the steps run so far, in the order they finished
+func (s *Shared) Steps() []Step {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Step(nil), s.steps...)
+}
+
+func (s *Shared) addStep(step Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, step)
+}
+
+type sharedKey struct{}
+
+// WithShared returns a context carrying a shared context. Teams run with a
+// context without one create their own.
+func WithShared(ctx context.Context, shared *Shared) context.Context {
+	return context.WithValue(ctx, sharedKey{}, shared)
+}
+
+// SharedFromContext returns the shared context of a team run, or nil outside one
+func SharedFromContext(ctx context.Context) *Shared {
+	shared, _ := ctx.Value(sharedKey{}).(*Shared)
+	return shared
+}
+
+// withShared returns ctx, adding a new shared context if it has none
+func withShared(ctx context.Context) context.Context {
+	if SharedFromContext(ctx) != nil {
+		return ctx
+	}
+	return WithShared(ctx, NewShared())
+}
+
+// execute runs a task on a member inside a trace span and records the step
+// in the shared context
+func execute(ctx context.Context, logger *zap.Logger, team string, member Member, task string, opts []types.ExecuteOption) (*types.Response, Step, error) {
+	ctx, span := tracing.Start(ctx, "orchestration.execute")
+	defer span.End()
+	span.SetAttribute("team", team)
+	span.SetAttribute("member", member.Name())
+
+	start := time.Now()
+	resp, err := member.Execute(ctx, task, opts...)
+	step := Step{Member: member.Name(), Task: task, Duration: time.Since(start)}
+	if err == nil && resp == nil {
+		err = errors.New("no response")
+	}
+	if err != nil {
+		err = fmt.Errorf("member %s failed: %w", member.Name(), err)
+		step.Error = err.Error()
+	} else {
+		step.Content = resp.Content
+		step.Usage = resp.Usage
+	}
+	span.RecordError(err)
+	if shared := SharedFromContext(ctx); shared != nil {
+		shared.addStep(step)
+	}
+
+	logger.Debug("Member finished task",
+		zap.String("team", team),
+		zap.String("member", member.Name()),
+		zap.Duration("duration", step.Duration),
+		zap.Int("total_tokens", step.Usage.TotalTokens),
+		zap.Error(err))
+	return resp, step, err
+}
+
+// newResponse is the response of a team: its answer, the usage of every
+// step it ran and the steps themselves in the metadata
+func newResponse(team, content, finishReason string, steps []Step) *types.Response {
+	var usage types.Usage
+	for _, step := range steps {
+		usage.PromptTokens += step.Usage.PromptTokens
+		usage.CompletionTokens += step.Usage.CompletionTokens
+		usage.TotalTokens += step.Usage.TotalTokens
+	}
+	return &types.Response{
+		Content: content,
+		Metadata: map[string]interface{}{
+			"team":  team,
+			"steps": steps,
+		},
+		Usage:        usage,
+		Timestamp:    time.Now().Unix(),
+		FinishReason: finishReason,
+	}
+}
+
+// validateMembers checks that a team has a name and uniquely named members
+func validateMembers(name string, members []Member) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("at least one member is required")
+	}
+	seen := make(map[string]bool, len(members))
+	for i, member := range members {
+		if member == nil {
+			return fmt.Errorf("member %d is nil", i)
+		}
+		if seen[member.Name()] {
+			return fmt.Errorf("duplicate member %q", member.Name())
+		}
+		seen[member.Name()] = true
+	}
+	return nil
+}
+
+// delegateMembers returns the members of delegates
+func delegateMembers(delegates []Delegate) []Member {
+	members := make([]Member, len(delegates))
+	for i, d := range delegates {
+		members[i] = d.Member
+	}
+	return members
+}
+
+// describeDelegates lists delegates for a supervisor or router prompt
+func describeDelegates(delegates []Delegate) string {
+	var sb strings.Builder
+	for _, d := range delegates {
+		sb.WriteString("- " + d.Member.Name())
+		if d.Description != "" {
+			sb.WriteString(": " + d.Description)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
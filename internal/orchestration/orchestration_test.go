@@ -0,0 +1,123 @@
+package orchestration
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// fakeMember answers with reply, using one token of each kind per call, and
+// records the tasks it gets
+type fakeMember struct {
+	name  string
+	reply func(ctx context.Context, task string) (string, error)
+
+	mu    sync.Mutex
+	tasks []string
+}
+
+func (m *fakeMember) Name() string { return m.name }
+
+func (m *fakeMember) Execute(ctx context.Context, task string, opts ...types.ExecuteOption) (*types.Response, error) {
+	m.mu.Lock()
+	m.tasks = append(m.tasks, task)
+	m.mu.Unlock()
+
+	content, err := m.reply(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Response{
+		Content:      content,
+		Usage:        types.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+		FinishReason: "stop",
+	}, nil
+}
+
+func (m *fakeMember) received() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.tasks...)
+}
+
+// echo answers with its name and the task
+func echo(name string) *fakeMember {
+	return &fakeMember{name: name, reply: func(ctx context.Context, task string) (string, error) {
+		return name + ": " + task, nil
+	}}
+}
+
+// replies answers with the given replies in turn, repeating the last one
+func replies(name string, contents ...string) *fakeMember {
+	var mu sync.Mutex
+	calls := 0
+	return &fakeMember{name: name, reply: func(ctx context.Context, task string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		content := contents[len(contents)-1]
+		if calls < len(contents) {
+			content = contents[calls]
+		}
+		calls++
+		return content, nil
+	}}
+}
+
+// failing fails every task with err
+func failing(name string, err error) *fakeMember {
+	return &fakeMember{name: name, reply: func(ctx context.Context, task string) (string, error) {
+		return "", err
+	}}
+}
+
+// stepsOf returns the steps in a team response's metadata
+func stepsOf(t *testing.T, resp *types.Response) []Step {
+	t.Helper()
+	steps, ok := resp.Metadata["steps"].([]Step)
+	require.True(t, ok)
+	return steps
+}
+
+func TestShared(t *testing.T) {
+	shared := NewShared()
+	shared.Set("plan", "review then audit")
+	value, ok := shared.Get("plan")
+	assert.True(t, ok)
+	assert.Equal(t, "review then audit", value)
+	_, ok = shared.Get("missing")
+	assert.False(t, ok)
+
+	assert.Nil(t, SharedFromContext(context.Background()))
+	ctx := WithShared(context.Background(), shared)
+	assert.Same(t, shared, SharedFromContext(ctx))
+	assert.Same(t, shared, SharedFromContext(withShared(ctx)), "an existing shared context is kept")
+
+	// Members and their tools see the shared context of the team
+	writer := &fakeMember{name: "writer", reply: func(ctx context.Context, task string) (string, error) {
+		SharedFromContext(ctx).Set("draft", task)
+		return "done", nil
+	}}
+	team, err := NewSequential(zaptest.NewLogger(t), &SequentialConfig{Name: "team", Members: []Member{writer}})
+	require.NoError(t, err)
+	_, err = team.Execute(ctx, "write")
+	require.NoError(t, err)
+
+	value, _ = shared.Get("draft")
+	assert.Equal(t, "write", value)
+	require.Len(t, shared.Steps(), 1)
+	assert.Equal(t, "writer", shared.Steps()[0].Member)
+}
+
+func TestValidateMembers(t *testing.T) {
+	assert.ErrorContains(t, validateMembers("", []Member{echo("a")}), "name is required")
+	assert.ErrorContains(t, validateMembers("team", nil), "at least one member")
+	assert.ErrorContains(t, validateMembers("team", []Member{echo("a"), echo("a")}), `duplicate member "a"`)
+	assert.ErrorContains(t, validateMembers("team", []Member{nil}), "member 0 is nil")
+	assert.NoError(t, validateMembers("team", []Member{echo("a"), echo("b")}))
+}
@@ -0,0 +1,138 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// ParallelConfig configures a fan-out team
+type ParallelConfig struct {
+	Name string
+
+	// Members all get the task at the same time
+	Members []Member
+
+	// Aggregator, when set, gets the task and the answers of the members and
+	// writes the team's answer; otherwise the answers are joined
+	Aggregator Member
+
+	// MaxConcurrent bounds the members running at once (0 runs all of them)
+	MaxConcurrent int
+}
+
+// Parallel fans a task out to its members and fans their answers back in
+type Parallel struct {
+	logger *zap.Logger
+	config *ParallelConfig
+}
+
+// NewParallel creates a fan-out team
+func NewParallel(logger *zap.Logger, config *ParallelConfig) (*Parallel, error) {
+	if err := validateMembers(config.Name, config.Members); err != nil {
+		return nil, fmt.Errorf("invalid parallel team: %w", err)
+	}
+	if config.MaxConcurrent < 0 {
+		return nil, fmt.Errorf("invalid parallel team: max concurrent must not be negative")
+	}
+	return &Parallel{logger: logger, config: config}, nil
+}
+
+// Name returns the name of the team
+func (p *Parallel) Name() string {
+	return p.config.Name
+}
+
+// Execute runs every member on the task. The first failing member cancels
+// the others and fails the team.
+func (p *Parallel) Execute(ctx context.Context, task string, opts ...types.ExecuteOption) (*types.Response, error) {
+	ctx = withShared(ctx)
+	assignments := make([]assignment, len(p.config.Members))
+	for i, member := range p.config.Members {
+		assignments[i] = assignment{member: member, task: task}
+	}
+	steps, err := fanOut(ctx, p.logger, p.config.Name, assignments, p.config.MaxConcurrent, true, opts)
+	if err != nil {
+		return nil, fmt.Errorf("team %s: %w", p.config.Name, err)
+	}
+
+	if p.config.Aggregator == nil {
+		return newResponse(p.config.Name, joinAnswers(steps), "stop", steps), nil
+	}
+	aggregatorTask := fmt.Sprintf("%s\n\nAnswers of the team:\n\n%s", task, joinAnswers(steps))
+	resp, step, err := execute(ctx, p.logger, p.config.Name, p.config.Aggregator, aggregatorTask, opts)
+	steps = append(steps, step)
+	if err != nil {
+		return nil, fmt.Errorf("team %s: %w", p.config.Name, err)
+	}
+	return newResponse(p.config.Name, resp.Content, resp.FinishReason, steps), nil
+}
+
+// assignment is a task for a member
+type assignment struct {
+	member Member
+	task   string
+}
+
+// fanOut runs assignments concurrently, at most limit at a time when limit is
+// positive, and returns their steps in order. With failFast the first failure
+// cancels the assignments still running and is returned; otherwise failures
+// are only recorded in the steps.
+func fanOut(ctx context.Context, logger *zap.Logger, team string, assignments []assignment, limit int, failFast bool, opts []types.ExecuteOption) ([]Step, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if limit <= 0 || limit > len(assignments) {
+		limit = len(assignments)
+	}
+	slots := make(chan struct{}, limit)
+	steps := make([]Step, len(assignments))
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for i, a := range assignments {
+		wg.Add(1)
+		go func(i int, a assignment) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				steps[i] = Step{Member: a.member.Name(), Task: a.task, Error: ctx.Err().Error()}
+				return
+			}
+
+			var err error
+			_, steps[i], err = execute(ctx, logger, team, a.member, a.task, opts)
+			if err != nil && failFast {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i, a)
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return steps, firstErr
+}
+
+// joinAnswers joins the answers of steps under the names of their members
+func joinAnswers(steps []Step) string {
+	sections := make([]string, 0, len(steps))
+	for _, step := range steps {
+		answer := step.Content
+		if step.Error != "" {
+			answer = "error: " + step.Error
+		}
+		sections = append(sections, fmt.Sprintf("## %s\n\n%s", step.Member, answer))
+	}
+	return strings.Join(sections, "\n\n")
+}
@@ -0,0 +1,88 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestParallel(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	team, err := NewParallel(logger, &ParallelConfig{Name: "review", Members: []Member{echo("style"), echo("security")}})
+	require.NoError(t, err)
+
+	resp, err := team.Execute(ctx, "check main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "## style\n\nstyle: check main.go\n\n## security\n\nsecurity: check main.go", resp.Content)
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+
+	t.Run("aggregator", func(t *testing.T) {
+		aggregator := replies("lead", "all good")
+		team, err := NewParallel(logger, &ParallelConfig{
+			Name:       "review",
+			Members:    []Member{echo("style"), echo("security")},
+			Aggregator: aggregator,
+		})
+		require.NoError(t, err)
+
+		resp, err := team.Execute(ctx, "check main.go")
+		require.NoError(t, err)
+		assert.Equal(t, "all good", resp.Content)
+		assert.Equal(t, 6, resp.Usage.TotalTokens)
+		assert.Len(t, stepsOf(t, resp), 3)
+		require.Len(t, aggregator.received(), 1)
+		assert.Contains(t, aggregator.received()[0], "check main.go\n\nAnswers of the team:\n\n## style\n\nstyle: check main.go")
+	})
+
+	t.Run("bounded concurrency", func(t *testing.T) {
+		var running, peak int32
+		slow := func(name string) Member {
+			return &fakeMember{name: name, reply: func(ctx context.Context, task string) (string, error) {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				return "ok", nil
+			}}
+		}
+		team, err := NewParallel(logger, &ParallelConfig{
+			Name:          "team",
+			Members:       []Member{slow("a"), slow("b"), slow("c"), slow("d")},
+			MaxConcurrent: 2,
+		})
+		require.NoError(t, err)
+		_, err = team.Execute(ctx, "task")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+	})
+
+	t.Run("failure cancels the others", func(t *testing.T) {
+		blocked := &fakeMember{name: "blocked", reply: func(ctx context.Context, task string) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}}
+		team, err := NewParallel(logger, &ParallelConfig{
+			Name:    "team",
+			Members: []Member{blocked, failing("broken", errors.New("down"))},
+		})
+		require.NoError(t, err)
+		_, err = team.Execute(ctx, "task")
+		assert.EqualError(t, err, "team team: member broken failed: down")
+	})
+
+	_, err = NewParallel(logger, &ParallelConfig{Name: "team", Members: []Member{echo("a")}, MaxConcurrent: -1})
+	assert.ErrorContains(t, err, "max concurrent must not be negative")
+}
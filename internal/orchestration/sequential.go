@@ -0,0 +1,76 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// Handoff builds the task of a pipeline stage from the pipeline's task and
+// the step of the previous stage
+type Handoff func(task string, previous Step) string
+
+// DefaultHandoff gives a stage the pipeline's task followed by the output of the previous stage
+func DefaultHandoff(task string, previous Step) string {
+	return fmt.Sprintf("%s\n\nOutput of %s:\n%s", task, previous.Member, previous.Content)
+}
+
+// SequentialConfig configures a pipeline
+type SequentialConfig struct {
+	Name string
+
+	// Members run in order; the first gets the task as is
+	Members []Member
+
+	// Handoff builds the task of every later member (defaults to DefaultHandoff)
+	Handoff Handoff
+}
+
+// Sequential is a pipeline of members, each working on the output of the one before
+type Sequential struct {
+	logger *zap.Logger
+	config *SequentialConfig
+}
+
+// NewSequential creates a pipeline
+func NewSequential(logger *zap.Logger, config *SequentialConfig) (*Sequential, error) {
+	if err := validateMembers(config.Name, config.Members); err != nil {
+		return nil, fmt.Errorf("invalid sequential team: %w", err)
+	}
+	if config.Handoff == nil {
+		config.Handoff = DefaultHandoff
+	}
+	return &Sequential{logger: logger, config: config}, nil
+}
+
+// Name returns the name of the pipeline
+func (s *Sequential) Name() string {
+	return s.config.Name
+}
+
+// Execute runs the members in order and answers with the output of the last.
+// A failing member stops the pipeline.
+func (s *Sequential) Execute(ctx context.Context, task string, opts ...types.ExecuteOption) (*types.Response, error) {
+	ctx = withShared(ctx)
+
+	var resp *types.Response
+	steps := make([]Step, 0, len(s.config.Members))
+	for i, member := range s.config.Members {
+		stageTask := task
+		if i > 0 {
+			stageTask = s.config.Handoff(task, steps[i-1])
+		}
+
+		var step Step
+		var err error
+		resp, step, err = execute(ctx, s.logger, s.config.Name, member, stageTask, opts)
+		steps = append(steps, step)
+		if err != nil {
+			return nil, fmt.Errorf("team %s: %w", s.config.Name, err)
+		}
+	}
+	return newResponse(s.config.Name, resp.Content, resp.FinishReason, steps), nil
+}
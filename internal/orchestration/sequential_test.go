@@ -0,0 +1,75 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func TestSequential(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+	planner, reviewer := echo("planner"), echo("reviewer")
+
+	team, err := NewSequential(logger, &SequentialConfig{Name: "pipeline", Members: []Member{planner, reviewer}})
+	require.NoError(t, err)
+	assert.Equal(t, "pipeline", team.Name())
+
+	resp, err := team.Execute(ctx, "review main.go")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"review main.go"}, planner.received())
+	assert.Equal(t, []string{"review main.go\n\nOutput of planner:\nplanner: review main.go"}, reviewer.received())
+	assert.Equal(t, "reviewer: "+reviewer.received()[0], resp.Content)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, types.Usage{PromptTokens: 2, CompletionTokens: 2, TotalTokens: 4}, resp.Usage)
+	assert.Equal(t, "pipeline", resp.Metadata["team"])
+	assert.Len(t, stepsOf(t, resp), 2)
+
+	t.Run("handoff", func(t *testing.T) {
+		last := echo("last")
+		team, err := NewSequential(logger, &SequentialConfig{
+			Name:    "pipeline",
+			Members: []Member{echo("first"), last},
+			Handoff: func(task string, previous Step) string { return previous.Content },
+		})
+		require.NoError(t, err)
+		_, err = team.Execute(ctx, "task")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first: task"}, last.received())
+	})
+
+	t.Run("failure stops the pipeline", func(t *testing.T) {
+		last := echo("last")
+		team, err := NewSequential(logger, &SequentialConfig{
+			Name:    "pipeline",
+			Members: []Member{failing("first", errors.New("down")), last},
+		})
+		require.NoError(t, err)
+		_, err = team.Execute(ctx, "task")
+		assert.EqualError(t, err, "team pipeline: member first failed: down")
+		assert.Empty(t, last.received())
+	})
+
+	t.Run("nested teams", func(t *testing.T) {
+		inner, err := NewSequential(logger, &SequentialConfig{Name: "inner", Members: []Member{echo("a"), echo("b")}})
+		require.NoError(t, err)
+		outer, err := NewSequential(logger, &SequentialConfig{Name: "outer", Members: []Member{inner, echo("c")}})
+		require.NoError(t, err)
+
+		shared := NewShared()
+		resp, err := outer.Execute(WithShared(ctx, shared), "task")
+		require.NoError(t, err)
+		assert.Equal(t, 6, resp.Usage.TotalTokens, "usage of the nested team is rolled up")
+		assert.Len(t, stepsOf(t, resp), 2)
+		assert.Len(t, shared.Steps(), 4, "a, b, inner and c")
+	})
+
+	_, err = NewSequential(logger, &SequentialConfig{Name: "empty"})
+	assert.ErrorContains(t, err, "invalid sequential team: at least one member is required")
+}
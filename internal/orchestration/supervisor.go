@@ -0,0 +1,276 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// DefaultMaxRounds bounds the delegation rounds of a supervisor
+const DefaultMaxRounds = 5
+
+// FinishReasonMaxRounds is the finish reason of a supervisor that answered
+// once it could no longer delegate
+const FinishReasonMaxRounds = "max_rounds"
+
+// ErrNoRoute is returned when a router picks none of its members
+var ErrNoRoute = errors.New("no member chosen for the task")
+
+// SupervisorConfig configures a supervised team
+type SupervisorConfig struct {
+	Name string
+
+	// Supervisor plans the work, delegates subtasks and writes the answer
+	Supervisor Member
+
+	// Members are the members the supervisor may delegate to
+	Members []Delegate
+
+	// MaxRounds bounds the rounds of delegation before the supervisor must
+	// answer (defaults to 5)
+	MaxRounds int
+
+	// MaxConcurrent bounds the subtasks of a round running at once (0 runs all of them)
+	MaxConcurrent int
+}
+
+// Supervisor is a team whose supervisor delegates subtasks to the other
+// members, round after round, until it answers the task
+type Supervisor struct {
+	logger  *zap.Logger
+	config  *SupervisorConfig
+	members map[string]Member
+}
+
+// NewSupervisor creates a supervised team
+func NewSupervisor(logger *zap.Logger, config *SupervisorConfig) (*Supervisor, error) {
+	members, err := delegatesByName(config.Name, config.Supervisor, config.Members)
+	if err != nil {
+		return nil, fmt.Errorf("invalid supervisor team: %w", err)
+	}
+	if config.MaxRounds < 0 || config.MaxConcurrent < 0 {
+		return nil, fmt.Errorf("invalid supervisor team: limits must not be negative")
+	}
+	if config.MaxRounds == 0 {
+		config.MaxRounds = DefaultMaxRounds
+	}
+	return &Supervisor{logger: logger, config: config, members: members}, nil
+}
+
+// Name returns the name of the team
+func (s *Supervisor) Name() string {
+	return s.config.Name
+}
+
+// decision is the supervisor's reply: subtasks to delegate or the answer
+type decision struct {
+	Delegate []struct {
+		Member string `json:"member"`
+		Task   string `json:"task"`
+	} `json:"delegate"`
+	Answer string `json:"answer"`
+}
+
+// Execute runs the task. Each round the supervisor either delegates subtasks,
+// which run in parallel and whose results it sees in the next round, or
+// answers. A reply that is not a decision is taken as the answer. Failed
+// subtasks are reported to the supervisor rather than failing the team.
+func (s *Supervisor) Execute(ctx context.Context, task string, opts ...types.ExecuteOption) (*types.Response, error) {
+	ctx = withShared(ctx)
+
+	var steps, results []Step
+	for round := 0; ; round++ {
+		final := round == s.config.MaxRounds
+		resp, step, err := execute(ctx, s.logger, s.config.Name, s.config.Supervisor, s.prompt(task, results, final), opts)
+		steps = append(steps, step)
+		if err != nil {
+			return nil, fmt.Errorf("team %s: %w", s.config.Name, err)
+		}
+
+		d, ok := parseDecision(resp.Content)
+		switch {
+		case final:
+			answer := resp.Content
+			if ok && d.Answer != "" {
+				answer = d.Answer
+			}
+			return newResponse(s.config.Name, answer, FinishReasonMaxRounds, steps), nil
+		case !ok:
+			return newResponse(s.config.Name, resp.Content, resp.FinishReason, steps), nil
+		case len(d.Delegate) == 0:
+			return newResponse(s.config.Name, d.Answer, resp.FinishReason, steps), nil
+		}
+
+		var assignments []assignment
+		for _, subtask := range d.Delegate {
+			member, ok := s.members[subtask.Member]
+			if !ok {
+				results = append(results, Step{Member: subtask.Member, Task: subtask.Task, Error: fmt.Sprintf("unknown member %q", subtask.Member)})
+				continue
+			}
+			assignments = append(assignments, assignment{member: member, task: subtask.Task})
+		}
+		delegated, err := fanOut(ctx, s.logger, s.config.Name, assignments, s.config.MaxConcurrent, false, opts)
+		if err != nil {
+			return nil, fmt.Errorf("team %s: %w", s.config.Name, err)
+		}
+		steps = append(steps, delegated...)
+		results = append(results, delegated...)
+	}
+}
+
+// prompt is the supervisor's task for a round
+func (s *Supervisor) prompt(task string, results []Step, final bool) string {
+	var sb strings.Builder
+	sb.WriteString("You coordinate a team working on a task. The team members are:\n")
+	sb.WriteString(describeDelegates(s.config.Members))
+	sb.WriteString("\nTask:\n" + task + "\n")
+	if len(results) > 0 {
+		sb.WriteString("\nResults of the subtasks so far:\n")
+		for _, result := range results {
+			answer := result.Content
+			if result.Error != "" {
+				answer = "error: " + result.Error
+			}
+			fmt.Fprintf(&sb, "\n### %s: %s\n%s\n", result.Member, result.Task, answer)
+		}
+	}
+	if final {
+		sb.WriteString("\nNo more subtasks can be delegated. Reply with the final answer to the task.")
+		return sb.String()
+	}
+	sb.WriteString("\nReply with JSON only, either\n" +
+		`{"delegate": [{"member": "<name>", "task": "<subtask>"}]}` +
+		" to delegate subtasks, which run in parallel, or\n" +
+		`{"answer": "<final answer>"}` + " once the task is done.")
+	return sb.String()
+}
+
+// parseDecision reads a supervisor's reply, allowing text or a code fence
+// around the JSON object
+func parseDecision(reply string) (decision, bool) {
+	var d decision
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return d, false
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &d); err != nil {
+		return d, false
+	}
+	return d, len(d.Delegate) > 0 || d.Answer != ""
+}
+
+// RouterConfig configures a routed team
+type RouterConfig struct {
+	Name string
+
+	// Router picks the member that handles a task
+	Router Member
+
+	// Members are the members the router picks from
+	Members []Delegate
+
+	// Fallback is the member that handles tasks the router picks no member
+	// for; without one such tasks fail with ErrNoRoute
+	Fallback string
+}
+
+// Router is a team that hands each task to the one member its router picks
+type Router struct {
+	logger  *zap.Logger
+	config  *RouterConfig
+	members map[string]Member
+}
+
+// NewRouter creates a routed team
+func NewRouter(logger *zap.Logger, config *RouterConfig) (*Router, error) {
+	members, err := delegatesByName(config.Name, config.Router, config.Members)
+	if err != nil {
+		return nil, fmt.Errorf("invalid router team: %w", err)
+	}
+	if _, ok := members[config.Fallback]; config.Fallback != "" && !ok {
+		return nil, fmt.Errorf("invalid router team: unknown fallback member %q", config.Fallback)
+	}
+	return &Router{logger: logger, config: config, members: members}, nil
+}
+
+// Name returns the name of the team
+func (r *Router) Name() string {
+	return r.config.Name
+}
+
+// Execute asks the router for a member and answers with that member's
+// response. The chosen member is reported in the "route" metadata.
+func (r *Router) Execute(ctx context.Context, task string, opts ...types.ExecuteOption) (*types.Response, error) {
+	ctx = withShared(ctx)
+
+	prompt := "Choose the team member best suited to the task. The team members are:\n" +
+		describeDelegates(r.config.Members) +
+		"\nTask:\n" + task + "\n\nReply with the name of the member only."
+	resp, step, err := execute(ctx, r.logger, r.config.Name, r.config.Router, prompt, opts)
+	steps := []Step{step}
+	if err != nil {
+		return nil, fmt.Errorf("team %s: %w", r.config.Name, err)
+	}
+
+	name := r.route(resp.Content)
+	if name == "" {
+		return nil, fmt.Errorf("team %s: %w", r.config.Name, ErrNoRoute)
+	}
+	resp, step, err = execute(ctx, r.logger, r.config.Name, r.members[name], task, opts)
+	steps = append(steps, step)
+	if err != nil {
+		return nil, fmt.Errorf("team %s: %w", r.config.Name, err)
+	}
+
+	response := newResponse(r.config.Name, resp.Content, resp.FinishReason, steps)
+	response.Metadata["route"] = name
+	return response, nil
+}
+
+// route returns the member named in the router's reply: the exact name, or
+// else the name mentioned first, or else the fallback
+func (r *Router) route(reply string) string {
+	reply = strings.Trim(strings.TrimSpace(reply), "`\"'.")
+	for name := range r.members {
+		if strings.EqualFold(reply, name) {
+			return name
+		}
+	}
+
+	best, at := "", -1
+	lower := strings.ToLower(reply)
+	for name := range r.members {
+		i := strings.Index(lower, strings.ToLower(name))
+		if i >= 0 && (at < 0 || i < at || i == at && len(name) > len(best)) {
+			best, at = name, i
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return r.config.Fallback
+}
+
+// delegatesByName validates the coordinator and delegates of a team and
+// returns the delegates by name
+func delegatesByName(team string, coordinator Member, delegates []Delegate) (map[string]Member, error) {
+	if coordinator == nil {
+		return nil, fmt.Errorf("coordinating member is required")
+	}
+	members := delegateMembers(delegates)
+	if err := validateMembers(team, members); err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Member, len(members))
+	for _, member := range members {
+		byName[member.Name()] = member
+	}
+	return byName, nil
+}
@@ -0,0 +1,129 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSupervisor(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+	reviewer, auditor := echo("code-reviewer"), echo("security-auditor")
+	members := []Delegate{
+		{Member: reviewer, Description: "reviews code"},
+		{Member: auditor, Description: "audits security"},
+	}
+
+	planner := replies("planner",
+		"```json\n"+`{"delegate": [{"member": "code-reviewer", "task": "review main.go"}, {"member": "security-auditor", "task": "audit main.go"}]}`+"\n```",
+		`{"answer": "main.go looks fine"}`)
+	team, err := NewSupervisor(logger, &SupervisorConfig{Name: "planning", Supervisor: planner, Members: members})
+	require.NoError(t, err)
+
+	resp, err := team.Execute(ctx, "check main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "main.go looks fine", resp.Content)
+	assert.Equal(t, []string{"review main.go"}, reviewer.received())
+	assert.Equal(t, []string{"audit main.go"}, auditor.received())
+	assert.Equal(t, 8, resp.Usage.TotalTokens, "two supervisor turns and two subtasks")
+	assert.Len(t, stepsOf(t, resp), 4)
+
+	prompts := planner.received()
+	require.Len(t, prompts, 2)
+	assert.Contains(t, prompts[0], "- code-reviewer: reviews code\n- security-auditor: audits security\n")
+	assert.Contains(t, prompts[0], "Task:\ncheck main.go\n")
+	assert.Contains(t, prompts[1], "### code-reviewer: review main.go\ncode-reviewer: review main.go\n")
+
+	t.Run("plain reply is the answer", func(t *testing.T) {
+		team, err := NewSupervisor(logger, &SupervisorConfig{Name: "planning", Supervisor: replies("planner", "Just do it."), Members: members})
+		require.NoError(t, err)
+		resp, err := team.Execute(ctx, "task")
+		require.NoError(t, err)
+		assert.Equal(t, "Just do it.", resp.Content)
+	})
+
+	t.Run("failed subtasks are reported", func(t *testing.T) {
+		planner := replies("planner",
+			`{"delegate": [{"member": "broken", "task": "a"}, {"member": "ghost", "task": "b"}]}`,
+			`{"answer": "done anyway"}`)
+		team, err := NewSupervisor(logger, &SupervisorConfig{
+			Name:       "planning",
+			Supervisor: planner,
+			Members:    []Delegate{{Member: failing("broken", errors.New("down"))}},
+		})
+		require.NoError(t, err)
+		resp, err := team.Execute(ctx, "task")
+		require.NoError(t, err)
+		assert.Equal(t, "done anyway", resp.Content)
+		assert.Contains(t, planner.received()[1], "### ghost: b\nerror: unknown member \"ghost\"")
+		assert.Contains(t, planner.received()[1], "### broken: a\nerror: member broken failed: down")
+	})
+
+	t.Run("round limit", func(t *testing.T) {
+		planner := replies("planner", `{"delegate": [{"member": "code-reviewer", "task": "again"}]}`)
+		team, err := NewSupervisor(logger, &SupervisorConfig{Name: "planning", Supervisor: planner, Members: members, MaxRounds: 2})
+		require.NoError(t, err)
+		resp, err := team.Execute(ctx, "task")
+		require.NoError(t, err)
+		assert.Equal(t, FinishReasonMaxRounds, resp.FinishReason)
+		prompts := planner.received()
+		require.Len(t, prompts, 3)
+		assert.Contains(t, prompts[2], "No more subtasks can be delegated.")
+	})
+
+	t.Run("supervisor failure", func(t *testing.T) {
+		team, err := NewSupervisor(logger, &SupervisorConfig{Name: "planning", Supervisor: failing("planner", errors.New("down")), Members: members})
+		require.NoError(t, err)
+		_, err = team.Execute(ctx, "task")
+		assert.EqualError(t, err, "team planning: member planner failed: down")
+	})
+
+	_, err = NewSupervisor(logger, &SupervisorConfig{Name: "planning", Members: members})
+	assert.ErrorContains(t, err, "coordinating member is required")
+}
+
+func TestRouter(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+	reviewer, auditor := echo("code-reviewer"), echo("security-auditor")
+	members := []Delegate{{Member: reviewer}, {Member: auditor}}
+
+	team, err := NewRouter(logger, &RouterConfig{Name: "desk", Router: replies("router", "security-auditor."), Members: members})
+	require.NoError(t, err)
+	resp, err := team.Execute(ctx, "audit main.go")
+	require.NoError(t, err)
+	assert.Equal(t, "security-auditor: audit main.go", resp.Content)
+	assert.Equal(t, "security-auditor", resp.Metadata["route"])
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+	assert.Empty(t, reviewer.received())
+
+	t.Run("name in a sentence", func(t *testing.T) {
+		team, err := NewRouter(logger, &RouterConfig{Name: "desk", Router: replies("router", "I would pick Code-Reviewer for this."), Members: members})
+		require.NoError(t, err)
+		resp, err := team.Execute(ctx, "task")
+		require.NoError(t, err)
+		assert.Equal(t, "code-reviewer", resp.Metadata["route"])
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		router := replies("router", "nobody")
+		team, err := NewRouter(logger, &RouterConfig{Name: "desk", Router: router, Members: members})
+		require.NoError(t, err)
+		_, err = team.Execute(ctx, "task")
+		assert.ErrorIs(t, err, ErrNoRoute)
+
+		team, err = NewRouter(logger, &RouterConfig{Name: "desk", Router: router, Members: members, Fallback: "code-reviewer"})
+		require.NoError(t, err)
+		resp, err := team.Execute(ctx, "task")
+		require.NoError(t, err)
+		assert.Equal(t, "code-reviewer", resp.Metadata["route"])
+	})
+
+	_, err = NewRouter(logger, &RouterConfig{Name: "desk", Router: replies("router", ""), Members: members, Fallback: "ghost"})
+	assert.ErrorContains(t, err, `unknown fallback member "ghost"`)
+}
@@ -0,0 +1,217 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// Config represents the configuration for the policy Engine. Every policy
+// that applies to a request is enforced, so the strictest limit wins.
+type Config struct {
+	// Default applies to all requests
+	Default Policy `yaml:"default"`
+
+	// Providers apply to requests sent to the named provider
+	Providers map[string]Policy `yaml:"providers"`
+
+	// Tenants apply to requests made for the named tenant
+	Tenants map[string]Policy `yaml:"tenants"`
+}
+
+// Engine evaluates policies against requests and responses
+type Engine struct {
+	logger    *zap.Logger
+	defaults  *scopedPolicy
+	providers map[string]*scopedPolicy
+	tenants   map[string]*scopedPolicy
+}
+
+// scopedPolicy is a compiled policy and the scope it was configured for
+type scopedPolicy struct {
+	scope   string
+	policy  Policy
+	allowed map[string]bool
+	input   compiledRules
+	output  compiledRules
+}
+
+func compile(scope string, p Policy) (*scopedPolicy, error) {
+	sp := &scopedPolicy{scope: scope, policy: p}
+	if len(p.AllowedModels) > 0 {
+		sp.allowed = make(map[string]bool, len(p.AllowedModels))
+		for _, model := range p.AllowedModels {
+			sp.allowed[model] = true
+		}
+	}
+
+	var err error
+	if sp.input, err = p.Input.compile(); err != nil {
+		return nil, fmt.Errorf("%s input rules: %w", scope, err)
+	}
+	if sp.output, err = p.Output.compile(); err != nil {
+		return nil, fmt.Errorf("%s output rules: %w", scope, err)
+	}
+	return sp, nil
+}
+
+// NewEngine creates a new policy engine
+func NewEngine(logger *zap.Logger, config *Config) (*Engine, error) {
+	defaults, err := compile("default", config.Default)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		logger:    logger,
+		defaults:  defaults,
+		providers: make(map[string]*scopedPolicy, len(config.Providers)),
+		tenants:   make(map[string]*scopedPolicy, len(config.Tenants)),
+	}
+	for name, p := range config.Providers {
+		if e.providers[name], err = compile("provider:"+name, p); err != nil {
+			return nil, err
+		}
+	}
+	for name, p := range config.Tenants {
+		if e.tenants[name], err = compile("tenant:"+name, p); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// policies returns the policies applying to a provider and tenant
+func (e *Engine) policies(provider, tenant string) []*scopedPolicy {
+	policies := []*scopedPolicy{e.defaults}
+	if p, ok := e.providers[provider]; ok {
+		policies = append(policies, p)
+	}
+	if p, ok := e.tenants[tenant]; ok {
+		policies = append(policies, p)
+	}
+	return policies
+}
+
+// CheckRequest validates a chat request. It returns the request to send,
+// which is a copy when max_tokens defaults or required system prompts were applied.
+func (e *Engine) CheckRequest(provider, tenant string, req *types.ChatRequest) (*types.ChatRequest, error) {
+	out := req
+	copied := false
+	ensureCopy := func() {
+		if !copied {
+			c := *req
+			c.Messages = append([]types.Message(nil), req.Messages...)
+			out, copied = &c, true
+		}
+	}
+
+	maxTokens := 0
+	for _, p := range e.policies(provider, tenant) {
+		if err := p.checkModel(req.Model); err != nil {
+			return nil, e.reject(err, provider, tenant)
+		}
+
+		if limit := p.policy.MaxTokens; limit > 0 {
+			if req.MaxTokens > limit {
+				return nil, e.reject(p.violation(StageInput, RuleMaxTokens,
+					fmt.Sprintf("max_tokens %d exceeds limit %d", req.MaxTokens, limit)), provider, tenant)
+			}
+			if maxTokens == 0 || limit < maxTokens {
+				maxTokens = limit
+			}
+		}
+
+		if required := p.policy.RequiredSystemPrompt; required != "" && !hasSystemPrompt(out.Messages, required) {
+			ensureCopy()
+			out.Messages = append([]types.Message{{Role: "system", Content: required}}, out.Messages...)
+		}
+	}
+	if req.MaxTokens == 0 && maxTokens > 0 {
+		ensureCopy()
+		out.MaxTokens = maxTokens
+	}
+
+	// Content and length rules apply to what the caller sent, including its
+	// system messages; the required system prompts added above are trusted
+	length := 0
+	for _, msg := range req.Messages {
+		length += len([]rune(msg.Text()))
+	}
+	for _, p := range e.policies(provider, tenant) {
+		if limit := p.policy.MaxPromptLength; limit > 0 && length > limit {
+			return nil, e.reject(p.violation(StageInput, RuleMaxPromptLength,
+				fmt.Sprintf("prompt length %d exceeds limit %d", length, limit)), provider, tenant)
+		}
+		for _, msg := range req.Messages {
+			if rule, message := p.input.match(msg.Text()); rule != "" {
+				return nil, e.reject(p.violation(StageInput, rule, message), provider, tenant)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// CheckModel validates the model of a non-chat request such as embeddings
+func (e *Engine) CheckModel(provider, tenant, model string) error {
+	for _, p := range e.policies(provider, tenant) {
+		if err := p.checkModel(model); err != nil {
+			return e.reject(err, provider, tenant)
+		}
+	}
+	return nil
+}
+
+// CheckOutput validates response content
+func (e *Engine) CheckOutput(provider, tenant, text string) error {
+	for _, p := range e.policies(provider, tenant) {
+		if rule, message := p.output.match(text); rule != "" {
+			return e.reject(p.violation(StageOutput, rule, message), provider, tenant)
+		}
+	}
+	return nil
+}
+
+// HasOutputRules reports whether responses for a provider and tenant need checking
+func (e *Engine) HasOutputRules(provider, tenant string) bool {
+	for _, p := range e.policies(provider, tenant) {
+		if len(p.output.denyList) > 0 || len(p.output.denyPatterns) > 0 || p.output.promptInjection {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *scopedPolicy) checkModel(model string) *Violation {
+	if p.allowed == nil || p.allowed[model] {
+		return nil
+	}
+	return p.violation(StageInput, RuleAllowedModels, fmt.Sprintf("model %q is not allowed", model))
+}
+
+func (p *scopedPolicy) violation(stage, rule, message string) *Violation {
+	return &Violation{Rule: rule, Scope: p.scope, Stage: stage, Message: message}
+}
+
+func (e *Engine) reject(v *Violation, provider, tenant string) error {
+	e.logger.Warn("Policy violation",
+		zap.String("provider", provider),
+		zap.String("tenant", tenant),
+		zap.String("scope", v.Scope),
+		zap.String("stage", v.Stage),
+		zap.String("rule", v.Rule))
+	return v
+}
+
+func hasSystemPrompt(messages []types.Message, required string) bool {
+	for _, msg := range messages {
+		if msg.Role == "system" && strings.Contains(msg.Text(), required) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+// Package policy enforces request limits and content rules on chat traffic.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Stages at which a policy is evaluated
+const (
+	StageInput  = "input"
+	StageOutput = "output"
+)
+
+// Rules reported in violations
+const (
+	RuleMaxPromptLength = "max_prompt_length"
+	RuleMaxTokens       = "max_tokens"
+	RuleAllowedModels   = "allowed_models"
+	RuleDenyList        = "deny_list"
+	RuleDenyPattern     = "deny_pattern"
+	RulePromptInjection = "prompt_injection"
+)
+
+// ErrPolicyViolation is matched by all policy violations
+var ErrPolicyViolation = errors.New("policy violation")
+
+// Violation describes a request or response rejected by a policy
+type Violation struct {
+	// Rule is the rule that was violated, e.g. max_tokens
+	Rule string `json:"rule"`
+
+	// Scope is the policy the rule belongs to: default, provider:<name> or tenant:<name>
+	Scope string `json:"scope"`
+
+	// Stage is input or output
+	Stage string `json:"stage"`
+
+	// Message explains the violation
+	Message string `json:"message"`
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s: %s (%s %s rule %s)", ErrPolicyViolation, v.Message, v.Scope, v.Stage, v.Rule)
+}
+
+// Is reports whether target is ErrPolicyViolation
+func (v *Violation) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// Policy is a set of limits and content rules. Its limit fields match the
+// security.request_validation section of provider configs.
+type Policy struct {
+	// MaxPromptLength is the maximum number of characters across all messages
+	MaxPromptLength int `yaml:"max_prompt_length"`
+
+	// MaxTokens is the maximum completion tokens a request may ask for;
+	// requests that do not set max_tokens are given this value
+	MaxTokens int `yaml:"max_tokens"`
+
+	// AllowedModels restricts the models that may be requested
+	AllowedModels []string `yaml:"allowed_models"`
+
+	// RequiredSystemPrompt is prepended as a system message unless a system
+	// message already contains it
+	RequiredSystemPrompt string `yaml:"required_system_prompt"`
+
+	// Input rules apply to request messages
+	Input ContentRules `yaml:"input"`
+
+	// Output rules apply to response content
+	Output ContentRules `yaml:"output"`
+}
+
+// ContentRules reject text containing forbidden content
+type ContentRules struct {
+	// DenyList are phrases that may not appear, matched case-insensitively
+	DenyList []string `yaml:"deny_list"`
+
+	// DenyPatterns are regular expressions that may not match
+	DenyPatterns []string `yaml:"deny_patterns"`
+
+	// DetectPromptInjection enables heuristics for common injection phrasing
+	DetectPromptInjection bool `yaml:"detect_prompt_injection"`
+}
+
+// compiledRules are ContentRules ready for matching
+type compiledRules struct {
+	denyList        []string
+	denyPatterns    []*regexp.Regexp
+	promptInjection bool
+}
+
+func (r ContentRules) compile() (compiledRules, error) {
+	compiled := compiledRules{promptInjection: r.DetectPromptInjection}
+	for _, phrase := range r.DenyList {
+		if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" {
+			compiled.denyList = append(compiled.denyList, phrase)
+		}
+	}
+	for _, pattern := range r.DenyPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return compiledRules{}, fmt.Errorf("invalid deny pattern %q: %w", pattern, err)
+		}
+		compiled.denyPatterns = append(compiled.denyPatterns, re)
+	}
+	return compiled, nil
+}
+
+// injectionPatterns are phrasings commonly used to override instructions
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,30}\b(all|any|the|your|previous|prior|above|earlier)\b.{0,20}\b(instructions|prompts?|rules|directions)\b`),
+	regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output)\b.{0,30}\b(system|hidden|initial)\s+(prompt|instructions|message)\b`),
+	regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(DAN|in\s+developer\s+mode|jailbroken|unrestricted)\b`),
+	regexp.MustCompile(`(?i)\b(act|respond|behave)\s+as\b.{0,40}\bwithout\s+(any\s+)?(restrictions|filters|limitations|rules)\b`),
+	regexp.MustCompile(`(?i)</?\s*(system|im_start|im_end)\s*>|\[/?INST\]|<<SYS>>`),
+}
+
+// match returns the violated rule and a description, or empty strings
+func (r compiledRules) match(text string) (string, string) {
+	if rule, message := r.matchPhrases(strings.ToLower(text)); rule != "" {
+		return rule, message
+	}
+	return r.matchPatterns(text)
+}
+
+// matchPhrases checks the deny list against lowercased text
+func (r compiledRules) matchPhrases(lower string) (string, string) {
+	for _, phrase := range r.denyList {
+		if strings.Contains(lower, phrase) {
+			return RuleDenyList, fmt.Sprintf("content contains denied phrase %q", phrase)
+		}
+	}
+	return "", ""
+}
+
+// matchPatterns checks the deny patterns and prompt injection heuristics
+func (r compiledRules) matchPatterns(text string) (string, string) {
+	for _, re := range r.denyPatterns {
+		if re.MatchString(text) {
+			return RuleDenyPattern, fmt.Sprintf("content matches denied pattern %q", re.String())
+		}
+	}
+	if r.promptInjection {
+		for _, re := range injectionPatterns {
+			if re.MatchString(text) {
+				return RulePromptInjection, "content looks like a prompt injection attempt"
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,77 @@
+package policy
+
+import "strings"
+
+// streamPatternWindow is how much of the content preceding a chunk deny
+// patterns are matched against while streaming. Patterns can match text of
+// any length, so the complete content is checked again when the stream ends.
+const streamPatternWindow = 4 << 10
+
+// OutputStream checks response content as it is streamed. Each chunk is
+// checked together with the end of the content before it, so checking a
+// stream costs time linear in its length.
+type OutputStream struct {
+	engine   *Engine
+	provider string
+	tenant   string
+	policies []*scopedPolicy
+	text     strings.Builder
+
+	// tail is the lowercased end of the content, long enough to hold all
+	// but the last byte of the longest denied phrase
+	tail    string
+	tailLen int
+}
+
+// NewOutputStream returns a checker for one streamed response choice
+func (e *Engine) NewOutputStream(provider, tenant string) *OutputStream {
+	s := &OutputStream{
+		engine:   e,
+		provider: provider,
+		tenant:   tenant,
+		policies: e.policies(provider, tenant),
+	}
+	for _, p := range s.policies {
+		for _, phrase := range p.output.denyList {
+			if len(phrase)-1 > s.tailLen {
+				s.tailLen = len(phrase) - 1
+			}
+		}
+	}
+	return s
+}
+
+// Write checks a chunk of streamed content
+func (s *OutputStream) Write(chunk string) error {
+	if chunk == "" {
+		return nil
+	}
+	start := s.text.Len() - streamPatternWindow
+	if start < 0 {
+		start = 0
+	}
+	s.text.WriteString(chunk)
+
+	// Denied phrases are matched exactly: any occurrence ending in this
+	// chunk starts within the tail
+	lower := s.tail + strings.ToLower(chunk)
+	window := s.text.String()[start:]
+	for _, p := range s.policies {
+		if rule, message := p.output.matchPhrases(lower); rule != "" {
+			return s.engine.reject(p.violation(StageOutput, rule, message), s.provider, s.tenant)
+		}
+		if rule, message := p.output.matchPatterns(window); rule != "" {
+			return s.engine.reject(p.violation(StageOutput, rule, message), s.provider, s.tenant)
+		}
+	}
+	if len(lower) > s.tailLen {
+		lower = lower[len(lower)-s.tailLen:]
+	}
+	s.tail = lower
+	return nil
+}
+
+// Close checks the complete content once the stream has finished
+func (s *OutputStream) Close() error {
+	return s.engine.CheckOutput(s.provider, s.tenant, s.text.String())
+}
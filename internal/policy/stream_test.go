@@ -0,0 +1,54 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestOutputStream(t *testing.T) {
+	engine, err := NewEngine(zaptest.NewLogger(t), &Config{
+		Default: Policy{Output: ContentRules{
+			DenyList:     []string{"Top Secret"},
+			DenyPatterns: []string{`(?s)BEGIN.*END`},
+		}},
+	})
+	require.NoError(t, err)
+
+	t.Run("phrase split across chunks", func(t *testing.T) {
+		stream := engine.NewOutputStream("openai", "")
+		for _, chunk := range []string{"this is t", "op", " SEC", "ret stuff"} {
+			if err = stream.Write(chunk); err != nil {
+				break
+			}
+		}
+		requireViolation(t, err, "default", StageOutput, RuleDenyList)
+	})
+
+	t.Run("pattern within the window", func(t *testing.T) {
+		stream := engine.NewOutputStream("openai", "")
+		require.NoError(t, stream.Write("BEGIN and "))
+		requireViolation(t, stream.Write("then END"), "default", StageOutput, RuleDenyPattern)
+	})
+
+	t.Run("pattern longer than the window", func(t *testing.T) {
+		stream := engine.NewOutputStream("openai", "")
+		require.NoError(t, stream.Write("BEGIN"))
+		for i := 0; i < 10; i++ {
+			require.NoError(t, stream.Write(strings.Repeat("x", streamPatternWindow/4)))
+		}
+		require.NoError(t, stream.Write("END"))
+		requireViolation(t, stream.Close(), "default", StageOutput, RuleDenyPattern)
+	})
+
+	t.Run("clean", func(t *testing.T) {
+		stream := engine.NewOutputStream("openai", "")
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, stream.Write("nothing to see here. "))
+		}
+		assert.NoError(t, stream.Close())
+	})
+}
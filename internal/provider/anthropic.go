@@ -0,0 +1,697 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/pimentel/peppergo/internal/tracing"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com/v1"
+	defaultAnthropicMaxTokens = 1024
+	anthropicAPIVersion       = "2023-06-01"
+)
+
+// AnthropicConfig holds the configuration for the Anthropic provider
+type AnthropicConfig struct {
+	// Name is the name the provider is registered under (defaults to "anthropic")
+	Name string
+
+	// Models overrides the list of advertised models
+	Models []string
+
+	APIKey      string
+	BaseURL     string
+	Model       string
+	MaxTokens   int
+	RateLimiter *rate.Limiter
+
+	// Timeout bounds each HTTP request, or the wait for the response headers
+	// of a stream (defaults to 60s)
+	Timeout time.Duration
+}
+
+// AnthropicProvider implements the types.Provider interface for the Anthropic Messages API
+type AnthropicProvider struct {
+	name   string
+	models []string
+	config *AnthropicConfig
+	client *http.Client
+	// stream sends streaming requests, whose body may outlast the timeout
+	stream *http.Client
+	logger *zap.Logger
+}
+
+// NewAnthropicProvider creates a new Anthropic provider instance
+func NewAnthropicProvider(logger *zap.Logger, config *AnthropicConfig) *AnthropicProvider {
+	// Defaults are applied to a copy so the caller's configuration is left as is
+	copied := *config
+	config = &copied
+	if config.BaseURL == "" {
+		config.BaseURL = defaultAnthropicBaseURL
+	}
+	name := config.Name
+	if name == "" {
+		name = "anthropic"
+	}
+
+	models := config.Models
+	if len(models) == 0 {
+		models = []string{
+			"claude-3-5-sonnet-latest",
+			"claude-3-5-haiku-latest",
+			"claude-3-opus-latest",
+			"claude-2",
+			"claude-instant-1",
+		}
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+
+	return &AnthropicProvider{
+		name:   name,
+		models: models,
+		config: config,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: tracing.NewTransport(nil),
+		},
+		stream: newStreamClient(timeout),
+		logger: logger,
+	}
+}
+
+// Name returns the provider's name
+func (p *AnthropicProvider) Name() string {
+	return p.name
+}
+
+// AvailableModels returns the list of available models
+func (p *AnthropicProvider) AvailableModels() []string {
+	return p.models
+}
+
+// Initialize validates the provider configuration
+func (p *AnthropicProvider) Initialize(ctx context.Context) error {
+	if p.config.APIKey == "" {
+		return fmt.Errorf("API key is required")
+	}
+	if p.config.MaxTokens < 0 {
+		return fmt.Errorf("invalid max tokens: must not be negative")
+	}
+	return nil
+}
+
+// Chat sends a chat completion request to Anthropic
+func (p *AnthropicProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	anthropicReq, err := p.toAnthropicRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	anthropicReq.Stream = false
+
+	resp, err := p.send(ctx, anthropicReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var msg anthropicResponse
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var text strings.Builder
+	var toolCalls []types.ToolCall
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, types.ToolCall{
+				ID:       block.ID,
+				Type:     types.ToolTypeFunction,
+				Function: types.FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+
+	return &types.ChatResponse{
+		ID:      msg.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   msg.Model,
+		Choices: []types.Choice{
+			{
+				Index: 0,
+				Message: types.Message{
+					Role:      "assistant",
+					Content:   text.String(),
+					ToolCalls: toolCalls,
+				},
+				FinishReason: anthropicFinishReason(msg.StopReason),
+			},
+		},
+		Usage: types.Usage{
+			PromptTokens:     msg.Usage.InputTokens,
+			CompletionTokens: msg.Usage.OutputTokens,
+			TotalTokens:      msg.Usage.InputTokens + msg.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+// StreamChat streams chat completion responses from Anthropic
+func (p *AnthropicProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	anthropicReq, err := p.toAnthropicRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	anthropicReq.Stream = true
+
+	resp, err := p.send(ctx, anthropicReq)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make(chan *types.ChatResponse)
+
+	go func() {
+		defer close(responses)
+		defer resp.Body.Close()
+
+		var (
+			id    string
+			model string
+			usage types.Usage
+		)
+
+		emit := func(delta types.Message, finishReason string) bool {
+			delta.Role = "assistant"
+			chunk := &types.ChatResponse{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Created: time.Now().Unix(),
+				Model:   model,
+				Choices: []types.Choice{
+					{
+						Index:        0,
+						Message:      delta,
+						FinishReason: finishReason,
+					},
+				},
+			}
+			if finishReason != "" {
+				chunk.Usage = usage
+			}
+			select {
+			case <-ctx.Done():
+				return false
+			case responses <- chunk:
+				return true
+			}
+		}
+
+		fail := func(err error) {
+			select {
+			case <-ctx.Done():
+			case responses <- &types.ChatResponse{ID: id, Object: "chat.completion.chunk", Model: model, Error: err}:
+			}
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			var event anthropicStreamEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				p.logger.Error("failed to decode stream event", zap.Error(err))
+				continue
+			}
+
+			switch event.Type {
+			case "message_start":
+				if event.Message != nil {
+					id = event.Message.ID
+					model = event.Message.Model
+					usage.PromptTokens = event.Message.Usage.InputTokens
+				}
+			case "content_block_start":
+				// A tool_use block opens a tool call; its arguments follow
+				// as input_json_delta fragments for the same block index
+				if block := event.ContentBlock; block != nil && block.Type == "tool_use" {
+					call := types.ToolCall{
+						Index:    intPtr(event.Index),
+						ID:       block.ID,
+						Type:     types.ToolTypeFunction,
+						Function: types.FunctionCall{Name: block.Name},
+					}
+					if !emit(types.Message{ToolCalls: []types.ToolCall{call}}, "") {
+						return
+					}
+				}
+			case "content_block_delta":
+				switch event.Delta.Type {
+				case "text_delta":
+					if !emit(types.Message{Content: event.Delta.Text}, "") {
+						return
+					}
+				case "input_json_delta":
+					if event.Delta.PartialJSON == "" {
+						continue
+					}
+					call := types.ToolCall{
+						Index:    intPtr(event.Index),
+						Function: types.FunctionCall{Arguments: event.Delta.PartialJSON},
+					}
+					if !emit(types.Message{ToolCalls: []types.ToolCall{call}}, "") {
+						return
+					}
+				}
+			case "message_delta":
+				if event.Usage != nil {
+					usage.CompletionTokens = event.Usage.OutputTokens
+					usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+				}
+				if event.Delta.StopReason != "" && !emit(types.Message{}, anthropicFinishReason(event.Delta.StopReason)) {
+					return
+				}
+			case "error":
+				err := errors.New("unknown error")
+				if event.Error != nil {
+					err = fmt.Errorf("%s: %s", event.Error.Type, event.Error.Message)
+				}
+				p.logger.Error("error in stream chat",
+					zap.Error(err),
+					zap.String("model", req.Model))
+				fail(fmt.Errorf("stream failed: %w", err))
+				return
+			case "message_stop":
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			p.logger.Error("error reading stream",
+				zap.Error(err),
+				zap.String("model", req.Model))
+			fail(fmt.Errorf("failed to read stream: %w", err))
+		}
+	}()
+
+	return responses, nil
+}
+
+// send posts a Messages API request and returns the raw HTTP response
+func (p *AnthropicProvider) send(ctx context.Context, anthropicReq *anthropicRequest) (*http.Response, error) {
+	if p.config.APIKey == "" {
+		return nil, fmt.Errorf("API key is required")
+	}
+
+	if p.config.RateLimiter != nil {
+		if err := p.config.RateLimiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limit exceeded: %w", err)
+		}
+	}
+
+	jsonBody, err := json.Marshal(anthropicReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/messages", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.config.APIKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	client := p.client
+	if anthropicReq.Stream {
+		client = p.stream
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &types.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return resp, nil
+}
+
+// toAnthropicRequest converts a standardized chat request into the Messages API format
+func (p *AnthropicProvider) toAnthropicRequest(req *types.ChatRequest) (*anthropicRequest, error) {
+	anthropicReq := &anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     req.MaxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+	}
+	if anthropicReq.Model == "" {
+		anthropicReq.Model = p.config.Model
+	}
+	if anthropicReq.MaxTokens == 0 {
+		anthropicReq.MaxTokens = p.config.MaxTokens
+	}
+	if anthropicReq.MaxTokens == 0 {
+		anthropicReq.MaxTokens = defaultAnthropicMaxTokens
+	}
+
+	for _, tool := range req.Tools {
+		anthropicReq.Tools = append(anthropicReq.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: tool.Function.Parameters,
+		})
+	}
+	if req.ToolChoice != nil {
+		choice, err := toAnthropicToolChoice(*req.ToolChoice)
+		if err != nil {
+			return nil, err
+		}
+		anthropicReq.ToolChoice = choice
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		switch {
+		case msg.Role == "system":
+			system = append(system, msg.Text())
+			continue
+		case msg.Role == "tool":
+			// Tool results are sent back in a user turn, which must hold every
+			// result for the preceding assistant turn
+			result := anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Text()}
+			if n := len(anthropicReq.Messages); n > 0 && isToolResultTurn(anthropicReq.Messages[n-1]) {
+				anthropicReq.Messages[n-1].Content = append(anthropicReq.Messages[n-1].Content, result)
+				continue
+			}
+			anthropicReq.Messages = append(anthropicReq.Messages, anthropicMessage{
+				Role:    "user",
+				Content: []anthropicBlock{result},
+			})
+			continue
+		}
+
+		blocks, err := toAnthropicBlocks(msg)
+		if err != nil {
+			return nil, err
+		}
+		anthropicReq.Messages = append(anthropicReq.Messages, anthropicMessage{
+			Role:    msg.Role,
+			Content: blocks,
+		})
+	}
+	anthropicReq.System = strings.Join(system, "\n\n")
+
+	return anthropicReq, nil
+}
+
+// toAnthropicToolChoice converts a tool choice into the Messages API format
+func toAnthropicToolChoice(choice types.ToolChoice) (*anthropicToolChoice, error) {
+	if choice.Function != "" {
+		return &anthropicToolChoice{Type: "tool", Name: choice.Function}, nil
+	}
+	switch choice.Mode {
+	case types.ToolChoiceAuto:
+		return &anthropicToolChoice{Type: "auto"}, nil
+	case types.ToolChoiceRequired:
+		return &anthropicToolChoice{Type: "any"}, nil
+	case types.ToolChoiceNone:
+		return &anthropicToolChoice{Type: "none"}, nil
+	default:
+		return nil, fmt.Errorf("invalid tool choice: %s", choice.Mode)
+	}
+}
+
+// isToolResultTurn reports whether a message is a user turn of tool results
+func isToolResultTurn(msg anthropicMessage) bool {
+	return msg.Role == "user" && len(msg.Content) > 0 && msg.Content[len(msg.Content)-1].Type == "tool_result"
+}
+
+// toAnthropicToolUse converts the tool calls of an assistant message into tool_use blocks
+func toAnthropicToolUse(calls []types.ToolCall) ([]anthropicBlock, error) {
+	blocks := make([]anthropicBlock, 0, len(calls))
+	for _, call := range calls {
+		input := json.RawMessage(call.Function.Arguments)
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		if !json.Valid(input) {
+			return nil, fmt.Errorf("invalid arguments for tool call %s: not JSON", call.ID)
+		}
+		blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+	}
+	return blocks, nil
+}
+
+// toAnthropicBlocks converts message content into Anthropic content blocks,
+// followed by tool_use blocks for the message's tool calls
+func toAnthropicBlocks(msg types.Message) ([]anthropicBlock, error) {
+	blocks, err := toAnthropicContent(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.ToolCalls) == 0 {
+		return blocks, nil
+	}
+
+	toolUse, err := toAnthropicToolUse(msg.ToolCalls)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Parts) == 0 && msg.Content == "" {
+		// A message of only tool calls has no text block
+		return toolUse, nil
+	}
+	return append(blocks, toolUse...), nil
+}
+
+// toAnthropicContent converts the content or parts of a message into Anthropic content blocks
+func toAnthropicContent(msg types.Message) ([]anthropicBlock, error) {
+	if len(msg.Parts) == 0 {
+		return []anthropicBlock{{Type: "text", Text: msg.Content}}, nil
+	}
+
+	blocks := make([]anthropicBlock, 0, len(msg.Parts))
+	for _, part := range msg.Parts {
+		switch part.Type {
+		case types.ContentTypeText:
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+		case types.ContentTypeImageURL:
+			if part.ImageURL == nil {
+				return nil, fmt.Errorf("invalid image part: missing image_url")
+			}
+			if !strings.HasPrefix(part.ImageURL.URL, "data:") {
+				blocks = append(blocks, anthropicBlock{
+					Type:   "image",
+					Source: &anthropicSource{Type: "url", URL: part.ImageURL.URL},
+				})
+				continue
+			}
+			mediaType, data, err := types.ParseDataURI(part.ImageURL.URL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid image part: %w", err)
+			}
+			blocks = append(blocks, anthropicBlock{
+				Type:   "image",
+				Source: &anthropicSource{Type: "base64", MediaType: mediaType, Data: data},
+			})
+		case types.ContentTypeFile:
+			block, err := toAnthropicDocument(part.File)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, block)
+		default:
+			return nil, fmt.Errorf("invalid content part type: %s", part.Type)
+		}
+	}
+	return blocks, nil
+}
+
+// toAnthropicDocument converts a file reference into an Anthropic document or image block
+func toAnthropicDocument(file *types.FileRef) (anthropicBlock, error) {
+	if file == nil {
+		return anthropicBlock{}, fmt.Errorf("invalid file part: missing file")
+	}
+	if file.FileID != "" {
+		return anthropicBlock{
+			Type:   "document",
+			Source: &anthropicSource{Type: "file", FileID: file.FileID},
+		}, nil
+	}
+
+	mediaType, data, err := types.ParseDataURI(file.FileData)
+	if err != nil {
+		return anthropicBlock{}, fmt.Errorf("invalid file part: %w", err)
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return anthropicBlock{
+			Type:   "image",
+			Source: &anthropicSource{Type: "base64", MediaType: mediaType, Data: data},
+		}, nil
+	case mediaType == "application/pdf":
+		return anthropicBlock{
+			Type:   "document",
+			Source: &anthropicSource{Type: "base64", MediaType: mediaType, Data: data},
+		}, nil
+	case strings.HasPrefix(mediaType, "text/"):
+		text, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return anthropicBlock{}, fmt.Errorf("invalid file part: %w", err)
+		}
+		return anthropicBlock{
+			Type:   "document",
+			Source: &anthropicSource{Type: "text", MediaType: "text/plain", Data: string(text)},
+		}, nil
+	default:
+		return anthropicBlock{}, fmt.Errorf("invalid file part: unsupported media type %s", mediaType)
+	}
+}
+
+// intPtr returns a pointer to a copy of i
+func intPtr(i int) *int {
+	return &i
+}
+
+// anthropicFinishReason maps Anthropic stop reasons onto OpenAI-style finish reasons
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return stopReason
+	}
+}
+
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	System        string               `json:"system,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   float32              `json:"temperature,omitempty"`
+	TopP          float32              `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	InputSchema *types.ToolSchema `json:"input_schema"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type   string           `json:"type"`
+	Text   string           `json:"text,omitempty"`
+	Source *anthropicSource `json:"source,omitempty"`
+
+	// Tool use and tool result blocks
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+type anthropicStreamEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *anthropicResponse `json:"message,omitempty"`
+	ContentBlock *anthropicBlock    `json:"content_block,omitempty"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// HealthCheck lists the available models as a lightweight liveness probe
+func (p *AnthropicProvider) HealthCheck(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("x-api-key", p.config.APIKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	return checkHealth(p.client, httpReq)
+}
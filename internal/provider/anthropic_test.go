@@ -0,0 +1,282 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func TestAnthropicProvider(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	t.Run("chat translates multimodal content", func(t *testing.T) {
+		var received anthropicRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/messages", r.URL.Path)
+			assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"msg_1","model":"claude-test","content":[{"type":"text","text":"a cat"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":3}}`)
+		}))
+		defer server.Close()
+
+		provider := NewAnthropicProvider(logger, &AnthropicConfig{
+			APIKey:  "test-key",
+			BaseURL: server.URL,
+			Model:   "claude-test",
+		})
+
+		resp, err := provider.Chat(ctx, &types.ChatRequest{
+			Messages: []types.Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Parts: []types.ContentPart{
+					types.TextPart("What is this?"),
+					types.ImagePart("data:image/png;base64,iVBORw0KGgo="),
+					types.FilePart("notes.txt", "data:text/plain,hello%20world"),
+				}},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "claude-test", received.Model)
+		assert.Equal(t, "Be brief.", received.System)
+		assert.Equal(t, defaultAnthropicMaxTokens, received.MaxTokens)
+		require.Len(t, received.Messages, 1)
+
+		blocks := received.Messages[0].Content
+		require.Len(t, blocks, 3)
+		assert.Equal(t, "text", blocks[0].Type)
+		assert.Equal(t, "image", blocks[1].Type)
+		assert.Equal(t, "base64", blocks[1].Source.Type)
+		assert.Equal(t, "image/png", blocks[1].Source.MediaType)
+		assert.Equal(t, "iVBORw0KGgo=", blocks[1].Source.Data)
+		assert.Equal(t, "document", blocks[2].Type)
+		assert.Equal(t, "hello world", blocks[2].Source.Data)
+
+		assert.Equal(t, "a cat", resp.Choices[0].Message.Content)
+		assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+		assert.Equal(t, 15, resp.Usage.TotalTokens)
+	})
+
+	t.Run("stream chat", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			events := []string{
+				`{"type":"message_start","message":{"id":"msg_2","model":"claude-test","usage":{"input_tokens":5}}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" there"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":2}}`,
+				`{"type":"message_stop"}`,
+			}
+			for _, event := range events {
+				fmt.Fprintf(w, "event: x\ndata: %s\n\n", event)
+			}
+		}))
+		defer server.Close()
+
+		provider := NewAnthropicProvider(logger, &AnthropicConfig{
+			APIKey:  "test-key",
+			BaseURL: server.URL,
+		})
+
+		respChan, err := provider.StreamChat(ctx, &types.ChatRequest{
+			Model:    "claude-test",
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		require.NoError(t, err)
+
+		var content strings.Builder
+		var last *types.ChatResponse
+		for resp := range respChan {
+			content.WriteString(resp.Choices[0].Message.Content)
+			last = resp
+		}
+
+		assert.Equal(t, "Hello there", content.String())
+		require.NotNil(t, last)
+		assert.Equal(t, "length", last.Choices[0].FinishReason)
+		assert.Equal(t, 7, last.Usage.TotalTokens)
+	})
+
+	t.Run("tool use", func(t *testing.T) {
+		var received anthropicRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"msg_3","model":"claude-test","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_2","name":"lookup","input":{"query":"go"}}],"stop_reason":"tool_use","usage":{"input_tokens":30,"output_tokens":10}}`)
+		}))
+		defer server.Close()
+
+		provider := NewAnthropicProvider(logger, &AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+		schema := types.NewToolSchema().AddProperty("query", types.NewPropertySchema("string"))
+		resp, err := provider.Chat(ctx, &types.ChatRequest{
+			Model: "claude-test",
+			Tools: []types.ToolDefinition{{
+				Type:     types.ToolTypeFunction,
+				Function: types.FunctionDefinition{Name: "lookup", Description: "Looks things up", Parameters: schema},
+			}},
+			ToolChoice: &types.ToolChoice{Mode: types.ToolChoiceRequired},
+			Messages: []types.Message{
+				{Role: "user", Content: "Look up go and rust"},
+				{Role: "assistant", ToolCalls: []types.ToolCall{
+					{ID: "toolu_0", Type: "function", Function: types.FunctionCall{Name: "lookup", Arguments: `{"query":"go"}`}},
+					{ID: "toolu_1", Type: "function", Function: types.FunctionCall{Name: "lookup", Arguments: `{"query":"rust"}`}},
+				}},
+				{Role: "tool", ToolCallID: "toolu_0", Content: "a language"},
+				{Role: "tool", ToolCallID: "toolu_1", Content: "another language"},
+			},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, received.Tools, 1)
+		assert.Equal(t, "lookup", received.Tools[0].Name)
+		assert.Equal(t, "string", received.Tools[0].InputSchema.Properties["query"].Type)
+		assert.Equal(t, &anthropicToolChoice{Type: "any"}, received.ToolChoice)
+
+		require.Len(t, received.Messages, 3)
+		toolUse := received.Messages[1].Content
+		require.Len(t, toolUse, 2, "no empty text block")
+		assert.Equal(t, "tool_use", toolUse[0].Type)
+		assert.JSONEq(t, `{"query":"go"}`, string(toolUse[0].Input))
+		results := received.Messages[2]
+		assert.Equal(t, "user", results.Role)
+		require.Len(t, results.Content, 2, "results share one user turn")
+		assert.Equal(t, "toolu_1", results.Content[1].ToolUseID)
+		assert.Equal(t, "another language", results.Content[1].Content)
+
+		msg := resp.Choices[0].Message
+		assert.Equal(t, "Checking.", msg.Content)
+		assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+		require.Len(t, msg.ToolCalls, 1)
+		assert.Equal(t, types.ToolCall{
+			ID:       "toolu_2",
+			Type:     types.ToolTypeFunction,
+			Function: types.FunctionCall{Name: "lookup", Arguments: `{"query":"go"}`},
+		}, msg.ToolCalls[0])
+	})
+
+	t.Run("stream tool use", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_5\",\"model\":\"claude-test\"}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Checking.\"}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"content_block_stop\",\"index\":0}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_3\",\"name\":\"lookup\",\"input\":{}}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\"}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"query\\\":\"}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\\\"go\\\"}\"}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"content_block_stop\",\"index\":1}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":12}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"message_stop\"}\n\n")
+		}))
+		defer server.Close()
+
+		provider := NewAnthropicProvider(logger, &AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+		respChan, err := provider.StreamChat(ctx, &types.ChatRequest{
+			Model:    "claude-test",
+			Messages: []types.Message{{Role: "user", Content: "Look up go"}},
+		})
+		require.NoError(t, err)
+
+		var (
+			text         string
+			calls        []types.ToolCall
+			finishReason string
+		)
+		for resp := range respChan {
+			require.NoError(t, resp.Error)
+			require.Len(t, resp.Choices, 1)
+			text += resp.Choices[0].Message.Content
+			calls = append(calls, resp.Choices[0].Message.ToolCalls...)
+			if resp.Choices[0].FinishReason != "" {
+				finishReason = resp.Choices[0].FinishReason
+			}
+		}
+
+		assert.Equal(t, "Checking.", text)
+		assert.Equal(t, "tool_calls", finishReason)
+		require.Len(t, calls, 3)
+		assert.Equal(t, "toolu_3", calls[0].ID)
+		assert.Equal(t, types.ToolTypeFunction, calls[0].Type)
+		assert.Equal(t, "lookup", calls[0].Function.Name)
+		var arguments string
+		for _, call := range calls {
+			require.NotNil(t, call.Index)
+			assert.Equal(t, 1, *call.Index)
+			arguments += call.Function.Arguments
+		}
+		assert.JSONEq(t, `{"query":"go"}`, arguments)
+	})
+
+	t.Run("stream error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_4\",\"model\":\"claude-test\"}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n")
+			fmt.Fprint(w, "data: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+		}))
+		defer server.Close()
+
+		provider := NewAnthropicProvider(logger, &AnthropicConfig{APIKey: "test-key", BaseURL: server.URL})
+		respChan, err := provider.StreamChat(ctx, &types.ChatRequest{
+			Model:    "claude-test",
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		require.NoError(t, err)
+
+		var chunks []*types.ChatResponse
+		for resp := range respChan {
+			chunks = append(chunks, resp)
+		}
+		require.Len(t, chunks, 2)
+		assert.Equal(t, "Hel", chunks[0].Choices[0].Message.Content)
+		assert.EqualError(t, chunks[1].Error, "stream failed: overloaded_error: Overloaded")
+		assert.Empty(t, chunks[1].Choices)
+	})
+
+	t.Run("config is not modified", func(t *testing.T) {
+		config := &AnthropicConfig{APIKey: "test-key"}
+		NewAnthropicProvider(logger, config)
+		assert.Empty(t, config.BaseURL)
+	})
+
+	t.Run("parts with tool calls", func(t *testing.T) {
+		blocks, err := toAnthropicBlocks(types.Message{
+			Role: "assistant",
+			Parts: []types.ContentPart{
+				types.TextPart("Here is the chart."),
+				types.ImagePart("https://example.com/chart.png"),
+			},
+			ToolCalls: []types.ToolCall{{ID: "toolu_1", Type: "function", Function: types.FunctionCall{Name: "lookup", Arguments: `{}`}}},
+		})
+		require.NoError(t, err)
+		require.Len(t, blocks, 3)
+		assert.Equal(t, "text", blocks[0].Type)
+		assert.Equal(t, "image", blocks[1].Type)
+		assert.Equal(t, "tool_use", blocks[2].Type)
+	})
+
+	t.Run("invalid content part", func(t *testing.T) {
+		provider := NewAnthropicProvider(logger, &AnthropicConfig{APIKey: "test-key"})
+		_, err := provider.Chat(ctx, &types.ChatRequest{
+			Messages: []types.Message{{Role: "user", Parts: []types.ContentPart{
+				types.FilePart("blob.bin", "data:application/octet-stream;base64,AAAA"),
+			}}},
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported media type")
+	})
+}
@@ -0,0 +1,18 @@
+package provider
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pimentel/peppergo/internal/tracing"
+)
+
+// newStreamClient returns a client for streamed responses. Only the wait for
+// the response headers is bounded by timeout, since a client timeout would
+// also cut off a long stream; the body is read for as long as the request
+// context allows.
+func newStreamClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	return &http.Client{Transport: tracing.NewTransport(transport)}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Built-in provider types
+const (
+	TypeOpenAI     = "openai"
+	TypeAnthropic  = "anthropic"
+	TypeOpenRouter = "openrouter"
+	TypeOllama     = "ollama"
+)
+
+// Config describes a provider instance of any type, as received in
+// configuration files and admin API payloads
+type Config struct {
+	// Type selects the provider implementation
+	Type string `json:"type" yaml:"type"`
+
+	// Name is the name the provider is registered under (defaults to Type)
+	Name string `json:"name,omitempty" yaml:"name"`
+
+	APIKey         string   `json:"api_key,omitempty" yaml:"api_key"`
+	BaseURL        string   `json:"base_url,omitempty" yaml:"base_url"`
+	Model          string   `json:"model,omitempty" yaml:"model"`
+	EmbeddingModel string   `json:"embedding_model,omitempty" yaml:"embedding_model"`
+	Models         []string `json:"models,omitempty" yaml:"models"`
+	MaxTokens      int      `json:"max_tokens,omitempty" yaml:"max_tokens"`
+
+	// RateLimit is the maximum number of requests per second (0 means unlimited)
+	RateLimit float64 `json:"rate_limit,omitempty" yaml:"rate_limit"`
+
+	// Burst is the rate limiter burst size (defaults to 1)
+	Burst int `json:"burst,omitempty" yaml:"burst"`
+
+	// Timeout bounds each HTTP request to the provider, or the wait for the
+	// response headers of a stream (defaults per type)
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout"`
+}
+
+// Validate checks the configuration for missing or unknown values
+func (c *Config) Validate() error {
+	if c.Type == "" {
+		return fmt.Errorf("provider type is required")
+	}
+	if _, ok := lookupFactory(c.Type); !ok {
+		return fmt.Errorf("provider %s: unknown type %q", c.ProviderName(), c.Type)
+	}
+	if c.RateLimit < 0 {
+		return fmt.Errorf("provider %s: rate_limit must not be negative", c.ProviderName())
+	}
+	return nil
+}
+
+// ProviderName returns the name the provider is registered under
+func (c *Config) ProviderName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Type
+}
+
+// requireAPIKey fails for provider types that cannot work without an API key
+func (c *Config) requireAPIKey() error {
+	if c.APIKey == "" {
+		return fmt.Errorf("provider %s: api_key is required for type %s", c.ProviderName(), c.Type)
+	}
+	return nil
+}
+
+func (c *Config) rateLimiter() *rate.Limiter {
+	if c.RateLimit == 0 {
+		return nil
+	}
+	burst := c.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(c.RateLimit), burst)
+}
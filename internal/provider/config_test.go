@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func TestNew(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	tests := []struct {
+		config *Config
+		name   string
+		embeds bool
+	}{
+		{config: &Config{Type: TypeOpenAI}, name: "openai", embeds: true},
+		{config: &Config{Type: TypeOpenAI, Name: "vllm", BaseURL: "http://localhost:8000/v1"}, name: "vllm", embeds: true},
+		{config: &Config{Type: TypeAnthropic, APIKey: "key"}, name: "anthropic"},
+		{config: &Config{Type: TypeOpenRouter, Name: "router", APIKey: "key", Models: []string{"m"}}, name: "router"},
+		{config: &Config{Type: TypeOllama, RateLimit: 2}, name: "ollama", embeds: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(logger, tt.config)
+			require.NoError(t, err)
+			assert.Equal(t, tt.name, p.Name())
+			_, embeds := p.(types.EmbeddingProvider)
+			assert.Equal(t, tt.embeds, embeds)
+			_, checks := p.(types.HealthChecker)
+			assert.True(t, checks)
+		})
+	}
+
+	p, err := New(logger, &Config{Type: TypeOpenRouter, APIKey: "key", Models: []string{"a", "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, p.AvailableModels())
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, config := range map[string]*Config{
+		"missing type":   {},
+		"unknown type":   {Type: "bedrock"},
+		"negative limit": {Type: TypeOpenAI, RateLimit: -1},
+	} {
+		assert.Error(t, config.Validate(), name)
+	}
+
+	_, err := New(zaptest.NewLogger(t), &Config{Type: TypeAnthropic})
+	assert.ErrorContains(t, err, "api_key is required")
+}
+
+func TestRegister(t *testing.T) {
+	assert.Subset(t, Types(), []string{TypeAnthropic, TypeOllama, TypeOpenAI, TypeOpenRouter})
+	assert.Panics(t, func() {
+		Register(TypeOpenAI, nil)
+	})
+
+	if _, ok := lookupFactory("test-echo"); !ok {
+		Register("test-echo", func(logger *zap.Logger, config *Config) (types.Provider, error) {
+			return NewOpenAIProvider(logger, &OpenAIConfig{Name: config.ProviderName(), Models: config.Models}), nil
+		})
+	}
+	p, err := New(zaptest.NewLogger(t), &Config{Type: "test-echo", Models: []string{"m"}})
+	require.NoError(t, err)
+	assert.Equal(t, "test-echo", p.Name())
+	assert.Equal(t, []string{"m"}, p.AvailableModels())
+}
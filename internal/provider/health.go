@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// checkHealth sends a probe request and fails on any non-2xx status
+func checkHealth(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &types.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/tracing"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+// OllamaConfig holds the configuration for the Ollama provider
+type OllamaConfig struct {
+	// Name is the name the provider is registered under (defaults to "ollama")
+	Name string
+
+	BaseURL        string
+	Model          string
+	EmbeddingModel string
+	Models         []string
+
+	// Timeout bounds each HTTP request, or the wait for the response headers
+	// of a stream (defaults to 120s)
+	Timeout time.Duration
+}
+
+// OllamaProvider implements types.Provider and types.EmbeddingProvider for a
+// local Ollama server. Chat goes through Ollama's OpenAI-compatible endpoint,
+// embeddings through the native /api/embed endpoint.
+type OllamaProvider struct {
+	*OpenAIProvider
+	config *OllamaConfig
+	client *http.Client
+}
+
+// NewOllamaProvider creates a new Ollama provider instance
+func NewOllamaProvider(logger *zap.Logger, config *OllamaConfig) *OllamaProvider {
+	// Defaults are applied to a copy so the caller's configuration is left as is
+	copied := *config
+	config = &copied
+	if config.BaseURL == "" {
+		config.BaseURL = defaultOllamaBaseURL
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	models := config.Models
+	if len(models) == 0 {
+		models = []string{"llama3.1", "nomic-embed-text"}
+	}
+
+	name := config.Name
+	if name == "" {
+		name = "ollama"
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 120 * time.Second
+	}
+
+	return &OllamaProvider{
+		OpenAIProvider: NewOpenAIProvider(logger, &OpenAIConfig{
+			Name:    name,
+			BaseURL: config.BaseURL + "/v1",
+			Model:   config.Model,
+			Models:  models,
+			Timeout: timeout,
+		}),
+		config: config,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: tracing.NewTransport(nil),
+		},
+	}
+}
+
+type ollamaEmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type ollamaEmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+// Embed computes embeddings using Ollama's native embed API
+func (p *OllamaProvider) Embed(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.config.EmbeddingModel
+	}
+
+	jsonBody, err := json.Marshal(ollamaEmbedRequest{
+		Model:      model,
+		Input:      req.Input,
+		Dimensions: req.Dimensions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/api/embed", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &types.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var ollamaResp ollamaEmbedResponse
+	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	embedResp := &types.EmbeddingResponse{
+		Object: "list",
+		Model:  ollamaResp.Model,
+		Data:   make([]types.Embedding, 0, len(ollamaResp.Embeddings)),
+		Usage: types.Usage{
+			PromptTokens: ollamaResp.PromptEvalCount,
+			TotalTokens:  ollamaResp.PromptEvalCount,
+		},
+	}
+	for i, vector := range ollamaResp.Embeddings {
+		embedResp.Data = append(embedResp.Data, types.Embedding{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: vector,
+		})
+	}
+
+	return embedResp, nil
+}
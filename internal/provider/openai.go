@@ -0,0 +1,311 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/pimentel/peppergo/internal/tracing"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIConfig holds the configuration for OpenAI-compatible providers
+type OpenAIConfig struct {
+	// Name is the name the provider is registered under (defaults to "openai")
+	Name string
+
+	APIKey         string
+	BaseURL        string
+	Model          string
+	EmbeddingModel string
+	Models         []string
+	RateLimiter    *rate.Limiter
+
+	// Timeout bounds each HTTP request, or the wait for the response headers
+	// of a stream (defaults to 60s)
+	Timeout time.Duration
+}
+
+// OpenAIProvider implements types.Provider and types.EmbeddingProvider for
+// any backend speaking the OpenAI chat completions and embeddings API
+type OpenAIProvider struct {
+	name   string
+	models []string
+	config *OpenAIConfig
+	client *http.Client
+	// stream sends streaming requests, whose body may outlast the timeout
+	stream *http.Client
+	logger *zap.Logger
+}
+
+// NewOpenAIProvider creates a new OpenAI-compatible provider instance
+func NewOpenAIProvider(logger *zap.Logger, config *OpenAIConfig) *OpenAIProvider {
+	// Defaults are applied to a copy so the caller's configuration is left as is
+	copied := *config
+	config = &copied
+	if config.BaseURL == "" {
+		config.BaseURL = defaultOpenAIBaseURL
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	name := config.Name
+	if name == "" {
+		name = "openai"
+	}
+
+	models := config.Models
+	if len(models) == 0 {
+		models = []string{
+			"gpt-4o",
+			"gpt-4o-mini",
+			"text-embedding-3-small",
+			"text-embedding-3-large",
+		}
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+
+	return &OpenAIProvider{
+		name:   name,
+		models: models,
+		config: config,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: tracing.NewTransport(nil),
+		},
+		stream: newStreamClient(timeout),
+		logger: logger,
+	}
+}
+
+// Name returns the provider's name
+func (p *OpenAIProvider) Name() string {
+	return p.name
+}
+
+// AvailableModels returns the list of available models
+func (p *OpenAIProvider) AvailableModels() []string {
+	return p.models
+}
+
+// Chat sends a chat completion request
+func (p *OpenAIProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	chatReq := *req
+	chatReq.Stream = false
+	if chatReq.Model == "" {
+		chatReq.Model = p.config.Model
+	}
+
+	resp, err := p.post(ctx, p.client, "/chat/completions", &chatReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResp types.ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &chatResp, nil
+}
+
+// StreamChat streams chat completion responses
+func (p *OpenAIProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	chatReq := *req
+	chatReq.Stream = true
+	if chatReq.Model == "" {
+		chatReq.Model = p.config.Model
+	}
+
+	resp, err := p.post(ctx, p.stream, "/chat/completions", &chatReq)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make(chan *types.ChatResponse)
+
+	go func() {
+		defer close(responses)
+		defer resp.Body.Close()
+
+		fail := func(err error) {
+			select {
+			case <-ctx.Done():
+			case responses <- &types.ChatResponse{Object: "chat.completion.chunk", Model: chatReq.Model, Error: err}:
+			}
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			data := strings.TrimPrefix(line, "data: ")
+			if data == "[DONE]" {
+				return
+			}
+
+			var chunk openAIStreamChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				p.logger.Error("failed to decode stream chunk", zap.Error(err))
+				continue
+			}
+			if chunk.Error != nil {
+				err := fmt.Errorf("stream failed: %s", chunk.Error.Message)
+				p.logger.Error("error in stream chat",
+					zap.Error(err),
+					zap.String("model", chatReq.Model))
+				fail(err)
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case responses <- chunk.toChatResponse():
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			p.logger.Error("error reading stream",
+				zap.Error(err),
+				zap.String("model", chatReq.Model))
+			fail(fmt.Errorf("failed to read stream: %w", err))
+		}
+	}()
+
+	return responses, nil
+}
+
+// Embed computes embeddings for the request inputs
+func (p *OpenAIProvider) Embed(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	embedReq := *req
+	if embedReq.Model == "" {
+		embedReq.Model = p.config.EmbeddingModel
+	}
+	// The response is decoded as float vectors whatever the caller asked for
+	embedReq.EncodingFormat = types.EncodingFormatFloat
+
+	resp, err := p.post(ctx, p.client, "/embeddings", &embedReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var embedResp types.EmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &embedResp, nil
+}
+
+// post sends a JSON request to the given API path with client and returns the raw HTTP response
+func (p *OpenAIProvider) post(ctx context.Context, client *http.Client, path string, body interface{}) (*http.Response, error) {
+	if p.config.RateLimiter != nil {
+		if err := p.config.RateLimiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limit exceeded: %w", err)
+		}
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+path, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &types.StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return resp, nil
+}
+
+type openAIStreamChunk struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index        int           `json:"index"`
+		Delta        types.Message `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *types.Usage `json:"usage,omitempty"`
+
+	// Error is sent instead of a chunk when the stream fails
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// toChatResponse converts a streamed delta chunk into the standardized format
+func (c *openAIStreamChunk) toChatResponse() *types.ChatResponse {
+	resp := &types.ChatResponse{
+		ID:      c.ID,
+		Object:  "chat.completion.chunk",
+		Created: c.Created,
+		Model:   c.Model,
+		Choices: make([]types.Choice, 0, len(c.Choices)),
+	}
+	for _, choice := range c.Choices {
+		msg := choice.Delta
+		if msg.Role == "" {
+			msg.Role = "assistant"
+		}
+		resp.Choices = append(resp.Choices, types.Choice{
+			Index:        choice.Index,
+			Message:      msg,
+			FinishReason: choice.FinishReason,
+		})
+	}
+	if c.Usage != nil {
+		resp.Usage = *c.Usage
+	}
+	return resp
+}
+
+// HealthCheck lists the available models as a lightweight liveness probe
+func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if p.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
+	}
+
+	return checkHealth(p.client, httpReq)
+}
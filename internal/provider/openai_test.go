@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func TestOpenAIProvider(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		switch r.URL.Path {
+		case "/chat/completions":
+			var req types.ChatRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "default-model", req.Model)
+
+			if req.Stream {
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprint(w, "data: {\"id\":\"c1\",\"model\":\"default-model\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+				fmt.Fprint(w, "data: {\"id\":\"c1\",\"model\":\"default-model\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n")
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				return
+			}
+
+			fmt.Fprint(w, `{"id":"c1","object":"chat.completion","model":"default-model","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+		case "/embeddings":
+			var req types.EmbeddingRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "embed-model", req.Model)
+
+			resp := types.EmbeddingResponse{Object: "list", Model: req.Model}
+			for i := range req.Input {
+				resp.Data = append(resp.Data, types.Embedding{Object: "embedding", Index: i, Embedding: []float32{float32(i), 1}})
+			}
+			resp.Usage = types.Usage{PromptTokens: len(req.Input), TotalTokens: len(req.Input)}
+			json.NewEncoder(w).Encode(resp)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(logger, &OpenAIConfig{
+		APIKey:         "test-key",
+		BaseURL:        server.URL,
+		Model:          "default-model",
+		EmbeddingModel: "embed-model",
+	})
+
+	t.Run("chat", func(t *testing.T) {
+		resp, err := provider.Chat(ctx, &types.ChatRequest{
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", resp.Choices[0].Message.Content)
+		assert.Equal(t, 4, resp.Usage.TotalTokens)
+	})
+
+	t.Run("stream chat", func(t *testing.T) {
+		respChan, err := provider.StreamChat(ctx, &types.ChatRequest{
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		require.NoError(t, err)
+
+		var content strings.Builder
+		var finishReason string
+		for resp := range respChan {
+			content.WriteString(resp.Choices[0].Message.Content)
+			finishReason = resp.Choices[0].FinishReason
+		}
+		assert.Equal(t, "Hello", content.String())
+		assert.Equal(t, "stop", finishReason)
+	})
+
+	t.Run("stream error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"id\":\"c2\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"error\":{\"message\":\"model overloaded\"}}\n\n")
+		}))
+		defer server.Close()
+
+		provider := NewOpenAIProvider(logger, &OpenAIConfig{APIKey: "test-key", BaseURL: server.URL})
+		respChan, err := provider.StreamChat(ctx, &types.ChatRequest{
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		require.NoError(t, err)
+
+		var last *types.ChatResponse
+		for resp := range respChan {
+			last = resp
+		}
+		require.NotNil(t, last)
+		assert.EqualError(t, last.Error, "stream failed: model overloaded")
+	})
+
+	t.Run("stream outlasts timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"id\":\"c3\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+			time.Sleep(150 * time.Millisecond)
+			fmt.Fprint(w, "data: {\"id\":\"c3\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		}))
+		defer server.Close()
+
+		provider := NewOpenAIProvider(logger, &OpenAIConfig{APIKey: "test-key", BaseURL: server.URL, Timeout: 50 * time.Millisecond})
+		respChan, err := provider.StreamChat(ctx, &types.ChatRequest{
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		require.NoError(t, err)
+
+		var content strings.Builder
+		for resp := range respChan {
+			require.NoError(t, resp.Error)
+			content.WriteString(resp.Choices[0].Message.Content)
+		}
+		assert.Equal(t, "Hello", content.String())
+	})
+
+	t.Run("config is not modified", func(t *testing.T) {
+		config := &OpenAIConfig{APIKey: "test-key", BaseURL: "http://localhost/v1/"}
+		NewOpenAIProvider(logger, config)
+		assert.Equal(t, "http://localhost/v1/", config.BaseURL)
+
+		ollama := &OllamaConfig{}
+		NewOllamaProvider(logger, ollama)
+		assert.Empty(t, ollama.BaseURL)
+	})
+
+	t.Run("embed", func(t *testing.T) {
+		resp, err := provider.Embed(ctx, &types.EmbeddingRequest{
+			Input: types.EmbeddingInput{"a", "b"},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Data, 2)
+		assert.Equal(t, []float32{1, 1}, resp.Data[1].Embedding)
+		assert.Equal(t, 2, resp.Usage.TotalTokens)
+	})
+}
+
+func TestOllamaProviderEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/embed", r.URL.Path)
+
+		var req ollamaEmbedRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "nomic-embed-text", req.Model)
+		assert.Equal(t, []string{"x", "y"}, req.Input)
+
+		fmt.Fprint(w, `{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]],"prompt_eval_count":4}`)
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(zaptest.NewLogger(t), &OllamaConfig{
+		BaseURL:        server.URL,
+		EmbeddingModel: "nomic-embed-text",
+	})
+	assert.Equal(t, "ollama", provider.Name())
+
+	resp, err := provider.Embed(context.Background(), &types.EmbeddingRequest{
+		Input: types.EmbeddingInput{"x", "y"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, 1, resp.Data[1].Index)
+	assert.Equal(t, []float32{0.3, 0.4}, resp.Data[1].Embedding)
+	assert.Equal(t, 4, resp.Usage.PromptTokens)
+}
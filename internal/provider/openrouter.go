@@ -13,16 +13,26 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 
+	"github.com/pimentel/peppergo/internal/tracing"
 	"github.com/pimentel/peppergo/pkg/types"
 )
 
 // OpenRouterConfig holds the configuration for the OpenRouter provider
 type OpenRouterConfig struct {
+	// Name is the name the provider is registered under (defaults to "openrouter")
+	Name string
+
+	// Models overrides the list of advertised models
+	Models []string
+
 	APIKey      string
 	Model       string
 	MaxTokens   int
 	Temperature float64
 	RateLimiter *rate.Limiter
+
+	// Timeout bounds each HTTP request (defaults to 30s)
+	Timeout time.Duration
 }
 
 // OpenRouterProvider implements the types.Provider interface for OpenRouter
@@ -36,17 +46,33 @@ type OpenRouterProvider struct {
 
 // NewOpenRouterProvider creates a new OpenRouter provider instance
 func NewOpenRouterProvider(logger *zap.Logger, config *OpenRouterConfig) *OpenRouterProvider {
-	return &OpenRouterProvider{
-		name: "openrouter",
-		models: []string{
+	name := config.Name
+	if name == "" {
+		name = "openrouter"
+	}
+
+	models := config.Models
+	if len(models) == 0 {
+		models = []string{
 			"openai/gpt-4",
 			"openai/gpt-3.5-turbo",
 			"anthropic/claude-2",
 			"google/gemini-pro",
-		},
+		}
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &OpenRouterProvider{
+		name:   name,
+		models: models,
 		config: config,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   timeout,
+			Transport: tracing.NewTransport(nil),
 		},
 		logger: logger,
 	}
@@ -103,7 +129,7 @@ func (p *OpenRouterProvider) Chat(ctx context.Context, req *types.ChatRequest) (
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, &types.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var chatResp types.ChatResponse
@@ -114,33 +140,25 @@ func (p *OpenRouterProvider) Chat(ctx context.Context, req *types.ChatRequest) (
 	return &chatResp, nil
 }
 
-// StreamChat streams chat completion responses from OpenRouter
+// StreamChat sends a chat completion request to OpenRouter and returns the
+// complete response as a single chunk. Errors are returned before the
+// stream opens.
 func (p *OpenRouterProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
-	responses := make(chan *types.ChatResponse)
-
-	go func() {
-		defer close(responses)
-
-		// Set streaming flag
-		req.Stream = true
+	// Chat decodes a complete response, so the request must not ask for a stream
+	chatReq := *req
+	chatReq.Stream = false
 
-		// Make the request
-		resp, err := p.Chat(ctx, req)
-		if err != nil {
-			p.logger.Error("error in stream chat",
-				zap.Error(err),
-				zap.String("model", req.Model))
-			return
-		}
-
-		// Send the response
-		select {
-		case <-ctx.Done():
-			return
-		case responses <- resp:
-		}
-	}()
+	resp, err := p.Chat(ctx, &chatReq)
+	if err != nil {
+		p.logger.Error("error in stream chat",
+			zap.Error(err),
+			zap.String("model", req.Model))
+		return nil, err
+	}
 
+	responses := make(chan *types.ChatResponse, 1)
+	responses <- resp
+	close(responses)
 	return responses, nil
 }
 
@@ -161,15 +179,10 @@ func (p *OpenRouterProvider) Initialize(ctx context.Context) error {
 }
 
 type generateRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
-}
-
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Model       string          `json:"model"`
+	Messages    []types.Message `json:"messages"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Temperature float64         `json:"temperature,omitempty"`
 }
 
 type generateResponse struct {
@@ -226,10 +239,8 @@ func (p *OpenRouterProvider) Generate(ctx context.Context, prompt string, opts .
 	}
 
 	reqBody := generateRequest{
-		Model: options.Model,
-		Messages: []Message{
-			{Role: "user", Content: prompt},
-		},
+		Model:       options.Model,
+		Messages:    types.PromptMessages(prompt, ""),
 		MaxTokens:   options.MaxTokens,
 		Temperature: options.Temperature,
 	}
@@ -287,7 +298,7 @@ func (p *OpenRouterProvider) makeRequest(ctx context.Context, reqBody generateRe
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, &types.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var genResp generateResponse
@@ -314,4 +325,15 @@ func (p *OpenRouterProvider) makeRequest(ctx context.Context, reqBody generateRe
 func isValidationError(err error) bool {
 	return strings.Contains(err.Error(), "invalid") ||
 		strings.Contains(err.Error(), "empty prompt")
-} 
\ No newline at end of file
+} 
+
+// HealthCheck lists the available models as a lightweight liveness probe
+func (p *OpenRouterProvider) HealthCheck(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", "https://openrouter.ai/api/v1/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.config.APIKey))
+
+	return checkHealth(p.client, httpReq)
+}
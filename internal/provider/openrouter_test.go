@@ -215,4 +215,18 @@ func TestOpenRouterProviderConfig(t *testing.T) {
 			})
 		}
 	})
-} 
\ No newline at end of file
+
+	t.Run("stream errors are returned", func(t *testing.T) {
+		provider := NewOpenRouterProvider(logger, &OpenRouterConfig{Model: "test-model"})
+		req := &types.ChatRequest{
+			Model:    "test-model",
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+			Stream:   true,
+		}
+
+		respChan, err := provider.StreamChat(context.Background(), req)
+		assert.EqualError(t, err, "API key is required")
+		assert.Nil(t, respChan)
+		assert.True(t, req.Stream, "caller's request must not be modified")
+	})
+}
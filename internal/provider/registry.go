@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// Factory creates a provider from a generic configuration
+type Factory func(logger *zap.Logger, config *Config) (types.Provider, error)
+
+var (
+	factories   = make(map[string]Factory)
+	factoriesMu sync.RWMutex
+)
+
+// Register makes a provider type available to New. It panics if the type is
+// registered twice, so it is meant to be called from init functions.
+func Register(providerType string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if _, exists := factories[providerType]; exists {
+		panic(fmt.Sprintf("provider type %s already registered", providerType))
+	}
+	factories[providerType] = factory
+}
+
+// Types returns the registered provider types in alphabetical order
+func Types() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	providerTypes := make([]string, 0, len(factories))
+	for providerType := range factories {
+		providerTypes = append(providerTypes, providerType)
+	}
+	sort.Strings(providerTypes)
+	return providerTypes
+}
+
+func lookupFactory(providerType string) (Factory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	factory, ok := factories[providerType]
+	return factory, ok
+}
+
+// New creates a provider using the factory registered for its type
+func New(logger *zap.Logger, config *Config) (types.Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	factory, _ := lookupFactory(config.Type)
+	return factory(logger.With(zap.String("provider", config.ProviderName())), config)
+}
+
+func init() {
+	Register(TypeOpenAI, func(logger *zap.Logger, config *Config) (types.Provider, error) {
+		return NewOpenAIProvider(logger, &OpenAIConfig{
+			Name:           config.ProviderName(),
+			APIKey:         config.APIKey,
+			BaseURL:        config.BaseURL,
+			Model:          config.Model,
+			EmbeddingModel: config.EmbeddingModel,
+			Models:         config.Models,
+			RateLimiter:    config.rateLimiter(),
+			Timeout:        config.Timeout,
+		}), nil
+	})
+
+	Register(TypeAnthropic, func(logger *zap.Logger, config *Config) (types.Provider, error) {
+		if err := config.requireAPIKey(); err != nil {
+			return nil, err
+		}
+		return NewAnthropicProvider(logger, &AnthropicConfig{
+			Name:        config.ProviderName(),
+			Models:      config.Models,
+			APIKey:      config.APIKey,
+			BaseURL:     config.BaseURL,
+			Model:       config.Model,
+			MaxTokens:   config.MaxTokens,
+			RateLimiter: config.rateLimiter(),
+			Timeout:     config.Timeout,
+		}), nil
+	})
+
+	Register(TypeOpenRouter, func(logger *zap.Logger, config *Config) (types.Provider, error) {
+		if err := config.requireAPIKey(); err != nil {
+			return nil, err
+		}
+		return NewOpenRouterProvider(logger, &OpenRouterConfig{
+			Name:        config.ProviderName(),
+			Models:      config.Models,
+			APIKey:      config.APIKey,
+			Model:       config.Model,
+			MaxTokens:   config.MaxTokens,
+			RateLimiter: config.rateLimiter(),
+			Timeout:     config.Timeout,
+		}), nil
+	})
+
+	Register(TypeOllama, func(logger *zap.Logger, config *Config) (types.Provider, error) {
+		return NewOllamaProvider(logger, &OllamaConfig{
+			Name:           config.ProviderName(),
+			BaseURL:        config.BaseURL,
+			Model:          config.Model,
+			EmbeddingModel: config.EmbeddingModel,
+			Models:         config.Models,
+			Timeout:        config.Timeout,
+		}), nil
+	})
+}
@@ -0,0 +1,776 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// Batch statuses
+const (
+	BatchStatusInProgress = "in_progress"
+	BatchStatusCompleted  = "completed"
+	BatchStatusFailed     = "failed"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+const (
+	defaultBatchConcurrency = 4
+	defaultBatchMaxInput    = 100 << 20
+	defaultBatchRetryDelay  = time.Second
+	batchMaxRetryDelay      = time.Minute
+	batchPersistInterval    = 25
+
+	batchMetaFile   = "batch.json"
+	batchInputFile  = "input.jsonl"
+	batchOutputFile = "output.jsonl"
+	batchErrorsFile = "errors.jsonl"
+)
+
+var (
+	// ErrBatchNotFound is returned when a batch ID is unknown
+	ErrBatchNotFound = errors.New("batch not found")
+
+	// ErrInvalidBatchInput is returned when a batch input file is malformed
+	ErrInvalidBatchInput = errors.New("invalid batch input")
+)
+
+// BatchConfig holds the configuration for the batch subsystem
+type BatchConfig struct {
+	// Dir is the directory where batch inputs, results and progress are persisted
+	Dir string
+
+	// Concurrency bounds the number of requests in flight across all batches
+	Concurrency int
+
+	// MaxInputBytes bounds the size of a batch input file (defaults to 100 MiB)
+	MaxInputBytes int64
+
+	// RateLimits holds optional per-provider rate limiters for batch traffic
+	RateLimits map[string]*rate.Limiter
+
+	// RetryDelay is the initial delay before retrying a request that failed
+	// because the provider was unavailable. It doubles on every attempt up
+	// to a minute (defaults to 1s).
+	RetryDelay time.Duration
+}
+
+// BatchRequestCounts tracks the progress of a batch
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// Batch describes an asynchronous batch job
+type Batch struct {
+	ID            string             `json:"id"`
+	Object        string             `json:"object"`
+	Provider      string             `json:"provider"`
+	Status        string             `json:"status"`
+	Error         string             `json:"error,omitempty"`
+	CreatedAt     int64              `json:"created_at"`
+	InProgressAt  int64              `json:"in_progress_at,omitempty"`
+	CompletedAt   int64              `json:"completed_at,omitempty"`
+	FailedAt      int64              `json:"failed_at,omitempty"`
+	CancelledAt   int64              `json:"cancelled_at,omitempty"`
+	RequestCounts BatchRequestCounts `json:"request_counts"`
+}
+
+// BatchRequest is a single line of a batch input file
+type BatchRequest struct {
+	CustomID string            `json:"custom_id"`
+	Method   string            `json:"method,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Body     types.ChatRequest `json:"body"`
+}
+
+// BatchResult is a single line of a batch output or errors file
+type BatchResult struct {
+	ID       string               `json:"id"`
+	CustomID string               `json:"custom_id"`
+	Response *BatchResultResponse `json:"response"`
+	Error    *BatchResultError    `json:"error"`
+}
+
+// BatchResultResponse holds the response for a successful batch request
+type BatchResultResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Body       *types.ChatResponse `json:"body"`
+}
+
+// BatchResultError holds the error for a failed batch request
+type BatchResultError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BatchManager processes batch jobs in the background through a Service
+type BatchManager struct {
+	service *Service
+	config  BatchConfig
+	logger  *zap.Logger
+	slots   chan struct{}
+	jobs    map[string]*batchJob
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+}
+
+// batchJob is the in-memory state of a batch
+type batchJob struct {
+	batch           Batch
+	tenant          string
+	owner           string
+	dir             string
+	cancel          context.CancelFunc
+	cancelRequested bool
+	sinceSave       int
+	mu              sync.Mutex
+}
+
+// batchMeta is the persisted state of a batch, with the caller it was created for
+type batchMeta struct {
+	Batch
+	Tenant string `json:"tenant,omitempty"`
+	Owner  string `json:"owner,omitempty"`
+}
+
+// NewBatchManager creates a batch manager, loading persisted batches from
+// config.Dir and resuming any that were in progress
+func NewBatchManager(service *Service, config BatchConfig, logger *zap.Logger) (*BatchManager, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("batch directory is required")
+	}
+	if config.Concurrency < 1 {
+		config.Concurrency = defaultBatchConcurrency
+	}
+	if config.MaxInputBytes <= 0 {
+		config.MaxInputBytes = defaultBatchMaxInput
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = defaultBatchRetryDelay
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create batch directory: %w", err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	m := &BatchManager{
+		service: service,
+		config:  config,
+		logger:  logger,
+		slots:   make(chan struct{}, config.Concurrency),
+		jobs:    make(map[string]*batchJob),
+		ctx:     ctx,
+		stop:    stop,
+	}
+
+	if err := m.load(); err != nil {
+		stop()
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// CreateBatch validates a JSONL input of chat requests, persists it and
+// starts processing it in the background. The batch belongs to owner, who
+// alone can see it, and its requests are made for the tenant of ctx.
+func (m *BatchManager) CreateBatch(ctx context.Context, owner, provider string, input io.Reader) (*Batch, error) {
+	if _, err := m.service.GetProvider(provider); err != nil {
+		return nil, fmt.Errorf("failed to get provider: %w", err)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(input, m.config.MaxInputBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch input: %w", err)
+	}
+	if int64(len(data)) > m.config.MaxInputBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidBatchInput, m.config.MaxInputBytes)
+	}
+
+	requests, err := parseBatchInput(data)
+	if err != nil {
+		return nil, err
+	}
+
+	id := "batch_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	job := &batchJob{
+		tenant: TenantFromContext(ctx),
+		owner:  owner,
+		dir:    filepath.Join(m.config.Dir, id),
+		batch: Batch{
+			ID:            id,
+			Object:        "batch",
+			Provider:      provider,
+			Status:        BatchStatusInProgress,
+			CreatedAt:     time.Now().Unix(),
+			InProgressAt:  time.Now().Unix(),
+			RequestCounts: BatchRequestCounts{Total: len(requests)},
+		},
+	}
+
+	if err := os.MkdirAll(job.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create batch directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(job.dir, batchInputFile), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to persist batch input: %w", err)
+	}
+	if err := job.save(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.jobs[id] = job
+	m.mu.Unlock()
+
+	m.start(job)
+
+	batch := job.snapshot()
+	return &batch, nil
+}
+
+// MaxInputBytes returns the largest batch input file CreateBatch accepts
+func (m *BatchManager) MaxInputBytes() int64 {
+	return m.config.MaxInputBytes
+}
+
+// GetBatch returns the current state of a batch of owner
+func (m *BatchManager) GetBatch(owner, id string) (*Batch, error) {
+	job, err := m.job(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	batch := job.snapshot()
+	return &batch, nil
+}
+
+// ListBatches returns the batches of owner, newest first
+func (m *BatchManager) ListBatches(owner string) []Batch {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	batches := make([]Batch, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if job.owner == owner {
+			batches = append(batches, job.snapshot())
+		}
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		if batches[i].CreatedAt == batches[j].CreatedAt {
+			return batches[i].ID > batches[j].ID
+		}
+		return batches[i].CreatedAt > batches[j].CreatedAt
+	})
+	return batches
+}
+
+// CancelBatch stops a running batch of owner. Requests already completed are kept.
+func (m *BatchManager) CancelBatch(owner, id string) (*Batch, error) {
+	job, err := m.job(owner, id)
+	if err != nil {
+		return nil, err
+	}
+
+	job.mu.Lock()
+	if job.batch.Status == BatchStatusInProgress {
+		job.batch.Status = BatchStatusCancelling
+		job.cancelRequested = true
+		if err := job.saveLocked(); err != nil {
+			m.logger.Error("failed to persist batch", zap.String("batch_id", id), zap.Error(err))
+		}
+		if job.cancel != nil {
+			job.cancel()
+		}
+	}
+	batch := job.batch
+	job.mu.Unlock()
+
+	return &batch, nil
+}
+
+// OpenOutput opens the JSONL file of successful results for a batch of owner
+func (m *BatchManager) OpenOutput(owner, id string) (io.ReadCloser, error) {
+	return m.openResults(owner, id, batchOutputFile)
+}
+
+// OpenErrors opens the JSONL file of failed requests for a batch of owner
+func (m *BatchManager) OpenErrors(owner, id string) (io.ReadCloser, error) {
+	return m.openResults(owner, id, batchErrorsFile)
+}
+
+// Close stops processing and waits for in-flight requests to finish.
+// Unfinished batches keep their in-progress status and resume on the next start.
+func (m *BatchManager) Close() {
+	m.stop()
+	m.wg.Wait()
+}
+
+func (m *BatchManager) openResults(owner, id, name string) (io.ReadCloser, error) {
+	job, err := m.job(owner, id)
+	if err != nil {
+		return nil, err
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	file, err := os.Open(filepath.Join(job.dir, name))
+	if os.IsNotExist(err) {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch results: %w", err)
+	}
+	return file, nil
+}
+
+// job returns a batch of owner; batches of other owners are reported as not found
+func (m *BatchManager) job(owner, id string) (*batchJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, exists := m.jobs[id]
+	if !exists || job.owner != owner {
+		return nil, fmt.Errorf("batch %s: %w", id, ErrBatchNotFound)
+	}
+	return job, nil
+}
+
+// load reads persisted batches and resumes unfinished ones
+func (m *BatchManager) load() error {
+	entries, err := os.ReadDir(m.config.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to list batch directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(m.config.Dir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, batchMetaFile))
+		if err != nil {
+			m.logger.Warn("skipping batch directory", zap.String("dir", dir), zap.Error(err))
+			continue
+		}
+
+		var meta batchMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			m.logger.Warn("skipping corrupt batch", zap.String("dir", dir), zap.Error(err))
+			continue
+		}
+		job := &batchJob{batch: meta.Batch, tenant: meta.Tenant, owner: meta.Owner, dir: dir}
+		m.jobs[job.batch.ID] = job
+
+		switch job.batch.Status {
+		case BatchStatusInProgress:
+			m.logger.Info("resuming batch", zap.String("batch_id", job.batch.ID))
+			m.start(job)
+		case BatchStatusCancelling:
+			job.finish(BatchStatusCancelled, "")
+		}
+	}
+
+	return nil
+}
+
+func (m *BatchManager) start(job *batchJob) {
+	ctx, cancel := context.WithCancel(m.ctx)
+
+	job.mu.Lock()
+	job.cancel = cancel
+	if job.cancelRequested {
+		cancel()
+	}
+	job.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+		m.run(ctx, job)
+	}()
+}
+
+// run processes every request of a batch that has no recorded result yet
+func (m *BatchManager) run(ctx context.Context, job *batchJob) {
+	data, err := os.ReadFile(filepath.Join(job.dir, batchInputFile))
+	if err != nil {
+		job.finish(BatchStatusFailed, fmt.Sprintf("failed to read batch input: %v", err))
+		return
+	}
+	requests, err := parseBatchInput(data)
+	if err != nil {
+		job.finish(BatchStatusFailed, err.Error())
+		return
+	}
+
+	completed, err := readResultIDs(filepath.Join(job.dir, batchOutputFile))
+	if err != nil {
+		job.finish(BatchStatusFailed, err.Error())
+		return
+	}
+	failed, err := readResultIDs(filepath.Join(job.dir, batchErrorsFile))
+	if err != nil {
+		job.finish(BatchStatusFailed, err.Error())
+		return
+	}
+
+	job.mu.Lock()
+	job.batch.RequestCounts = BatchRequestCounts{
+		Total:     len(requests),
+		Completed: len(completed),
+		Failed:    len(failed),
+	}
+	provider := job.batch.Provider
+	job.mu.Unlock()
+
+	// Requests are made for the tenant the batch was created for, so its
+	// policies, limits and metrics apply
+	if job.tenant != "" {
+		ctx = WithTenant(ctx, job.tenant)
+	}
+
+	output, err := openResultsForAppend(filepath.Join(job.dir, batchOutputFile))
+	if err != nil {
+		job.finish(BatchStatusFailed, fmt.Sprintf("failed to open batch output: %v", err))
+		return
+	}
+	defer output.Close()
+
+	errorsFile, err := openResultsForAppend(filepath.Join(job.dir, batchErrorsFile))
+	if err != nil {
+		job.finish(BatchStatusFailed, fmt.Sprintf("failed to open batch errors: %v", err))
+		return
+	}
+	defer errorsFile.Close()
+
+	var wg sync.WaitGroup
+dispatch:
+	for _, req := range requests {
+		if completed[req.CustomID] || failed[req.CustomID] {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case m.slots <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(req BatchRequest) {
+			defer wg.Done()
+			defer func() { <-m.slots }()
+			m.process(ctx, job, provider, req, output, errorsFile)
+		}(req)
+	}
+	wg.Wait()
+
+	job.mu.Lock()
+	cancelled := job.cancelRequested
+	job.mu.Unlock()
+
+	switch {
+	case cancelled:
+		job.finish(BatchStatusCancelled, "")
+	case ctx.Err() != nil:
+		// Shutting down: keep the batch in progress so it resumes on restart
+		job.mu.Lock()
+		if err := job.saveLocked(); err != nil {
+			m.logger.Error("failed to persist batch", zap.String("batch_id", job.batch.ID), zap.Error(err))
+		}
+		job.mu.Unlock()
+	default:
+		job.finish(BatchStatusCompleted, "")
+	}
+}
+
+// process sends a single batch request and records its result. Requests
+// that fail because the provider is unavailable are retried with backoff
+// rather than recorded as failed, and interrupted requests are left
+// unprocessed so they run again when the batch resumes.
+func (m *BatchManager) process(ctx context.Context, job *batchJob, provider string, req BatchRequest, output, errorsFile io.Writer) {
+	body := req.Body
+	body.Stream = false
+
+	delay := m.config.RetryDelay
+	for {
+		resp, err := m.send(ctx, provider, &body)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil || !isTransientBatchError(err) {
+			m.record(job, req, resp, err, output, errorsFile)
+			return
+		}
+
+		m.logger.Warn("retrying batch request",
+			zap.String("batch_id", job.batch.ID),
+			zap.String("custom_id", req.CustomID),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, batchMaxRetryDelay)
+	}
+}
+
+// send makes a single attempt at a batch request
+func (m *BatchManager) send(ctx context.Context, provider string, body *types.ChatRequest) (*types.ChatResponse, error) {
+	if limiter := m.config.RateLimits[provider]; limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limit exceeded: %w", err)
+		}
+	}
+	return m.service.Chat(WithPriority(ctx, PriorityBatch), provider, body)
+}
+
+// isTransientBatchError reports whether a request failed because the
+// provider was unavailable rather than because of the request itself
+func isTransientBatchError(err error) bool {
+	return errors.Is(err, ErrQueueTimeout) ||
+		errors.Is(err, ErrQueueFull) ||
+		errors.Is(err, ErrCircuitOpen) ||
+		errors.Is(err, ErrProviderDisabled) ||
+		isProviderFailure(err)
+}
+
+// record writes the result of a batch request to the output or errors file
+func (m *BatchManager) record(job *batchJob, req BatchRequest, resp *types.ChatResponse, err error, output, errorsFile io.Writer) {
+	result := BatchResult{
+		ID:       "batch_req_" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		CustomID: req.CustomID,
+	}
+	target := output
+	if err != nil {
+		result.Error = &BatchResultError{Code: "provider_error", Message: err.Error()}
+		target = errorsFile
+	} else {
+		result.Response = &BatchResultResponse{StatusCode: 200, Body: resp}
+	}
+
+	line, err := json.Marshal(result)
+	if err != nil {
+		m.logger.Error("failed to marshal batch result", zap.String("custom_id", req.CustomID), zap.Error(err))
+		return
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if _, err := target.Write(append(line, '\n')); err != nil {
+		m.logger.Error("failed to write batch result", zap.String("custom_id", req.CustomID), zap.Error(err))
+		return
+	}
+	if result.Error != nil {
+		job.batch.RequestCounts.Failed++
+	} else {
+		job.batch.RequestCounts.Completed++
+	}
+
+	job.sinceSave++
+	if job.sinceSave >= batchPersistInterval {
+		if err := job.saveLocked(); err != nil {
+			m.logger.Error("failed to persist batch", zap.String("batch_id", job.batch.ID), zap.Error(err))
+		}
+	}
+}
+
+// snapshot returns a copy of the batch state
+func (j *batchJob) snapshot() Batch {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.batch
+}
+
+// finish records a terminal status
+func (j *batchJob) finish(status, message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now().Unix()
+	j.batch.Status = status
+	j.batch.Error = message
+	switch status {
+	case BatchStatusCompleted:
+		j.batch.CompletedAt = now
+	case BatchStatusFailed:
+		j.batch.FailedAt = now
+	case BatchStatusCancelled:
+		j.batch.CancelledAt = now
+	}
+	_ = j.saveLocked()
+}
+
+func (j *batchJob) save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.saveLocked()
+}
+
+// saveLocked atomically persists the batch metadata; j.mu must be held
+func (j *batchJob) saveLocked() error {
+	data, err := json.MarshalIndent(batchMeta{Batch: j.batch, Tenant: j.tenant, Owner: j.owner}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	tmp := filepath.Join(j.dir, batchMetaFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to persist batch: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(j.dir, batchMetaFile)); err != nil {
+		return fmt.Errorf("failed to persist batch: %w", err)
+	}
+
+	j.sinceSave = 0
+	return nil
+}
+
+// parseBatchInput parses and validates a JSONL batch input
+func parseBatchInput(data []byte) ([]BatchRequest, error) {
+	var requests []BatchRequest
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req BatchRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			return nil, fmt.Errorf("%w on line %d: %v", ErrInvalidBatchInput, lineNum, err)
+		}
+		if req.CustomID == "" {
+			return nil, fmt.Errorf("%w on line %d: custom_id is required", ErrInvalidBatchInput, lineNum)
+		}
+		if seen[req.CustomID] {
+			return nil, fmt.Errorf("%w on line %d: duplicate custom_id %s", ErrInvalidBatchInput, lineNum, req.CustomID)
+		}
+		if req.URL != "" && req.URL != "/v1/chat/completions" {
+			return nil, fmt.Errorf("%w on line %d: unsupported url %s", ErrInvalidBatchInput, lineNum, req.URL)
+		}
+		if len(req.Body.Messages) == 0 {
+			return nil, fmt.Errorf("%w on line %d: messages are required", ErrInvalidBatchInput, lineNum)
+		}
+
+		seen[req.CustomID] = true
+		requests = append(requests, req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBatchInput, err)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("%w: no requests", ErrInvalidBatchInput)
+	}
+
+	return requests, nil
+}
+
+// readResultIDs returns the custom IDs recorded in a results file
+func readResultIDs(path string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return ids, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch results: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var result BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			// A torn final line from a crash is ignored and the request retried
+			continue
+		}
+		ids[result.CustomID] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch results: %w", err)
+	}
+
+	return ids, nil
+}
+
+// openResultsForAppend opens a results file for appending, truncating a
+// torn final line left by a crash so the file stays valid JSONL
+func openResultsForAppend(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := lastCompleteLine(file)
+	if err == nil {
+		err = file.Truncate(end)
+	}
+	if err == nil {
+		_, err = file.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// lastCompleteLine returns the offset just past the last newline in the file
+func lastCompleteLine(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 4096)
+	for end := info.Size(); end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
@@ -0,0 +1,296 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// batchTestProvider echoes the last message and fails on "fail". The first
+// unavailable requests answer with a server error.
+type batchTestProvider struct {
+	block       chan struct{}
+	unavailable int
+	mu          sync.Mutex
+	seen        []string
+	tenants     []string
+}
+
+func (p *batchTestProvider) Name() string { return "batch-test" }
+
+func (p *batchTestProvider) AvailableModels() []string { return []string{"test-model"} }
+
+func (p *batchTestProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	content := req.Messages[len(req.Messages)-1].Content
+
+	p.mu.Lock()
+	p.seen = append(p.seen, content)
+	p.tenants = append(p.tenants, TenantFromContext(ctx))
+	unavailable := p.unavailable > 0
+	if unavailable {
+		p.unavailable--
+	}
+	p.mu.Unlock()
+
+	if unavailable {
+		return nil, &types.StatusError{StatusCode: 503, Body: "overloaded"}
+	}
+
+	if p.block != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-p.block:
+		}
+	}
+	if content == "fail" {
+		return nil, fmt.Errorf("provider failure")
+	}
+
+	return &types.ChatResponse{
+		ID:      "resp-" + content,
+		Object:  "chat.completion",
+		Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: "echo " + content}, FinishReason: "stop"}},
+		Usage:   types.Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3},
+	}, nil
+}
+
+func (p *batchTestProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func batchInput(contents ...string) string {
+	var b strings.Builder
+	for i, content := range contents {
+		fmt.Fprintf(&b, `{"custom_id":"req-%d","method":"POST","url":"/v1/chat/completions","body":{"model":"test-model","messages":[{"role":"user","content":%q}]}}`+"\n", i, content)
+	}
+	return b.String()
+}
+
+func readResults(t *testing.T, r io.ReadCloser) []BatchResult {
+	t.Helper()
+	defer r.Close()
+
+	var results []BatchResult
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var result BatchResult
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		results = append(results, result)
+	}
+	require.NoError(t, scanner.Err())
+	return results
+}
+
+func waitForStatus(t *testing.T, m *BatchManager, owner, id, status string) *Batch {
+	t.Helper()
+	var batch *Batch
+	require.Eventually(t, func() bool {
+		var err error
+		batch, err = m.GetBatch(owner, id)
+		require.NoError(t, err)
+		return batch.Status == status
+	}, 5*time.Second, 10*time.Millisecond)
+	return batch
+}
+
+func TestBatchManager(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	t.Run("process batch", func(t *testing.T) {
+		service := NewService()
+		require.NoError(t, service.RegisterProvider(&batchTestProvider{}))
+
+		m, err := NewBatchManager(service, BatchConfig{Dir: t.TempDir(), Concurrency: 2}, logger)
+		require.NoError(t, err)
+		defer m.Close()
+
+		batch, err := m.CreateBatch(context.Background(), "tenant:acme", "batch-test", strings.NewReader(batchInput("a", "b", "fail", "c")))
+		require.NoError(t, err)
+		assert.Equal(t, 4, batch.RequestCounts.Total)
+
+		batch = waitForStatus(t, m, "tenant:acme", batch.ID, BatchStatusCompleted)
+		assert.Equal(t, BatchRequestCounts{Total: 4, Completed: 3, Failed: 1}, batch.RequestCounts)
+
+		output, err := m.OpenOutput("tenant:acme", batch.ID)
+		require.NoError(t, err)
+		results := readResults(t, output)
+		assert.Len(t, results, 3)
+		for _, result := range results {
+			assert.Equal(t, 200, result.Response.StatusCode)
+			assert.Nil(t, result.Error)
+		}
+
+		errorsFile, err := m.OpenErrors("tenant:acme", batch.ID)
+		require.NoError(t, err)
+		failures := readResults(t, errorsFile)
+		require.Len(t, failures, 1)
+		assert.Equal(t, "req-2", failures[0].CustomID)
+		assert.Contains(t, failures[0].Error.Message, "provider failure")
+
+		assert.Len(t, m.ListBatches("tenant:acme"), 1)
+	})
+
+	t.Run("tenants and owners", func(t *testing.T) {
+		service := NewService()
+		provider := &batchTestProvider{}
+		require.NoError(t, service.RegisterProvider(provider))
+
+		m, err := NewBatchManager(service, BatchConfig{Dir: t.TempDir()}, logger)
+		require.NoError(t, err)
+		defer m.Close()
+
+		ctx := WithTenant(context.Background(), "acme")
+		batch, err := m.CreateBatch(ctx, "tenant:acme", "batch-test", strings.NewReader(batchInput("a")))
+		require.NoError(t, err)
+		waitForStatus(t, m, "tenant:acme", batch.ID, BatchStatusCompleted)
+		assert.Equal(t, []string{"acme"}, provider.tenants)
+
+		assert.Empty(t, m.ListBatches("tenant:globex"))
+		_, err = m.GetBatch("tenant:globex", batch.ID)
+		assert.ErrorIs(t, err, ErrBatchNotFound)
+		_, err = m.CancelBatch("tenant:globex", batch.ID)
+		assert.ErrorIs(t, err, ErrBatchNotFound)
+		_, err = m.OpenOutput("tenant:globex", batch.ID)
+		assert.ErrorIs(t, err, ErrBatchNotFound)
+
+		// Owners survive a restart
+		m.Close()
+		m, err = NewBatchManager(service, BatchConfig{Dir: m.config.Dir}, logger)
+		require.NoError(t, err)
+		defer m.Close()
+		assert.Len(t, m.ListBatches("tenant:acme"), 1)
+		assert.Empty(t, m.ListBatches(""))
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		service := NewService()
+		require.NoError(t, service.RegisterProvider(&batchTestProvider{}))
+
+		m, err := NewBatchManager(service, BatchConfig{Dir: t.TempDir()}, logger)
+		require.NoError(t, err)
+		defer m.Close()
+
+		_, err = m.CreateBatch(context.Background(), "tenant:acme", "batch-test", strings.NewReader(batchInput("a")+batchInput("b")))
+		assert.ErrorContains(t, err, "duplicate custom_id")
+
+		_, err = m.CreateBatch(context.Background(), "tenant:acme", "batch-test", strings.NewReader("not json\n"))
+		assert.ErrorContains(t, err, "line 1")
+
+		_, err = m.CreateBatch(context.Background(), "tenant:acme", "missing", strings.NewReader(batchInput("a")))
+		assert.Error(t, err)
+
+		_, err = m.GetBatch("tenant:acme", "batch_unknown")
+		assert.ErrorIs(t, err, ErrBatchNotFound)
+	})
+
+	t.Run("cancel batch", func(t *testing.T) {
+		service := NewService()
+		provider := &batchTestProvider{block: make(chan struct{})}
+		require.NoError(t, service.RegisterProvider(provider))
+
+		m, err := NewBatchManager(service, BatchConfig{Dir: t.TempDir(), Concurrency: 1}, logger)
+		require.NoError(t, err)
+		defer m.Close()
+
+		batch, err := m.CreateBatch(context.Background(), "tenant:acme", "batch-test", strings.NewReader(batchInput("a", "b", "c")))
+		require.NoError(t, err)
+
+		_, err = m.CancelBatch("tenant:acme", batch.ID)
+		require.NoError(t, err)
+
+		batch = waitForStatus(t, m, "tenant:acme", batch.ID, BatchStatusCancelled)
+		assert.Equal(t, 0, batch.RequestCounts.Completed)
+	})
+
+	t.Run("retry unavailable provider", func(t *testing.T) {
+		service := NewService()
+		provider := &batchTestProvider{unavailable: 3}
+		require.NoError(t, service.RegisterProvider(provider))
+
+		m, err := NewBatchManager(service, BatchConfig{Dir: t.TempDir(), Concurrency: 1, RetryDelay: time.Millisecond}, logger)
+		require.NoError(t, err)
+		defer m.Close()
+
+		batch, err := m.CreateBatch(context.Background(), "tenant:acme", "batch-test", strings.NewReader(batchInput("a", "fail")))
+		require.NoError(t, err)
+
+		batch = waitForStatus(t, m, "tenant:acme", batch.ID, BatchStatusCompleted)
+		assert.Equal(t, BatchRequestCounts{Total: 2, Completed: 1, Failed: 1}, batch.RequestCounts, "only the request error is recorded")
+		assert.Len(t, provider.seen, 5)
+	})
+
+	t.Run("close while provider is disabled", func(t *testing.T) {
+		service := NewService()
+		require.NoError(t, service.RegisterProvider(&batchTestProvider{}))
+		require.NoError(t, service.SetProviderEnabled("batch-test", false))
+
+		m, err := NewBatchManager(service, BatchConfig{Dir: t.TempDir(), RetryDelay: time.Millisecond}, logger)
+		require.NoError(t, err)
+
+		batch, err := m.CreateBatch(context.Background(), "tenant:acme", "batch-test", strings.NewReader(batchInput("a", "b")))
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		m.Close()
+
+		batch, err = m.GetBatch("tenant:acme", batch.ID)
+		require.NoError(t, err)
+		assert.Equal(t, BatchStatusInProgress, batch.Status, "the batch resumes on restart")
+		assert.Equal(t, BatchRequestCounts{Total: 2}, batch.RequestCounts)
+
+		errorsFile, err := m.OpenErrors("tenant:acme", batch.ID)
+		require.NoError(t, err)
+		assert.Empty(t, readResults(t, errorsFile))
+	})
+
+	t.Run("resume after restart", func(t *testing.T) {
+		dir := t.TempDir()
+		batchDir := filepath.Join(dir, "batch_resume")
+		require.NoError(t, os.MkdirAll(batchDir, 0o755))
+
+		meta := batchMeta{
+			Batch:  Batch{ID: "batch_resume", Object: "batch", Provider: "batch-test", Status: BatchStatusInProgress, CreatedAt: 1},
+			Tenant: "acme",
+			Owner:  "tenant:acme",
+		}
+		data, err := json.Marshal(meta)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(batchDir, batchMetaFile), data, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(batchDir, batchInputFile), []byte(batchInput("a", "b", "c")), 0o644))
+
+		// req-0 finished before the crash; the torn line for req-1 must be retried
+		done := `{"id":"batch_req_1","custom_id":"req-0","response":{"status_code":200,"body":{"id":"resp-a"}},"error":null}` + "\n" + `{"id":"batch_req_2","cust`
+		require.NoError(t, os.WriteFile(filepath.Join(batchDir, batchOutputFile), []byte(done), 0o644))
+
+		service := NewService()
+		provider := &batchTestProvider{}
+		require.NoError(t, service.RegisterProvider(provider))
+
+		m, err := NewBatchManager(service, BatchConfig{Dir: dir}, logger)
+		require.NoError(t, err)
+		defer m.Close()
+
+		batch := waitForStatus(t, m, "tenant:acme", "batch_resume", BatchStatusCompleted)
+		assert.Equal(t, BatchRequestCounts{Total: 3, Completed: 3}, batch.RequestCounts)
+		assert.ElementsMatch(t, []string{"b", "c"}, provider.seen)
+		assert.Equal(t, []string{"acme", "acme"}, provider.tenants, "the tenant is restored")
+
+		output, err := m.OpenOutput("tenant:acme", "batch_resume")
+		require.NoError(t, err)
+		assert.Len(t, readResults(t, output), 3)
+	})
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// CacheConfig configures the chat response cache
+type CacheConfig struct {
+	// TTL is how long a response is served from the cache
+	TTL time.Duration
+	// MaxEntries bounds the number of cached responses; the least recently used are evicted
+	MaxEntries int
+}
+
+type noCacheKey struct{}
+
+// WithoutCache returns a context whose chat requests bypass the response cache
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+func cacheDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noCacheKey{}).(bool)
+	return disabled
+}
+
+type cacheEntry struct {
+	key     string
+	resp    *types.ChatResponse
+	expires time.Time
+}
+
+// responseCache is an LRU cache of chat responses
+type responseCache struct {
+	config  CacheConfig
+	entries map[string]*list.Element
+	order   *list.List
+	mu      sync.Mutex
+}
+
+func newResponseCache(config CacheConfig) *responseCache {
+	return &responseCache{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *responseCache) get(key string) *types.ChatResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	return copyResponse(entry.resp)
+}
+
+func (c *responseCache) put(key string, resp *types.ChatResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, resp: copyResponse(resp), expires: time.Now().Add(c.config.TTL)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.config.MaxEntries > 0 && c.order.Len() > c.config.MaxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// copyResponse copies a response so that callers cannot modify cached choices
+func copyResponse(resp *types.ChatResponse) *types.ChatResponse {
+	c := *resp
+	c.Choices = append([]types.Choice(nil), resp.Choices...)
+	return &c
+}
+
+// cacheKey identifies a chat request for a provider and tenant. Messages
+// serialize their content parts, so multimodal requests get distinct keys.
+func cacheKey(providerName, tenant string, req *types.ChatRequest) (string, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	if err := enc.Encode([]string{providerName, tenant}); err != nil {
+		return "", err
+	}
+	if err := enc.Encode(req); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SetCache enables the chat response cache. Streaming requests are never cached.
+// A zero TTL disables the cache.
+func (s *Service) SetCache(config CacheConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if config.TTL <= 0 {
+		s.cache = nil
+		return
+	}
+	s.cache = newResponseCache(config)
+}
+
+func (s *Service) getCache() *responseCache {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cache
+}
+
+// cachedChat returns a cached response for the request, if any, and the key
+// to store the response under otherwise
+func (s *Service) cachedChat(ctx context.Context, providerName string, req *types.ChatRequest) (*types.ChatResponse, string) {
+	cache := s.getCache()
+	if cache == nil || req.Stream || cacheDisabled(ctx) {
+		return nil, ""
+	}
+	// Disabled and removed providers are not served from the cache
+	_, done, err := s.lease(providerName)
+	if err != nil {
+		return nil, ""
+	}
+	done()
+
+	key, err := cacheKey(providerName, TenantFromContext(ctx), req)
+	if err != nil {
+		return nil, ""
+	}
+	resp := cache.get(key)
+	if m := s.getMetrics(); m != nil {
+		provider, model := s.metricLabels(m, providerName, req.Model)
+		m.ObserveCache(ctx, provider, model, resp != nil)
+	}
+	return resp, key
+}
+
+// storeChat caches a response under a key returned by cachedChat
+func (s *Service) storeChat(key string, resp *types.ChatResponse) {
+	if cache := s.getCache(); cache != nil && key != "" {
+		cache.put(key, resp)
+	}
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func TestServiceCache(t *testing.T) {
+	provider := &flakyProvider{}
+	service := NewService()
+	service.SetCache(CacheConfig{TTL: time.Hour, MaxEntries: 2})
+	require.NoError(t, service.RegisterProvider(provider))
+	ctx := context.Background()
+
+	resp, err := service.Chat(ctx, "flaky", chatRequest("a"))
+	require.NoError(t, err)
+	resp.Model = "modified"
+
+	resp, err = service.Chat(ctx, "flaky", chatRequest("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "test-model", resp.Model, "cached responses are copies")
+	assert.Equal(t, 1, provider.calls)
+
+	t.Run("keys on tenant and content", func(t *testing.T) {
+		_, err := service.Chat(WithTenant(ctx, "acme"), "flaky", chatRequest("a"))
+		require.NoError(t, err)
+		_, err = service.Chat(ctx, "flaky", chatRequest("b"))
+		require.NoError(t, err)
+
+		req := chatRequest("a")
+		req.Messages[0].Parts = []types.ContentPart{{Type: types.ContentTypeText, Text: "other"}}
+		_, err = service.Chat(ctx, "flaky", req)
+		require.NoError(t, err)
+		assert.Equal(t, 4, provider.calls)
+	})
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		calls := provider.calls
+		_, err := service.Chat(ctx, "flaky", chatRequest("a"))
+		require.NoError(t, err)
+		assert.Equal(t, calls+1, provider.calls)
+	})
+
+	t.Run("bypass", func(t *testing.T) {
+		calls := provider.calls
+		_, err := service.Chat(WithoutCache(ctx), "flaky", chatRequest("a"))
+		require.NoError(t, err)
+		assert.Equal(t, calls+1, provider.calls)
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		service.SetCache(CacheConfig{TTL: 10 * time.Millisecond})
+		_, err := service.Chat(ctx, "flaky", chatRequest("c"))
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		calls := provider.calls
+		_, err = service.Chat(ctx, "flaky", chatRequest("c"))
+		require.NoError(t, err)
+		assert.Equal(t, calls+1, provider.calls)
+	})
+}
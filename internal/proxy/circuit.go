@@ -0,0 +1,227 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// ErrCircuitOpen is returned when a provider's circuit breaker is rejecting requests
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitState is the state of a provider's circuit breaker
+type CircuitState string
+
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects requests until the cooldown elapses
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single trial request through
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+const (
+	// DefaultFailureThreshold is the number of consecutive failures that opens a circuit
+	DefaultFailureThreshold = 5
+	// DefaultCircuitCooldown is how long an open circuit rejects requests
+	DefaultCircuitCooldown = 30 * time.Second
+)
+
+// CircuitConfig configures the per-provider circuit breakers
+type CircuitConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit
+	FailureThreshold int
+	// Cooldown is how long the circuit stays open before allowing a trial request
+	Cooldown time.Duration
+}
+
+// circuitBreaker tracks consecutive provider failures
+type circuitBreaker struct {
+	config   CircuitConfig
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trial    bool
+	mu       sync.Mutex
+}
+
+func newCircuitBreaker(config CircuitConfig) *circuitBreaker {
+	if config.FailureThreshold < 1 {
+		config.FailureThreshold = DefaultFailureThreshold
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = DefaultCircuitCooldown
+	}
+	return &circuitBreaker{config: config, state: CircuitClosed}
+}
+
+// allow reports whether a request may be sent to the provider
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.config.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.trial = true
+		return nil
+	case CircuitHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// success closes the circuit
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = CircuitClosed
+	b.failures = 0
+	b.trial = false
+}
+
+// probeSuccess closes a circuit whose cooldown has elapsed after a healthy
+// probe. A probe only shows the provider is reachable, not that requests
+// succeed, so it neither closes an open circuit early nor resets failures
+// of a closed one, and it leaves a trial request in flight to decide.
+func (b *circuitBreaker) probeSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	halfOpen := b.state == CircuitHalfOpen ||
+		b.state == CircuitOpen && time.Since(b.openedAt) >= b.config.Cooldown
+	if !halfOpen || b.trial {
+		return
+	}
+	b.state = CircuitClosed
+	b.failures = 0
+}
+
+// failure counts a failure, opening the circuit once the threshold is reached
+// or immediately if a trial request failed
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+	}
+	b.trial = false
+}
+
+// release ends a trial request whose outcome says nothing about the provider
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// currentState returns the circuit state, reporting an open circuit whose
+// cooldown has elapsed as half open
+func (b *circuitBreaker) currentState() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.config.Cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// SetCircuitConfig configures the circuit breakers of all providers.
+// Existing breaker state is reset.
+func (s *Service) SetCircuitConfig(config CircuitConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.circuitConfig = config
+	s.circuits = make(map[string]*circuitBreaker)
+}
+
+// circuit returns the circuit breaker of a provider, creating it if needed
+func (s *Service) circuit(providerName string) *circuitBreaker {
+	s.mu.RLock()
+	breaker := s.circuits[providerName]
+	s.mu.RUnlock()
+	if breaker != nil {
+		return breaker
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if breaker = s.circuits[providerName]; breaker == nil {
+		breaker = newCircuitBreaker(s.circuitConfig)
+		s.circuits[providerName] = breaker
+	}
+	return breaker
+}
+
+// allowCircuit fails fast if the provider's circuit is open
+func (s *Service) allowCircuit(providerName string) error {
+	if err := s.circuit(providerName).allow(); err != nil {
+		return fmt.Errorf("provider %s: %w", providerName, err)
+	}
+	return nil
+}
+
+// recordCircuit feeds the outcome of a provider call into its circuit breaker.
+// Only errors showing the provider failing count against it: transport
+// errors and server error or rate limit responses. Cancellations by the
+// caller and errors caused by the request itself, such as validation
+// failures and other 4xx responses, end a trial request without counting.
+func (s *Service) recordCircuit(ctx context.Context, providerName string, err error) {
+	breaker := s.circuit(providerName)
+	switch {
+	case err == nil:
+		breaker.success()
+	case ctx.Err() != nil || !isProviderFailure(err):
+		breaker.release()
+	default:
+		breaker.failure()
+	}
+}
+
+// recordStream feeds the outcome of a stream the provider accepted into its
+// circuit breaker. The request was valid, so an error reported mid-stream
+// counts against the provider.
+func (s *Service) recordStream(ctx context.Context, providerName string, err error) {
+	breaker := s.circuit(providerName)
+	switch {
+	case ctx.Err() != nil:
+		breaker.release()
+	case err == nil:
+		breaker.success()
+	default:
+		breaker.failure()
+	}
+}
+
+// isProviderFailure reports whether err shows the provider failing rather
+// than the request being rejected
+func isProviderFailure(err error) bool {
+	var statusErr *types.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// serviceProvider sends requests to a registered provider through the service
+type serviceProvider struct {
+	service *Service
+	name    string
+}
+
+// Client returns a provider that sends requests to the named provider through
+// the service, so they pass its queues, policies, cache and circuit breaker.
+// The provider is looked up on every request and may be registered later.
+func (s *Service) Client(name string) types.Provider {
+	return &serviceProvider{service: s, name: name}
+}
+
+// Chat sends a chat completion request through the service
+func (p *serviceProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	return p.service.Chat(ctx, p.name, req)
+}
+
+// StreamChat streams a chat completion through the service
+func (p *serviceProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	return p.service.StreamChat(ctx, p.name, req)
+}
+
+// Name returns the name of the provider requests are sent to
+func (p *serviceProvider) Name() string {
+	return p.name
+}
+
+// AvailableModels returns the models of the provider, if it is registered
+func (p *serviceProvider) AvailableModels() []string {
+	provider, err := p.service.GetProvider(p.name)
+	if err != nil {
+		return nil
+	}
+	return provider.AvailableModels()
+}
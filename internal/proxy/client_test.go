@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	service := NewService()
+	client := service.Client("echo-stream")
+	assert.Equal(t, "echo-stream", client.Name())
+	assert.Nil(t, client.AvailableModels())
+
+	_, err := client.Chat(context.Background(), chatRequest("hi"))
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+
+	provider := &echoStreamProvider{}
+	require.NoError(t, service.RegisterProvider(provider))
+
+	resp, err := client.Chat(context.Background(), chatRequest("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "you said hi", resp.Choices[0].Message.Content)
+
+	stream, err := client.StreamChat(context.Background(), chatRequest("hello"))
+	require.NoError(t, err)
+	var content string
+	for resp := range stream {
+		content += resp.Choices[0].Message.Content
+	}
+	assert.Equal(t, "hello", content)
+
+	// Requests are refused like API requests when the provider is disabled
+	require.NoError(t, service.SetProviderEnabled("echo-stream", false))
+	_, err = client.Chat(context.Background(), chatRequest("hi"))
+	assert.ErrorIs(t, err, ErrProviderDisabled)
+}
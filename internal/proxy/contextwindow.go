@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// SetContextWindow fits chat requests into the context window of their model
+// before they are sent. Strategies that summarize call the request's provider
+// while the request holds its concurrency slot. A nil manager sends requests
+// as they are.
+func (s *Service) SetContextWindow(manager *contextwindow.Manager) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contextWindow = manager
+}
+
+func (s *Service) getContextWindow() *contextwindow.Manager {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.contextWindow
+}
+
+// fitContextWindow returns the request to send, shortened to fit the context
+// window, or checked to fit it when no manager is set. It runs once the
+// circuit breaker has admitted the request, so a failure to fit releases the
+// breaker without counting as a provider failure.
+func (s *Service) fitContextWindow(ctx context.Context, providerName string, provider types.Provider, req *types.ChatRequest) (*types.ChatRequest, error) {
+	manager := s.getContextWindow()
+	if manager == nil {
+		if err := s.checkContextLimit(providerName, req); err != nil {
+			s.circuit(providerName).release()
+			return nil, err
+		}
+		return req, nil
+	}
+
+	fitted, err := manager.Fit(ctx, provider, req)
+	if err != nil {
+		s.circuit(providerName).release()
+		return nil, fmt.Errorf("provider %s: %w", providerName, err)
+	}
+	return fitted, nil
+}
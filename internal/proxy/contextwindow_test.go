@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// recordingProvider records the messages of the requests it receives
+type recordingProvider struct {
+	batchTestProvider
+	received [][]types.Message
+}
+
+func (p *recordingProvider) Name() string { return "recording" }
+
+func (p *recordingProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	p.received = append(p.received, req.Messages)
+	return &types.ChatResponse{Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: "ok"}}}}, nil
+}
+
+func (p *recordingProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	p.received = append(p.received, req.Messages)
+	ch := make(chan *types.ChatResponse, 1)
+	ch <- &types.ChatResponse{Choices: []types.Choice{{Message: types.Message{Content: "ok"}}}}
+	close(ch)
+	return ch, nil
+}
+
+func TestServiceContextWindow(t *testing.T) {
+	ctx := context.Background()
+	long := strings.Repeat("lorem ipsum ", 200)
+	messages := []types.Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: "noted"},
+		{Role: "user", Content: "question"},
+	}
+
+	service := NewService()
+	provider := &recordingProvider{}
+	require.NoError(t, service.RegisterProvider(provider))
+	manager, err := contextwindow.NewManager(zaptest.NewLogger(t), &contextwindow.Config{ContextWindow: 300, Reserve: 100})
+	require.NoError(t, err)
+	service.SetContextWindow(manager)
+
+	req := &types.ChatRequest{Messages: messages}
+	_, err = service.Chat(ctx, "recording", req)
+	require.NoError(t, err)
+	require.Len(t, provider.received, 1)
+	assert.Equal(t, messages[2:], provider.received[0][1:], "the oldest turn is dropped")
+	assert.Len(t, req.Messages, 4, "caller's request must not be modified")
+
+	stream, err := service.StreamChat(ctx, "recording", &types.ChatRequest{Messages: messages})
+	require.NoError(t, err)
+	for range stream {
+	}
+	assert.Len(t, provider.received[1], 3)
+
+	// Requests that cannot fit are rejected before reaching the provider
+	_, err = service.Chat(ctx, "recording", &types.ChatRequest{Messages: []types.Message{{Role: "user", Content: long}}})
+	assert.ErrorIs(t, err, contextwindow.ErrContextOverflow)
+	assert.Len(t, provider.received, 2)
+
+	service.SetContextWindow(nil)
+	_, err = service.Chat(ctx, "recording", &types.ChatRequest{Messages: messages})
+	require.NoError(t, err)
+	assert.Len(t, provider.received[2], 4)
+}
@@ -0,0 +1,194 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// ProviderStatus describes the health of a registered provider
+type ProviderStatus struct {
+	Name               string       `json:"name"`
+	Enabled            bool         `json:"enabled"`
+	Healthy            bool         `json:"healthy"`
+	Circuit            CircuitState `json:"circuit"`
+	LastChecked        *time.Time   `json:"last_checked,omitempty"`
+	LatencyMS          int64        `json:"latency_ms"`
+	LastError          string       `json:"last_error,omitempty"`
+	LastErrorAt        *time.Time   `json:"last_error_at,omitempty"`
+	InFlight           int          `json:"in_flight"`
+	Models             []string     `json:"models"`
+	SupportsEmbeddings bool         `json:"supports_embeddings"`
+}
+
+// healthState holds the result of the latest probes of a provider
+type healthState struct {
+	checked     time.Time
+	latency     time.Duration
+	healthy     bool
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// StartHealthChecks probes every provider immediately and then at each
+// interval until the context is canceled. Each probe is bounded by timeout.
+func (s *Service) StartHealthChecks(ctx context.Context, interval, timeout time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.CheckHealth(ctx, timeout)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CheckHealth probes all registered providers concurrently
+func (s *Service) CheckHealth(ctx context.Context, timeout time.Duration) {
+	s.mu.RLock()
+	providers := make([]types.Provider, 0, len(s.providers))
+	for _, entry := range s.providers {
+		if !entry.disabled {
+			providers = append(providers, entry.provider)
+		}
+	}
+	s.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, provider := range providers {
+		wg.Add(1)
+		go func(provider types.Provider) {
+			defer wg.Done()
+			s.probe(ctx, provider, timeout)
+		}(provider)
+	}
+	wg.Wait()
+}
+
+// probe runs a single health check against a provider. Providers that do not
+// implement types.HealthChecker are considered healthy if they expose models.
+// A failed probe counts against the circuit breaker, but a healthy one only
+// closes a circuit whose cooldown has already elapsed.
+func (s *Service) probe(ctx context.Context, provider types.Provider, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	if checker, ok := provider.(types.HealthChecker); ok {
+		err = checker.HealthCheck(ctx)
+	} else if len(provider.AvailableModels()) == 0 {
+		err = errors.New("no models available")
+	}
+	latency := time.Since(start)
+
+	name := provider.Name()
+	s.mu.Lock()
+	state := s.health[name]
+	if state == nil {
+		state = &healthState{}
+		s.health[name] = state
+	}
+	state.checked = start
+	state.latency = latency
+	state.healthy = err == nil
+	if err != nil {
+		state.lastError = err.Error()
+		state.lastErrorAt = start
+	}
+	s.mu.Unlock()
+
+	if err == nil {
+		s.circuit(name).probeSuccess()
+	} else if !errors.Is(ctx.Err(), context.Canceled) {
+		s.circuit(name).failure()
+	}
+}
+
+// ProviderStatus returns the health of a provider
+func (s *Service) ProviderStatus(name string) (*ProviderStatus, error) {
+	s.mu.RLock()
+	entry, exists := s.providers[name]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	return s.providerStatus(entry), nil
+}
+
+// ProviderStatuses returns the health of every registered provider, sorted by name
+func (s *Service) ProviderStatuses() []ProviderStatus {
+	s.mu.RLock()
+	entries := make([]*providerEntry, 0, len(s.providers))
+	for _, entry := range s.providers {
+		entries = append(entries, entry)
+	}
+	s.mu.RUnlock()
+
+	statuses := make([]ProviderStatus, 0, len(entries))
+	for _, entry := range entries {
+		statuses = append(statuses, *s.providerStatus(entry))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Ready reports whether at least one enabled provider is healthy with a closed or half-open circuit
+func (s *Service) Ready() bool {
+	for _, status := range s.ProviderStatuses() {
+		if status.Enabled && status.Healthy && status.Circuit != CircuitOpen {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) providerStatus(entry *providerEntry) *ProviderStatus {
+	provider := entry.provider
+	name := provider.Name()
+	_, embeds := provider.(types.EmbeddingProvider)
+	status := &ProviderStatus{
+		Name:               name,
+		Healthy:            true,
+		InFlight:           entry.inFlight(),
+		Circuit:            s.circuit(name).currentState(),
+		Models:             provider.AvailableModels(),
+		SupportsEmbeddings: embeds,
+	}
+	if status.Models == nil {
+		status.Models = []string{}
+	}
+
+	s.mu.RLock()
+	status.Enabled = !entry.disabled
+	state := s.health[name]
+	if state != nil {
+		checked := state.checked
+		status.LastChecked = &checked
+		status.LatencyMS = state.latency.Milliseconds()
+		status.Healthy = state.healthy
+		status.LastError = state.lastError
+		if !state.lastErrorAt.IsZero() {
+			lastErrorAt := state.lastErrorAt
+			status.LastErrorAt = &lastErrorAt
+		}
+	}
+	s.mu.RUnlock()
+
+	if status.Circuit == CircuitOpen {
+		status.Healthy = false
+	}
+	return status
+}
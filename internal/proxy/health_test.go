@@ -0,0 +1,238 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// flakyProvider fails chat and health checks while err is set. Streams fail
+// to open while err is set and fail after their first chunk while streamErr is.
+type flakyProvider struct {
+	err       error
+	streamErr error
+	calls     int
+}
+
+func (p *flakyProvider) Name() string { return "flaky" }
+
+func (p *flakyProvider) AvailableModels() []string { return []string{"test-model"} }
+
+func (p *flakyProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &types.ChatResponse{Model: req.Model}, nil
+}
+
+func (p *flakyProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	streamErr := p.streamErr
+	ch := make(chan *types.ChatResponse)
+	go func() {
+		defer close(ch)
+		ch <- &types.ChatResponse{Model: req.Model, Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: "partial"}}}}
+		if streamErr != nil {
+			ch <- &types.ChatResponse{Model: req.Model, Error: streamErr}
+		}
+	}()
+	return ch, nil
+}
+
+func (p *flakyProvider) HealthCheck(ctx context.Context) error {
+	return p.err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(CircuitConfig{FailureThreshold: 2, Cooldown: 20 * time.Millisecond})
+
+	require.NoError(t, b.allow())
+	b.failure()
+	assert.Equal(t, CircuitClosed, b.currentState())
+	b.failure()
+	assert.Equal(t, CircuitOpen, b.currentState())
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen)
+
+	time.Sleep(25 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, b.currentState())
+	require.NoError(t, b.allow())
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen, "only one trial request")
+
+	b.failure()
+	assert.Equal(t, CircuitOpen, b.currentState())
+
+	time.Sleep(25 * time.Millisecond)
+	require.NoError(t, b.allow())
+	b.success()
+	assert.Equal(t, CircuitClosed, b.currentState())
+	require.NoError(t, b.allow())
+}
+
+func TestServiceCircuit(t *testing.T) {
+	provider := &flakyProvider{err: &types.StatusError{StatusCode: 503, Body: "upstream down"}}
+	service := NewService()
+	service.SetCircuitConfig(CircuitConfig{FailureThreshold: 2, Cooldown: time.Hour})
+	require.NoError(t, service.RegisterProvider(provider))
+
+	for i := 0; i < 2; i++ {
+		_, err := service.Chat(context.Background(), "flaky", chatRequest("hi"))
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+
+	_, err := service.Chat(context.Background(), "flaky", chatRequest("hi"))
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, provider.calls, "open circuit fails fast")
+
+	t.Run("canceled requests do not count", func(t *testing.T) {
+		service := NewService()
+		service.SetCircuitConfig(CircuitConfig{FailureThreshold: 1, Cooldown: time.Hour})
+		require.NoError(t, service.RegisterProvider(&flakyProvider{err: context.Canceled}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := service.Chat(ctx, "flaky", chatRequest("hi"))
+		require.Error(t, err)
+
+		status, err := service.ProviderStatus("flaky")
+		require.NoError(t, err)
+		assert.Equal(t, CircuitClosed, status.Circuit)
+	})
+
+	t.Run("client errors do not count", func(t *testing.T) {
+		for _, err := range []error{
+			&types.StatusError{StatusCode: 400, Body: "prompt is too long"},
+			errors.New("invalid content part type"),
+		} {
+			service := NewService()
+			service.SetCircuitConfig(CircuitConfig{FailureThreshold: 1, Cooldown: time.Hour})
+			require.NoError(t, service.RegisterProvider(&flakyProvider{err: err}))
+
+			for i := 0; i < 3; i++ {
+				_, chatErr := service.Chat(context.Background(), "flaky", chatRequest("hi"))
+				assert.NotErrorIs(t, chatErr, ErrCircuitOpen, err.Error())
+			}
+		}
+	})
+
+	t.Run("rate limits count", func(t *testing.T) {
+		service := NewService()
+		service.SetCircuitConfig(CircuitConfig{FailureThreshold: 1, Cooldown: time.Hour})
+		require.NoError(t, service.RegisterProvider(&flakyProvider{err: &types.StatusError{StatusCode: 429}}))
+
+		_, err := service.Chat(context.Background(), "flaky", chatRequest("hi"))
+		require.Error(t, err)
+
+		status, err := service.ProviderStatus("flaky")
+		require.NoError(t, err)
+		assert.Equal(t, CircuitOpen, status.Circuit)
+	})
+}
+
+func TestServiceStreamCircuit(t *testing.T) {
+	provider := &flakyProvider{err: &types.StatusError{StatusCode: 503}}
+	service := NewService()
+	service.SetCircuitConfig(CircuitConfig{FailureThreshold: 3, Cooldown: 20 * time.Millisecond})
+	require.NoError(t, service.RegisterProvider(provider))
+
+	circuit := func() CircuitState {
+		status, err := service.ProviderStatus("flaky")
+		require.NoError(t, err)
+		return status.Circuit
+	}
+	trial := func() {
+		for i := 0; i < 3; i++ {
+			_, err := service.Chat(context.Background(), "flaky", chatRequest("hi"))
+			require.Error(t, err)
+		}
+		require.Equal(t, CircuitOpen, circuit())
+		time.Sleep(25 * time.Millisecond)
+	}
+
+	t.Run("failed trial stream reopens the circuit", func(t *testing.T) {
+		trial()
+		provider.err = nil
+		provider.streamErr = errors.New("stream reset")
+
+		respChan, err := service.StreamChat(context.Background(), "flaky", chatRequest("hi"))
+		require.NoError(t, err)
+		assert.Equal(t, CircuitHalfOpen, circuit(), "an open stream does not close the circuit")
+		for range respChan {
+		}
+		assert.Equal(t, CircuitOpen, circuit())
+	})
+
+	t.Run("completed trial stream closes the circuit", func(t *testing.T) {
+		provider.err = &types.StatusError{StatusCode: 503}
+		time.Sleep(25 * time.Millisecond)
+		trial()
+		provider.err = nil
+		provider.streamErr = nil
+
+		respChan, err := service.StreamChat(context.Background(), "flaky", chatRequest("hi"))
+		require.NoError(t, err)
+		for range respChan {
+		}
+		assert.Equal(t, CircuitClosed, circuit())
+	})
+}
+
+func TestServiceHealth(t *testing.T) {
+	provider := &flakyProvider{}
+	service := NewService()
+	service.SetCircuitConfig(CircuitConfig{FailureThreshold: 1, Cooldown: time.Hour})
+	require.NoError(t, service.RegisterProvider(provider))
+
+	status, err := service.ProviderStatus("flaky")
+	require.NoError(t, err)
+	assert.True(t, status.Healthy)
+	assert.Nil(t, status.LastChecked)
+	assert.True(t, service.Ready())
+
+	provider.err = errors.New("connection refused")
+	service.CheckHealth(context.Background(), time.Second)
+
+	status, err = service.ProviderStatus("flaky")
+	require.NoError(t, err)
+	assert.False(t, status.Healthy)
+	assert.Equal(t, CircuitOpen, status.Circuit)
+	assert.Equal(t, "connection refused", status.LastError)
+	require.NotNil(t, status.LastChecked)
+	require.NotNil(t, status.LastErrorAt)
+	assert.Equal(t, []string{"test-model"}, status.Models)
+	assert.False(t, service.Ready())
+
+	provider.err = nil
+	service.CheckHealth(context.Background(), time.Second)
+
+	status, err = service.ProviderStatus("flaky")
+	require.NoError(t, err)
+	assert.Equal(t, CircuitOpen, status.Circuit, "a healthy probe does not skip the cooldown")
+	assert.False(t, service.Ready())
+
+	breaker := service.circuit("flaky")
+	breaker.mu.Lock()
+	breaker.openedAt = time.Now().Add(-2 * time.Hour)
+	breaker.mu.Unlock()
+	service.CheckHealth(context.Background(), time.Second)
+
+	status, err = service.ProviderStatus("flaky")
+	require.NoError(t, err)
+	assert.True(t, status.Healthy)
+	assert.Equal(t, CircuitClosed, status.Circuit, "a healthy probe closes a half-open circuit")
+	assert.Equal(t, "connection refused", status.LastError, "last error is kept")
+	assert.True(t, service.Ready())
+
+	_, err = service.ProviderStatus("missing")
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+}
@@ -0,0 +1,284 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pimentel/peppergo/internal/metrics"
+	"github.com/pimentel/peppergo/internal/policy"
+	"github.com/pimentel/peppergo/internal/redact"
+	"github.com/pimentel/peppergo/internal/tokenizer"
+	"github.com/pimentel/peppergo/internal/tracing"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// Operations recorded in request metrics
+const (
+	OperationChat       = "chat"
+	OperationStreamChat = "stream_chat"
+	OperationEmbeddings = "embeddings"
+)
+
+// otherLabel replaces provider and model names that are not known to the
+// service in metric labels
+const otherLabel = "other"
+
+// ModelPricing is the price of a model in USD per million tokens
+type ModelPricing = tokenizer.Pricing
+
+// Metrics records proxy request metrics into a registry
+type Metrics struct {
+	registry   *metrics.Registry
+	requests   *metrics.CounterVec
+	errors     *metrics.CounterVec
+	duration   *metrics.HistogramVec
+	ttft       *metrics.HistogramVec
+	tokens     *metrics.CounterVec
+	cost       *metrics.CounterVec
+	cache      *metrics.CounterVec
+	inFlight   *metrics.GaugeVec
+	queueWait  *metrics.HistogramVec
+	queueDepth *metrics.GaugeVec
+	queueSlots *metrics.GaugeVec
+	pricing    map[string]ModelPricing
+	queueStats func() []QueueStats
+	mu         sync.RWMutex
+}
+
+// NewMetrics registers the proxy metric families in the registry
+func NewMetrics(registry *metrics.Registry) *Metrics {
+	m := &Metrics{
+		registry: registry,
+		requests: registry.NewCounter("peppergo_requests_total",
+			"Provider requests by outcome.", "provider", "model", "tenant", "operation", "status"),
+		errors: registry.NewCounter("peppergo_request_errors_total",
+			"Failed provider requests by error class.", "provider", "model", "tenant", "class"),
+		duration: registry.NewHistogram("peppergo_request_duration_seconds",
+			"Total provider request latency.", nil, "provider", "model", "tenant", "operation"),
+		ttft: registry.NewHistogram("peppergo_time_to_first_token_seconds",
+			"Latency until the first streamed token.", nil, "provider", "model", "tenant"),
+		tokens: registry.NewCounter("peppergo_tokens_total",
+			"Tokens processed by type.", "provider", "model", "tenant", "type"),
+		cost: registry.NewCounter("peppergo_cost_usd_total",
+			"Estimated spend in USD for models with known pricing.", "provider", "model", "tenant"),
+		cache: registry.NewCounter("peppergo_cache_requests_total",
+			"Response cache lookups by result.", "provider", "model", "tenant", "result"),
+		inFlight: registry.NewGauge("peppergo_requests_in_flight",
+			"Provider requests currently in flight.", "provider", "model", "tenant"),
+		queueWait: registry.NewHistogram("peppergo_queue_wait_seconds",
+			"Time spent waiting for a provider concurrency slot.", nil, "provider", "priority"),
+		queueDepth: registry.NewGauge("peppergo_queue_depth",
+			"Requests waiting for a provider concurrency slot.", "provider", "priority"),
+		queueSlots: registry.NewGauge("peppergo_queue_slots_in_use",
+			"Provider concurrency slots in use.", "provider"),
+		pricing: make(map[string]ModelPricing),
+	}
+	registry.OnCollect(m.collect)
+	return m
+}
+
+// collect refreshes the queue gauges from the service the metrics are set on
+func (m *Metrics) collect() {
+	m.mu.RLock()
+	queueStats := m.queueStats
+	m.mu.RUnlock()
+	if queueStats != nil {
+		m.observeQueues(queueStats())
+	}
+}
+
+// SetPricing sets the price used to estimate cost for a model
+func (m *Metrics) SetPricing(model string, pricing ModelPricing) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pricing[model] = pricing
+}
+
+func (m *Metrics) hasPricing(model string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.pricing[model]
+	return ok
+}
+
+// ObserveCache records a response cache lookup
+func (m *Metrics) ObserveCache(ctx context.Context, provider, model string, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cache.With(provider, model, TenantFromContext(ctx), result).Inc()
+}
+
+// observeQueues mirrors the provider queue state into gauges
+func (m *Metrics) observeQueues(stats []QueueStats) {
+	for _, s := range stats {
+		m.queueSlots.With(s.Provider).Set(float64(s.InFlight))
+		for priority, depth := range s.Depth {
+			m.queueDepth.With(s.Provider, priority).Set(float64(depth))
+		}
+	}
+}
+
+func (m *Metrics) observeQueueWait(provider string, priority Priority, wait time.Duration) {
+	if m == nil {
+		return
+	}
+	m.queueWait.With(provider, priority.String()).Observe(wait.Seconds())
+}
+
+// requestObservation tracks a single provider request in metrics, traces and request records
+type requestObservation struct {
+	metrics    *Metrics
+	span       *tracing.Span
+	record     *RequestRecord
+	operation  string
+	provider   string
+	model      string
+	tenant     string
+	start      time.Time
+	firstToken bool
+
+	// labels are the provider and model reported in metrics
+	providerLabel string
+	modelLabel    string
+}
+
+// observe starts recording a provider request and returns a context carrying its span
+func (s *Service) observe(ctx context.Context, operation, provider, model string) (context.Context, *requestObservation) {
+	ctx, span := tracing.Start(ctx, "proxy."+operation)
+	o := &requestObservation{
+		metrics:   s.getMetrics(),
+		span:      span,
+		record:    recordFromContext(ctx),
+		operation: operation,
+		provider:  provider,
+		model:     model,
+		tenant:    TenantFromContext(ctx),
+		start:     time.Now(),
+	}
+	span.SetAttribute("provider", provider)
+	span.SetAttribute("model", model)
+	if o.tenant != "" {
+		span.SetAttribute("tenant", o.tenant)
+	}
+	if o.metrics != nil {
+		o.providerLabel, o.modelLabel = s.metricLabels(o.metrics, provider, model)
+		o.metrics.inFlight.With(o.providerLabel, o.modelLabel, o.tenant).Inc()
+	}
+	return ctx, o
+}
+
+// metricLabels returns the provider and model labels of a request. Every
+// name a caller makes up would create new series, so providers that are not
+// registered and models their provider does not advertise are reported as
+// "other". Models with pricing are always reported by name.
+func (s *Service) metricLabels(m *Metrics, providerName, model string) (string, string) {
+	provider, err := s.GetProvider(providerName)
+	if err != nil {
+		providerName = otherLabel
+	}
+	if model == "" || m.hasPricing(model) {
+		return providerName, model
+	}
+	if provider != nil {
+		for _, available := range provider.AvailableModels() {
+			if available == model {
+				return providerName, model
+			}
+		}
+	}
+	return providerName, otherLabel
+}
+
+// token records the time to first token of a stream
+func (o *requestObservation) token() {
+	if o.firstToken {
+		return
+	}
+	o.firstToken = true
+	ttft := time.Since(o.start)
+	o.span.SetAttribute("time_to_first_token_ms", ttft.Milliseconds())
+	if o.metrics != nil {
+		o.metrics.ttft.With(o.providerLabel, o.modelLabel, o.tenant).Observe(ttft.Seconds())
+	}
+}
+
+// finish records the outcome of the request
+func (o *requestObservation) finish(usage types.Usage, err error) {
+	if err != nil {
+		o.span.RecordError(err)
+	} else {
+		o.span.SetAttribute("prompt_tokens", usage.PromptTokens)
+		o.span.SetAttribute("completion_tokens", usage.CompletionTokens)
+	}
+	o.span.End()
+
+	if o.record != nil {
+		o.record.add(CallRecord{
+			Operation: o.operation,
+			Provider:  o.provider,
+			Model:     o.model,
+			Usage:     usage,
+			Duration:  time.Since(o.start),
+			Err:       err,
+		})
+	}
+
+	m := o.metrics
+	if m == nil {
+		return
+	}
+	m.inFlight.With(o.providerLabel, o.modelLabel, o.tenant).Dec()
+	m.duration.With(o.providerLabel, o.modelLabel, o.tenant, o.operation).Observe(time.Since(o.start).Seconds())
+
+	if err != nil {
+		m.requests.With(o.providerLabel, o.modelLabel, o.tenant, o.operation, "error").Inc()
+		m.errors.With(o.providerLabel, o.modelLabel, o.tenant, errorClass(err)).Inc()
+		return
+	}
+	m.requests.With(o.providerLabel, o.modelLabel, o.tenant, o.operation, "success").Inc()
+
+	m.tokens.With(o.providerLabel, o.modelLabel, o.tenant, "prompt").Add(float64(usage.PromptTokens))
+	m.tokens.With(o.providerLabel, o.modelLabel, o.tenant, "completion").Add(float64(usage.CompletionTokens))
+
+	m.mu.RLock()
+	pricing, ok := m.pricing[o.model]
+	m.mu.RUnlock()
+	if ok {
+		m.cost.With(o.providerLabel, o.modelLabel, o.tenant).Add(pricing.Cost(usage))
+	}
+}
+
+// errorClass buckets an error into a low-cardinality label value
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, ErrQueueTimeout):
+		return "queue_timeout"
+	case errors.Is(err, ErrQueueFull):
+		return "queue_full"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrProviderDisabled):
+		return "provider_disabled"
+	case errors.Is(err, ErrProviderNotFound):
+		return "provider_not_found"
+	case errors.Is(err, ErrEmbeddingsNotSupported):
+		return "unsupported"
+	case errors.Is(err, policy.ErrPolicyViolation):
+		return "policy_violation"
+	case errors.Is(err, redact.ErrSensitiveData):
+		return "sensitive_data"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "provider_error"
+	}
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/metrics"
+	"github.com/pimentel/peppergo/internal/tracing"
+)
+
+func TestServiceMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := NewMetrics(registry)
+	m.SetPricing("test-model", ModelPricing{PromptPerMillion: 1000000, CompletionPerMillion: 2000000})
+
+	service := NewService()
+	service.SetMetrics(m)
+	require.NoError(t, service.RegisterProvider(&batchTestProvider{}))
+	require.NoError(t, service.SetQueueConfig("batch-test", QueueConfig{MaxConcurrent: 2, MaxQueueTime: time.Second}))
+
+	ctx := WithTenant(context.Background(), "acme")
+	_, err := service.Chat(ctx, "batch-test", chatRequest("a"))
+	require.NoError(t, err)
+	_, err = service.Chat(ctx, "batch-test", chatRequest("fail"))
+	require.Error(t, err)
+	_, err = service.Chat(ctx, "missing", chatRequest("a"))
+	require.ErrorIs(t, err, ErrProviderNotFound)
+	m.ObserveCache(ctx, "batch-test", "test-model", true)
+
+	var b strings.Builder
+	require.NoError(t, registry.WriteText(&b))
+	out := b.String()
+
+	assert.Contains(t, out, `peppergo_requests_total{provider="batch-test",model="test-model",tenant="acme",operation="chat",status="success"} 1`)
+	assert.Contains(t, out, `peppergo_requests_total{provider="batch-test",model="test-model",tenant="acme",operation="chat",status="error"} 1`)
+	assert.Contains(t, out, `peppergo_request_errors_total{provider="batch-test",model="test-model",tenant="acme",class="provider_error"} 1`)
+	assert.Contains(t, out, `peppergo_request_errors_total{provider="other",model="test-model",tenant="acme",class="provider_not_found"} 1`)
+	assert.Contains(t, out, `peppergo_request_duration_seconds_count{provider="batch-test",model="test-model",tenant="acme",operation="chat"} 2`)
+	assert.Contains(t, out, `peppergo_requests_in_flight{provider="batch-test",model="test-model",tenant="acme"} 0`)
+	assert.Contains(t, out, `peppergo_tokens_total{provider="batch-test",model="test-model",tenant="acme",type="completion"} 2`)
+	assert.Contains(t, out, `peppergo_cost_usd_total{provider="batch-test",model="test-model",tenant="acme"} 5`)
+	assert.Contains(t, out, `peppergo_cache_requests_total{provider="batch-test",model="test-model",tenant="acme",result="hit"} 1`)
+	assert.Contains(t, out, `peppergo_queue_wait_seconds_count{provider="batch-test",priority="default"} 2`)
+	assert.Contains(t, out, `peppergo_queue_depth{provider="batch-test",priority="interactive"} 0`)
+	assert.Contains(t, out, `peppergo_queue_slots_in_use{provider="batch-test"} 0`)
+}
+
+func TestServiceMetricLabels(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := NewMetrics(registry)
+
+	service := NewService()
+	service.SetMetrics(m)
+	service.SetMetrics(m)
+	require.NoError(t, service.RegisterProvider(&batchTestProvider{}))
+	require.NoError(t, service.SetQueueConfig("batch-test", QueueConfig{MaxConcurrent: 2, MaxQueueTime: time.Second}))
+
+	ctx := WithTenant(context.Background(), "acme")
+	req := chatRequest("a")
+	req.Model = "made-up-model"
+	_, err := service.Chat(ctx, "batch-test", req)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	require.NoError(t, registry.WriteText(&b))
+	out := b.String()
+
+	assert.Contains(t, out, `peppergo_requests_total{provider="batch-test",model="other",tenant="acme",operation="chat",status="success"} 1`)
+	assert.NotContains(t, out, "made-up-model")
+	assert.Equal(t, 1, strings.Count(out, "# TYPE peppergo_queue_depth gauge"))
+	assert.Equal(t, 1, strings.Count(out, `peppergo_queue_slots_in_use{provider="batch-test"} 0`))
+}
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, "queue_timeout", errorClass(ErrQueueTimeout))
+	assert.Equal(t, "queue_full", errorClass(ErrQueueFull))
+	assert.Equal(t, "unsupported", errorClass(ErrEmbeddingsNotSupported))
+	assert.Equal(t, "canceled", errorClass(context.Canceled))
+	assert.Equal(t, "timeout", errorClass(context.DeadlineExceeded))
+	assert.Equal(t, "provider_error", errorClass(assert.AnError))
+}
+
+func TestServiceTracing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := tracing.NewFileExporter(path)
+	require.NoError(t, err)
+	tracer := tracing.NewTracer(zaptest.NewLogger(t), &tracing.TracerConfig{SampleRate: 1, Exporter: exporter})
+
+	service := NewService()
+	require.NoError(t, service.RegisterProvider(&batchTestProvider{}))
+
+	ctx, root := tracer.Start(context.Background(), "request", tracing.SpanKindServer)
+	_, err = service.Chat(ctx, "batch-test", chatRequest("a"))
+	require.NoError(t, err)
+	root.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var span tracing.SpanData
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &span))
+	assert.Equal(t, "proxy.chat", span.Name)
+	assert.Equal(t, root.SpanContext().SpanID.String(), span.ParentSpanID)
+	assert.Equal(t, "batch-test", span.Attributes["provider"])
+	assert.Equal(t, float64(2), span.Attributes["completion_tokens"])
+}
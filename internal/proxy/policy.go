@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pimentel/peppergo/internal/policy"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// SetPolicy enables policy enforcement on requests and responses
+func (s *Service) SetPolicy(engine *policy.Engine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = engine
+
+	// Cached responses were checked against the previous policy
+	if s.cache != nil {
+		s.cache = newResponseCache(s.cache.config)
+	}
+}
+
+func (s *Service) getPolicy() *policy.Engine {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy
+}
+
+// checkRequestPolicy validates a chat request, returning the request to send
+func (s *Service) checkRequestPolicy(ctx context.Context, providerName string, req *types.ChatRequest) (*types.ChatRequest, error) {
+	engine := s.getPolicy()
+	if engine == nil {
+		return req, nil
+	}
+
+	checked, err := engine.CheckRequest(providerName, TenantFromContext(ctx), req)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", providerName, err)
+	}
+	return checked, nil
+}
+
+// checkResponsePolicy validates the content of every choice of a response
+func (s *Service) checkResponsePolicy(ctx context.Context, providerName string, resp *types.ChatResponse) error {
+	engine := s.getPolicy()
+	if engine == nil {
+		return nil
+	}
+
+	for _, choice := range resp.Choices {
+		if err := engine.CheckOutput(providerName, TenantFromContext(ctx), choice.Message.Text()); err != nil {
+			return fmt.Errorf("provider %s: %w", providerName, err)
+		}
+	}
+	return nil
+}
+
+// streamPolicy checks streamed content as it accumulates
+type streamPolicy struct {
+	engine   *policy.Engine
+	provider string
+	tenant   string
+	choices  map[int]*policy.OutputStream
+}
+
+// newStreamPolicy returns nil when no output rules apply
+func (s *Service) newStreamPolicy(ctx context.Context, providerName string) *streamPolicy {
+	engine := s.getPolicy()
+	tenant := TenantFromContext(ctx)
+	if engine == nil || !engine.HasOutputRules(providerName, tenant) {
+		return nil
+	}
+	return &streamPolicy{
+		engine:   engine,
+		provider: providerName,
+		tenant:   tenant,
+		choices:  make(map[int]*policy.OutputStream),
+	}
+}
+
+// check validates a chunk against the content streamed so far. A choice's
+// complete content is checked again when it finishes.
+func (p *streamPolicy) check(resp *types.ChatResponse) error {
+	for _, choice := range resp.Choices {
+		stream, ok := p.choices[choice.Index]
+		if !ok {
+			stream = p.engine.NewOutputStream(p.provider, p.tenant)
+			p.choices[choice.Index] = stream
+		}
+		err := stream.Write(choice.Message.Content)
+		if err == nil && choice.FinishReason != "" {
+			err = stream.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("provider %s: %w", p.provider, err)
+		}
+	}
+	return nil
+}
+
+// contentFilteredChunk ends a stream whose content violated a policy
+func contentFilteredChunk(resp *types.ChatResponse) *types.ChatResponse {
+	return &types.ChatResponse{
+		ID:      resp.ID,
+		Object:  resp.Object,
+		Created: resp.Created,
+		Model:   resp.Model,
+		Choices: []types.Choice{{Message: types.Message{Role: "assistant"}, FinishReason: "content_filter"}},
+	}
+}
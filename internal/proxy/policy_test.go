@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/policy"
+)
+
+func TestServicePolicy(t *testing.T) {
+	engine, err := policy.NewEngine(zaptest.NewLogger(t), &policy.Config{
+		Providers: map[string]policy.Policy{
+			"echo-stream": {
+				AllowedModels: []string{"test-model"},
+				Input:         policy.ContentRules{DenyList: []string{"forbidden"}},
+				Output:        policy.ContentRules{DenyList: []string{"classified"}},
+			},
+		},
+		Tenants: map[string]policy.Policy{
+			"trial": {MaxTokens: 50},
+		},
+	})
+	require.NoError(t, err)
+
+	service := NewService()
+	service.SetPolicy(engine)
+	provider := &echoStreamProvider{}
+	require.NoError(t, service.RegisterProvider(provider))
+
+	_, err = service.Chat(context.Background(), "echo-stream", chatRequest("a forbidden word"))
+	assert.ErrorIs(t, err, policy.ErrPolicyViolation)
+	assert.Empty(t, provider.received)
+
+	req := chatRequest("hi")
+	req.Model = "other-model"
+	_, err = service.Chat(context.Background(), "echo-stream", req)
+	assert.ErrorIs(t, err, policy.ErrPolicyViolation)
+
+	req = chatRequest("hi")
+	req.MaxTokens = 100
+	_, err = service.Chat(WithTenant(context.Background(), "trial"), "echo-stream", req)
+	assert.ErrorIs(t, err, policy.ErrPolicyViolation)
+
+	_, err = service.Chat(context.Background(), "echo-stream", chatRequest("this is classified"))
+	var violation *policy.Violation
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, policy.StageOutput, violation.Stage)
+
+	ch, err := service.StreamChat(context.Background(), "echo-stream", chatRequest("public then classified then more"))
+	require.NoError(t, err)
+
+	var out strings.Builder
+	var finishReason string
+	for resp := range ch {
+		out.WriteString(resp.Choices[0].Message.Content)
+		finishReason = resp.Choices[0].FinishReason
+	}
+	assert.Equal(t, "content_filter", finishReason)
+	assert.NotContains(t, out.String(), "classified")
+	assert.True(t, strings.HasPrefix(out.String(), "public"))
+}
@@ -0,0 +1,194 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// providerEntry is a registered provider instance with its in-flight request count.
+// Replaced and removed entries are retired: they accept no new requests and are
+// closed once their in-flight requests have drained.
+type providerEntry struct {
+	provider types.Provider
+	disabled bool
+	inflight int
+	retired  bool
+	drained  chan struct{}
+	mu       sync.Mutex
+}
+
+func newProviderEntry(provider types.Provider) *providerEntry {
+	return &providerEntry{
+		provider: provider,
+		drained:  make(chan struct{}),
+	}
+}
+
+func (e *providerEntry) acquire() {
+	e.mu.Lock()
+	e.inflight++
+	e.mu.Unlock()
+}
+
+func (e *providerEntry) release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.inflight--
+	if e.retired && e.inflight == 0 {
+		close(e.drained)
+	}
+}
+
+func (e *providerEntry) inFlight() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.inflight
+}
+
+// retire marks the entry as replaced and closes the provider, if it
+// implements io.Closer, after its in-flight requests complete
+func (e *providerEntry) retire() {
+	e.mu.Lock()
+	e.retired = true
+	if e.inflight == 0 {
+		close(e.drained)
+	}
+	e.mu.Unlock()
+
+	go func() {
+		<-e.drained
+		if closer, ok := e.provider.(io.Closer); ok {
+			closer.Close()
+		}
+	}()
+}
+
+// lease returns an enabled provider and marks a request to it as in flight
+// until the returned release function is called
+func (s *Service) lease(name string) (types.Provider, func(), error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, exists := s.providers[name]
+	if !exists {
+		return nil, nil, fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	if entry.disabled {
+		return nil, nil, fmt.Errorf("%w: %s", ErrProviderDisabled, name)
+	}
+
+	entry.acquire()
+	return entry.provider, entry.release, nil
+}
+
+// ReplaceProvider swaps a registered provider for a new instance with the same
+// name, e.g. to rotate its API key. Requests already sent to the old instance
+// are allowed to complete. The disabled state of the provider is kept.
+func (s *Service) ReplaceProvider(provider types.Provider) error {
+	return s.UpdateProviders(ProviderChanges{Replace: []types.Provider{provider}})
+}
+
+// RemoveProvider unregisters a provider. Requests already sent to it are
+// allowed to complete.
+func (s *Service) RemoveProvider(name string) error {
+	return s.UpdateProviders(ProviderChanges{Remove: []string{name}})
+}
+
+// SetProviderEnabled enables or disables a provider. Disabled providers stay
+// registered but reject new requests with ErrProviderDisabled.
+func (s *Service) SetProviderEnabled(name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.providers[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+
+	entry.disabled = !enabled
+	return nil
+}
+
+// resetProviderState forgets the health and circuit state of a replaced provider.
+// The caller must hold s.mu.
+func (s *Service) resetProviderState(name string) {
+	delete(s.health, name)
+	delete(s.circuits, name)
+}
+
+// ProviderChanges is a set of provider changes applied together by UpdateProviders
+type ProviderChanges struct {
+	// Add registers new providers
+	Add []types.Provider
+
+	// Replace swaps registered providers for new instances with the same name
+	Replace []types.Provider
+
+	// Remove unregisters providers by name
+	Remove []string
+
+	// Enabled enables or disables providers by name, after the other changes
+	Enabled map[string]bool
+}
+
+// UpdateProviders applies a set of provider changes atomically: requests see
+// either none or all of them, and if any change is invalid none is applied.
+// Replaced and removed providers drain as with ReplaceProvider.
+func (s *Service) UpdateProviders(changes ProviderChanges) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make(map[string]bool, len(s.providers))
+	for name := range s.providers {
+		names[name] = true
+	}
+	for _, name := range changes.Remove {
+		if !names[name] {
+			return fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+		}
+		delete(names, name)
+	}
+	for _, provider := range changes.Replace {
+		if _, exists := s.providers[provider.Name()]; !exists || !names[provider.Name()] {
+			return fmt.Errorf("%w: %s", ErrProviderNotFound, provider.Name())
+		}
+	}
+	for _, provider := range changes.Add {
+		if names[provider.Name()] {
+			return fmt.Errorf("%w: %s", ErrProviderExists, provider.Name())
+		}
+		names[provider.Name()] = true
+	}
+	for name := range changes.Enabled {
+		if !names[name] {
+			return fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+		}
+	}
+
+	for _, name := range changes.Remove {
+		entry := s.providers[name]
+		delete(s.providers, name)
+		s.resetProviderState(name)
+		entry.retire()
+	}
+	for _, provider := range changes.Replace {
+		name := provider.Name()
+		old := s.providers[name]
+		entry := newProviderEntry(provider)
+		entry.disabled = old.disabled
+		s.providers[name] = entry
+		s.resetProviderState(name)
+		old.retire()
+	}
+	for _, provider := range changes.Add {
+		s.providers[provider.Name()] = newProviderEntry(provider)
+	}
+	for name, enabled := range changes.Enabled {
+		s.providers[name].disabled = !enabled
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// blockingProvider holds chat requests until released and records when it is closed
+type blockingProvider struct {
+	model   string
+	started chan struct{}
+	unblock chan struct{}
+	closed  chan struct{}
+}
+
+func newBlockingProvider(model string) *blockingProvider {
+	return &blockingProvider{
+		model:   model,
+		started: make(chan struct{}, 1),
+		unblock: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (p *blockingProvider) Name() string { return "blocking" }
+
+func (p *blockingProvider) AvailableModels() []string { return []string{p.model} }
+
+func (p *blockingProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	p.started <- struct{}{}
+	<-p.unblock
+	return &types.ChatResponse{Model: p.model}, nil
+}
+
+func (p *blockingProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *blockingProvider) Close() error {
+	close(p.closed)
+	return nil
+}
+
+func TestReplaceProviderDrains(t *testing.T) {
+	service := NewService()
+	old := newBlockingProvider("old")
+	require.NoError(t, service.RegisterProvider(old))
+
+	result := make(chan *types.ChatResponse)
+	go func() {
+		resp, _ := service.Chat(context.Background(), "blocking", chatRequest("hi"))
+		result <- resp
+	}()
+	<-old.started
+
+	current := newBlockingProvider("new")
+	close(current.unblock)
+	require.NoError(t, service.ReplaceProvider(current))
+
+	resp, err := service.Chat(context.Background(), "blocking", chatRequest("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", resp.Model)
+
+	select {
+	case <-old.closed:
+		t.Fatal("old provider closed before draining")
+	default:
+	}
+
+	close(old.unblock)
+	assert.Equal(t, "old", (<-result).Model)
+	select {
+	case <-old.closed:
+	case <-time.After(time.Second):
+		t.Fatal("old provider not closed after draining")
+	}
+
+	assert.ErrorIs(t, service.ReplaceProvider(&flakyProvider{}), ErrProviderNotFound)
+}
+
+func TestProviderLifecycle(t *testing.T) {
+	service := NewService()
+	require.NoError(t, service.RegisterProvider(&flakyProvider{}))
+	assert.ErrorIs(t, service.RegisterProvider(&flakyProvider{}), ErrProviderExists)
+
+	require.NoError(t, service.SetProviderEnabled("flaky", false))
+	_, err := service.Chat(context.Background(), "flaky", chatRequest("hi"))
+	assert.ErrorIs(t, err, ErrProviderDisabled)
+	assert.False(t, service.Ready())
+
+	status, err := service.ProviderStatus("flaky")
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	require.NoError(t, service.SetProviderEnabled("flaky", true))
+	_, err = service.Chat(context.Background(), "flaky", chatRequest("hi"))
+	assert.NoError(t, err)
+
+	require.NoError(t, service.RemoveProvider("flaky"))
+	_, err = service.Chat(context.Background(), "flaky", chatRequest("hi"))
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+	assert.ErrorIs(t, service.RemoveProvider("flaky"), ErrProviderNotFound)
+	assert.ErrorIs(t, service.SetProviderEnabled("flaky", true), ErrProviderNotFound)
+}
+
+func TestUpdateProviders(t *testing.T) {
+	service := NewService()
+	require.NoError(t, service.RegisterProvider(&flakyProvider{}))
+
+	err := service.UpdateProviders(ProviderChanges{
+		Add:    []types.Provider{newBlockingProvider("m")},
+		Remove: []string{"missing"},
+	})
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+	assert.Equal(t, []string{"flaky"}, service.ListProviders(), "nothing applied")
+
+	err = service.UpdateProviders(ProviderChanges{
+		Add:     []types.Provider{newBlockingProvider("m")},
+		Remove:  []string{"flaky"},
+		Enabled: map[string]bool{"blocking": false},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"blocking"}, service.ListProviders())
+	_, err = service.Chat(context.Background(), "blocking", chatRequest("hi"))
+	assert.ErrorIs(t, err, ErrProviderDisabled)
+
+	err = service.UpdateProviders(ProviderChanges{Enabled: map[string]bool{"flaky": true}})
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+}
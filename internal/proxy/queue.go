@@ -0,0 +1,238 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Priority is the scheduling class of a request waiting for a provider slot
+type Priority int
+
+// Priority classes, from lowest to highest
+const (
+	PriorityBatch Priority = iota
+	PriorityDefault
+	PriorityInteractive
+
+	numPriorities = int(PriorityInteractive) + 1
+)
+
+var (
+	// ErrQueueTimeout is returned when a request waited longer than the queue time limit
+	ErrQueueTimeout = errors.New("timed out waiting for provider capacity")
+
+	// ErrQueueFull is returned when a provider queue is at its maximum depth
+	ErrQueueFull = errors.New("provider queue is full")
+)
+
+// String returns the name of the priority class
+func (p Priority) String() string {
+	switch p {
+	case PriorityBatch:
+		return "batch"
+	case PriorityInteractive:
+		return "interactive"
+	default:
+		return "default"
+	}
+}
+
+// ParsePriority parses a priority class name
+func ParsePriority(name string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "batch", "low":
+		return PriorityBatch, nil
+	case "", "default", "normal":
+		return PriorityDefault, nil
+	case "interactive", "high":
+		return PriorityInteractive, nil
+	default:
+		return PriorityDefault, fmt.Errorf("unknown priority: %s", name)
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context carrying the request priority class
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the request priority class, defaulting to PriorityDefault
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityDefault
+}
+
+// QueueConfig bounds concurrency for a single provider
+type QueueConfig struct {
+	// MaxConcurrent is the number of requests allowed in flight at once
+	MaxConcurrent int
+
+	// MaxQueueTime is how long a request may wait for a slot (0 waits until the context ends)
+	MaxQueueTime time.Duration
+
+	// MaxQueueDepth is the maximum number of waiting requests (0 is unbounded)
+	MaxQueueDepth int
+}
+
+// QueueStats reports the state of a provider queue
+type QueueStats struct {
+	Provider      string         `json:"provider"`
+	MaxConcurrent int            `json:"max_concurrent"`
+	InFlight      int            `json:"in_flight"`
+	Depth         map[string]int `json:"depth"`
+	Admitted      int64          `json:"admitted"`
+	TimedOut      int64          `json:"timed_out"`
+	Rejected      int64          `json:"rejected"`
+	WaitTotal     time.Duration  `json:"wait_total"`
+	WaitMax       time.Duration  `json:"wait_max"`
+}
+
+// providerQueue is a priority semaphore guarding a provider's concurrency slots
+type providerQueue struct {
+	config   QueueConfig
+	inFlight int
+	waiters  [numPriorities][]*queueWaiter
+	stats    QueueStats
+	mu       sync.Mutex
+}
+
+type queueWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newProviderQueue(provider string, config QueueConfig) *providerQueue {
+	return &providerQueue{
+		config: config,
+		stats:  QueueStats{Provider: provider},
+	}
+}
+
+// acquire waits for a slot and returns a function releasing it
+func (q *providerQueue) acquire(ctx context.Context, priority Priority) (func(), error) {
+	start := time.Now()
+
+	q.mu.Lock()
+	if q.inFlight < q.config.MaxConcurrent && q.waitingLocked() == 0 {
+		q.inFlight++
+		q.recordAdmitLocked(0)
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	}
+	if q.config.MaxQueueDepth > 0 && q.waitingLocked() >= q.config.MaxQueueDepth {
+		q.stats.Rejected++
+		q.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	w := &queueWaiter{ready: make(chan struct{})}
+	q.waiters[priority] = append(q.waiters[priority], w)
+	q.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if q.config.MaxQueueTime > 0 {
+		timer := time.NewTimer(q.config.MaxQueueTime)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		q.mu.Lock()
+		q.recordAdmitLocked(time.Since(start))
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrQueueTimeout
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if w.granted {
+		// The slot was handed over just as we gave up; pass it on
+		q.releaseLocked()
+	} else {
+		q.removeLocked(priority, w)
+	}
+	if err == ErrQueueTimeout {
+		q.stats.TimedOut++
+	}
+	return nil, err
+}
+
+func (q *providerQueue) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.releaseLocked()
+		})
+	}
+}
+
+// releaseLocked frees a slot, handing it to the highest-priority waiter
+func (q *providerQueue) releaseLocked() {
+	for p := numPriorities - 1; p >= 0; p-- {
+		if len(q.waiters[p]) == 0 {
+			continue
+		}
+		w := q.waiters[p][0]
+		q.waiters[p] = q.waiters[p][1:]
+		w.granted = true
+		close(w.ready)
+		return
+	}
+	q.inFlight--
+}
+
+func (q *providerQueue) removeLocked(priority Priority, w *queueWaiter) {
+	waiters := q.waiters[priority]
+	for i, candidate := range waiters {
+		if candidate == w {
+			q.waiters[priority] = append(waiters[:i], waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (q *providerQueue) waitingLocked() int {
+	total := 0
+	for _, waiters := range q.waiters {
+		total += len(waiters)
+	}
+	return total
+}
+
+func (q *providerQueue) recordAdmitLocked(wait time.Duration) {
+	q.stats.Admitted++
+	q.stats.WaitTotal += wait
+	if wait > q.stats.WaitMax {
+		q.stats.WaitMax = wait
+	}
+}
+
+// snapshot returns the current queue statistics
+func (q *providerQueue) snapshot() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := q.stats
+	stats.MaxConcurrent = q.config.MaxConcurrent
+	stats.InFlight = q.inFlight
+	stats.Depth = make(map[string]int, numPriorities)
+	for p, waiters := range q.waiters {
+		stats.Depth[Priority(p).String()] = len(waiters)
+	}
+	return stats
+}
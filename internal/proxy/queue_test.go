@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func chatRequest(content string) *types.ChatRequest {
+	return &types.ChatRequest{
+		Model:    "test-model",
+		Messages: []types.Message{{Role: "user", Content: content}},
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for name, want := range map[string]Priority{
+		"":            PriorityDefault,
+		"batch":       PriorityBatch,
+		"LOW":         PriorityBatch,
+		"normal":      PriorityDefault,
+		"interactive": PriorityInteractive,
+		"high":        PriorityInteractive,
+	} {
+		got, err := ParsePriority(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	_, err := ParsePriority("urgent")
+	assert.Error(t, err)
+}
+
+func TestProviderQueue(t *testing.T) {
+	t.Run("admits by priority", func(t *testing.T) {
+		q := newProviderQueue("test", QueueConfig{MaxConcurrent: 1})
+		release, err := q.acquire(context.Background(), PriorityDefault)
+		require.NoError(t, err)
+
+		order := make(chan Priority, 3)
+		for _, p := range []Priority{PriorityBatch, PriorityDefault, PriorityInteractive} {
+			p := p
+			go func() {
+				release, err := q.acquire(context.Background(), p)
+				if err != nil {
+					return
+				}
+				order <- p
+				release()
+			}()
+			require.Eventually(t, func() bool {
+				return q.snapshot().Depth[p.String()] == 1
+			}, time.Second, time.Millisecond)
+		}
+
+		release()
+		assert.Equal(t, PriorityInteractive, <-order)
+		assert.Equal(t, PriorityDefault, <-order)
+		assert.Equal(t, PriorityBatch, <-order)
+
+		stats := q.snapshot()
+		assert.Equal(t, int64(4), stats.Admitted)
+		assert.Equal(t, 0, stats.InFlight)
+		assert.Greater(t, stats.WaitMax, time.Duration(0))
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		q := newProviderQueue("test", QueueConfig{MaxConcurrent: 1, MaxQueueTime: 10 * time.Millisecond})
+		release, err := q.acquire(context.Background(), PriorityDefault)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = q.acquire(context.Background(), PriorityInteractive)
+		assert.ErrorIs(t, err, ErrQueueTimeout)
+
+		stats := q.snapshot()
+		assert.Equal(t, int64(1), stats.TimedOut)
+		assert.Equal(t, 0, stats.Depth["interactive"])
+	})
+
+	t.Run("rejects when full", func(t *testing.T) {
+		q := newProviderQueue("test", QueueConfig{MaxConcurrent: 1, MaxQueueDepth: 1})
+		release, err := q.acquire(context.Background(), PriorityDefault)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		waited := make(chan error, 1)
+		go func() {
+			_, err := q.acquire(ctx, PriorityDefault)
+			waited <- err
+		}()
+		require.Eventually(t, func() bool {
+			return q.snapshot().Depth["default"] == 1
+		}, time.Second, time.Millisecond)
+
+		_, err = q.acquire(context.Background(), PriorityDefault)
+		assert.ErrorIs(t, err, ErrQueueFull)
+		assert.Equal(t, int64(1), q.snapshot().Rejected)
+
+		cancel()
+		assert.ErrorIs(t, <-waited, context.Canceled)
+		release()
+		assert.Equal(t, 0, q.snapshot().InFlight)
+	})
+}
+
+func TestServiceQueue(t *testing.T) {
+	service := NewService()
+	provider := &batchTestProvider{block: make(chan struct{})}
+	require.NoError(t, service.RegisterProvider(provider))
+	require.NoError(t, service.SetQueueConfig("batch-test", QueueConfig{MaxConcurrent: 1, MaxQueueTime: 20 * time.Millisecond}))
+	assert.Error(t, service.SetQueueConfig("batch-test", QueueConfig{}))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.Chat(context.Background(), "batch-test", chatRequest("a"))
+		done <- err
+	}()
+	require.Eventually(t, func() bool {
+		return service.QueueStats()[0].InFlight == 1
+	}, time.Second, time.Millisecond)
+
+	_, err := service.Chat(context.Background(), "batch-test", chatRequest("b"))
+	assert.ErrorIs(t, err, ErrQueueTimeout)
+
+	close(provider.block)
+	require.NoError(t, <-done)
+
+	stats := service.QueueStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, "batch-test", stats[0].Provider)
+	assert.Equal(t, 0, stats[0].InFlight)
+	assert.Equal(t, int64(1), stats[0].TimedOut)
+}
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// CallRecord summarizes a single provider call made while serving a request
+type CallRecord struct {
+	Operation string
+	Provider  string
+	Model     string
+	Usage     types.Usage
+	Duration  time.Duration
+	Err       error
+}
+
+// RequestRecord collects the provider calls made on behalf of one request,
+// e.g. for access and audit logs
+type RequestRecord struct {
+	calls []CallRecord
+	mu    sync.Mutex
+}
+
+type recordKey struct{}
+
+// WithRequestRecord returns a context in which provider calls are collected
+// into the returned record. A record already present in ctx is reused.
+func WithRequestRecord(ctx context.Context) (context.Context, *RequestRecord) {
+	if record := recordFromContext(ctx); record != nil {
+		return ctx, record
+	}
+	record := &RequestRecord{}
+	return context.WithValue(ctx, recordKey{}, record), record
+}
+
+func recordFromContext(ctx context.Context) *RequestRecord {
+	record, _ := ctx.Value(recordKey{}).(*RequestRecord)
+	return record
+}
+
+func (r *RequestRecord) add(call CallRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+// Calls returns the recorded provider calls
+func (r *RequestRecord) Calls() []CallRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]CallRecord(nil), r.calls...)
+}
+
+// Summary returns the provider and model of the last call and the total usage of all calls
+func (r *RequestRecord) Summary() (provider, model string, usage types.Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, call := range r.calls {
+		provider, model = call.Provider, call.Model
+		usage.PromptTokens += call.Usage.PromptTokens
+		usage.CompletionTokens += call.Usage.CompletionTokens
+		usage.TotalTokens += call.Usage.TotalTokens
+	}
+	return provider, model, usage
+}
@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/internal/redact"
+	"github.com/pimentel/peppergo/internal/tracing"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// SetRedactor enables the redaction stage for chat and embeddings requests. Depending on
+// the redactor mode, sensitive values in outgoing messages are masked and
+// restored in the response, cause the request to be rejected, or are only logged.
+func (s *Service) SetRedactor(r *redact.Redactor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redactor = r
+}
+
+// redactRequest applies the redaction stage to an outgoing request. In mask
+// mode it returns a masked copy of req and a session to restore the response.
+func (s *Service) redactRequest(ctx context.Context, providerName string, req *types.ChatRequest) (*types.ChatRequest, *redact.Session, error) {
+	s.mu.RLock()
+	r := s.redactor
+	s.mu.RUnlock()
+	if r == nil {
+		return req, nil, nil
+	}
+
+	session := r.NewSession()
+	masked := *req
+	masked.Messages = make([]types.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		msg.Content = session.Mask(msg.Content)
+		if len(msg.Parts) > 0 {
+			parts := make([]types.ContentPart, len(msg.Parts))
+			for j, part := range msg.Parts {
+				if part.Type == types.ContentTypeText {
+					part.Text = session.Mask(part.Text)
+				}
+				parts[j] = part
+			}
+			msg.Parts = parts
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]types.ToolCall, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				call.Function.Arguments = session.Mask(call.Function.Arguments)
+				calls[j] = call
+			}
+			msg.ToolCalls = calls
+		}
+		masked.Messages[i] = msg
+	}
+
+	mask, err := redactionOutcome(ctx, r, session, providerName)
+	if err != nil || !mask {
+		return req, nil, err
+	}
+	return &masked, session, nil
+}
+
+// redactEmbedding applies the redaction stage to the input of an embeddings
+// request. Embeddings carry no text back, so masked values are not restored.
+func (s *Service) redactEmbedding(ctx context.Context, providerName string, req *types.EmbeddingRequest) (*types.EmbeddingRequest, error) {
+	s.mu.RLock()
+	r := s.redactor
+	s.mu.RUnlock()
+	if r == nil {
+		return req, nil
+	}
+
+	session := r.NewSession()
+	masked := *req
+	masked.Input = make(types.EmbeddingInput, len(req.Input))
+	for i, input := range req.Input {
+		masked.Input[i] = session.Mask(input)
+	}
+
+	mask, err := redactionOutcome(ctx, r, session, providerName)
+	if err != nil || !mask {
+		return req, err
+	}
+	return &masked, nil
+}
+
+// redactionOutcome logs the findings of a session and reports whether the
+// masked request is to be sent, or an error if the request is blocked
+func redactionOutcome(ctx context.Context, r *redact.Redactor, session *redact.Session, providerName string) (bool, error) {
+	findings := session.Findings()
+	if len(findings) == 0 {
+		return false, nil
+	}
+
+	session.Log(zap.String("provider", providerName), zap.String("tenant", TenantFromContext(ctx)))
+	tracing.SpanFromContext(ctx).SetAttribute("redaction.findings", len(findings))
+
+	switch r.Mode() {
+	case redact.ModeBlock:
+		return false, fmt.Errorf("provider %s: %w", providerName, &redact.BlockedError{Findings: findings})
+	case redact.ModeAudit:
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+// restoreResponse replaces placeholders in a response with the original values
+func restoreResponse(session *redact.Session, resp *types.ChatResponse) {
+	for i := range resp.Choices {
+		msg := &resp.Choices[i].Message
+		msg.Content = session.Restore(msg.Content)
+		for j := range msg.Parts {
+			msg.Parts[j].Text = session.Restore(msg.Parts[j].Text)
+		}
+		for j := range msg.ToolCalls {
+			msg.ToolCalls[j].Function.Arguments = session.Restore(msg.ToolCalls[j].Function.Arguments)
+		}
+	}
+}
+
+// streamRestorer restores placeholders in streamed choices. Tool call
+// arguments stream as fragments too, so each choice keeps a second restorer
+// for the arguments of the tool call currently being streamed, and the
+// index of that call so held back text is attributed to it.
+type streamRestorer struct {
+	session   *redact.Session
+	restorers map[int]*redact.StreamRestorer
+	arguments map[int]*redact.StreamRestorer
+	calls     map[int]*int
+}
+
+func newStreamRestorer(session *redact.Session) *streamRestorer {
+	return &streamRestorer{
+		session:   session,
+		restorers: make(map[int]*redact.StreamRestorer),
+		arguments: make(map[int]*redact.StreamRestorer),
+		calls:     make(map[int]*int),
+	}
+}
+
+// restorer returns the restorer for a choice, creating it on first use
+func (r *streamRestorer) restorer(restorers map[int]*redact.StreamRestorer, index int) *redact.StreamRestorer {
+	restorer, ok := restorers[index]
+	if !ok {
+		restorer = r.session.NewStreamRestorer()
+		restorers[index] = restorer
+	}
+	return restorer
+}
+
+// restore rewrites a chunk in place, flushing held back text when a choice finishes
+func (r *streamRestorer) restore(resp *types.ChatResponse) {
+	for i := range resp.Choices {
+		choice := &resp.Choices[i]
+		restorer := r.restorer(r.restorers, choice.Index)
+
+		choice.Message.Content = restorer.Write(choice.Message.Content)
+		if choice.FinishReason != "" {
+			choice.Message.Content += restorer.Flush()
+		}
+
+		if len(choice.Message.ToolCalls) > 0 || choice.FinishReason != "" {
+			choice.Message.ToolCalls = r.restoreToolCalls(choice.Index, choice.Message.ToolCalls, choice.FinishReason != "")
+		}
+	}
+}
+
+// restoreToolCalls restores streamed tool call arguments. Text held back from
+// the previous call is emitted as its own fragment before a new call starts.
+func (r *streamRestorer) restoreToolCalls(index int, calls []types.ToolCall, finished bool) []types.ToolCall {
+	restored := make([]types.ToolCall, 0, len(calls)+1)
+	for _, call := range calls {
+		if call.ID != "" {
+			if rest, ok := r.arguments[index]; ok {
+				if text := rest.Flush(); text != "" {
+					restored = append(restored, types.ToolCall{Index: r.calls[index], Function: types.FunctionCall{Arguments: text}})
+				}
+			}
+		}
+		if call.Index != nil {
+			r.calls[index] = call.Index
+		}
+		call.Function.Arguments = r.restorer(r.arguments, index).Write(call.Function.Arguments)
+		restored = append(restored, call)
+	}
+	if finished {
+		if rest, ok := r.arguments[index]; ok {
+			if text := rest.Flush(); text != "" {
+				if len(restored) > 0 {
+					restored[len(restored)-1].Function.Arguments += text
+				} else {
+					restored = append(restored, types.ToolCall{Index: r.calls[index], Function: types.FunctionCall{Arguments: text}})
+				}
+			}
+		}
+	}
+	if len(restored) == 0 {
+		return nil
+	}
+	return restored
+}
+
+// flush returns a final chunk with any text still held back, or nil
+func (r *streamRestorer) flush() *types.ChatResponse {
+	choices := make(map[int]*types.Choice)
+	choice := func(index int) *types.Choice {
+		if c, ok := choices[index]; ok {
+			return c
+		}
+		c := &types.Choice{Index: index, Message: types.Message{Role: "assistant"}}
+		choices[index] = c
+		return c
+	}
+	for index, restorer := range r.restorers {
+		if text := restorer.Flush(); text != "" {
+			choice(index).Message.Content = text
+		}
+	}
+	for index, restorer := range r.arguments {
+		if text := restorer.Flush(); text != "" {
+			c := choice(index)
+			c.Message.ToolCalls = []types.ToolCall{{Index: r.calls[index], Function: types.FunctionCall{Arguments: text}}}
+		}
+	}
+	if len(choices) == 0 {
+		return nil
+	}
+
+	resp := &types.ChatResponse{Object: "chat.completion.chunk"}
+	for _, c := range choices {
+		resp.Choices = append(resp.Choices, *c)
+	}
+	sort.Slice(resp.Choices, func(i, j int) bool { return resp.Choices[i].Index < resp.Choices[j].Index })
+	return resp
+}
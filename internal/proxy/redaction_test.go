@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/redact"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// echoStreamProvider repeats the last message, streaming it a few bytes at a
+// time; tool calls in the last message are echoed back as tool calls
+type echoStreamProvider struct {
+	batchTestProvider
+	received  string
+	arguments string
+}
+
+func (p *echoStreamProvider) Name() string { return "echo-stream" }
+
+func (p *echoStreamProvider) Chat(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	p.received = last.Content
+	if len(last.ToolCalls) > 0 {
+		p.arguments = last.ToolCalls[0].Function.Arguments
+	}
+	return &types.ChatResponse{
+		Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: "you said " + p.received, ToolCalls: last.ToolCalls}}},
+	}, nil
+}
+
+func (p *echoStreamProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	p.received = last.Content
+	ch := make(chan *types.ChatResponse)
+	go func() {
+		defer close(ch)
+		content := p.received
+		for len(content) > 0 {
+			n := 3
+			if n > len(content) {
+				n = len(content)
+			}
+			ch <- &types.ChatResponse{Choices: []types.Choice{{Message: types.Message{Content: content[:n]}}}}
+			content = content[n:]
+		}
+		for _, call := range last.ToolCalls {
+			p.arguments = call.Function.Arguments
+			arguments := call.Function.Arguments
+			delta := call
+			for len(arguments) > 0 {
+				n := 3
+				if n > len(arguments) {
+					n = len(arguments)
+				}
+				delta.Function.Arguments = arguments[:n]
+				ch <- &types.ChatResponse{Choices: []types.Choice{{Message: types.Message{ToolCalls: []types.ToolCall{delta}}}}}
+				delta = types.ToolCall{}
+				arguments = arguments[n:]
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (p *echoStreamProvider) Embed(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	p.received = strings.Join(req.Input, "\n")
+	resp := &types.EmbeddingResponse{}
+	for i := range req.Input {
+		resp.Data = append(resp.Data, types.Embedding{Index: i, Embedding: []float32{1}})
+	}
+	return resp, nil
+}
+
+func newRedactingService(t *testing.T, mode redact.Mode) (*Service, *echoStreamProvider) {
+	t.Helper()
+	r, err := redact.NewRedactor(zaptest.NewLogger(t), &redact.Config{Mode: mode})
+	require.NoError(t, err)
+
+	service := NewService()
+	service.SetRedactor(r)
+	provider := &echoStreamProvider{}
+	require.NoError(t, service.RegisterProvider(provider))
+	return service, provider
+}
+
+func TestServiceRedaction(t *testing.T) {
+	prompt := "email alice@example.com about card 4111-1111-1111-1111"
+
+	t.Run("mask", func(t *testing.T) {
+		service, provider := newRedactingService(t, redact.ModeMask)
+		req := chatRequest(prompt)
+
+		resp, err := service.Chat(context.Background(), "echo-stream", req)
+		require.NoError(t, err)
+		assert.Equal(t, "email [EMAIL_1] about card [CREDIT_CARD_1]", provider.received)
+		assert.Equal(t, "you said "+prompt, resp.Choices[0].Message.Content)
+		assert.Equal(t, prompt, req.Messages[0].Content, "caller's request must not be modified")
+	})
+
+	t.Run("mask stream", func(t *testing.T) {
+		service, provider := newRedactingService(t, redact.ModeMask)
+
+		ch, err := service.StreamChat(context.Background(), "echo-stream", chatRequest(prompt))
+		require.NoError(t, err)
+
+		var out strings.Builder
+		for resp := range ch {
+			out.WriteString(resp.Choices[0].Message.Content)
+		}
+		assert.NotContains(t, provider.received, "alice")
+		assert.Equal(t, prompt, out.String())
+	})
+
+	t.Run("mask tool call arguments", func(t *testing.T) {
+		service, provider := newRedactingService(t, redact.ModeMask)
+		arguments := `{"to":["alice@example.com"],"card":"4111-1111-1111-1111"}`
+		req := &types.ChatRequest{Model: "test-model", Messages: []types.Message{{
+			Role:      "assistant",
+			ToolCalls: []types.ToolCall{{ID: "call_1", Type: "function", Function: types.FunctionCall{Name: "send", Arguments: arguments}}},
+		}}}
+
+		resp, err := service.Chat(context.Background(), "echo-stream", req)
+		require.NoError(t, err)
+		assert.Equal(t, `{"to":["[EMAIL_1]"],"card":"[CREDIT_CARD_1]"}`, provider.arguments)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		assert.Equal(t, arguments, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+		assert.Equal(t, arguments, req.Messages[0].ToolCalls[0].Function.Arguments, "caller's request must not be modified")
+
+		ch, err := service.StreamChat(context.Background(), "echo-stream", req)
+		require.NoError(t, err)
+
+		var streamed strings.Builder
+		for resp := range ch {
+			for _, call := range resp.Choices[0].Message.ToolCalls {
+				streamed.WriteString(call.Function.Arguments)
+			}
+		}
+		assert.NotContains(t, provider.arguments, "alice")
+		assert.Equal(t, arguments, streamed.String())
+	})
+
+	t.Run("embeddings", func(t *testing.T) {
+		service, provider := newRedactingService(t, redact.ModeMask)
+		req := &types.EmbeddingRequest{Input: types.EmbeddingInput{"hello", prompt}}
+
+		resp, err := service.Embed(context.Background(), "echo-stream", req)
+		require.NoError(t, err)
+		assert.Len(t, resp.Data, 2)
+		assert.Equal(t, "hello\nemail [EMAIL_1] about card [CREDIT_CARD_1]", provider.received)
+		assert.Equal(t, prompt, req.Input[1], "caller's request must not be modified")
+
+		service, _ = newRedactingService(t, redact.ModeBlock)
+		_, err = service.Embed(context.Background(), "echo-stream", req)
+		assert.ErrorIs(t, err, redact.ErrSensitiveData)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		service, provider := newRedactingService(t, redact.ModeBlock)
+
+		_, err := service.Chat(context.Background(), "echo-stream", chatRequest(prompt))
+		assert.ErrorIs(t, err, redact.ErrSensitiveData)
+		assert.Empty(t, provider.received)
+
+		_, err = service.Chat(context.Background(), "echo-stream", chatRequest("nothing to see"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		service, provider := newRedactingService(t, redact.ModeAudit)
+
+		_, err := service.Chat(context.Background(), "echo-stream", chatRequest(prompt))
+		require.NoError(t, err)
+		assert.Equal(t, prompt, provider.received)
+	})
+}
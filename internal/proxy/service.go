@@ -2,23 +2,152 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/internal/policy"
+	"github.com/pimentel/peppergo/internal/redact"
+	"github.com/pimentel/peppergo/internal/tokenizer"
+	"github.com/pimentel/peppergo/internal/tracing"
 	"github.com/pimentel/peppergo/pkg/types"
 )
 
+// DefaultEmbeddingBatchSize is the maximum number of inputs sent to a
+// provider in a single embeddings call
+const DefaultEmbeddingBatchSize = 256
+
+var (
+	// ErrProviderNotFound is returned when no provider is registered under the requested name
+	ErrProviderNotFound = errors.New("provider not found")
+
+	// ErrProviderExists is returned when registering a provider under a name that is already taken
+	ErrProviderExists = errors.New("provider already registered")
+
+	// ErrProviderDisabled is returned when the requested provider has been disabled
+	ErrProviderDisabled = errors.New("provider disabled")
+
+	// ErrEmbeddingsNotSupported is returned when a provider does not implement types.EmbeddingProvider
+	ErrEmbeddingsNotSupported = errors.New("provider does not support embeddings")
+)
+
 // Service represents the LLM proxy service
 type Service struct {
-	providers map[string]types.Provider
-	mu        sync.RWMutex
+	providers          map[string]*providerEntry
+	queues             map[string]*providerQueue
+	circuits           map[string]*circuitBreaker
+	circuitConfig      CircuitConfig
+	health             map[string]*healthState
+	cache              *responseCache
+	metrics            *Metrics
+	redactor           *redact.Redactor
+	policy             *policy.Engine
+	contextWindow      *contextwindow.Manager
+	tokenizer          *tokenizer.Registry
+	embeddingBatchSize int
+	mu                 sync.RWMutex
 }
 
 // NewService creates a new proxy service
 func NewService() *Service {
 	return &Service{
-		providers: make(map[string]types.Provider),
+		providers:          make(map[string]*providerEntry),
+		queues:             make(map[string]*providerQueue),
+		circuits:           make(map[string]*circuitBreaker),
+		health:             make(map[string]*healthState),
+		embeddingBatchSize: DefaultEmbeddingBatchSize,
+	}
+}
+
+// SetEmbeddingBatchSize sets the maximum number of inputs per provider embeddings call
+func (s *Service) SetEmbeddingBatchSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if size < 1 {
+		size = DefaultEmbeddingBatchSize
+	}
+	s.embeddingBatchSize = size
+}
+
+// SetMetrics enables request metrics
+func (s *Service) SetMetrics(m *Metrics) {
+	s.mu.Lock()
+	s.metrics = m
+	s.mu.Unlock()
+
+	m.mu.Lock()
+	m.queueStats = s.QueueStats
+	m.mu.Unlock()
+}
+
+func (s *Service) getMetrics() *Metrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.metrics
+}
+
+// SetQueueConfig bounds the number of concurrent requests sent to a provider.
+// Requests beyond the limit wait in a priority queue; see WithPriority.
+func (s *Service) SetQueueConfig(providerName string, config QueueConfig) error {
+	if config.MaxConcurrent < 1 {
+		return fmt.Errorf("invalid queue config for %s: max concurrent must be greater than 0", providerName)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queues[providerName] = newProviderQueue(providerName, config)
+	return nil
+}
+
+// RemoveQueueConfig removes the concurrency limit of a provider.
+// Requests already admitted by the queue are unaffected.
+func (s *Service) RemoveQueueConfig(providerName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.queues, providerName)
+}
+
+// QueueStats returns the queue statistics of every provider with a queue configured
+func (s *Service) QueueStats() []QueueStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]QueueStats, 0, len(s.queues))
+	for _, queue := range s.queues {
+		stats = append(stats, queue.snapshot())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Provider < stats[j].Provider
+	})
+	return stats
+}
+
+// acquire waits for a concurrency slot on the provider, if it has a queue
+func (s *Service) acquire(ctx context.Context, providerName string) (func(), error) {
+	s.mu.RLock()
+	queue := s.queues[providerName]
+	s.mu.RUnlock()
+
+	if queue == nil {
+		return func() {}, nil
 	}
+
+	priority := PriorityFromContext(ctx)
+	start := time.Now()
+	release, err := queue.acquire(ctx, priority)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", providerName, err)
+	}
+	wait := time.Since(start)
+	tracing.SpanFromContext(ctx).SetAttribute("queue_wait_ms", wait.Milliseconds())
+	s.getMetrics().observeQueueWait(providerName, priority, wait)
+	return release, nil
 }
 
 // RegisterProvider registers a new provider with the service
@@ -28,10 +157,10 @@ func (s *Service) RegisterProvider(provider types.Provider) error {
 
 	name := provider.Name()
 	if _, exists := s.providers[name]; exists {
-		return fmt.Errorf("provider %s already registered", name)
+		return fmt.Errorf("%w: %s", ErrProviderExists, name)
 	}
 
-	s.providers[name] = provider
+	s.providers[name] = newProviderEntry(provider)
 	return nil
 }
 
@@ -40,26 +169,79 @@ func (s *Service) GetProvider(name string) (types.Provider, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	provider, exists := s.providers[name]
+	entry, exists := s.providers[name]
 	if !exists {
-		return nil, fmt.Errorf("provider %s not found", name)
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, name)
 	}
 
-	return provider, nil
+	return entry.provider, nil
 }
 
 // Chat handles a chat completion request
 func (s *Service) Chat(ctx context.Context, providerName string, req *types.ChatRequest) (*types.ChatResponse, error) {
-	provider, err := s.GetProvider(providerName)
+	ctx, obs := s.observe(ctx, OperationChat, providerName, req.Model)
+	cached, key := s.cachedChat(ctx, providerName, req)
+	if cached != nil {
+		// Cached responses cost nothing, so no usage is recorded
+		obs.span.SetAttribute("cache_hit", true)
+		obs.finish(types.Usage{}, nil)
+		return cached, nil
+	}
+
+	resp, err := s.chat(ctx, providerName, req)
+	if err != nil {
+		obs.finish(types.Usage{}, err)
+		return nil, err
+	}
+	s.storeChat(key, resp)
+	obs.finish(resp.Usage, nil)
+	return resp, nil
+}
+
+func (s *Service) chat(ctx context.Context, providerName string, req *types.ChatRequest) (*types.ChatResponse, error) {
+	provider, done, err := s.lease(providerName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get provider: %w", err)
 	}
+	defer done()
+
+	req, err = s.checkRequestPolicy(ctx, providerName, req)
+	if err != nil {
+		return nil, err
+	}
+
+	req, session, err := s.redactRequest(ctx, providerName, req)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.acquire(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if err := s.allowCircuit(providerName); err != nil {
+		return nil, err
+	}
+
+	req, err = s.fitContextWindow(ctx, providerName, provider, req)
+	if err != nil {
+		return nil, err
+	}
 
 	// Here we could add request normalization if needed
 	resp, err := provider.Chat(ctx, req)
+	s.recordCircuit(ctx, providerName, err)
 	if err != nil {
 		return nil, fmt.Errorf("provider %s chat failed: %w", providerName, err)
 	}
+	if session != nil {
+		restoreResponse(session, resp)
+	}
+	if err := s.checkResponsePolicy(ctx, providerName, resp); err != nil {
+		return nil, err
+	}
 
 	// Here we could add response normalization if needed
 	return resp, nil
@@ -67,15 +249,63 @@ func (s *Service) Chat(ctx context.Context, providerName string, req *types.Chat
 
 // StreamChat handles a streaming chat completion request
 func (s *Service) StreamChat(ctx context.Context, providerName string, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
-	provider, err := s.GetProvider(providerName)
+	ctx, obs := s.observe(ctx, OperationStreamChat, providerName, req.Model)
+
+	provider, done, err := s.lease(providerName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get provider: %w", err)
+		err = fmt.Errorf("failed to get provider: %w", err)
+		obs.finish(types.Usage{}, err)
+		return nil, err
+	}
+
+	req, err = s.checkRequestPolicy(ctx, providerName, req)
+	if err != nil {
+		done()
+		obs.finish(types.Usage{}, err)
+		return nil, err
+	}
+
+	req, session, err := s.redactRequest(ctx, providerName, req)
+	if err != nil {
+		done()
+		obs.finish(types.Usage{}, err)
+		return nil, err
+	}
+	outputPolicy := s.newStreamPolicy(ctx, providerName)
+
+	release, err := s.acquire(ctx, providerName)
+	if err != nil {
+		done()
+		obs.finish(types.Usage{}, err)
+		return nil, err
+	}
+
+	if err := s.allowCircuit(providerName); err != nil {
+		release()
+		done()
+		obs.finish(types.Usage{}, err)
+		return nil, err
+	}
+
+	req, err = s.fitContextWindow(ctx, providerName, provider, req)
+	if err != nil {
+		release()
+		done()
+		obs.finish(types.Usage{}, err)
+		return nil, err
 	}
 
 	// Here we could add request normalization if needed
 	respChan, err := provider.StreamChat(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("provider %s stream chat failed: %w", providerName, err)
+		// A stream that opens is recorded once it ends, so a trial request
+		// only closes the circuit after the provider has answered in full
+		s.recordCircuit(ctx, providerName, err)
+		release()
+		done()
+		err = fmt.Errorf("provider %s stream chat failed: %w", providerName, err)
+		obs.finish(types.Usage{}, err)
+		return nil, err
 	}
 
 	// Create a new channel for normalized responses
@@ -84,15 +314,148 @@ func (s *Service) StreamChat(ctx context.Context, providerName string, req *type
 	// Start a goroutine to normalize responses
 	go func() {
 		defer close(normalizedChan)
+		defer done()
+		defer release()
+
+		var restorer *streamRestorer
+		if session != nil {
+			restorer = newStreamRestorer(session)
+		}
+
+		usage := newStreamUsage(s.counter(), req)
+		var streamErr, providerErr error
 		for resp := range respChan {
+			if streamErr != nil {
+				// Drain the provider stream after a violation or failure
+				continue
+			}
+			if resp.Error != nil {
+				// The provider failed mid-stream; pass its error chunk on
+				providerErr = resp.Error
+				streamErr = fmt.Errorf("provider %s stream chat failed: %w", providerName, resp.Error)
+				normalizedChan <- &types.ChatResponse{ID: resp.ID, Object: resp.Object, Model: resp.Model, Error: streamErr}
+				continue
+			}
+			if len(resp.Choices) > 0 && resp.Choices[0].Message.Content != "" {
+				obs.token()
+			}
+			usage.observe(resp)
+
 			// Here we could add response normalization if needed
+			if restorer != nil {
+				restorer.restore(resp)
+			}
+			if outputPolicy != nil {
+				if streamErr = outputPolicy.check(resp); streamErr != nil {
+					normalizedChan <- contentFilteredChunk(resp)
+					continue
+				}
+			}
 			normalizedChan <- resp
 		}
+		s.recordStream(ctx, providerName, providerErr)
+		if restorer != nil && streamErr == nil {
+			if resp := restorer.flush(); resp != nil {
+				normalizedChan <- resp
+			}
+		}
+		if streamErr == nil {
+			streamErr = ctx.Err()
+		}
+		obs.finish(usage.usage(), streamErr)
 	}()
 
 	return normalizedChan, nil
 }
 
+// Embed handles an embeddings request, splitting large input arrays into
+// batches and merging the results in input order
+func (s *Service) Embed(ctx context.Context, providerName string, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	ctx, obs := s.observe(ctx, OperationEmbeddings, providerName, req.Model)
+	resp, err := s.embed(ctx, providerName, req)
+	if err != nil {
+		obs.finish(types.Usage{}, err)
+		return nil, err
+	}
+	obs.finish(resp.Usage, nil)
+	return resp, nil
+}
+
+func (s *Service) embed(ctx context.Context, providerName string, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	provider, done, err := s.lease(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %w", err)
+	}
+	defer done()
+
+	embedder, ok := provider.(types.EmbeddingProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %s: %w", providerName, ErrEmbeddingsNotSupported)
+	}
+
+	if engine := s.getPolicy(); engine != nil {
+		if err := engine.CheckModel(providerName, TenantFromContext(ctx), req.Model); err != nil {
+			return nil, fmt.Errorf("provider %s: %w", providerName, err)
+		}
+	}
+
+	req, err = s.redactEmbedding(ctx, providerName, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	batchSize := s.embeddingBatchSize
+	s.mu.RUnlock()
+
+	result := &types.EmbeddingResponse{
+		Object: "list",
+		Model:  req.Model,
+		Data:   make([]types.Embedding, 0, len(req.Input)),
+	}
+
+	for start := 0; start < len(req.Input); start += batchSize {
+		end := start + batchSize
+		if end > len(req.Input) {
+			end = len(req.Input)
+		}
+
+		batch := *req
+		batch.Input = req.Input[start:end]
+
+		release, err := s.acquire(ctx, providerName)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.allowCircuit(providerName); err != nil {
+			release()
+			return nil, err
+		}
+		resp, err := embedder.Embed(ctx, &batch)
+		s.recordCircuit(ctx, providerName, err)
+		release()
+		if err != nil {
+			return nil, fmt.Errorf("provider %s embed failed: %w", providerName, err)
+		}
+		if len(resp.Data) != len(batch.Input) {
+			return nil, fmt.Errorf("provider %s returned %d embeddings for %d inputs", providerName, len(resp.Data), len(batch.Input))
+		}
+
+		for _, embedding := range resp.Data {
+			embedding.Object = "embedding"
+			embedding.Index += start
+			result.Data = append(result.Data, embedding)
+		}
+		if resp.Model != "" {
+			result.Model = resp.Model
+		}
+		result.Usage.PromptTokens += resp.Usage.PromptTokens
+		result.Usage.TotalTokens += resp.Usage.TotalTokens
+	}
+
+	return result, nil
+}
+
 // ListProviders returns a list of registered providers
 func (s *Service) ListProviders() []string {
 	s.mu.RLock()
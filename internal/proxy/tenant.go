@@ -0,0 +1,16 @@
+package proxy
+
+import "context"
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying the tenant a request is made for
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the request tenant, or an empty string if unset
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
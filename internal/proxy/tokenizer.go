@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/internal/tokenizer"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// SetTokenizer counts tokens with registry. When set, requests that cannot
+// fit the context window of their model are rejected before they are sent,
+// unless a context window manager shortens them. A nil registry estimates
+// tokens from text length.
+func (s *Service) SetTokenizer(registry *tokenizer.Registry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenizer = registry
+}
+
+func (s *Service) getTokenizer() *tokenizer.Registry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tokenizer
+}
+
+// counter returns the token counter of the service
+func (s *Service) counter() tokenizer.Counter {
+	if registry := s.getTokenizer(); registry != nil {
+		return registry
+	}
+	return tokenizer.Heuristic{}
+}
+
+// EstimateChat estimates the usage and cost of a chat request without sending
+// it. Costs are only known for models priced in the tokenizer configuration.
+func (s *Service) EstimateChat(req *types.ChatRequest) tokenizer.Estimate {
+	if registry := s.getTokenizer(); registry != nil {
+		return registry.Estimate(req)
+	}
+	return tokenizer.Estimate{
+		Model:               req.Model,
+		PromptTokens:        tokenizer.CountRequest(tokenizer.Heuristic{}, req),
+		MaxCompletionTokens: req.MaxTokens,
+	}
+}
+
+// checkContextLimit rejects a request whose prompt and answer cannot fit the
+// context window of its model. Only requests counted by a configured
+// tokenizer are checked, and models with an unknown window pass.
+func (s *Service) checkContextLimit(providerName string, req *types.ChatRequest) error {
+	registry := s.getTokenizer()
+	if registry == nil {
+		return nil
+	}
+	window := contextwindow.ModelContextWindow(req.Model)
+	if window == 0 {
+		return nil
+	}
+	needed := tokenizer.CountRequest(registry, req) + req.MaxTokens
+	if needed > window {
+		return fmt.Errorf("provider %s: request needs %d tokens, the window of %s is %d: %w",
+			providerName, needed, req.Model, window, contextwindow.ErrContextOverflow)
+	}
+	return nil
+}
+
+// streamUsage fills in the usage of streams whose provider does not report it
+type streamUsage struct {
+	counter    tokenizer.Counter
+	req        *types.ChatRequest
+	content    strings.Builder
+	completion types.Message
+	reported   types.Usage
+}
+
+func newStreamUsage(counter tokenizer.Counter, req *types.ChatRequest) *streamUsage {
+	return &streamUsage{counter: counter, req: req}
+}
+
+// observe records a chunk, and sets the estimated usage on the final chunk
+// of a stream that has reported none
+func (u *streamUsage) observe(resp *types.ChatResponse) {
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		u.content.WriteString(choice.Message.Content)
+		u.completion.ToolCalls = append(u.completion.ToolCalls, choice.Message.ToolCalls...)
+	}
+	if !isZeroUsage(resp.Usage) {
+		u.reported = resp.Usage
+		return
+	}
+	if isZeroUsage(u.reported) && len(resp.Choices) > 0 && resp.Choices[0].FinishReason != "" {
+		resp.Usage = u.estimate()
+		u.reported = resp.Usage
+	}
+}
+
+// usage returns the usage reported by the stream, or estimates it
+func (u *streamUsage) usage() types.Usage {
+	if isZeroUsage(u.reported) {
+		return u.estimate()
+	}
+	return u.reported
+}
+
+func (u *streamUsage) estimate() types.Usage {
+	u.completion.Content = u.content.String()
+	return tokenizer.CountUsage(u.counter, u.req, u.completion)
+}
+
+func isZeroUsage(usage types.Usage) bool {
+	return usage.PromptTokens == 0 && usage.CompletionTokens == 0 && usage.TotalTokens == 0
+}
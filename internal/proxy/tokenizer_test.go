@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/internal/contextwindow"
+	"github.com/pimentel/peppergo/internal/tokenizer"
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// finishingStreamProvider streams a reply in two chunks, the last with a
+// finish reason and the configured usage
+type finishingStreamProvider struct {
+	batchTestProvider
+	usage types.Usage
+	calls int
+}
+
+func (p *finishingStreamProvider) Name() string { return "finishing" }
+
+func (p *finishingStreamProvider) StreamChat(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatResponse, error) {
+	p.calls++
+	ch := make(chan *types.ChatResponse, 2)
+	ch <- &types.ChatResponse{Choices: []types.Choice{{Message: types.Message{Content: "hello "}}}}
+	ch <- &types.ChatResponse{
+		Choices: []types.Choice{{Message: types.Message{Content: "world"}, FinishReason: "stop"}},
+		Usage:   p.usage,
+	}
+	close(ch)
+	return ch, nil
+}
+
+// collect returns the chunks of a stream
+func collect(stream <-chan *types.ChatResponse) []*types.ChatResponse {
+	var chunks []*types.ChatResponse
+	for resp := range stream {
+		chunks = append(chunks, resp)
+	}
+	return chunks
+}
+
+func TestServiceStreamUsage(t *testing.T) {
+	ctx := context.Background()
+	req := &types.ChatRequest{Model: "gpt-4o", Messages: []types.Message{{Role: "user", Content: "say hello"}}}
+
+	service := NewService()
+	provider := &finishingStreamProvider{}
+	require.NoError(t, service.RegisterProvider(provider))
+
+	stream, err := service.StreamChat(ctx, "finishing", req)
+	require.NoError(t, err)
+	chunks := collect(stream)
+	require.Len(t, chunks, 2)
+	assert.Zero(t, chunks[0].Usage)
+
+	want := tokenizer.CountUsage(tokenizer.Heuristic{}, req, types.Message{Content: "hello world"})
+	assert.Equal(t, want, chunks[1].Usage, "usage is estimated for the final chunk")
+	assert.Positive(t, want.PromptTokens)
+	assert.Positive(t, want.CompletionTokens)
+
+	// Reported usage is left as is
+	provider.usage = types.Usage{PromptTokens: 7, CompletionTokens: 2, TotalTokens: 9}
+	stream, err = service.StreamChat(ctx, "finishing", req)
+	require.NoError(t, err)
+	chunks = collect(stream)
+	assert.Equal(t, provider.usage, chunks[1].Usage)
+}
+
+func TestServiceContextLimit(t *testing.T) {
+	ctx := context.Background()
+	service := NewService()
+	provider := &finishingStreamProvider{}
+	require.NoError(t, service.RegisterProvider(provider))
+
+	// gpt-4 has a window of 8192 tokens
+	req := &types.ChatRequest{
+		Model:     "gpt-4",
+		Messages:  []types.Message{{Role: "user", Content: strings.Repeat("word ", 100)}},
+		MaxTokens: 8100,
+	}
+
+	// Without a tokenizer requests are sent as they are
+	_, err := service.StreamChat(ctx, "finishing", req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.calls)
+
+	registry, err := tokenizer.NewRegistry(zaptest.NewLogger(t), &tokenizer.Config{})
+	require.NoError(t, err)
+	service.SetTokenizer(registry)
+
+	_, err = service.StreamChat(ctx, "finishing", req)
+	assert.ErrorIs(t, err, contextwindow.ErrContextOverflow)
+	assert.ErrorContains(t, err, "the window of gpt-4 is 8192")
+	assert.Equal(t, 1, provider.calls, "the request is not sent")
+
+	req.MaxTokens = 1000
+	_, err = service.StreamChat(ctx, "finishing", req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, provider.calls)
+
+	// A context window manager shortens requests instead
+	manager, err := contextwindow.NewManager(zaptest.NewLogger(t), &contextwindow.Config{})
+	require.NoError(t, err)
+	service.SetContextWindow(manager)
+	req.MaxTokens = 8100
+	_, err = service.StreamChat(ctx, "finishing", req)
+	assert.ErrorIs(t, err, contextwindow.ErrContextOverflow, "a single turn cannot be shortened")
+}
+
+func TestServiceEstimateChat(t *testing.T) {
+	req := &types.ChatRequest{
+		Model:     "gpt-4o",
+		Messages:  []types.Message{{Role: "user", Content: "hello there"}},
+		MaxTokens: 100,
+	}
+
+	service := NewService()
+	estimate := service.EstimateChat(req)
+	assert.Equal(t, tokenizer.CountRequest(tokenizer.Heuristic{}, req), estimate.PromptTokens)
+	assert.Equal(t, 100, estimate.MaxCompletionTokens)
+	assert.False(t, estimate.Priced)
+
+	registry, err := tokenizer.NewRegistry(zaptest.NewLogger(t), &tokenizer.Config{
+		Pricing: map[string]tokenizer.Pricing{"gpt-4o": {PromptPerMillion: 1e6, CompletionPerMillion: 2e6}},
+	})
+	require.NoError(t, err)
+	service.SetTokenizer(registry)
+
+	estimate = service.EstimateChat(req)
+	assert.True(t, estimate.Priced)
+	assert.InDelta(t, float64(estimate.PromptTokens), estimate.PromptCost, 1e-9)
+	assert.InDelta(t, float64(estimate.PromptTokens)+200, estimate.MaxCost, 1e-9)
+}
@@ -0,0 +1,123 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Built-in detector names
+const (
+	KindEmail        = "email"
+	KindPhone        = "phone"
+	KindCreditCard   = "credit_card"
+	KindAWSAccessKey = "aws_access_key"
+	KindAWSSecretKey = "aws_secret_key"
+	KindGitHubToken  = "github_token"
+)
+
+// detector finds one kind of sensitive value. If the pattern has a capture
+// group, only the first group is treated as sensitive.
+type detector struct {
+	kind    string
+	pattern *regexp.Regexp
+	valid   func(match string) bool
+}
+
+// builtinDetectors are ordered so that more specific kinds match first
+var builtinDetectors = []detector{
+	{kind: KindAWSAccessKey, pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{kind: KindAWSSecretKey, pattern: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`)},
+	{kind: KindGitHubToken, pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`)},
+	{kind: KindEmail, pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{kind: KindCreditCard, pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: luhn},
+	{kind: KindPhone, pattern: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]?\d{4}\b`)},
+}
+
+// BuiltinKinds returns the names of the built-in detectors
+func BuiltinKinds() []string {
+	kinds := make([]string, len(builtinDetectors))
+	for i, d := range builtinDetectors {
+		kinds[i] = d.kind
+	}
+	return kinds
+}
+
+// newDetectors selects built-in detectors by name and compiles custom ones
+func newDetectors(kinds []string, custom map[string]string) ([]detector, error) {
+	var detectors []detector
+
+	if len(kinds) == 0 {
+		kinds = BuiltinKinds()
+	}
+	enabled := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		enabled[kind] = true
+	}
+	for _, d := range builtinDetectors {
+		if enabled[d.kind] {
+			detectors = append(detectors, d)
+			delete(enabled, d.kind)
+		}
+	}
+	for kind := range enabled {
+		return nil, fmt.Errorf("unknown detector: %s", kind)
+	}
+
+	for kind, pattern := range custom {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for %s: %w", kind, err)
+		}
+		detectors = append(detectors, detector{kind: kind, pattern: re})
+	}
+	return detectors, nil
+}
+
+// replace calls fn for every sensitive value found by d in text and
+// substitutes its result
+func (d detector) replace(text string, fn func(value string) string) string {
+	matches := d.pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		value := text[start:end]
+		if d.valid != nil && !d.valid(value) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(fn(value))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// luhn reports whether the digits in s pass the Luhn checksum
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if n%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,213 @@
+// Package redact detects sensitive values such as emails, card numbers and
+// credentials in text and replaces them with reversible placeholders.
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Mode controls what happens when sensitive values are found
+type Mode string
+
+// Redaction modes
+const (
+	// ModeMask replaces values with placeholders and restores them in responses
+	ModeMask Mode = "mask"
+
+	// ModeBlock rejects requests containing sensitive values
+	ModeBlock Mode = "block"
+
+	// ModeAudit only logs what was found
+	ModeAudit Mode = "audit"
+)
+
+// ErrSensitiveData is matched by errors returned for blocked requests
+var ErrSensitiveData = errors.New("request contains sensitive data")
+
+// BlockedError reports the kinds of sensitive values that blocked a request
+type BlockedError struct {
+	Findings map[string]int
+}
+
+func (e *BlockedError) Error() string {
+	kinds := make([]string, 0, len(e.Findings))
+	for kind := range e.Findings {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return fmt.Sprintf("%s: %s", ErrSensitiveData, strings.Join(kinds, ", "))
+}
+
+// Is reports whether target is ErrSensitiveData
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrSensitiveData
+}
+
+// Config represents the configuration for a Redactor
+type Config struct {
+	// Mode is mask, block or audit
+	Mode Mode `yaml:"mode"`
+
+	// Detectors are the built-in detectors to enable (all when empty)
+	Detectors []string `yaml:"detectors"`
+
+	// Patterns are custom detectors, keyed by kind, as regular expressions
+	Patterns map[string]string `yaml:"patterns"`
+}
+
+// Redactor detects sensitive values in text
+type Redactor struct {
+	logger    *zap.Logger
+	config    *Config
+	detectors []detector
+}
+
+// NewRedactor creates a new Redactor
+func NewRedactor(logger *zap.Logger, config *Config) (*Redactor, error) {
+	switch config.Mode {
+	case "":
+		config.Mode = ModeMask
+	case ModeMask, ModeBlock, ModeAudit:
+	default:
+		return nil, fmt.Errorf("unknown redaction mode: %s", config.Mode)
+	}
+
+	detectors, err := newDetectors(config.Detectors, config.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Redactor{
+		logger:    logger,
+		config:    config,
+		detectors: detectors,
+	}, nil
+}
+
+// Mode returns the configured redaction mode
+func (r *Redactor) Mode() Mode {
+	return r.config.Mode
+}
+
+// NewSession starts a redaction session; placeholders are consistent within
+// a session so the same value always maps to the same placeholder
+func (r *Redactor) NewSession() *Session {
+	return &Session{
+		redactor:  r,
+		values:    make(map[string]string),
+		originals: make(map[string]string),
+		counts:    make(map[string]int),
+		findings:  make(map[string]int),
+	}
+}
+
+// Session masks the messages of a single request and restores its response
+type Session struct {
+	redactor  *Redactor
+	values    map[string]string // value -> placeholder
+	originals map[string]string // placeholder -> value
+	counts    map[string]int
+	findings  map[string]int
+	mu        sync.Mutex
+}
+
+// Mask replaces sensitive values in text with placeholders
+func (s *Session) Mask(text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.redactor.detectors {
+		text = d.replace(text, func(value string) string {
+			s.findings[d.kind]++
+			if placeholder, ok := s.values[value]; ok {
+				return placeholder
+			}
+			s.counts[d.kind]++
+			placeholder := fmt.Sprintf("[%s_%d]", strings.ToUpper(d.kind), s.counts[d.kind])
+			s.values[value] = placeholder
+			s.originals[placeholder] = value
+			return placeholder
+		})
+	}
+	return text
+}
+
+// Findings returns the number of sensitive values found, by kind
+func (s *Session) Findings() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	findings := make(map[string]int, len(s.findings))
+	for kind, n := range s.findings {
+		findings[kind] = n
+	}
+	return findings
+}
+
+// Restore replaces placeholders in text with the original values
+func (s *Session) Restore(text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.originals) == 0 || !strings.Contains(text, "[") {
+		return text
+	}
+	for placeholder, value := range s.originals {
+		text = strings.ReplaceAll(text, placeholder, value)
+	}
+	return text
+}
+
+// Log records the findings of the session without their values
+func (s *Session) Log(fields ...zap.Field) {
+	findings := s.Findings()
+	if len(findings) == 0 {
+		return
+	}
+	fields = append(fields,
+		zap.String("mode", string(s.redactor.config.Mode)),
+		zap.Any("findings", findings))
+	s.redactor.logger.Warn("Sensitive data detected in request", fields...)
+}
+
+// maxPlaceholderLen bounds how much streamed text is held back while a
+// placeholder may still be incomplete
+const maxPlaceholderLen = 64
+
+// StreamRestorer restores placeholders in streamed text that may split a
+// placeholder across chunks
+type StreamRestorer struct {
+	session *Session
+	pending string
+}
+
+// NewStreamRestorer creates a restorer for a single stream of text
+func (s *Session) NewStreamRestorer() *StreamRestorer {
+	return &StreamRestorer{session: s}
+}
+
+// Write returns the restorable text of chunk, holding back a trailing
+// fragment that may be the start of a placeholder
+func (r *StreamRestorer) Write(chunk string) string {
+	text := r.pending + chunk
+	r.pending = ""
+
+	if i := strings.LastIndex(text, "["); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i < maxPlaceholderLen {
+		r.pending = text[i:]
+		text = text[:i]
+	}
+	return r.session.Restore(text)
+}
+
+// Flush returns any held back text
+func (r *StreamRestorer) Flush() string {
+	text := r.session.Restore(r.pending)
+	r.pending = ""
+	return text
+}
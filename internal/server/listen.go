@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// DefaultSocketMode is the file mode of unix domain sockets
+const DefaultSocketMode os.FileMode = 0o660
+
+// Listen listens on the unix domain socket at socket if set, and on the TCP
+// address addr otherwise. A stale socket file left by a previous run is removed.
+func Listen(addr, socket string, mode os.FileMode) (net.Listener, error) {
+	if socket == "" {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		return ln, nil
+	}
+
+	if info, err := os.Lstat(socket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("failed to listen on %s: file exists and is not a socket", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	if mode == 0 {
+		mode = DefaultSocketMode
+	}
+	if err := os.Chmod(socket, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "peppergo.sock")
+
+	// A stale socket file from a previous run is replaced
+	stale, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	listener, err := Listen("", socket, 0)
+	require.NoError(t, err)
+
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSocketMode, info.Mode().Perm())
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://peppergo/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestListenRefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peppergo.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	_, err := Listen("", path, 0)
+	assert.ErrorContains(t, err, "not a socket")
+}
@@ -0,0 +1,191 @@
+// Package server provides the network listeners of the peppergo HTTP server
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Client certificate modes
+const (
+	// ClientAuthNone does not ask for client certificates
+	ClientAuthNone = "none"
+	// ClientAuthRequest verifies client certificates when presented
+	ClientAuthRequest = "request"
+	// ClientAuthRequire rejects connections without a valid client certificate
+	ClientAuthRequire = "require"
+)
+
+// certCheckInterval is how often the certificate files are checked for changes
+const certCheckInterval = time.Second
+
+// TLSConfig configures TLS for the server listener
+type TLSConfig struct {
+	// CertFile and KeyFile hold the PEM-encoded server certificate and key.
+	// They are reloaded when the files change.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// ClientCAFile holds the PEM-encoded CAs that client certificates are verified against
+	ClientCAFile string `yaml:"client_ca_file"`
+
+	// ClientAuth is none, request or require (defaults to none, or require
+	// when a client CA is configured)
+	ClientAuth string `yaml:"client_auth"`
+
+	// MinVersion is 1.2 or 1.3 (defaults to 1.2)
+	MinVersion string `yaml:"min_version"`
+}
+
+// Validate checks the TLS configuration for missing or unknown values
+func (c *TLSConfig) Validate() error {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("cert_file and key_file are required")
+	}
+	switch c.ClientAuth {
+	case "", ClientAuthNone:
+	case ClientAuthRequest, ClientAuthRequire:
+		if c.ClientCAFile == "" {
+			return fmt.Errorf("client_ca_file is required for client_auth %s", c.ClientAuth)
+		}
+	default:
+		return fmt.Errorf("unknown client_auth %q", c.ClientAuth)
+	}
+	if _, err := c.minVersion(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *TLSConfig) minVersion() (uint16, error) {
+	switch c.MinVersion {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported min_version %q", c.MinVersion)
+	}
+}
+
+func (c *TLSConfig) clientAuth() tls.ClientAuthType {
+	switch c.ClientAuth {
+	case ClientAuthRequest:
+		return tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert
+	case ClientAuthNone:
+		return tls.NoClientCert
+	default:
+		if c.ClientCAFile != "" {
+			return tls.RequireAndVerifyClientCert
+		}
+		return tls.NoClientCert
+	}
+}
+
+// NewTLSConfig creates a server TLS configuration whose certificate is
+// reloaded when its files change
+func NewTLSConfig(logger *zap.Logger, config *TLSConfig) (*tls.Config, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid tls config: %w", err)
+	}
+	minVersion, _ := config.minVersion()
+
+	certs := &certReloader{
+		logger:        logger,
+		certFile:      config.CertFile,
+		keyFile:       config.KeyFile,
+		checkInterval: certCheckInterval,
+	}
+	if err := certs.load(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: certs.getCertificate,
+		ClientAuth:     config.clientAuth(),
+	}
+	if config.ClientCAFile != "" {
+		pem, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", config.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// certReloader serves a certificate, reloading it when its files change
+type certReloader struct {
+	logger        *zap.Logger
+	certFile      string
+	keyFile       string
+	checkInterval time.Duration
+	cert          *tls.Certificate
+	modTime       time.Time
+	checked       time.Time
+	mu            sync.Mutex
+}
+
+// load reads the certificate and key. The caller must hold r.mu or be the only user.
+func (r *certReloader) load() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, filename := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) < r.checkInterval {
+		return r.cert, nil
+	}
+	r.checked = time.Now()
+
+	modTime, err := r.latestModTime()
+	if err != nil || !modTime.After(r.modTime) {
+		return r.cert, nil
+	}
+	// Keep serving the previous certificate if the new files are incomplete
+	if err := r.load(); err != nil {
+		r.logger.Warn("Failed to reload TLS certificate", zap.Error(err))
+		return r.cert, nil
+	}
+	r.logger.Info("Reloaded TLS certificate", zap.String("cert_file", r.certFile))
+	return r.cert, nil
+}
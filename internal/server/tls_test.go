@@ -0,0 +1,205 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// testCA issues certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM-encoded certificate and key for name
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFiles writes the certificate and key of name into dir
+func (ca *testCA) writeFiles(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, name, usage)
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+	return certFile, keyFile
+}
+
+func TestTLSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config TLSConfig
+		err    string
+	}{
+		{name: "valid", config: TLSConfig{CertFile: "c", KeyFile: "k"}},
+		{name: "mtls", config: TLSConfig{CertFile: "c", KeyFile: "k", ClientCAFile: "ca", ClientAuth: ClientAuthRequire}},
+		{name: "missing key", config: TLSConfig{CertFile: "c"}, err: "cert_file and key_file are required"},
+		{name: "missing client ca", config: TLSConfig{CertFile: "c", KeyFile: "k", ClientAuth: ClientAuthRequest}, err: "client_ca_file is required"},
+		{name: "unknown client auth", config: TLSConfig{CertFile: "c", KeyFile: "k", ClientAuth: "always"}, err: `unknown client_auth "always"`},
+		{name: "unknown version", config: TLSConfig{CertFile: "c", KeyFile: "k", MinVersion: "1.1"}, err: `unsupported min_version "1.1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCertReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.writeFiles(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+
+	_, err := NewTLSConfig(zaptest.NewLogger(t), &TLSConfig{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")})
+	assert.Error(t, err)
+
+	certs := &certReloader{logger: zaptest.NewLogger(t), certFile: certFile, keyFile: keyFile}
+	require.NoError(t, certs.load())
+	first, err := certs.getCertificate(nil)
+	require.NoError(t, err)
+
+	// Replace the certificate with a newer one
+	certPEM, keyPEM := ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+
+	second, err := certs.getCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Certificate[0], second.Certificate[0])
+
+	// A broken certificate keeps the previous one in service
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	third, err := certs.getCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.Certificate[0], third.Certificate[0])
+}
+
+func TestClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.writeFiles(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+
+	tlsConfig, err := NewTLSConfig(zaptest.NewLogger(t), &TLSConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: caFile,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth, "defaults to require with a client CA")
+
+	listener, err := Listen("127.0.0.1:0", "", 0)
+	require.NoError(t, err)
+	srv := &http.Server{
+		TLSConfig: tlsConfig,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+		}),
+	}
+	go srv.ServeTLS(listener, "", "")
+	t.Cleanup(func() { srv.Close() })
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	url := "https://" + listener.Addr().String()
+
+	t.Run("without client certificate", func(t *testing.T) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+		_, err := client.Get(url)
+		assert.Error(t, err)
+	})
+
+	t.Run("with client certificate", func(t *testing.T) {
+		certPEM, keyPEM := ca.issue(t, "billing-service", x509.ExtKeyUsageClientAuth)
+		clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{clientCert},
+		}}}
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		assert.Equal(t, "billing-service", string(body[:n]))
+	})
+}
@@ -0,0 +1,212 @@
+package tokenizer
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Encodings with a built-in pre-tokenization pattern
+const (
+	CL100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+// patterns are the pre-tokenization patterns of the supported encodings
+var patterns = map[string][]matcher{
+	CL100kBase: cl100kPattern,
+	O200kBase:  o200kPattern,
+}
+
+// BPE is a byte pair encoding tokenizer producing the same tokens as tiktoken
+// for a supported encoding and its vocabulary
+type BPE struct {
+	name    string
+	pattern []matcher
+	ranks   map[string]int
+	tokens  map[int]string
+}
+
+// NewBPE creates a tokenizer for an encoding from the rank of each token of
+// its vocabulary. The vocabulary must contain every single byte.
+func NewBPE(encoding string, ranks map[string]int) (*BPE, error) {
+	pattern, ok := patterns[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("vocabulary of %s lacks byte 0x%02x", encoding, b)
+		}
+	}
+
+	tokens := make(map[int]string, len(ranks))
+	for token, rank := range ranks {
+		tokens[rank] = token
+	}
+	return &BPE{name: encoding, pattern: pattern, ranks: ranks, tokens: tokens}, nil
+}
+
+// LoadBPE creates a tokenizer for an encoding from a tiktoken vocabulary file
+func LoadBPE(encoding, path string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	ranks, err := ReadVocab(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary %s: %w", path, err)
+	}
+	return NewBPE(encoding, ranks)
+}
+
+// ReadVocab reads a vocabulary in the tiktoken format: a base64 encoded token
+// and its rank per line
+func ReadVocab(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		encoded, rankText, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a token and a rank", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid token: %w", line, err)
+		}
+		rank, err := strconv.Atoi(strings.TrimSpace(rankText))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rank: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ranks, nil
+}
+
+// Name returns the name of the encoding
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Encode returns the tokens of text. Special tokens such as <|endoftext|>
+// are encoded as ordinary text.
+func (b *BPE) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range split(b.pattern, text) {
+		if rank, ok := b.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		tokens = append(tokens, b.merge(piece)...)
+	}
+	return tokens
+}
+
+// Decode returns the text of tokens, skipping tokens not in the vocabulary
+func (b *BPE) Decode(tokens []int) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(b.tokens[token])
+	}
+	return sb.String()
+}
+
+// Count returns the number of tokens of text
+func (b *BPE) Count(text string) int {
+	return len(b.Encode(text))
+}
+
+// merge encodes a piece missing from the vocabulary by merging its bytes,
+// always merging the adjacent pair with the lowest rank first, leftmost on
+// ties. Parts form a linked list and candidate pairs wait in a heap, so a
+// long piece costs O(n log n) rather than a rescan of every pair per merge.
+func (b *BPE) merge(piece string) []int {
+	n := len(piece)
+	// Part i starts at byte i and ends where next[i] starts; merging keeps
+	// the left part, so a part is identified by its start for its lifetime
+	next := make([]int, n)
+	prev := make([]int, n)
+	for i := range next {
+		next[i] = i + 1
+		prev[i] = i - 1
+	}
+
+	pairs := &pairHeap{}
+	push := func(left int) {
+		if left < 0 || next[left] >= n {
+			return
+		}
+		end := next[next[left]]
+		if rank, ok := b.ranks[piece[left:end]]; ok {
+			heap.Push(pairs, pair{rank: rank, left: left, end: end})
+		}
+	}
+	for i := 0; i+1 < n; i++ {
+		push(i)
+	}
+
+	for pairs.Len() > 0 {
+		p := heap.Pop(pairs).(pair)
+		// Skip pairs whose parts have changed since they were pushed
+		right := next[p.left]
+		if prev[p.left] == -2 || right >= n || next[right] != p.end {
+			continue
+		}
+		next[p.left] = p.end
+		if p.end < n {
+			prev[p.end] = p.left
+		}
+		prev[right] = -2
+		push(prev[p.left])
+		push(p.left)
+	}
+
+	var tokens []int
+	for i := 0; i < n; i = next[i] {
+		tokens = append(tokens, b.ranks[piece[i:next[i]]])
+	}
+	return tokens
+}
+
+// pair is a candidate merge of the part starting at left with the part
+// after it, which ends at end
+type pair struct {
+	rank, left, end int
+}
+
+// pairHeap orders candidate merges by rank, then position
+type pairHeap []pair
+
+func (h pairHeap) Len() int { return len(h) }
+
+func (h pairHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].left < h[j].left
+}
+
+func (h pairHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *pairHeap) Push(x interface{}) { *h = append(*h, x.(pair)) }
+
+func (h *pairHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
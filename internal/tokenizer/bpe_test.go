@@ -0,0 +1,158 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRanks is a vocabulary of every byte, ranked by value, and a few merges
+func testRanks(merges ...string) map[string]int {
+	ranks := make(map[string]int)
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	for i, token := range merges {
+		ranks[token] = 256 + i
+	}
+	return ranks
+}
+
+// writeVocab writes ranks to a tiktoken vocabulary file
+func writeVocab(t *testing.T, path string, ranks map[string]int) {
+	t.Helper()
+	var sb strings.Builder
+	for token, rank := range ranks {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	require.NoError(t, os.WriteFile(path, []byte(sb.String()), 0o644))
+}
+
+func TestSplit(t *testing.T) {
+	for _, tc := range []struct {
+		text  string
+		cl100 []string
+		o200  []string
+	}{
+		{"Hello world", []string{"Hello", " world"}, []string{"Hello", " world"}},
+		{"I'm here", []string{"I", "'m", " here"}, []string{"I'm", " here"}},
+		{"HelloWorld", []string{"HelloWorld"}, []string{"Hello", "World"}},
+		{"1234567", []string{"123", "456", "7"}, []string{"123", "456", "7"}},
+		{"  hello", []string{" ", " hello"}, []string{" ", " hello"}},
+		{"a\n\nb", []string{"a", "\n\n", "b"}, []string{"a", "\n\n", "b"}},
+		{"end  ", []string{"end", "  "}, []string{"end", "  "}},
+		{"x = y;\n", []string{"x", " =", " y", ";\n"}, []string{"x", " =", " y", ";\n"}},
+		{"path/to", []string{"path", "/to"}, []string{"path", "/to"}},
+		{"héllo, 世界", []string{"héllo", ",", " 世界"}, []string{"héllo", ",", " 世界"}},
+	} {
+		assert.Equal(t, tc.cl100, split(cl100kPattern, tc.text), "cl100k %q", tc.text)
+		assert.Equal(t, tc.o200, split(o200kPattern, tc.text), "o200k %q", tc.text)
+	}
+}
+
+func TestBPE(t *testing.T) {
+	bpe, err := NewBPE(CL100kBase, testRanks("he", "ll", "hell", " w", " wo", " wor"))
+	require.NoError(t, err)
+	assert.Equal(t, CL100kBase, bpe.Name())
+
+	// "hello" is not in the vocabulary: h+e, l+l, he+ll, then o stays
+	tokens := bpe.Encode("hello world")
+	assert.Equal(t, []int{258, 'o', 261, 'l', 'd'}, tokens)
+	assert.Equal(t, "hello world", bpe.Decode(tokens))
+	assert.Equal(t, 5, bpe.Count("hello world"))
+	assert.Empty(t, bpe.Encode(""))
+
+	// Multi-byte runes round-trip through their bytes
+	assert.Equal(t, "héllo 世界", bpe.Decode(bpe.Encode("héllo 世界")))
+
+	t.Run("unknown encoding", func(t *testing.T) {
+		_, err := NewBPE("p50k_base", testRanks())
+		assert.ErrorContains(t, err, `unknown encoding "p50k_base"`)
+	})
+
+	t.Run("missing byte", func(t *testing.T) {
+		ranks := testRanks()
+		delete(ranks, "a")
+		_, err := NewBPE(O200kBase, ranks)
+		assert.ErrorContains(t, err, "lacks byte 0x61")
+	})
+}
+
+// naiveMerge is the reference merge: rescan every pair and merge the
+// leftmost one with the lowest rank until none is in the vocabulary
+func naiveMerge(ranks map[string]int, piece string) []int {
+	parts := make([]string, 0, len(piece))
+	for i := 0; i < len(piece); i++ {
+		parts = append(parts, piece[i:i+1])
+	}
+	for {
+		best, at := -1, -1
+		for i := 0; i+1 < len(parts); i++ {
+			if rank, ok := ranks[parts[i]+parts[i+1]]; ok && (best < 0 || rank < best) {
+				best, at = rank, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		parts = append(parts[:at], append([]string{parts[at] + parts[at+1]}, parts[at+2:]...)...)
+	}
+	tokens := make([]int, len(parts))
+	for i, part := range parts {
+		tokens[i] = ranks[part]
+	}
+	return tokens
+}
+
+func TestBPEMerge(t *testing.T) {
+	ranks := testRanks("ab", "ba", "aa", "aab", "bb", "abab", "bab", "aaa", "abba")
+	bpe, err := NewBPE(CL100kBase, ranks)
+	require.NoError(t, err)
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		piece := make([]byte, 1+rng.Intn(40))
+		for j := range piece {
+			piece[j] = "ab"[rng.Intn(2)]
+		}
+		assert.Equal(t, naiveMerge(ranks, string(piece)), bpe.merge(string(piece)), "%s", piece)
+	}
+
+	t.Run("long piece", func(t *testing.T) {
+		piece := strings.Repeat("ab", 200000)
+		start := time.Now()
+		tokens := bpe.merge(piece)
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.Equal(t, piece, bpe.Decode(tokens))
+	})
+}
+
+func TestLoadBPE(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cl100k_base.tiktoken")
+	writeVocab(t, path, testRanks("he", "ll"))
+
+	bpe, err := LoadBPE(CL100kBase, path)
+	require.NoError(t, err)
+	assert.Equal(t, []int{256, 257}, bpe.Encode("hell"))
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ReadVocab(strings.NewReader("aGk= 1\nnot-base64 2\n"))
+		assert.ErrorContains(t, err, "line 2: invalid token")
+
+		_, err = ReadVocab(strings.NewReader("aGk=\n"))
+		assert.ErrorContains(t, err, "line 1: expected a token and a rank")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadBPE(CL100kBase, filepath.Join(t.TempDir(), "missing"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
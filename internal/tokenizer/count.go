@@ -0,0 +1,93 @@
+// Package tokenizer counts the tokens of text and chat requests, with byte
+// pair encodings compatible with tiktoken's cl100k_base and o200k_base and a
+// heuristic for other models
+package tokenizer
+
+import (
+	"encoding/json"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// Counter counts the tokens of text for a model
+type Counter interface {
+	Count(model, text string) int
+}
+
+// Token overheads of the chat format, as documented for OpenAI models
+const (
+	messageOverhead = 4
+	replyOverhead   = 3
+	imageTokens     = 85
+)
+
+// CountMessages counts the prompt tokens of messages, including the tokens
+// the chat format adds to every message and to prime the reply
+func CountMessages(counter Counter, model string, messages []types.Message) int {
+	if len(messages) == 0 {
+		return 0
+	}
+	total := replyOverhead
+	for _, message := range messages {
+		total += CountMessage(counter, model, message)
+	}
+	return total
+}
+
+// CountMessage counts the tokens of a single message
+func CountMessage(counter Counter, model string, message types.Message) int {
+	total := messageOverhead + counter.Count(model, message.Role)
+	if len(message.Parts) == 0 {
+		total += counter.Count(model, message.Content)
+	}
+	for _, part := range message.Parts {
+		switch part.Type {
+		case types.ContentTypeText:
+			total += counter.Count(model, part.Text)
+		case types.ContentTypeImageURL:
+			total += imageTokens
+		case types.ContentTypeFile:
+			if part.File != nil {
+				total += counter.Count(model, part.File.FileData)
+			}
+		}
+	}
+	for _, call := range message.ToolCalls {
+		total += counter.Count(model, call.Function.Name) + counter.Count(model, call.Function.Arguments)
+	}
+	if message.Name != "" {
+		total += counter.Count(model, message.Name)
+	}
+	return total
+}
+
+// CountTools counts the tokens tool definitions add to a prompt
+func CountTools(counter Counter, model string, tools []types.ToolDefinition) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	return counter.Count(model, string(data))
+}
+
+// CountRequest counts the prompt tokens of a chat request: its messages and tool definitions
+func CountRequest(counter Counter, req *types.ChatRequest) int {
+	return CountMessages(counter, req.Model, req.Messages) + CountTools(counter, req.Model, req.Tools)
+}
+
+// CountUsage estimates the usage of a chat request answered with completion,
+// for providers that do not report it
+func CountUsage(counter Counter, req *types.ChatRequest, completion types.Message) types.Usage {
+	usage := types.Usage{
+		PromptTokens:     CountRequest(counter, req),
+		CompletionTokens: counter.Count(req.Model, completion.Content),
+	}
+	for _, call := range completion.ToolCalls {
+		usage.CompletionTokens += counter.Count(req.Model, call.Function.Name) + counter.Count(req.Model, call.Function.Arguments)
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
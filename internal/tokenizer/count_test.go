@@ -0,0 +1,62 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// wordCounter counts a token per word, making counts easy to follow
+type wordCounter struct{}
+
+func (wordCounter) Count(model, text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestHeuristic(t *testing.T) {
+	var h Heuristic
+	assert.Equal(t, 0, h.Count("gpt-4o", ""))
+	assert.Equal(t, 3, h.Count("gpt-4o", "hello world!"))
+	assert.Equal(t, 4, h.Count("claude-3-5-sonnet", "hello world!"))
+	assert.Equal(t, 3, h.Count("unknown", "hello world!"))
+	assert.Equal(t, 4, h.Count("gpt-4o", "你好世界"), "a token per CJK character")
+	assert.Equal(t, h.Count("claude-3-haiku", "some text"), h.Count("anthropic/claude-3-haiku", "some text"))
+}
+
+func TestCountMessages(t *testing.T) {
+	assert.Equal(t, 0, CountMessages(wordCounter{}, "", nil))
+
+	messages := []types.Message{
+		{Role: "user", Content: "two words"},
+		{Role: "user", Parts: []types.ContentPart{types.TextPart("one"), types.ImagePart("https://example.com/a.png")}},
+		{Role: "assistant", ToolCalls: []types.ToolCall{{Function: types.FunctionCall{Name: "search", Arguments: `{"q": "go"}`}}}},
+		{Role: "tool", Name: "search", Content: "result"},
+	}
+	// Reply 3, then overhead 4 and role 1 per message plus content
+	assert.Equal(t, 3+(5+2)+(5+1+imageTokens)+(5+1+2)+(5+1+1), CountMessages(wordCounter{}, "", messages))
+}
+
+func TestCountUsage(t *testing.T) {
+	req := &types.ChatRequest{
+		Model:    "m",
+		Messages: []types.Message{{Role: "user", Content: "hi there"}},
+		Tools: []types.ToolDefinition{{
+			Type:     types.ToolTypeFunction,
+			Function: types.FunctionDefinition{Name: "search"},
+		}},
+	}
+	completion := types.Message{
+		Role:      "assistant",
+		Content:   "let me look",
+		ToolCalls: []types.ToolCall{{Function: types.FunctionCall{Name: "search", Arguments: `{"q": "go"}`}}},
+	}
+
+	usage := CountUsage(wordCounter{}, req, completion)
+	assert.Equal(t, 3+(5+2)+CountTools(wordCounter{}, "m", req.Tools), usage.PromptTokens)
+	assert.Equal(t, 3+1+2, usage.CompletionTokens)
+	assert.Equal(t, usage.PromptTokens+usage.CompletionTokens, usage.TotalTokens)
+	assert.Equal(t, 1, CountTools(wordCounter{}, "m", req.Tools), "the definitions encode as a single word")
+}
@@ -0,0 +1,65 @@
+package tokenizer
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Heuristic estimates token counts from text length, using the typical
+// characters per token of the model family. CJK characters count as a
+// token each.
+type Heuristic struct{}
+
+// charsPerToken is the typical number of characters per token by model family
+var charsPerToken = []struct {
+	prefix string
+	chars  float64
+}{
+	{"claude", 3.5},
+	{"llama", 3.8},
+	{"mistral", 3.8},
+	{"mixtral", 3.8},
+	{"gemini", 4},
+	{"gpt", 4},
+}
+
+// Count estimates the tokens of text for a model
+func (Heuristic) Count(model, text string) int {
+	if text == "" {
+		return 0
+	}
+
+	chars := 4.0
+	name := ModelName(model)
+	for _, family := range charsPerToken {
+		if strings.HasPrefix(name, family.prefix) {
+			chars = family.chars
+			break
+		}
+	}
+
+	wide := 0
+	for _, r := range text {
+		if isWide(r) {
+			wide++
+		}
+	}
+	other := utf8.RuneCountInString(text) - wide
+	return wide + int(math.Ceil(float64(other)/chars))
+}
+
+// isWide reports whether r is a CJK character, which tokenizers encode as
+// at least one token each
+func isWide(r rune) bool {
+	return r >= 0x2E80 && r <= 0x9FFF || r >= 0xAC00 && r <= 0xD7AF || r >= 0xF900 && r <= 0xFAFF
+}
+
+// ModelName strips the vendor prefix of routed model names, as in
+// "openai/gpt-4o", and lowercases the rest
+func ModelName(model string) string {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return strings.ToLower(model)
+}
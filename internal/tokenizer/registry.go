@@ -0,0 +1,194 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// modelEncodings are the encodings of known models by name prefix; the
+// longest matching prefix wins
+var modelEncodings = map[string]string{
+	"gpt-3.5-turbo":          CL100kBase,
+	"gpt-4":                  CL100kBase,
+	"gpt-4o":                 O200kBase,
+	"gpt-4.1":                O200kBase,
+	"gpt-4.5":                O200kBase,
+	"o1":                     O200kBase,
+	"o3":                     O200kBase,
+	"o4-mini":                O200kBase,
+	"text-embedding-3":       CL100kBase,
+	"text-embedding-ada-002": CL100kBase,
+}
+
+// Config configures how tokens are counted and priced
+type Config struct {
+	// VocabDir is a directory of tiktoken vocabulary files named after their
+	// encoding, as in cl100k_base.tiktoken. Models whose encoding has no
+	// vocabulary are counted with a heuristic.
+	VocabDir string `yaml:"vocab_dir"`
+
+	// Encodings maps model name prefixes to cl100k_base or o200k_base, in
+	// addition to the encodings of known OpenAI models
+	Encodings map[string]string `yaml:"encodings"`
+
+	// Pricing is the price of models by name, used to estimate the cost of requests
+	Pricing map[string]Pricing `yaml:"pricing"`
+}
+
+// Validate checks the configuration
+func (c *Config) Validate() error {
+	for prefix, encoding := range c.Encodings {
+		if _, ok := patterns[encoding]; !ok {
+			return fmt.Errorf("encodings: unknown encoding %q for %s", encoding, prefix)
+		}
+	}
+	for model, pricing := range c.Pricing {
+		if pricing.PromptPerMillion < 0 || pricing.CompletionPerMillion < 0 {
+			return fmt.Errorf("pricing: price of %s must not be negative", model)
+		}
+	}
+	return nil
+}
+
+// Pricing is the price of a model in USD per million tokens
+type Pricing struct {
+	PromptPerMillion     float64 `yaml:"prompt_per_million" json:"prompt_per_million"`
+	CompletionPerMillion float64 `yaml:"completion_per_million" json:"completion_per_million"`
+}
+
+// Cost returns the cost of usage in USD
+func (p Pricing) Cost(usage types.Usage) float64 {
+	return float64(usage.PromptTokens)*p.PromptPerMillion/1e6 +
+		float64(usage.CompletionTokens)*p.CompletionPerMillion/1e6
+}
+
+// Estimate is the expected usage and cost of a chat request before it is sent
+type Estimate struct {
+	Model string `json:"model"`
+
+	// Encoding is the encoding the prompt was counted with, empty when it was estimated
+	Encoding string `json:"encoding,omitempty"`
+
+	PromptTokens int `json:"prompt_tokens"`
+
+	// MaxCompletionTokens is the request's max_tokens, 0 when unbounded
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+
+	// Priced reports whether the model has a price; the costs are 0 otherwise
+	Priced bool `json:"priced"`
+
+	// PromptCost is the cost of the prompt in USD
+	PromptCost float64 `json:"prompt_cost"`
+
+	// MaxCost is the cost of the prompt and the longest answer in USD
+	MaxCost float64 `json:"max_cost"`
+}
+
+// Registry counts tokens with the tokenizer of each model's encoding, falling
+// back to a heuristic for models without a loaded vocabulary
+type Registry struct {
+	logger    *zap.Logger
+	config    *Config
+	encodings map[string]*BPE
+}
+
+// NewRegistry creates a registry, loading the vocabularies found in the
+// configured directory
+func NewRegistry(logger *zap.Logger, config *Config) (*Registry, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid tokenizer config: %w", err)
+	}
+
+	r := &Registry{
+		logger:    logger,
+		config:    config,
+		encodings: make(map[string]*BPE),
+	}
+	if config.VocabDir == "" {
+		return r, nil
+	}
+	for encoding := range patterns {
+		path := filepath.Join(config.VocabDir, encoding+".tiktoken")
+		bpe, err := LoadBPE(encoding, path)
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Debug("No vocabulary for encoding, estimating its tokens",
+				zap.String("encoding", encoding),
+				zap.String("path", path))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.encodings[encoding] = bpe
+		logger.Info("Loaded tokenizer vocabulary",
+			zap.String("encoding", encoding),
+			zap.Int("tokens", len(bpe.ranks)))
+	}
+	return r, nil
+}
+
+// Encoding returns the encoding of a model, or "" if it is unknown
+func (r *Registry) Encoding(model string) string {
+	name := ModelName(model)
+	best, encoding := "", ""
+	for _, table := range []map[string]string{modelEncodings, r.config.Encodings} {
+		for prefix, enc := range table {
+			prefix = strings.ToLower(prefix)
+			if strings.HasPrefix(name, prefix) && len(prefix) >= len(best) {
+				best, encoding = prefix, enc
+			}
+		}
+	}
+	return encoding
+}
+
+// Tokenizer returns the tokenizer of a model, or nil if its encoding is
+// unknown or has no vocabulary loaded
+func (r *Registry) Tokenizer(model string) *BPE {
+	return r.encodings[r.Encoding(model)]
+}
+
+// Count counts the tokens of text for a model
+func (r *Registry) Count(model, text string) int {
+	if bpe := r.Tokenizer(model); bpe != nil {
+		return bpe.Count(text)
+	}
+	return Heuristic{}.Count(model, text)
+}
+
+// Pricing returns the price of a model, looking it up with and without its vendor prefix
+func (r *Registry) Pricing(model string) (Pricing, bool) {
+	if pricing, ok := r.config.Pricing[model]; ok {
+		return pricing, true
+	}
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		pricing, ok := r.config.Pricing[model[i+1:]]
+		return pricing, ok
+	}
+	return Pricing{}, false
+}
+
+// Estimate estimates the usage and cost of a chat request
+func (r *Registry) Estimate(req *types.ChatRequest) Estimate {
+	estimate := Estimate{
+		Model:               req.Model,
+		PromptTokens:        CountRequest(r, req),
+		MaxCompletionTokens: req.MaxTokens,
+	}
+	if bpe := r.Tokenizer(req.Model); bpe != nil {
+		estimate.Encoding = bpe.Name()
+	}
+	if pricing, ok := r.Pricing(req.Model); ok {
+		estimate.Priced = true
+		estimate.PromptCost = pricing.Cost(types.Usage{PromptTokens: estimate.PromptTokens})
+		estimate.MaxCost = pricing.Cost(types.Usage{PromptTokens: estimate.PromptTokens, CompletionTokens: req.MaxTokens})
+	}
+	return estimate
+}
@@ -0,0 +1,86 @@
+package tokenizer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+func TestRegistry(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	dir := t.TempDir()
+	writeVocab(t, filepath.Join(dir, "o200k_base.tiktoken"), testRanks("he", "ll", "hell"))
+
+	r, err := NewRegistry(logger, &Config{
+		VocabDir:  dir,
+		Encodings: map[string]string{"my-model": O200kBase, "gpt-4-legacy": O200kBase},
+		Pricing: map[string]Pricing{
+			"gpt-4o": {PromptPerMillion: 2.5, CompletionPerMillion: 10},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, O200kBase, r.Encoding("gpt-4o-mini"))
+	assert.Equal(t, O200kBase, r.Encoding("openai/GPT-4o"))
+	assert.Equal(t, CL100kBase, r.Encoding("gpt-4-0613"))
+	assert.Equal(t, O200kBase, r.Encoding("gpt-4-legacy"), "configured encodings extend the table")
+	assert.Equal(t, O200kBase, r.Encoding("my-model-v2"))
+	assert.Equal(t, "", r.Encoding("claude-3-haiku"))
+
+	// Models with a vocabulary are tokenized, others estimated
+	assert.NotNil(t, r.Tokenizer("gpt-4o"))
+	assert.Nil(t, r.Tokenizer("gpt-4"), "no cl100k_base vocabulary")
+	assert.Equal(t, 2, r.Count("gpt-4o", "hello"))
+	assert.Equal(t, Heuristic{}.Count("gpt-4", "hello"), r.Count("gpt-4", "hello"))
+	assert.Equal(t, Heuristic{}.Count("claude-3", "hello"), r.Count("claude-3", "hello"))
+
+	t.Run("estimate", func(t *testing.T) {
+		req := &types.ChatRequest{
+			Model:     "openai/gpt-4o",
+			Messages:  []types.Message{{Role: "user", Content: "hello"}},
+			MaxTokens: 1000,
+		}
+		estimate := r.Estimate(req)
+		// Reply 3, overhead 4, "user" 4 bytes and "hello" 2 tokens
+		assert.Equal(t, 3+4+4+2, estimate.PromptTokens)
+		assert.Equal(t, O200kBase, estimate.Encoding)
+		assert.Equal(t, 1000, estimate.MaxCompletionTokens)
+		assert.True(t, estimate.Priced)
+		assert.InDelta(t, 13*2.5/1e6, estimate.PromptCost, 1e-12)
+		assert.InDelta(t, 13*2.5/1e6+1000*10/1e6, estimate.MaxCost, 1e-12)
+
+		estimate = r.Estimate(&types.ChatRequest{Model: "claude-3", Messages: req.Messages})
+		assert.False(t, estimate.Priced)
+		assert.Empty(t, estimate.Encoding)
+		assert.Zero(t, estimate.MaxCost)
+	})
+
+	t.Run("heuristic only", func(t *testing.T) {
+		r, err := NewRegistry(logger, &Config{})
+		require.NoError(t, err)
+		assert.Nil(t, r.Tokenizer("gpt-4o"))
+		assert.Equal(t, Heuristic{}.Count("gpt-4o", "hello world"), r.Count("gpt-4o", "hello world"))
+	})
+
+	t.Run("invalid vocabulary", func(t *testing.T) {
+		dir := t.TempDir()
+		writeVocab(t, filepath.Join(dir, "cl100k_base.tiktoken"), map[string]int{"a": 0})
+		_, err := NewRegistry(logger, &Config{VocabDir: dir})
+		assert.ErrorContains(t, err, "lacks byte")
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+
+	err := (&Config{Encodings: map[string]string{"m": "r50k_base"}}).Validate()
+	assert.ErrorContains(t, err, `unknown encoding "r50k_base"`)
+
+	err = (&Config{Pricing: map[string]Pricing{"m": {PromptPerMillion: -1}}}).Validate()
+	assert.ErrorContains(t, err, "must not be negative")
+}
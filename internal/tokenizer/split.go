@@ -0,0 +1,230 @@
+package tokenizer
+
+import (
+	"unicode"
+)
+
+// A matcher matches a pattern alternative at r[i:], returning the end of the
+// match or -1
+type matcher func(r []rune, i int) int
+
+// cl100kPattern matches the pieces of the cl100k_base pattern:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+var cl100kPattern = []matcher{
+	matchContraction,
+	matchLetters,
+	matchNumbers,
+	matchPunctuation("\r\n"),
+	matchNewlines,
+	matchTrailingSpace,
+	matchSpace,
+}
+
+// o200kPattern matches the pieces of the o200k_base pattern, which splits
+// words on case changes and keeps contractions with their word:
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+//	|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?
+//	|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+var o200kPattern = []matcher{
+	matchLowerWord,
+	matchUpperWord,
+	matchNumbers,
+	matchPunctuation("\r\n/"),
+	matchNewlines,
+	matchTrailingSpace,
+	matchSpace,
+}
+
+// split splits text into the pieces matched by the first matching
+// alternative of a pattern, as the regular expressions of tiktoken do. Go's
+// regexp package has no lookahead, so the alternatives are matched by hand.
+func split(pattern []matcher, text string) []string {
+	r := []rune(text)
+	var pieces []string
+	for i := 0; i < len(r); {
+		end := -1
+		for _, match := range pattern {
+			if end = match(r, i); end > i {
+				break
+			}
+		}
+		if end <= i {
+			end = i + 1
+		}
+		pieces = append(pieces, string(r[i:end]))
+		i = end
+	}
+	return pieces
+}
+
+// contractions are the suffixes matched by (?i:'s|'t|'re|'ve|'m|'ll|'d), in order
+var contractions = []string{"s", "t", "re", "ve", "m", "ll", "d"}
+
+// matchContraction matches (?i:'s|'t|'re|'ve|'m|'ll|'d)
+func matchContraction(r []rune, i int) int {
+	if i >= len(r) || r[i] != '\'' {
+		return -1
+	}
+	for _, suffix := range contractions {
+		end := i + 1
+		for _, c := range suffix {
+			if end >= len(r) || unicode.ToLower(r[end]) != c {
+				end = -1
+				break
+			}
+			end++
+		}
+		if end > 0 {
+			return end
+		}
+	}
+	return -1
+}
+
+// isWordPrefix matches [^\r\n\p{L}\p{N}]
+func isWordPrefix(r rune) bool {
+	return r != '\r' && r != '\n' && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// wordStarts returns where a word may start after an optional prefix, the
+// longer match first
+func wordStarts(r []rune, i int) []int {
+	if isWordPrefix(r[i]) {
+		return []int{i + 1, i}
+	}
+	return []int{i}
+}
+
+// run returns the end of the run of runes from i that satisfy f
+func run(r []rune, i int, f func(rune) bool) int {
+	for i < len(r) && f(r[i]) {
+		i++
+	}
+	return i
+}
+
+// matchLetters matches [^\r\n\p{L}\p{N}]?\p{L}+
+func matchLetters(r []rune, i int) int {
+	for _, start := range wordStarts(r, i) {
+		if end := run(r, start, unicode.IsLetter); end > start {
+			return end
+		}
+	}
+	return -1
+}
+
+// isUpper matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]
+func isUpper(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// isLower matches [\p{Ll}\p{Lm}\p{Lo}\p{M}]
+func isLower(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// withContraction extends a match ending at end with an optional contraction
+func withContraction(r []rune, end int) int {
+	if next := matchContraction(r, end); next > end {
+		return next
+	}
+	return end
+}
+
+// matchLowerWord matches prefix?[upper]*[lower]+contraction?, backtracking
+// over upper runes that are also lower
+func matchLowerWord(r []rune, i int) int {
+	for _, start := range wordStarts(r, i) {
+		for k := run(r, start, isUpper); k >= start; k-- {
+			if k < len(r) && isLower(r[k]) {
+				return withContraction(r, run(r, k, isLower))
+			}
+		}
+	}
+	return -1
+}
+
+// matchUpperWord matches prefix?[upper]+[lower]*contraction?
+func matchUpperWord(r []rune, i int) int {
+	for _, start := range wordStarts(r, i) {
+		if end := run(r, start, isUpper); end > start {
+			return withContraction(r, run(r, end, isLower))
+		}
+	}
+	return -1
+}
+
+// matchNumbers matches \p{N}{1,3}
+func matchNumbers(r []rune, i int) int {
+	end := i
+	for end < len(r) && end-i < 3 && unicode.IsNumber(r[end]) {
+		end++
+	}
+	if end == i {
+		return -1
+	}
+	return end
+}
+
+// matchPunctuation matches ` ?[^\s\p{L}\p{N}]+` followed by any runes of trailing
+func matchPunctuation(trailing string) matcher {
+	isPunctuation := func(r rune) bool {
+		return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}
+	isTrailing := func(r rune) bool {
+		for _, t := range trailing {
+			if r == t {
+				return true
+			}
+		}
+		return false
+	}
+	return func(r []rune, i int) int {
+		start := i
+		if r[start] == ' ' {
+			start++
+		}
+		end := run(r, start, isPunctuation)
+		if end == start {
+			return -1
+		}
+		return run(r, end, isTrailing)
+	}
+}
+
+// matchNewlines matches \s*[\r\n]+, which ends after the last line break of
+// a run of whitespace
+func matchNewlines(r []rune, i int) int {
+	end := -1
+	for k := i; k < len(r) && unicode.IsSpace(r[k]); k++ {
+		if r[k] == '\r' || r[k] == '\n' {
+			end = k + 1
+		}
+	}
+	return end
+}
+
+// matchTrailingSpace matches \s+(?!\S): a run of whitespace up to the end of
+// the text, or all but the last rune of a run followed by other text
+func matchTrailingSpace(r []rune, i int) int {
+	end := run(r, i, unicode.IsSpace)
+	switch {
+	case end == i:
+		return -1
+	case end == len(r):
+		return end
+	case end-1 > i:
+		return end - 1
+	}
+	return -1
+}
+
+// matchSpace matches \s+
+func matchSpace(r []rune, i int) int {
+	if end := run(r, i, unicode.IsSpace); end > i {
+		return end
+	}
+	return -1
+}
@@ -0,0 +1,156 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// DefaultMaxAgentDepth bounds how deeply agents called as tools may nest
+const DefaultMaxAgentDepth = 3
+
+// AgentToolConfig configures an agent exposed as a tool
+type AgentToolConfig struct {
+	// Agent is the agent that runs the tasks; its lifecycle stays with its owner
+	Agent types.Agent
+
+	// Name is the tool name (defaults to the agent's name)
+	Name string
+
+	// Description tells the calling model what the agent is good at
+	// (defaults to the agent's description)
+	Description string
+
+	// MaxDepth bounds the agents called as tools in a chain of calls,
+	// counting this one (defaults to 3)
+	MaxDepth int
+
+	// Options are passed to every task of the agent
+	Options []types.ExecuteOption
+}
+
+// AgentTool exposes an agent as a tool, so a coordinating agent can hand it
+// tasks through the tool-calling loop. Its result is the agent's response,
+// whose usage the calling agent adds to its own.
+type AgentTool struct {
+	logger *zap.Logger
+	config *AgentToolConfig
+}
+
+// invalidToolName matches the characters not allowed in tool names
+var invalidToolName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// NewAgentTool creates a tool running tasks on an agent
+func NewAgentTool(logger *zap.Logger, config *AgentToolConfig) *AgentTool {
+	// Defaults are applied to a copy so the caller's configuration is left as is
+	copied := *config
+	config = &copied
+	if config.Name == "" && config.Agent != nil {
+		config.Name = invalidToolName.ReplaceAllString(config.Agent.Name(), "_")
+	}
+	if config.Description == "" && config.Agent != nil {
+		if described, ok := config.Agent.(interface{ Description() string }); ok {
+			config.Description = described.Description()
+		}
+	}
+	if config.MaxDepth == 0 {
+		config.MaxDepth = DefaultMaxAgentDepth
+	}
+	return &AgentTool{
+		logger: logger,
+		config: config,
+	}
+}
+
+// Name returns the tool's name
+func (t *AgentTool) Name() string {
+	return t.config.Name
+}
+
+// Description returns the tool's description
+func (t *AgentTool) Description() string {
+	if t.config.Description == "" {
+		return fmt.Sprintf("Hands a task to the %s agent and returns its answer", t.config.Name)
+	}
+	return t.config.Description
+}
+
+// Initialize checks the configuration. The agent is initialized by its owner.
+func (t *AgentTool) Initialize(ctx context.Context) error {
+	if t.config.Agent == nil {
+		return fmt.Errorf("agent is required")
+	}
+	if t.config.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if t.config.MaxDepth < 1 {
+		return fmt.Errorf("max depth must be positive")
+	}
+	return nil
+}
+
+// agentDepthKey carries the number of agents called as tools in the current chain of calls
+type agentDepthKey struct{}
+
+// AgentDepth returns the number of agents called as tools that ctx runs within
+func AgentDepth(ctx context.Context) int {
+	depth, _ := ctx.Value(agentDepthKey{}).(int)
+	return depth
+}
+
+// Execute runs the task on the agent and returns its *types.Response
+func (t *AgentTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	task, ok := args["task"].(string)
+	if !ok || strings.TrimSpace(task) == "" {
+		return nil, fmt.Errorf("task argument is required")
+	}
+
+	depth := AgentDepth(ctx) + 1
+	if depth > t.config.MaxDepth {
+		return nil, fmt.Errorf("agent %s not called: agents may only be nested %d deep", t.config.Name, t.config.MaxDepth)
+	}
+	ctx = context.WithValue(ctx, agentDepthKey{}, depth)
+
+	resp, err := t.config.Agent.Execute(ctx, task, t.config.Options...)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s failed: %w", t.config.Name, err)
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("agent %s returned no response", t.config.Name)
+	}
+
+	t.logger.Debug("Agent finished delegated task",
+		zap.String("agent", t.config.Agent.Name()),
+		zap.Int("depth", depth),
+		zap.Int("total_tokens", resp.Usage.TotalTokens))
+	return resp, nil
+}
+
+// Cleanup performs cleanup. The agent is cleaned up by its owner.
+func (t *AgentTool) Cleanup(ctx context.Context) error {
+	return nil
+}
+
+// Schema returns the tool's schema
+func (t *AgentTool) Schema() *types.ToolSchema {
+	schema := types.NewToolSchema()
+	schema.AddProperty("task", &types.PropertySchema{
+		Type:        "string",
+		Description: "The task for the agent, with all the context it needs",
+	})
+	schema.AddRequired("task")
+	return schema
+}
+
+// Version returns the version of the agent
+func (t *AgentTool) Version() string {
+	if t.config.Agent == nil {
+		return ""
+	}
+	return t.config.Agent.Version()
+}
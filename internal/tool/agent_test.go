@@ -0,0 +1,101 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pimentel/peppergo/pkg/types"
+)
+
+// fakeAgent answers tasks with a function; the methods it does not need are left to the embedded interface
+type fakeAgent struct {
+	types.Agent
+	name        string
+	description string
+	execute     func(ctx context.Context, task string) (*types.Response, error)
+}
+
+func (a *fakeAgent) Name() string        { return a.name }
+func (a *fakeAgent) Version() string     { return "2.0.0" }
+func (a *fakeAgent) Description() string { return a.description }
+
+func (a *fakeAgent) Execute(ctx context.Context, task string, opts ...types.ExecuteOption) (*types.Response, error) {
+	return a.execute(ctx, task)
+}
+
+func TestAgentTool(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	var depths []int
+	reviewer := &fakeAgent{
+		name:        "code reviewer",
+		description: "Reviews Go code",
+		execute: func(ctx context.Context, task string) (*types.Response, error) {
+			depths = append(depths, AgentDepth(ctx))
+			return &types.Response{
+				Content: "reviewed: " + task,
+				Usage:   types.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+			}, nil
+		},
+	}
+
+	config := &AgentToolConfig{Agent: reviewer}
+	tool := NewAgentTool(logger, config)
+	require.NoError(t, tool.Initialize(ctx))
+	assert.Equal(t, AgentToolConfig{Agent: reviewer}, *config, "defaults are not written back")
+	assert.Equal(t, "code_reviewer", tool.Name())
+	assert.Equal(t, "Reviews Go code", tool.Description())
+	assert.Equal(t, "2.0.0", tool.Version())
+	schema := tool.Schema()
+	assert.Equal(t, "string", schema.Properties["task"].Type)
+	assert.Equal(t, []string{"task"}, schema.Required)
+
+	result, err := tool.Execute(ctx, map[string]interface{}{"task": "main.go"})
+	require.NoError(t, err)
+	resp, ok := result.(*types.Response)
+	require.True(t, ok)
+	assert.Equal(t, "reviewed: main.go", resp.Content)
+	assert.Equal(t, 5, resp.Usage.TotalTokens)
+	assert.Equal(t, []int{1}, depths)
+
+	_, err = tool.Execute(ctx, map[string]interface{}{"task": " "})
+	assert.EqualError(t, err, "task argument is required")
+
+	t.Run("depth limit", func(t *testing.T) {
+		var nested *AgentTool
+		calls := 0
+		recursive := &fakeAgent{name: "recursive", execute: func(ctx context.Context, task string) (*types.Response, error) {
+			calls++
+			if _, err := nested.Execute(ctx, map[string]interface{}{"task": task}); err != nil {
+				return nil, err
+			}
+			return &types.Response{Content: "done"}, nil
+		}}
+		nested = NewAgentTool(logger, &AgentToolConfig{Agent: recursive, MaxDepth: 2})
+		assert.Equal(t, "Hands a task to the recursive agent and returns its answer", nested.Description())
+
+		_, err := nested.Execute(ctx, map[string]interface{}{"task": "loop"})
+		assert.EqualError(t, err, "agent recursive failed: agent recursive failed: agent recursive not called: agents may only be nested 2 deep")
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("agent failure", func(t *testing.T) {
+		broken := NewAgentTool(logger, &AgentToolConfig{Agent: &fakeAgent{name: "broken", execute: func(ctx context.Context, task string) (*types.Response, error) {
+			return nil, errors.New("down")
+		}}})
+		_, err := broken.Execute(ctx, map[string]interface{}{"task": "task"})
+		assert.EqualError(t, err, "agent broken failed: down")
+	})
+
+	t.Run("initialize", func(t *testing.T) {
+		assert.EqualError(t, NewAgentTool(logger, &AgentToolConfig{}).Initialize(ctx), "agent is required")
+		invalid := NewAgentTool(logger, &AgentToolConfig{Agent: reviewer, MaxDepth: -1})
+		assert.EqualError(t, invalid.Initialize(ctx), "max depth must be positive")
+	})
+}
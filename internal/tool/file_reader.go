@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"go.uber.org/zap"
 
@@ -139,7 +140,7 @@ func (t *FileReaderTool) Version() string {
 func (t *FileReaderTool) isPathAllowed(path string) bool {
 	// Check if path is under base path
 	rel, err := filepath.Rel(t.config.BasePath, path)
-	if err != nil || rel == ".." || filepath.IsAbs(rel) {
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
 		return false
 	}
 
@@ -0,0 +1,251 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	// Export sends a batch of finished spans
+	Export(ctx context.Context, spans []SpanData) error
+
+	// Shutdown flushes and releases exporter resources
+	Shutdown(ctx context.Context) error
+}
+
+// FileExporter writes spans as JSON lines to a local file
+type FileExporter struct {
+	file *os.File
+	enc  *json.Encoder
+	mu   sync.Mutex
+}
+
+// NewFileExporter creates an exporter appending to the file at path
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return &FileExporter{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Export appends the spans to the file
+func (e *FileExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, span := range spans {
+		if err := e.enc.Encode(span); err != nil {
+			return fmt.Errorf("failed to write span: %w", err)
+		}
+	}
+	return nil
+}
+
+// Shutdown closes the file
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+// OTLPConfig represents the configuration for OTLPExporter
+type OTLPConfig struct {
+	// Endpoint is the collector base URL, e.g. http://localhost:4318
+	Endpoint string
+
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+
+	// Headers are added to every export request, e.g. for authentication
+	Headers map[string]string
+
+	// Timeout bounds a single export request
+	Timeout time.Duration
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector using OTLP/HTTP with JSON encoding
+type OTLPExporter struct {
+	config *OTLPConfig
+	client *http.Client
+}
+
+// NewOTLPExporter creates a new OTLP/HTTP exporter
+func NewOTLPExporter(config *OTLPConfig) *OTLPExporter {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "peppergo"
+	}
+	return &OTLPExporter{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Export posts the spans to the collector's /v1/traces endpoint
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.toRequest(spans))
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+
+	url := strings.TrimSuffix(e.config.Endpoint, "/") + "/v1/traces"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send spans: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("collector returned status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// Shutdown releases idle connections
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// OTLP JSON wire types
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// OTLP status codes
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+func (e *OTLPExporter) toRequest(spans []SpanData) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if span.Error != "" {
+			s.Status = otlpStatus{Code: otlpStatusError, Message: span.Error}
+		}
+		out = append(out, s)
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes(map[string]interface{}{"service.name": e.config.ServiceName}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/pimentel/peppergo/internal/tracing"},
+				Spans: out,
+			}},
+		}},
+	}
+}
+
+func otlpAttributes(attrs map[string]interface{}) []otlpAttribute {
+	out := make([]otlpAttribute, 0, len(attrs))
+	for key, value := range attrs {
+		var v otlpValue
+		switch value := value.(type) {
+		case string:
+			v.StringValue = &value
+		case bool:
+			v.BoolValue = &value
+		case int:
+			s := strconv.Itoa(value)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(value, 10)
+			v.IntValue = &s
+		case float32:
+			f := float64(value)
+			v.DoubleValue = &f
+		case float64:
+			v.DoubleValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		out = append(out, otlpAttribute{Key: key, Value: v})
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// TraceparentHeader is the W3C trace context header
+const TraceparentHeader = "traceparent"
+
+// Inject writes the traceparent of the current span into the headers
+func Inject(ctx context.Context, header http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		header.Set(TraceparentHeader, sc.Traceparent())
+	}
+}
+
+// Extract returns a context continuing the trace named by the request's
+// traceparent header, if it is present and valid
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, err := ParseTraceparent(header.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	return ContextWithRemoteParent(ctx, sc)
+}
+
+// Middleware starts a server span for each request, continuing any incoming
+// trace. routeName, if set, names the span after the request is served.
+func Middleware(tracer *Tracer, routeName func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := Extract(r.Context(), r.Header)
+			ctx, span := tracer.Start(ctx, "HTTP "+r.Method, SpanKindServer)
+			defer span.End()
+
+			span.SetAttribute("http.method", r.Method)
+			span.SetAttribute("http.target", r.URL.Path)
+			if sc := span.SpanContext(); sc.IsValid() {
+				w.Header().Set(TraceparentHeader, sc.Traceparent())
+			}
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			r = r.WithContext(ctx)
+			next.ServeHTTP(sw, r)
+
+			if routeName != nil {
+				if route := routeName(r); route != "" {
+					span.SetName("HTTP " + r.Method + " " + route)
+					span.SetAttribute("http.route", route)
+				}
+			}
+			span.SetAttribute("http.status_code", sw.status)
+		})
+	}
+}
+
+// statusWriter captures the response status while preserving streaming
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Transport is an http.RoundTripper that records a client span per request
+// and propagates the trace with the traceparent header
+type Transport struct {
+	// Base is the underlying transport; nil uses http.DefaultTransport
+	Base http.RoundTripper
+}
+
+// NewTransport wraps base with client tracing
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx, span := StartKind(req.Context(), "HTTP "+req.Method+" "+req.URL.Host, SpanKindClient)
+	if span == nil {
+		return base.RoundTrip(req)
+	}
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+
+	// Streaming responses are still in flight until the body is consumed
+	resp.Body = &spanBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+// spanBody ends its span when the response body is closed
+type spanBody struct {
+	io.ReadCloser
+	span *Span
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.span.End()
+	return err
+}
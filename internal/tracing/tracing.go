@@ -0,0 +1,448 @@
+// Package tracing implements lightweight distributed tracing with W3C
+// traceparent propagation and pluggable span exporters.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the lowercase hex encoding of the trace ID
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid reports whether the trace ID is non-zero
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String returns the lowercase hex encoding of the span ID
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid reports whether the span ID is non-zero
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext is the propagated identity of a span
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent returns the W3C traceparent header value for the span context
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header value
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent: %q", value)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || version == "ff" || (version == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("unsupported traceparent version: %q", value)
+	}
+	if len(traceID) != 32 || len(spanID) != 16 || len(flags) != 2 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent: %q", value)
+	}
+
+	var sc SpanContext
+	if _, err := hex.Decode(sc.TraceID[:], []byte(traceID)); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid trace id: %w", err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(spanID)); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid span id: %w", err)
+	}
+	var flagBytes [1]byte
+	if _, err := hex.Decode(flagBytes[:], []byte(flags)); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid trace flags: %w", err)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent: zero trace or span id")
+	}
+	sc.Sampled = flagBytes[0]&0x01 == 1
+	return sc, nil
+}
+
+// SpanKind describes the relationship of a span to its callers
+type SpanKind int
+
+// Span kinds, numbered as in OTLP
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// SpanData is the exported, immutable record of a finished span
+type SpanData struct {
+	Name         string                 `json:"name"`
+	Kind         SpanKind               `json:"kind"`
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	StartTime    time.Time              `json:"start_time"`
+	EndTime      time.Time              `json:"end_time"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// Duration returns the span duration
+func (d SpanData) Duration() time.Duration {
+	return d.EndTime.Sub(d.StartTime)
+}
+
+// Span is an in-progress unit of work. A nil Span is valid and records nothing.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	data   SpanData
+	ended  bool
+	mu     sync.Mutex
+}
+
+// SpanContext returns the propagated identity of the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetName renames the span, e.g. once an HTTP route is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttribute records a key/value attribute on the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{})
+	}
+	s.data.Attributes[key] = value
+}
+
+// RecordError marks the span as failed; nil errors are ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End finishes the span and hands it to the exporter if sampled
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.sc.Sampled {
+		s.tracer.enqueue(data)
+	}
+}
+
+// TracerConfig represents the configuration for a Tracer
+type TracerConfig struct {
+	// SampleRate is the fraction of new traces that are recorded (0 records none, 1 records all)
+	SampleRate float64
+
+	// Exporter receives finished spans
+	Exporter Exporter
+
+	// BatchSize is the number of spans exported at once
+	BatchSize int
+
+	// FlushInterval is the maximum time a finished span waits before export
+	FlushInterval time.Duration
+}
+
+// Tracer creates spans and exports them in batches
+type Tracer struct {
+	logger  *zap.Logger
+	config  *TracerConfig
+	spans   chan SpanData
+	flush   chan chan struct{}
+	done    chan struct{}
+	closing sync.Once
+}
+
+// NewTracer creates a new tracer and starts its export loop
+func NewTracer(logger *zap.Logger, config *TracerConfig) *Tracer {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 256
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+
+	t := &Tracer{
+		logger: logger,
+		config: config,
+		spans:  make(chan SpanData, config.BatchSize*4),
+		flush:  make(chan chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Start begins a span that is a child of the span in ctx, or the root of a
+// new trace. It returns a context carrying the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+		sc.Sampled = t.sample(sc.TraceID)
+	}
+
+	span := &Span{
+		tracer: t,
+		sc:     sc,
+		data: SpanData{
+			Name:      name,
+			Kind:      kind,
+			TraceID:   sc.TraceID.String(),
+			SpanID:    sc.SpanID.String(),
+			StartTime: time.Now(),
+		},
+	}
+	if parent.IsValid() {
+		span.data.ParentSpanID = parent.SpanID.String()
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// sample decides deterministically from the trace ID whether a new trace is recorded
+func (t *Tracer) sample(traceID TraceID) bool {
+	switch {
+	case t.config.SampleRate >= 1:
+		return true
+	case t.config.SampleRate <= 0:
+		return false
+	}
+	var n uint64
+	for _, b := range traceID[8:] {
+		n = n<<8 | uint64(b)
+	}
+	return float64(n>>11)/float64(1<<53) < t.config.SampleRate
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	select {
+	case t.spans <- data:
+	case <-t.done:
+	default:
+		t.logger.Warn("Dropping span, export queue is full", zap.String("span", data.Name))
+	}
+}
+
+// run batches finished spans and exports them
+func (t *Tracer) run() {
+	ticker := time.NewTicker(t.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, t.config.BatchSize)
+	export := func() {
+		if len(batch) == 0 || t.config.Exporter == nil {
+			batch = batch[:0]
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := t.config.Exporter.Export(ctx, batch); err != nil {
+			t.logger.Error("Failed to export spans", zap.Int("spans", len(batch)), zap.Error(err))
+		}
+		batch = make([]SpanData, 0, t.config.BatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case data := <-t.spans:
+				batch = append(batch, data)
+				if len(batch) >= t.config.BatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case data := <-t.spans:
+			batch = append(batch, data)
+			if len(batch) >= t.config.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ack := <-t.flush:
+			drain()
+			close(ack)
+		case <-t.done:
+			drain()
+			return
+		}
+	}
+}
+
+// Flush exports all finished spans
+func (t *Tracer) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case t.flush <- ack:
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports remaining spans and shuts down the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if err := t.Flush(ctx); err != nil {
+		return err
+	}
+	t.closing.Do(func() { close(t.done) })
+	if t.config.Exporter != nil {
+		return t.config.Exporter.Shutdown(ctx)
+	}
+	return nil
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// SpanFromContext returns the current span, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the identity of the current span, falling
+// back to a remote parent extracted from an incoming request
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithRemoteParent returns a context whose next span continues a remote trace
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+var (
+	globalTracer *Tracer
+	globalMu     sync.RWMutex
+)
+
+// SetTracer sets the tracer used by Start for spans without a parent tracer
+func SetTracer(t *Tracer) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalTracer = t
+}
+
+// Start begins an internal span using the tracer of the current span, or the
+// global tracer. Without a tracer it returns ctx and a nil span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, SpanKindInternal)
+}
+
+// StartKind is like Start with an explicit span kind
+func StartKind(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.tracer.Start(ctx, name, kind)
+	}
+
+	globalMu.RLock()
+	t := globalTracer
+	globalMu.RUnlock()
+	return t.Start(ctx, name, kind)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func readSpans(t *testing.T, path string) map[string]SpanData {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	spans := make(map[string]SpanData)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span SpanData
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		spans[span.Name] = span
+	}
+	require.NoError(t, scanner.Err())
+	return spans
+}
+
+func newFileTracer(t *testing.T, sampleRate float64) (*Tracer, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewFileExporter(path)
+	require.NoError(t, err)
+	return NewTracer(zaptest.NewLogger(t), &TracerConfig{SampleRate: sampleRate, Exporter: exporter}), path
+}
+
+func TestTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	// Future versions may append fields
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	assert.NoError(t, err)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, err := ParseTraceparent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTracerSpans(t *testing.T) {
+	tracer, path := newFileTracer(t, 1)
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	ctx, root := tracer.Start(context.Background(), "root", SpanKindServer)
+	childCtx, child := Start(ctx, "child")
+	child.SetAttribute("provider", "openai")
+	child.RecordError(errors.New("boom"))
+	_, grandchild := Start(childCtx, "grandchild")
+	grandchild.End()
+	child.End()
+	child.End()
+	root.End()
+
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := readSpans(t, path)
+	require.Len(t, spans, 3)
+	assert.Equal(t, spans["root"].TraceID, spans["grandchild"].TraceID)
+	assert.Empty(t, spans["root"].ParentSpanID)
+	assert.Equal(t, spans["root"].SpanID, spans["child"].ParentSpanID)
+	assert.Equal(t, spans["child"].SpanID, spans["grandchild"].ParentSpanID)
+	assert.Equal(t, "openai", spans["child"].Attributes["provider"])
+	assert.Equal(t, "boom", spans["child"].Error)
+	assert.Equal(t, SpanKindServer, spans["root"].Kind)
+}
+
+func TestTracerSampling(t *testing.T) {
+	tracer, path := newFileTracer(t, 0)
+
+	_, span := tracer.Start(context.Background(), "unsampled", SpanKindInternal)
+	assert.True(t, span.SpanContext().IsValid())
+	span.End()
+
+	// A sampled remote parent overrides the local sample rate
+	parent := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	_, span = tracer.Start(ContextWithRemoteParent(context.Background(), parent), "sampled", SpanKindServer)
+	span.End()
+
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := readSpans(t, path)
+	require.Len(t, spans, 1)
+	assert.Equal(t, parent.TraceID.String(), spans["sampled"].TraceID)
+	assert.Equal(t, parent.SpanID.String(), spans["sampled"].ParentSpanID)
+}
+
+func TestStartWithoutTracer(t *testing.T) {
+	ctx, span := Start(context.Background(), "noop")
+	assert.Nil(t, span)
+	assert.Nil(t, SpanFromContext(ctx))
+
+	// Nil spans are safe to use
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("ignored"))
+	span.End()
+}
+
+func TestHTTPPropagation(t *testing.T) {
+	tracer, path := newFileTracer(t, 1)
+
+	var upstreamTraceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get(TraceparentHeader)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+	handler := Middleware(tracer, func(r *http.Request) string { return "/v1/test" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodPost, "/v1/test", nil)
+	req.Header.Set(TraceparentHeader, incoming)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := readSpans(t, path)
+	require.Len(t, spans, 2)
+	server := spans["HTTP POST /v1/test"]
+	clientSpan := spans["HTTP GET "+upstream.Listener.Addr().String()]
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", server.ParentSpanID)
+	assert.Equal(t, float64(http.StatusAccepted), server.Attributes["http.status_code"])
+	assert.Equal(t, server.SpanID, clientSpan.ParentSpanID)
+	assert.Equal(t, SpanKindClient, clientSpan.Kind)
+
+	sc, err := ParseTraceparent(upstreamTraceparent)
+	require.NoError(t, err)
+	assert.Equal(t, clientSpan.SpanID, sc.SpanID.String())
+	assert.Contains(t, rec.Header().Get(TraceparentHeader), server.SpanID)
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var got otlpRequest
+	var auth string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(&OTLPConfig{
+		Endpoint:    collector.URL,
+		ServiceName: "test-service",
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+	})
+	tracer := NewTracer(zaptest.NewLogger(t), &TracerConfig{SampleRate: 1, Exporter: exporter})
+
+	_, span := tracer.Start(context.Background(), "proxy.chat", SpanKindInternal)
+	span.SetAttribute("prompt_tokens", 10)
+	span.RecordError(errors.New("failed"))
+	span.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	assert.Equal(t, "Bearer token", auth)
+	require.Len(t, got.ResourceSpans, 1)
+	assert.Equal(t, "service.name", got.ResourceSpans[0].Resource.Attributes[0].Key)
+	assert.Equal(t, "test-service", *got.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	assert.Equal(t, "proxy.chat", spans[0].Name)
+	assert.Len(t, spans[0].TraceID, 32)
+	assert.Equal(t, otlpStatusError, spans[0].Status.Code)
+	assert.Equal(t, "10", *spans[0].Attributes[0].Value.IntValue)
+}
@@ -48,6 +48,12 @@ type ExecuteOptions struct {
 	PresencePenalty  float64
 	Stop            []string
 	Metadata        map[string]interface{}
+
+	// MaxSteps bounds the model turns of a task that calls tools
+	MaxSteps int
+
+	// Session continues the conversation stored under this ID in the agent's memory
+	Session string
 }
 
 // Response represents a response from an agent
@@ -61,9 +67,9 @@ type Response struct {
 
 // Usage contains token usage information
 type Usage struct {
-	PromptTokens     int
-	CompletionTokens int
-	TotalTokens      int
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // WithTemperature sets the temperature for generation
@@ -122,6 +128,21 @@ func WithStop(stop []string) ExecuteOption {
 	}
 }
 
+// WithMaxSteps sets the maximum number of model turns when calling tools
+func WithMaxSteps(steps int) ExecuteOption {
+	return func(o *ExecuteOptions) {
+		o.MaxSteps = steps
+	}
+}
+
+// WithSession continues a conversation kept in the agent's memory, so the
+// task sees the earlier turns of the session and is added to it
+func WithSession(session string) ExecuteOption {
+	return func(o *ExecuteOptions) {
+		o.Session = session
+	}
+}
+
 // WithRetries sets the number of retry attempts
 func WithRetries(retries int) ExecuteOption {
 	return func(o *ExecuteOptions) {
@@ -0,0 +1,152 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Content part types supported in multimodal messages
+const (
+	ContentTypeText     = "text"
+	ContentTypeImageURL = "image_url"
+	ContentTypeFile     = "file"
+)
+
+// ContentPart represents a single part of a multimodal message
+type ContentPart struct {
+	// Type is one of ContentTypeText, ContentTypeImageURL or ContentTypeFile
+	Type string `json:"type"`
+
+	// Text holds the text for text parts
+	Text string `json:"text,omitempty"`
+
+	// ImageURL holds the image reference for image parts
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+
+	// File holds the file reference for file parts
+	File *FileRef `json:"file,omitempty"`
+}
+
+// ImageURL references an image by URL or data: URI
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// FileRef references a file either by provider file ID or inline data: URI
+type FileRef struct {
+	FileID   string `json:"file_id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+}
+
+// TextPart creates a text content part
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: ContentTypeText, Text: text}
+}
+
+// ImagePart creates an image content part from a URL or data: URI
+func ImagePart(url string) ContentPart {
+	return ContentPart{Type: ContentTypeImageURL, ImageURL: &ImageURL{URL: url}}
+}
+
+// FilePart creates a file content part from inline data
+func FilePart(filename, dataURI string) ContentPart {
+	return ContentPart{Type: ContentTypeFile, File: &FileRef{Filename: filename, FileData: dataURI}}
+}
+
+// Text returns the textual content of the message, joining text parts
+// when the message carries multimodal content
+func (m Message) Text() string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+
+	var texts []string
+	for _, part := range m.Parts {
+		if part.Type == ContentTypeText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// MarshalJSON encodes the message content as a string, or as an array of
+// content parts when the message is multimodal
+func (m Message) MarshalJSON() ([]byte, error) {
+	type alias Message
+	var content interface{} = m.Content
+	if len(m.Parts) > 0 {
+		content = m.Parts
+	}
+	return json.Marshal(struct {
+		alias
+		Content interface{} `json:"content"`
+	}{alias(m), content})
+}
+
+// UnmarshalJSON accepts message content either as a string or as an array
+// of content parts
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type alias Message
+	aux := struct {
+		*alias
+		Content json.RawMessage `json:"content"`
+	}{alias: (*alias)(m)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	m.Content = ""
+	m.Parts = nil
+	raw := strings.TrimSpace(string(aux.Content))
+	switch {
+	case raw == "" || raw == "null":
+		return nil
+	case strings.HasPrefix(raw, "["):
+		if err := json.Unmarshal(aux.Content, &m.Parts); err != nil {
+			return fmt.Errorf("invalid content parts: %w", err)
+		}
+		return nil
+	default:
+		if err := json.Unmarshal(aux.Content, &m.Content); err != nil {
+			return fmt.Errorf("content must be a string or an array of parts: %w", err)
+		}
+		return nil
+	}
+}
+
+// ParseDataURI splits a data: URI into its media type and base64 payload.
+// Payloads that are not base64-encoded in the URI are encoded.
+func ParseDataURI(uri string) (mediaType, data string, err error) {
+	if !strings.HasPrefix(uri, "data:") {
+		return "", "", fmt.Errorf("not a data URI")
+	}
+
+	header, payload, found := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !found {
+		return "", "", fmt.Errorf("malformed data URI")
+	}
+
+	isBase64 := strings.HasSuffix(header, ";base64")
+	mediaType = strings.TrimSuffix(header, ";base64")
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	if mediaType == "" {
+		mediaType = "text/plain"
+	}
+
+	if isBase64 {
+		return mediaType, payload, nil
+	}
+
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", "", fmt.Errorf("malformed data URI payload: %w", err)
+	}
+	return mediaType, base64.StdEncoding.EncodeToString([]byte(decoded)), nil
+}
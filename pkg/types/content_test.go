@@ -0,0 +1,83 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageJSON(t *testing.T) {
+	t.Run("string content", func(t *testing.T) {
+		var msg Message
+		require.NoError(t, json.Unmarshal([]byte(`{"role":"user","content":"hello"}`), &msg))
+		assert.Equal(t, "hello", msg.Content)
+		assert.Empty(t, msg.Parts)
+
+		data, err := json.Marshal(msg)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"role":"user","content":"hello"}`, string(data))
+	})
+
+	t.Run("content parts", func(t *testing.T) {
+		input := `{"role":"user","content":[{"type":"text","text":"describe"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]}`
+
+		var msg Message
+		require.NoError(t, json.Unmarshal([]byte(input), &msg))
+		require.Len(t, msg.Parts, 2)
+		assert.Equal(t, ContentTypeImageURL, msg.Parts[1].Type)
+		assert.Equal(t, "describe", msg.Text())
+
+		data, err := json.Marshal(msg)
+		require.NoError(t, err)
+		assert.JSONEq(t, input, string(data))
+	})
+
+	t.Run("tool calls", func(t *testing.T) {
+		input := `{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"file_reader","arguments":"{\"path\":\"go.mod\"}"}}]}`
+
+		var msg Message
+		require.NoError(t, json.Unmarshal([]byte(input), &msg))
+		require.Len(t, msg.ToolCalls, 1)
+		assert.Equal(t, "file_reader", msg.ToolCalls[0].Function.Name)
+		assert.Equal(t, `{"path":"go.mod"}`, msg.ToolCalls[0].Function.Arguments)
+
+		data, err := json.Marshal(Message{Role: "tool", Content: "module x", ToolCallID: "call_1", Name: "file_reader"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"role":"tool","content":"module x","tool_call_id":"call_1","name":"file_reader"}`, string(data))
+	})
+
+	t.Run("invalid content", func(t *testing.T) {
+		var msg Message
+		assert.Error(t, json.Unmarshal([]byte(`{"role":"user","content":42}`), &msg))
+	})
+}
+
+func TestParseDataURI(t *testing.T) {
+	testCases := []struct {
+		name      string
+		uri       string
+		mediaType string
+		data      string
+		wantErr   bool
+	}{
+		{name: "base64", uri: "data:image/png;base64,AAAA", mediaType: "image/png", data: "AAAA"},
+		{name: "plain text", uri: "data:,hi", mediaType: "text/plain", data: "aGk="},
+		{name: "not a data URI", uri: "https://example.com/a.png", wantErr: true},
+		{name: "missing payload", uri: "data:image/png;base64", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mediaType, data, err := ParseDataURI(tc.uri)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.mediaType, mediaType)
+			assert.Equal(t, tc.data, data)
+		})
+	}
+}
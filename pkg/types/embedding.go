@@ -0,0 +1,66 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// EmbeddingInput holds the texts to embed. It accepts either a single
+// string or an array of strings in JSON.
+type EmbeddingInput []string
+
+// UnmarshalJSON decodes a string or an array of strings
+func (in *EmbeddingInput) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*in = EmbeddingInput{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("input must be a string or an array of strings: %w", err)
+	}
+	*in = many
+	return nil
+}
+
+// Embedding encoding formats
+const (
+	EncodingFormatFloat  = "float"
+	EncodingFormatBase64 = "base64"
+)
+
+// EmbeddingRequest represents a standardized request format for embeddings.
+// Providers always return float vectors; a base64 EncodingFormat only
+// changes how the API encodes them in its response.
+type EmbeddingRequest struct {
+	Model          string         `json:"model"`
+	Input          EmbeddingInput `json:"input"`
+	EncodingFormat string         `json:"encoding_format,omitempty"`
+	Dimensions     int            `json:"dimensions,omitempty"`
+	User           string         `json:"user,omitempty"`
+}
+
+// EmbeddingResponse represents a standardized response format for embeddings
+type EmbeddingResponse struct {
+	Object string      `json:"object"`
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  Usage       `json:"usage"`
+}
+
+// Embedding is a single embedding vector, indexed by its position in the input
+type Embedding struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// EmbeddingProvider is implemented by providers that can compute embeddings.
+// It is optional; callers should type-assert a Provider to check for support.
+type EmbeddingProvider interface {
+	// Embed computes embeddings for every input in the request
+	Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// StatusError is returned by providers when the upstream API answers with
+// an unsuccessful HTTP status
+type StatusError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+
+	// Body is the response body, which usually describes the error
+	Body string
+}
+
+// Error implements error
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the status shows the provider failing rather
+// than the request: a server error or rate limiting
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,11 @@
+package types
+
+import "context"
+
+// HealthChecker is implemented by providers that support a lightweight
+// liveness probe, such as listing models. It is optional; callers should
+// type-assert a Provider to check for support.
+type HealthChecker interface {
+	// HealthCheck returns an error if the provider cannot currently serve requests
+	HealthCheck(ctx context.Context) error
+}
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"context"
+)
+
+// Memory stores the conversation turns of agent sessions
+type Memory interface {
+	// Load returns the messages of a session, oldest first
+	Load(ctx context.Context, session string) ([]Message, error)
+
+	// Append adds messages to the end of a session
+	Append(ctx context.Context, session string, messages ...Message) error
+
+	// Clear removes a session and its messages
+	Clear(ctx context.Context, session string) error
+
+	// Sessions returns the IDs of the stored sessions
+	Sessions(ctx context.Context) ([]string, error)
+
+	// Close releases the resources held by the memory
+	Close() error
+}
@@ -5,10 +5,35 @@ import (
 	"context"
 )
 
-// Message represents a standardized chat message format
+// Message represents a standardized chat message format.
+// Content holds plain text; Parts holds multimodal content and, when set,
+// takes precedence over Content on the wire.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string        `json:"role"`
+	Content string        `json:"content"`
+	Parts   []ContentPart `json:"-"`
+
+	// ToolCalls are the tools an assistant message asks to call
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// ToolCallID and Name identify the call a tool message answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+// PromptMessages converts a legacy prompt-style input into chat messages.
+// A non-empty suffix is passed as a system instruction, since chat models
+// have no native support for inserting text before a suffix.
+func PromptMessages(prompt, suffix string) []Message {
+	messages := make([]Message, 0, 2)
+	if suffix != "" {
+		messages = append(messages, Message{
+			Role: "system",
+			Content: "Continue the text provided by the user. Your output will be inserted " +
+				"between that text and the following suffix, so it must connect naturally to it:\n\n" + suffix,
+		})
+	}
+	return append(messages, Message{Role: "user", Content: prompt})
 }
 
 // ChatRequest represents a standardized request format for chat completions
@@ -17,7 +42,18 @@ type ChatRequest struct {
 	Messages    []Message  `json:"messages"`
 	MaxTokens   int        `json:"max_tokens,omitempty"`
 	Temperature float32    `json:"temperature,omitempty"`
+	TopP        float32    `json:"top_p,omitempty"`
+	Stop        []string   `json:"stop,omitempty"`
 	Stream      bool       `json:"stream,omitempty"`
+
+	FrequencyPenalty float32 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  float32 `json:"presence_penalty,omitempty"`
+
+	// Tools are the functions the model may call
+	Tools []ToolDefinition `json:"tools,omitempty"`
+
+	// ToolChoice controls whether and which tools the model calls
+	ToolChoice *ToolChoice `json:"tool_choice,omitempty"`
 }
 
 // ChatResponse represents a standardized response format for chat completions
@@ -28,6 +64,10 @@ type ChatResponse struct {
 	Model   string    `json:"model"`
 	Choices []Choice  `json:"choices"`
 	Usage   Usage     `json:"usage"`
+
+	// Error is set on the last chunk of a stream that failed after it
+	// started; such a chunk carries no choices
+	Error error `json:"-"`
 }
 
 // Choice represents a completion choice in the response
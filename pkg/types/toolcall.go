@@ -0,0 +1,104 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ToolTypeFunction is the only tool type supported by chat providers
+const ToolTypeFunction = "function"
+
+// Tool choice modes
+const (
+	// ToolChoiceNone forbids tool calls
+	ToolChoiceNone = "none"
+
+	// ToolChoiceAuto lets the model decide whether to call tools
+	ToolChoiceAuto = "auto"
+
+	// ToolChoiceRequired makes the model call at least one tool
+	ToolChoiceRequired = "required"
+)
+
+// ToolChoice controls whether and which tools the model calls. It is a mode,
+// or names the one function the model must call.
+type ToolChoice struct {
+	Mode     string
+	Function string
+}
+
+// MarshalJSON encodes the choice as a mode string, or as an object naming the function
+func (c ToolChoice) MarshalJSON() ([]byte, error) {
+	if c.Function == "" {
+		return json.Marshal(c.Mode)
+	}
+	return json.Marshal(map[string]interface{}{
+		"type":     ToolTypeFunction,
+		"function": map[string]string{"name": c.Function},
+	})
+}
+
+// UnmarshalJSON decodes a mode string or an object naming a function
+func (c *ToolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		*c = ToolChoice{Mode: mode}
+		return nil
+	}
+
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &named); err != nil || named.Function.Name == "" {
+		return fmt.Errorf("tool_choice must be a mode or name a function")
+	}
+	*c = ToolChoice{Function: named.Function.Name}
+	return nil
+}
+
+// ToolDefinition advertises a function the model may call
+type ToolDefinition struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition describes a callable function and its parameters
+type FunctionDefinition struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  *ToolSchema `json:"parameters"`
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	// Index identifies the call a streamed fragment belongs to; it is unset
+	// outside streams
+	Index    *int         `json:"index,omitempty"`
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall names the called function and holds its JSON-encoded arguments
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolDefinitionFor advertises a tool as a function taking its schema as parameters
+func ToolDefinitionFor(tool Tool) ToolDefinition {
+	schema := tool.Schema()
+	if schema == nil {
+		schema = NewToolSchema()
+	}
+	return ToolDefinition{
+		Type: ToolTypeFunction,
+		Function: FunctionDefinition{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  schema,
+		},
+	}
+}
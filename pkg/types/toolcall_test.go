@@ -0,0 +1,66 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubTool is a tool with a fixed schema
+type stubTool struct {
+	schema *ToolSchema
+}
+
+func (t *stubTool) Name() string                         { return "lookup" }
+func (t *stubTool) Description() string                  { return "Looks things up" }
+func (t *stubTool) Initialize(ctx context.Context) error { return nil }
+func (t *stubTool) Cleanup(ctx context.Context) error    { return nil }
+func (t *stubTool) Schema() *ToolSchema                  { return t.schema }
+func (t *stubTool) Version() string                      { return "1.0.0" }
+
+func (t *stubTool) Execute(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func TestToolDefinitionFor(t *testing.T) {
+	schema := NewToolSchema().
+		AddProperty("query", NewPropertySchema("string")).
+		AddRequired("query")
+
+	data, err := json.Marshal(ToolDefinitionFor(&stubTool{schema: schema}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "function",
+		"function": {
+			"name": "lookup",
+			"description": "Looks things up",
+			"parameters": {"type": "object", "properties": {"query": {"type": "string"}}, "required": ["query"]}
+		}
+	}`, string(data))
+
+	// Tools without a schema take no arguments
+	definition := ToolDefinitionFor(&stubTool{})
+	assert.Equal(t, "object", definition.Function.Parameters.Type)
+	assert.Empty(t, definition.Function.Parameters.Properties)
+}
+
+func TestToolChoiceJSON(t *testing.T) {
+	for input, want := range map[string]ToolChoice{
+		`"auto"`: {Mode: ToolChoiceAuto},
+		`{"type":"function","function":{"name":"lookup"}}`: {Function: "lookup"},
+	} {
+		var choice ToolChoice
+		require.NoError(t, json.Unmarshal([]byte(input), &choice))
+		assert.Equal(t, want, choice)
+
+		data, err := json.Marshal(choice)
+		require.NoError(t, err)
+		assert.JSONEq(t, input, string(data))
+	}
+
+	var choice ToolChoice
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"function"}`), &choice))
+}
@@ -3,8 +3,11 @@ package integration
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -12,6 +15,7 @@ import (
 	"time"
 
 	"github.com/pimentel/peppergo/internal/api"
+	"github.com/pimentel/peppergo/internal/metrics"
 	"github.com/pimentel/peppergo/internal/proxy"
 	"github.com/pimentel/peppergo/pkg/types"
 	"github.com/stretchr/testify/suite"
@@ -20,8 +24,9 @@ import (
 
 type ProxyTestSuite struct {
 	suite.Suite
-	server *httptest.Server
-	proxy  *proxy.Service
+	server   *httptest.Server
+	proxy    *proxy.Service
+	embedder *MockEmbeddingProvider
 }
 
 func TestProxySuite(t *testing.T) {
@@ -41,8 +46,18 @@ func (s *ProxyTestSuite) SetupSuite() {
 	err := s.proxy.RegisterProvider(mockProvider)
 	s.Require().NoError(err)
 
+	// Register embedding provider with a small batch size to exercise batching
+	s.embedder = &MockEmbeddingProvider{MockProvider: MockProvider{name: "mock-embed", models: []string{"embed-model"}}}
+	err = s.proxy.RegisterProvider(s.embedder)
+	s.Require().NoError(err)
+	s.proxy.SetEmbeddingBatchSize(2)
+
+	// Record metrics
+	registry := metrics.NewRegistry()
+	s.proxy.SetMetrics(proxy.NewMetrics(registry))
+
 	// Create API handler
-	handler := api.NewHandler(s.proxy)
+	handler := api.NewHandler(s.proxy, api.WithMetrics(registry), api.WithTenantHeader())
 
 	// Create test server
 	s.server = httptest.NewServer(handler.Router())
@@ -182,12 +197,368 @@ func (s *ProxyTestSuite) TestListProviders() {
 	s.Equal(http.StatusOK, resp.StatusCode)
 
 	// Parse response
-	var result map[string][]string
+	var result map[string][]proxy.ProviderStatus
 	err = json.NewDecoder(resp.Body).Decode(&result)
 	s.Require().NoError(err)
 
 	// Verify response
-	s.Contains(result["providers"], "mock")
+	var names []string
+	for _, provider := range result["providers"] {
+		names = append(names, provider.Name)
+	}
+	s.Contains(names, "mock")
+}
+
+func (s *ProxyTestSuite) TestHealth() {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	resp, err := client.Get(s.server.URL + "/healthz")
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = client.Get(s.server.URL + "/readyz")
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = client.Get(s.server.URL + "/v1/providers/mock/status")
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	var status proxy.ProviderStatus
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&status))
+	s.Equal("mock", status.Name)
+	s.Equal(proxy.CircuitClosed, status.Circuit)
+	s.Equal([]string{"test-model"}, status.Models)
+
+	resp, err = client.Get(s.server.URL + "/v1/providers/missing/status")
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *ProxyTestSuite) TestMetrics() {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	body := []byte(`{"model":"test-model","messages":[{"role":"user","content":"Hello!"}]}`)
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/v1/chat/completions", bytes.NewBuffer(body))
+	s.Require().NoError(err)
+	req.Header.Set("X-Provider", "mock")
+	req.Header.Set("X-Tenant", "metrics-tenant")
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = client.Get(s.server.URL + "/metrics")
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(metrics.ContentType, resp.Header.Get("Content-Type"))
+
+	var out bytes.Buffer
+	_, err = out.ReadFrom(resp.Body)
+	s.Require().NoError(err)
+
+	s.Contains(out.String(), `peppergo_requests_total{provider="mock",model="test-model",tenant="metrics-tenant",operation="chat",status="success"} 1`)
+	s.Contains(out.String(), `peppergo_tokens_total{provider="mock",model="test-model",tenant="metrics-tenant",type="prompt"} 10`)
+	s.Contains(out.String(), `peppergo_http_requests_total{method="POST",route="/v1/chat/completions",code="200"}`)
+}
+
+func (s *ProxyTestSuite) TestEmbeddings() {
+	body := []byte(`{"model":"embed-model","input":["a","bb","ccc","dddd","eeeee"]}`)
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/v1/embeddings", bytes.NewBuffer(body))
+	s.Require().NoError(err)
+	req.Header.Set("X-Provider", "mock-embed")
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	var embedResp types.EmbeddingResponse
+	err = json.NewDecoder(resp.Body).Decode(&embedResp)
+	s.Require().NoError(err)
+
+	// Five inputs with a batch size of two means three provider calls
+	s.Equal(3, s.embedder.calls)
+	s.Require().Len(embedResp.Data, 5)
+	for i, embedding := range embedResp.Data {
+		s.Equal(i, embedding.Index)
+		s.Equal(float32(i+1), embedding.Embedding[0])
+	}
+	s.Equal(15, embedResp.Usage.TotalTokens)
+}
+
+func (s *ProxyTestSuite) TestEmbeddingsBase64() {
+	client := &http.Client{Timeout: 5 * time.Second}
+	post := func(body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, s.server.URL+"/v1/embeddings", strings.NewReader(body))
+		s.Require().NoError(err)
+		req.Header.Set("X-Provider", "mock-embed")
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		return resp
+	}
+
+	resp := post(`{"model":"embed-model","input":["a","bb"],"encoding_format":"base64"}`)
+	defer resp.Body.Close()
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var embedResp struct {
+		Data []struct {
+			Index     int    `json:"index"`
+			Embedding string `json:"embedding"`
+		} `json:"data"`
+	}
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&embedResp))
+	s.Require().Len(embedResp.Data, 2)
+	raw, err := base64.StdEncoding.DecodeString(embedResp.Data[1].Embedding)
+	s.Require().NoError(err)
+	s.Require().GreaterOrEqual(len(raw), 4)
+	s.Equal(float32(2), math.Float32frombits(binary.LittleEndian.Uint32(raw)))
+
+	resp = post(`{"model":"embed-model","input":"a","encoding_format":"int8"}`)
+	defer resp.Body.Close()
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (s *ProxyTestSuite) TestEmbeddingsUnsupportedProvider() {
+	body := []byte(`{"model":"test-model","input":"hello"}`)
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/v1/embeddings?provider=mock", bytes.NewBuffer(body))
+	s.Require().NoError(err)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (s *ProxyTestSuite) TestAnthropicMessages() {
+	body := []byte(`{
+		"model": "test-model",
+		"max_tokens": 100,
+		"system": [{"type": "text", "text": "Be nice."}],
+		"messages": [{"role": "user", "content": [
+			{"type": "text", "text": "What is this?"},
+			{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "AAAA"}}
+		]}]
+	}`)
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/v1/messages", bytes.NewBuffer(body))
+	s.Require().NoError(err)
+	req.Header.Set("X-Provider", "mock")
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	var msgResp struct {
+		Type       string `json:"type"`
+		Role       string `json:"role"`
+		StopReason string `json:"stop_reason"`
+		Content    []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&msgResp)
+	s.Require().NoError(err)
+
+	s.Equal("message", msgResp.Type)
+	s.Equal("assistant", msgResp.Role)
+	s.Equal("end_turn", msgResp.StopReason)
+	s.Require().Len(msgResp.Content, 1)
+	s.Equal("Hello! I am a mock response.", msgResp.Content[0].Text)
+	s.Equal(10, msgResp.Usage.InputTokens)
+}
+
+func (s *ProxyTestSuite) TestAnthropicMessagesStream() {
+	body := []byte(`{"model":"test-model","max_tokens":100,"stream":true,"messages":[{"role":"user","content":"Hello!"}]}`)
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/v1/messages", bytes.NewBuffer(body))
+	s.Require().NoError(err)
+	req.Header.Set("X-Provider", "mock")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+	var eventTypes []string
+	var text strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var event struct {
+			Type  string `json:"type"`
+			Delta struct {
+				Text       string `json:"text"`
+				StopReason string `json:"stop_reason"`
+			} `json:"delta"`
+		}
+		s.Require().NoError(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+		eventTypes = append(eventTypes, event.Type)
+		if event.Type == "content_block_delta" {
+			text.WriteString(event.Delta.Text)
+		}
+		if event.Type == "message_delta" {
+			s.Equal("end_turn", event.Delta.StopReason)
+		}
+	}
+	s.Require().NoError(scanner.Err())
+
+	s.Equal("message_start", eventTypes[0])
+	s.Equal("content_block_start", eventTypes[1])
+	s.Equal("message_stop", eventTypes[len(eventTypes)-1])
+	s.Equal("Hello! I am a mock response. ", text.String())
+}
+
+func (s *ProxyTestSuite) TestAnthropicMessagesMissingMaxTokens() {
+	body := []byte(`{"model":"test-model","messages":[{"role":"user","content":"Hello!"}]}`)
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/v1/messages?provider=mock", bytes.NewBuffer(body))
+	s.Require().NoError(err)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	var errResp struct {
+		Type  string `json:"type"`
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&errResp))
+	s.Equal("error", errResp.Type)
+	s.Equal("invalid_request_error", errResp.Error.Type)
+}
+
+func (s *ProxyTestSuite) TestCompletions() {
+	body := []byte(`{"model":"test-model","prompt":["Say hi","Say bye"],"echo":true,"stop":"\n"}`)
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/v1/completions", bytes.NewBuffer(body))
+	s.Require().NoError(err)
+	req.Header.Set("X-Provider", "mock")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	var completion struct {
+		Object  string `json:"object"`
+		Choices []struct {
+			Text         string `json:"text"`
+			Index        int    `json:"index"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage types.Usage `json:"usage"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&completion)
+	s.Require().NoError(err)
+
+	s.Equal("text_completion", completion.Object)
+	s.Require().Len(completion.Choices, 2)
+	s.Equal("Say hiHello! I am a mock response.", completion.Choices[0].Text)
+	s.Equal(1, completion.Choices[1].Index)
+	s.Equal("stop", completion.Choices[1].FinishReason)
+	s.Equal(40, completion.Usage.TotalTokens)
+}
+
+func (s *ProxyTestSuite) TestStreamCompletions() {
+	body := []byte(`{"model":"test-model","prompt":"Say hi","stream":true}`)
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+"/v1/completions", bytes.NewBuffer(body))
+	s.Require().NoError(err)
+	req.Header.Set("X-Provider", "mock")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	s.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+	var text strings.Builder
+	done := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		data := strings.TrimPrefix(line, "data: ")
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+
+		var chunk struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Text string `json:"text"`
+			} `json:"choices"`
+		}
+		s.Require().NoError(json.Unmarshal([]byte(data), &chunk))
+		s.Equal("text_completion", chunk.Object)
+		text.WriteString(chunk.Choices[0].Text)
+	}
+
+	s.True(done)
+	s.Equal("Hello! I am a mock response. ", text.String())
+}
+
+// MockEmbeddingProvider implements types.EmbeddingProvider for testing.
+// Each embedding holds the input length so ordering can be verified.
+type MockEmbeddingProvider struct {
+	MockProvider
+	calls int
+}
+
+func (p *MockEmbeddingProvider) Embed(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	p.calls++
+	resp := &types.EmbeddingResponse{Object: "list", Model: req.Model}
+	for i, input := range req.Input {
+		resp.Data = append(resp.Data, types.Embedding{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: []float32{float32(len(input))},
+		})
+		resp.Usage.PromptTokens += len(input)
+		resp.Usage.TotalTokens += len(input)
+	}
+	return resp, nil
 }
 
 // MockProvider implements the types.Provider interface for testing